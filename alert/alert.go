@@ -0,0 +1,79 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alert notifies webhooks, mailboxes and syslog servers when the
+// health of a service changes.
+package alert
+
+import (
+	"fmt"
+	"time"
+)
+
+// Alert types
+const (
+	TypeHealth    = "health"    // a health check of an instance failed or recovered
+	TypeLifecycle = "lifecycle" // an instance started, stopped or restarted
+)
+
+// Severities, from lowest to highest
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+var severities = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityError:    2,
+	SeverityCritical: 3,
+}
+
+// ValidSeverity returns an error if severity is unknown
+func ValidSeverity(severity string) error {
+	if _, ok := severities[severity]; !ok {
+		return fmt.Errorf("unknown severity %q", severity)
+	}
+	return nil
+}
+
+// Alert describes a change in the health of a service instance
+type Alert struct {
+	Type        string
+	Name        string // health check name or lifecycle event
+	Severity    string
+	PoolID      string
+	TenantID    string
+	ServiceID   string
+	ServiceName string
+	InstanceID  string
+	Summary     string
+	Cleared     bool // the problem reported by an earlier alert went away
+	Timestamp   time.Time
+}
+
+// key identifies the alerts that report on the same problem
+func (a Alert) key() string {
+	return a.Type + "/" + a.ServiceID + "/" + a.InstanceID + "/" + a.Name
+}
+
+// Subject is a one line description of the alert
+func (a Alert) Subject() string {
+	status := a.Severity
+	if a.Cleared {
+		status = "cleared"
+	}
+	return fmt.Sprintf("[%s] %s: %s", status, a.ServiceName, a.Summary)
+}
@@ -0,0 +1,193 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		Sinks: []SinkConfig{
+			{Name: "hook", Type: SinkWebhook, URL: "http://example.com/hook"},
+			{Name: "mail", Type: SinkSMTP, Address: "mail:25", From: "cc@example.com", To: []string{"ops@example.com"}},
+			{Name: "log", Type: SinkSyslog, Address: "loghost:514", Network: "tcp"},
+		},
+		Routes: []Route{{Severity: SeverityError, Sinks: []string{"hook", "mail", "log"}}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i, config := range []Config{
+		{Sinks: []SinkConfig{{Type: SinkWebhook, URL: "http://x"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: "pager"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkWebhook}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkSMTP, Address: "mail:25"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkSyslog, Address: "x:514", Network: "unix"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkWebhook, URL: "http://x"}, {Name: "a", Type: SinkWebhook, URL: "http://y"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkWebhook, URL: "http://x"}}, Routes: []Route{{Sinks: []string{"b"}}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkWebhook, URL: "http://x"}}, Routes: []Route{{Severity: "fatal", Sinks: []string{"a"}}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkWebhook, URL: "http://x"}}, Routes: []Route{{}}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("config %d: expected an error", i)
+		}
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	a := Alert{Severity: SeverityError, PoolID: "default", TenantID: "t1", ServiceID: "s1", ServiceName: "zope"}
+	for i, tc := range []struct {
+		route   Route
+		matches bool
+	}{
+		{Route{}, true},
+		{Route{Severity: SeverityWarning}, true},
+		{Route{Severity: SeverityError}, true},
+		{Route{Severity: SeverityCritical}, false},
+		{Route{Pools: []string{"default"}}, true},
+		{Route{Pools: []string{"other"}}, false},
+		{Route{Tenants: []string{"t2", "t1"}}, true},
+		{Route{Services: []string{"zope"}}, true},
+		{Route{Services: []string{"s1"}}, true},
+		{Route{Services: []string{"redis"}}, false},
+		{Route{Pools: []string{"default"}, Services: []string{"redis"}}, false},
+	} {
+		if tc.route.Matches(a) != tc.matches {
+			t.Errorf("route %d: expected match %t", i, tc.matches)
+		}
+	}
+}
+
+func TestNotifierDedup(t *testing.T) {
+	n, err := NewNotifier(Config{DedupWindow: 60})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	now := time.Now()
+	failed := Alert{Type: TypeHealth, Name: "ready", ServiceID: "s1", InstanceID: "0", Timestamp: now}
+	cleared := failed
+	cleared.Cleared = true
+
+	for i, tc := range []struct {
+		alert Alert
+		after time.Duration
+		isNew bool
+	}{
+		{cleared, 0, false}, // never reported
+		{failed, 0, true},
+		{failed, 30 * time.Second, false},
+		{failed, 90 * time.Second, true}, // outside the window
+		{cleared, 100 * time.Second, true},
+		{cleared, 110 * time.Second, false},
+		{failed, 120 * time.Second, true},
+	} {
+		tc.alert.Timestamp = now.Add(tc.after)
+		if n.isNew(tc.alert) != tc.isNew {
+			t.Errorf("alert %d: expected new %t", i, tc.isNew)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := &rateLimiter{limit: 2, period: time.Minute}
+	now := time.Now()
+	if !r.allow(now) || !r.allow(now.Add(time.Second)) {
+		t.Fatalf("expected the first two events to be allowed")
+	}
+	if r.allow(now.Add(2 * time.Second)) {
+		t.Errorf("expected the third event to be limited")
+	}
+	if !r.allow(now.Add(61 * time.Second)) {
+		t.Errorf("expected an event to be allowed after the period")
+	}
+}
+
+func TestSyslogMessage(t *testing.T) {
+	a := Alert{
+		Type:        TypeHealth,
+		Name:        "ready",
+		Severity:    SeverityError,
+		PoolID:      "default",
+		ServiceID:   "s1",
+		ServiceName: "zope",
+		InstanceID:  "0",
+		Summary:     `health check "ready" failed`,
+		Timestamp:   time.Date(2015, 3, 2, 10, 4, 5, 0, time.UTC),
+	}
+	expected := `<27>1 2015-03-02T10:04:05.000000Z host1 serviced 42 health [serviced@32473 type="health" name="ready" severity="error" cleared="false" pool="default" tenant="" service="s1" instance="0"] [error] zope: health check "ready" failed`
+	if msg := syslogMessage("host1", 42, a); msg != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, msg)
+	}
+
+	a.Cleared = true
+	a.Name = `a"b]`
+	msg := syslogMessage("host1", 42, a)
+	if !strings.HasPrefix(msg, "<29>1 ") {
+		t.Errorf("expected notice priority for a cleared alert: %s", msg)
+	}
+	if !strings.Contains(msg, `name="a\"b\]"`) {
+		t.Errorf("expected escaped param value: %s", msg)
+	}
+}
+
+func TestNotifierWebhook(t *testing.T) {
+	received := make(chan Alert, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- a
+	}))
+	defer server.Close()
+
+	n, err := NewNotifier(Config{
+		Sinks: []SinkConfig{
+			{Name: "hook", Type: SinkWebhook, URL: server.URL, Headers: map[string]string{"X-Token": "secret"}, RateLimit: 2},
+		},
+		Routes: []Route{
+			{Severity: SeverityWarning, Sinks: []string{"hook"}},
+			{Services: []string{"zope"}, Sinks: []string{"hook"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	n.send(Alert{Type: TypeLifecycle, Name: "started", Severity: SeverityInfo, ServiceID: "s2", ServiceName: "redis"})
+	n.send(Alert{Type: TypeHealth, Name: "ready", Severity: SeverityError, ServiceID: "s1", ServiceName: "zope"})
+	n.send(Alert{Type: TypeHealth, Name: "alive", Severity: SeverityError, ServiceID: "s1", ServiceName: "zope"})
+	n.send(Alert{Type: TypeHealth, Name: "ping", Severity: SeverityError, ServiceID: "s1", ServiceName: "zope"})
+
+	// the info alert is not routed, the error alerts match both routes but
+	// are sent once each, and the third is over the rate limit
+	if len(received) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(received))
+	}
+	if a := <-received; a.Name != "ready" || a.ServiceName != "zope" {
+		t.Errorf("unexpected alert %+v", a)
+	}
+}
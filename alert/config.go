@@ -0,0 +1,161 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Sink types
+const (
+	SinkWebhook = "webhook"
+	SinkSMTP    = "smtp"
+	SinkSyslog  = "syslog"
+)
+
+// DefaultDedupWindow is how long, in seconds, a repeated alert is suppressed
+const DefaultDedupWindow = 3600
+
+// DefaultRateLimit is the number of alerts a sink may send per minute
+const DefaultRateLimit = 20
+
+// SinkConfig describes where alerts are sent
+type SinkConfig struct {
+	Name      string
+	Type      string            // webhook, smtp or syslog
+	URL       string            // webhook: url that alerts are POSTed to as json
+	Headers   map[string]string // webhook: extra request headers
+	Address   string            // smtp: mail server host:port; syslog: server host:port
+	Network   string            // syslog: udp (default) or tcp
+	From      string            // smtp: sender address
+	To        []string          // smtp: recipient addresses
+	Username  string            // smtp: optional PLAIN auth
+	Password  string            // smtp: optional PLAIN auth
+	RateLimit int               // alerts per minute; defaults to DefaultRateLimit
+}
+
+// Route sends the alerts that match all of its conditions to sinks.  Empty
+// conditions match every alert.
+type Route struct {
+	Pools    []string // pool ids
+	Tenants  []string // tenant service ids
+	Services []string // service ids or names
+	Severity string   // minimum severity; defaults to info
+	Sinks    []string // names of the sinks
+}
+
+// Config is the alert configuration of the master
+type Config struct {
+	Sinks       []SinkConfig
+	Routes      []Route
+	DedupWindow int // seconds; defaults to DefaultDedupWindow
+}
+
+// LoadConfig reads the json config at path
+func LoadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var config Config
+	if err := json.NewDecoder(file).Decode(&config); err != nil {
+		return nil, fmt.Errorf("could not parse alert config %s: %s", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks the sinks and routes of the config
+func (c Config) Validate() error {
+	names := make(map[string]bool)
+	for _, sink := range c.Sinks {
+		if sink.Name == "" {
+			return fmt.Errorf("alert sink has no name")
+		} else if names[sink.Name] {
+			return fmt.Errorf("duplicate alert sink %s", sink.Name)
+		}
+		names[sink.Name] = true
+
+		switch sink.Type {
+		case SinkWebhook:
+			if sink.URL == "" {
+				return fmt.Errorf("webhook sink %s has no URL", sink.Name)
+			}
+		case SinkSMTP:
+			if sink.Address == "" || sink.From == "" || len(sink.To) == 0 {
+				return fmt.Errorf("smtp sink %s needs Address, From and To", sink.Name)
+			}
+		case SinkSyslog:
+			if sink.Address == "" {
+				return fmt.Errorf("syslog sink %s has no Address", sink.Name)
+			}
+			if sink.Network != "" && sink.Network != "udp" && sink.Network != "tcp" {
+				return fmt.Errorf("syslog sink %s: unsupported network %s", sink.Name, sink.Network)
+			}
+		default:
+			return fmt.Errorf("alert sink %s has unknown type %q", sink.Name, sink.Type)
+		}
+		if sink.RateLimit < 0 {
+			return fmt.Errorf("alert sink %s has a negative rate limit", sink.Name)
+		}
+	}
+
+	for i, route := range c.Routes {
+		if route.Severity != "" {
+			if err := ValidSeverity(route.Severity); err != nil {
+				return fmt.Errorf("alert route %d: %s", i, err)
+			}
+		}
+		if len(route.Sinks) == 0 {
+			return fmt.Errorf("alert route %d has no sinks", i)
+		}
+		for _, name := range route.Sinks {
+			if !names[name] {
+				return fmt.Errorf("alert route %d: unknown sink %s", i, name)
+			}
+		}
+	}
+
+	if c.DedupWindow < 0 {
+		return fmt.Errorf("negative alert DedupWindow")
+	}
+	return nil
+}
+
+// Matches returns true if the alert should be sent to the sinks of the route
+func (r Route) Matches(a Alert) bool {
+	if r.Severity != "" && severities[a.Severity] < severities[r.Severity] {
+		return false
+	}
+	return matchAny(r.Pools, a.PoolID) && matchAny(r.Tenants, a.TenantID) &&
+		(matchAny(r.Services, a.ServiceID) || matchAny(r.Services, a.ServiceName))
+}
+
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
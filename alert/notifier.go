@@ -0,0 +1,176 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alert
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zenoss/glog"
+)
+
+// queueSize is the number of alerts waiting to be sent before new alerts
+// are dropped
+const queueSize = 1000
+
+// Notifier routes alerts to sinks, suppressing repeats and limiting the rate
+// at which each sink is sent alerts.  A nil Notifier drops every alert.
+type Notifier struct {
+	routes      []Route
+	sinks       map[string]Sink
+	limits      map[string]*rateLimiter
+	dedupWindow time.Duration
+	queue       chan Alert
+
+	mu   sync.Mutex
+	sent map[string]time.Time // when each open problem was last reported
+}
+
+// NewNotifier creates the sinks of the config
+func NewNotifier(config Config) (*Notifier, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		routes:      config.Routes,
+		sinks:       make(map[string]Sink),
+		limits:      make(map[string]*rateLimiter),
+		dedupWindow: time.Duration(config.DedupWindow) * time.Second,
+		queue:       make(chan Alert, queueSize),
+		sent:        make(map[string]time.Time),
+	}
+	if n.dedupWindow == 0 {
+		n.dedupWindow = DefaultDedupWindow * time.Second
+	}
+	for _, sc := range config.Sinks {
+		sink, err := NewSink(sc)
+		if err != nil {
+			return nil, err
+		}
+		n.sinks[sc.Name] = sink
+		limit := sc.RateLimit
+		if limit == 0 {
+			limit = DefaultRateLimit
+		}
+		n.limits[sc.Name] = &rateLimiter{limit: limit, period: time.Minute}
+	}
+	return n, nil
+}
+
+// Notify queues an alert to be sent
+func (n *Notifier) Notify(a Alert) {
+	if n == nil {
+		return
+	}
+	if a.Timestamp.IsZero() {
+		a.Timestamp = time.Now().UTC()
+	}
+	select {
+	case n.queue <- a:
+	default:
+		glog.Warningf("Alert queue is full, dropping alert %s", a.Subject())
+	}
+}
+
+// Run sends queued alerts until shutdown
+func (n *Notifier) Run(shutdown <-chan interface{}) {
+	for {
+		select {
+		case <-shutdown:
+			return
+		case a := <-n.queue:
+			n.send(a)
+		}
+	}
+}
+
+// send delivers the alert to the sinks of every matching route
+func (n *Notifier) send(a Alert) {
+	if !n.isNew(a) {
+		glog.V(2).Infof("Suppressing repeated alert %s", a.Subject())
+		return
+	}
+
+	for _, name := range n.sinksFor(a) {
+		if !n.limits[name].allow(a.Timestamp) {
+			glog.Warningf("Alert sink %s exceeded its rate limit, dropping alert %s", name, a.Subject())
+			continue
+		}
+		if err := n.sinks[name].Send(a); err != nil {
+			glog.Errorf("Could not send alert %s to sink %s: %s", a.Subject(), name, err)
+		} else {
+			glog.V(1).Infof("Sent alert %s to sink %s", a.Subject(), name)
+		}
+	}
+}
+
+// sinksFor returns the names of the sinks the alert is routed to, each once
+func (n *Notifier) sinksFor(a Alert) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, route := range n.routes {
+		if !route.Matches(a) {
+			continue
+		}
+		for _, name := range route.Sinks {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// isNew returns false for an alert that repeats the last alert about the
+// same problem within the dedup window, and for the clearing of a problem
+// that was never reported.
+func (n *Notifier) isNew(a Alert) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	last, ok := n.sent[a.key()]
+	if a.Cleared {
+		delete(n.sent, a.key())
+		return ok
+	}
+	if ok && a.Timestamp.Sub(last) < n.dedupWindow {
+		return false
+	}
+	n.sent[a.key()] = a.Timestamp
+	return true
+}
+
+// rateLimiter allows limit events in any period
+type rateLimiter struct {
+	limit  int
+	period time.Duration
+	times  []time.Time
+}
+
+func (r *rateLimiter) allow(now time.Time) bool {
+	recent := r.times[:0]
+	for _, t := range r.times {
+		if now.Sub(t) < r.period {
+			recent = append(recent, t)
+		}
+	}
+	r.times = recent
+	if len(r.times) >= r.limit {
+		return false
+	}
+	r.times = append(r.times, now)
+	return true
+}
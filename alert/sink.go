@@ -0,0 +1,176 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+const sinkTimeout = 10 * time.Second
+
+// Sink delivers alerts
+type Sink interface {
+	Send(a Alert) error
+}
+
+// NewSink creates the sink described by config
+func NewSink(config SinkConfig) (Sink, error) {
+	switch config.Type {
+	case SinkWebhook:
+		return &webhookSink{config.URL, config.Headers, &http.Client{Timeout: sinkTimeout}}, nil
+	case SinkSMTP:
+		return &smtpSink{config}, nil
+	case SinkSyslog:
+		network := config.Network
+		if network == "" {
+			network = "udp"
+		}
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "-"
+		}
+		return &syslogSink{network, config.Address, hostname}, nil
+	}
+	return nil, fmt.Errorf("unknown alert sink type %q", config.Type)
+}
+
+// webhookSink POSTs each alert as a json object
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *webhookSink) Send(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// smtpSink emails each alert
+type smtpSink struct {
+	config SinkConfig
+}
+
+func (s *smtpSink) Send(a Alert) error {
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		host, _, err := net.SplitHostPort(s.config.Address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, host)
+	}
+	return smtp.SendMail(s.config.Address, auth, s.config.From, s.config.To, emailMessage(s.config.From, s.config.To, a))
+}
+
+func emailMessage(from string, to []string, a Alert) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.Replace(a.Subject(), "\n", " ", -1))
+	fmt.Fprintf(&msg, "Date: %s\r\n", a.Timestamp.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\n", a.Summary)
+	fmt.Fprintf(&msg, "Severity: %s\r\n", a.Severity)
+	fmt.Fprintf(&msg, "Cleared:  %t\r\n", a.Cleared)
+	fmt.Fprintf(&msg, "Type:     %s %s\r\n", a.Type, a.Name)
+	fmt.Fprintf(&msg, "Service:  %s (%s)\r\n", a.ServiceName, a.ServiceID)
+	fmt.Fprintf(&msg, "Instance: %s\r\n", a.InstanceID)
+	fmt.Fprintf(&msg, "Tenant:   %s\r\n", a.TenantID)
+	fmt.Fprintf(&msg, "Pool:     %s\r\n", a.PoolID)
+	return msg.Bytes()
+}
+
+// syslogSink sends each alert as an RFC5424 message
+type syslogSink struct {
+	network  string
+	address  string
+	hostname string
+}
+
+func (s *syslogSink) Send(a Alert) error {
+	conn, err := net.DialTimeout(s.network, s.address, sinkTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+
+	msg := syslogMessage(s.hostname, os.Getpid(), a)
+	if s.network == "tcp" {
+		// octet counting framing (RFC6587)
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	_, err = conn.Write([]byte(msg))
+	return err
+}
+
+const (
+	syslogFacility = 3 // daemon
+	syslogSDID     = "serviced@32473"
+)
+
+var syslogSeverities = map[string]int{
+	SeverityCritical: 2,
+	SeverityError:    3,
+	SeverityWarning:  4,
+	SeverityInfo:     6,
+}
+
+// syslogMessage formats the alert as
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ELEMENT] MSG
+func syslogMessage(hostname string, pid int, a Alert) string {
+	severity := syslogSeverities[a.Severity]
+	if a.Cleared {
+		severity = 5 // notice
+	}
+	data := fmt.Sprintf("[%s type=\"%s\" name=\"%s\" severity=\"%s\" cleared=\"%t\" pool=\"%s\" tenant=\"%s\" service=\"%s\" instance=\"%s\"]",
+		syslogSDID, sdEscape(a.Type), sdEscape(a.Name), sdEscape(a.Severity), a.Cleared, sdEscape(a.PoolID),
+		sdEscape(a.TenantID), sdEscape(a.ServiceID), sdEscape(a.InstanceID))
+	return fmt.Sprintf("<%d>1 %s %s serviced %d %s %s %s",
+		syslogFacility*8+severity, a.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z"), hostname, pid, a.Type, data, a.Subject())
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func sdEscape(s string) string {
+	return sdEscaper.Replace(s)
+}
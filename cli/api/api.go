@@ -70,7 +70,11 @@ type Options struct {
 	AdminGroup           string // user group that can log in to control center
 	MaxRPCClients        int    // the max number of rpc clients to an endpoint
 	RPCDialTimeout       int
-	SnapshotTTL          int // hours to keep snapshots around, zero for infinity
+	SnapshotTTL          int    // hours to keep snapshots around, zero for infinity
+	MaxImageLayers       int    // squash tenant images with more layers, zero to disable
+	AlertConfig          string // json file of alert sinks and routes, empty to disable alerts
+	MetricsListen        string // address to serve prometheus metrics on, empty to disable
+	SecretKeyFile        string // master key that encrypts the secrets; created if missing
 }
 
 // LoadOptions overwrites the existing server options
@@ -16,6 +16,8 @@ package api
 import (
 	"fmt"
 	"path/filepath"
+
+	"github.com/control-center/serviced/dao"
 )
 
 // Dump all templates and services to a tgz file.
@@ -50,3 +52,45 @@ func (a *api) Restore(path string) error {
 
 	return client.Restore(filepath.Clean(fp), &unusedInt)
 }
+
+// ExportService writes an application, with a snapshot of its volume and the
+// docker images it runs, to a tgz file.
+func (a *api) ExportService(serviceID, path string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	fp, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("could not convert '%s' to an absolute file path: %v", path, err)
+	}
+
+	var filename string
+	req := dao.ServiceExportRequest{ServiceID: serviceID, Filename: filepath.Clean(fp)}
+	if err := client.ExportService(req, &filename); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// ImportService adds the application in a tgz file written by ExportService
+// to a resource pool. This is the inverse of ExportService.
+func (a *api) ImportService(path, poolID string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	fp, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("could not convert '%s' to an absolute file path: %v", path, err)
+	}
+
+	var tenantID string
+	req := dao.ServiceImportRequest{Filename: filepath.Clean(fp), PoolID: poolID}
+	if err := client.ImportService(req, &tenantID); err != nil {
+		return "", err
+	}
+	return tenantID, nil
+}
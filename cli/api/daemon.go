@@ -14,6 +14,9 @@
 package api
 
 import (
+	"github.com/control-center/serviced/alert"
+	"github.com/control-center/serviced/commons/logstash"
+	"github.com/control-center/serviced/commons/yaml"
 	coordclient "github.com/control-center/serviced/coordinator/client"
 	coordzk "github.com/control-center/serviced/coordinator/client/zookeeper"
 	"github.com/control-center/serviced/coordinator/storage"
@@ -23,8 +26,11 @@ import (
 	"github.com/control-center/serviced/datastore/elastic"
 	"github.com/control-center/serviced/dfs/nfs"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicetemplate"
@@ -40,6 +46,8 @@ import (
 	"github.com/control-center/serviced/scheduler"
 	"github.com/control-center/serviced/shell"
 	"github.com/control-center/serviced/stats"
+	"github.com/control-center/serviced/stream"
+	"github.com/control-center/serviced/threshold"
 	"github.com/control-center/serviced/utils"
 	"github.com/control-center/serviced/validation"
 	"github.com/control-center/serviced/volume"
@@ -52,7 +60,6 @@ import (
 	"github.com/control-center/serviced/zzk"
 
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -94,6 +101,7 @@ type daemon struct {
 	shutdown         chan interface{}
 	waitGroup        *sync.WaitGroup
 	rpcServer        *rpc.Server
+	metrics          *stats.PrometheusHandler
 }
 
 func newDaemon(servicedEndpoint string, staticIPs []string, masterPoolID string) (*daemon, error) {
@@ -177,7 +185,22 @@ func (d *daemon) startRPC() {
 			if err != nil {
 				glog.Fatalf("Error accepting connections: %s", err)
 			}
-			go d.rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+			go d.rpcServer.ServeCodec(rpcutils.NewTimedServerCodec(jsonrpc.NewServerCodec(conn)))
+		}
+	}()
+}
+
+// startMetrics serves the stats of this host and the control plane internals
+// to prometheus
+func (d *daemon) startMetrics() {
+	if options.MetricsListen == "" {
+		return
+	}
+	d.metrics = &stats.PrometheusHandler{}
+	go func() {
+		glog.V(0).Infof("Serving prometheus metrics on %s/metrics", options.MetricsListen)
+		if err := http.ListenAndServe(options.MetricsListen, d.metrics); err != nil {
+			glog.Errorf("Unable to serve prometheus metrics on %s: %s", options.MetricsListen, err)
 		}
 	}()
 }
@@ -268,6 +291,7 @@ func (d *daemon) run() (err error) {
 	}
 
 	d.startRPC()
+	d.startMetrics()
 	d.startDockerRegistryProxy()
 
 	if options.Master {
@@ -367,13 +391,35 @@ func (d *daemon) startMaster() error {
 	}
 
 	d.facade = d.initFacade()
+	stream.Default.SetTenantResolver(func(serviceID string) string {
+		tenantID, _ := d.facade.GetTenantID(d.dsContext, serviceID)
+		return tenantID
+	})
 
 	if d.cpDao, err = d.initDAO(); err != nil {
 		return err
 	}
 
 	health.SetDao(d.cpDao)
+	if options.AlertConfig != "" {
+		config, err := alert.LoadConfig(options.AlertConfig)
+		if err != nil {
+			return err
+		}
+		notifier, err := alert.NewNotifier(*config)
+		if err != nil {
+			return err
+		}
+		go notifier.Run(d.shutdown)
+		health.SetNotifier(notifier, d.facade)
+		glog.Infof("Sending alerts to %d sinks", len(config.Sinks))
+	}
 	go health.Cleanup(d.shutdown)
+	go health.RecordHistory(d.shutdown, d.facade)
+	go d.startLogstashPurger()
+
+	thresholds := threshold.NewEngine(d.facade, d.dsContext, "http://127.0.0.1:8888/api/performance/query", web.PoolThresholds())
+	go thresholds.Run(d.shutdown)
 
 	if err = d.facade.CreateDefaultPool(d.dsContext, d.masterPoolID); err != nil {
 		return err
@@ -630,14 +676,20 @@ func (d *daemon) startAgent() error {
 			glog.Fatalf("could not register ControlPlaneAgent RPC server: %v", err)
 		}
 
-		if options.ReportStats {
-			statsdest := fmt.Sprintf("http://%s/api/metrics/store", options.HostStats)
+		if options.ReportStats || d.metrics != nil {
+			statsdest := ""
+			if options.ReportStats {
+				statsdest = fmt.Sprintf("http://%s/api/metrics/store", options.HostStats)
+			}
 			statsduration := time.Duration(options.StatsPeriod) * time.Second
 			glog.V(1).Infoln("Staring container statistics reporter")
 			statsReporter, err := stats.NewStatsReporter(statsdest, statsduration, poolBasedConn)
 			if err != nil {
 				glog.Errorf("Error kicking off stats reporter %v", err)
 			} else {
+				if d.metrics != nil {
+					d.metrics.SetReporter(statsReporter)
+				}
 				go func() {
 					defer statsReporter.Close()
 					<-d.shutdown
@@ -688,10 +740,16 @@ func (d *daemon) initDriver() (datastore.Driver, error) {
 	eDriver.AddMapping(host.MAPPING)
 	eDriver.AddMapping(pool.MAPPING)
 	eDriver.AddMapping(servicetemplate.MAPPING)
+	eDriver.AddMapping(servicetemplate.DeploymentMapping)
+	eDriver.AddMapping(servicetemplate.VersionMapping)
 	eDriver.AddMapping(service.MAPPING)
 	eDriver.AddMapping(addressassignment.MAPPING)
 	eDriver.AddMapping(serviceconfigfile.MAPPING)
+	eDriver.AddMapping(serviceconfigfile.RevisionMapping)
 	eDriver.AddMapping(user.MAPPING)
+	eDriver.AddMapping(event.MAPPING)
+	eDriver.AddMapping(healthhistory.MAPPING)
+	eDriver.AddMapping(secret.MAPPING)
 	err := eDriver.Initialize(10 * time.Second)
 	if err != nil {
 		return nil, err
@@ -701,39 +759,54 @@ func (d *daemon) initDriver() (datastore.Driver, error) {
 
 func (d *daemon) initFacade() *facade.Facade {
 	f := facade.New(dockerRegistry)
+
+	keyFile := options.SecretKeyFile
+	if keyFile == "" {
+		keyFile = path.Join(options.VarPath, "secrets.key")
+	}
+	if cipher, err := secret.LoadCipher(keyFile); err != nil {
+		glog.Errorf("Could not load the master key of the secrets from %s: %s", keyFile, err)
+	} else {
+		f.SetSecretCipher(cipher)
+	}
 	return f
 }
 
 func (d *daemon) initISVCS() error {
-	if err := isvcs.Mgr.Start(); err != nil {
-		return err
+	return isvcs.Mgr.Start()
+}
+
+// startLogstashPurger applies the log retention policy and keeps the logstash
+// indices within their maximum size
+func (d *daemon) startLogstashPurger() {
+	purge := func() {
+		var report logstash.RetentionReport
+		if err := d.cpDao.EnforceLogRetention(options.LogstashMaxSize, &report); err != nil {
+			glog.Errorf("Could not enforce log retention policy: %s", err)
+		}
 	}
 
-	// Start the logstash purger
-	go func() {
-		// Run the first time after 10 minutes
+	// Run the first time after 10 minutes
+	select {
+	case <-d.shutdown:
+		return
+	case <-time.After(10 * time.Minute):
+		purge()
+	}
+	// Now run every 6 hours
+	for {
 		select {
 		case <-d.shutdown:
 			return
-		case <-time.After(10 * time.Minute):
-			isvcs.PurgeLogstashIndices(options.LogstashMaxDays, options.LogstashMaxSize)
-		}
-		// Now run every 6 hours
-		for {
-			select {
-			case <-d.shutdown:
-				return
-			case <-time.After(6 * time.Hour):
-				isvcs.PurgeLogstashIndices(options.LogstashMaxDays, options.LogstashMaxSize)
-			}
+		case <-time.After(6 * time.Hour):
+			purge()
 		}
-	}()
-	return nil
+	}
 }
 
 func (d *daemon) initDAO() (dao.ControlPlane, error) {
 	dfsTimeout := time.Duration(options.MaxDFSTimeout) * time.Second
-	return elasticsearch.NewControlSvc("localhost", 9200, d.facade, options.VarPath, options.FSType, dfsTimeout, dockerRegistry)
+	return elasticsearch.NewControlSvc("localhost", 9200, d.facade, options.VarPath, options.FSType, dfsTimeout, dockerRegistry, options.LogstashES, options.LogstashMaxDays)
 }
 
 func (d *daemon) initWeb() {
@@ -766,7 +839,7 @@ func (d *daemon) addTemplates() {
 			if err != nil {
 				return err
 			}
-			if info == nil || !strings.HasSuffix(info.Name(), ".json") {
+			if info == nil || !(strings.HasSuffix(info.Name(), ".json") || yaml.IsYAML(info.Name())) {
 				return nil
 			}
 			if info.IsDir() {
@@ -779,7 +852,7 @@ func (d *daemon) addTemplates() {
 			}
 			defer reader.Close()
 			st := servicetemplate.ServiceTemplate{}
-			if err := json.NewDecoder(reader).Decode(&st); err != nil {
+			if err := yaml.NewDecoder(reader).Decode(&st); err != nil {
 				glog.Warningf("Unable to parse template file %s", path)
 				return nil
 			}
@@ -795,7 +868,7 @@ func (d *daemon) addTemplates() {
 
 func (d *daemon) runScheduler() {
 	for {
-		sched, err := scheduler.NewScheduler(d.masterPoolID, d.hostID, d.storageHandler, d.cpDao, d.facade, options.SnapshotTTL)
+		sched, err := scheduler.NewScheduler(d.masterPoolID, d.hostID, d.storageHandler, d.cpDao, d.facade, options.SnapshotTTL, options.MaxImageLayers)
 		if err != nil {
 			glog.Errorf("Could not start scheduler: %s", err)
 			return
@@ -33,6 +33,35 @@ func (a *api) ResetRegistry() error {
 	return client.ResetRegistry(dao.NullRequest{}, new(int))
 }
 
+// MaintainImages squashes tenant images with more than maxLayers layers and
+// removes unused images and registry tags
+func (a *api) MaintainImages(maxLayers int) (*dao.ImageMaintenanceReport, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var report dao.ImageMaintenanceReport
+	if err := client.MaintainImages(dao.ImageMaintenanceRequest{MaxLayers: maxLayers}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetImageInventory reports on the images used by the tenant of a service
+func (a *api) GetImageInventory(serviceID string) (*dao.ImageInventoryReport, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var report dao.ImageInventoryReport
+	if err := client.GetImageInventory(serviceID, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
 // Squash flattens the image (or at least down the to optional downToLayer).
 // The resulting image is retagged with newName.
 func (a *api) Squash(imageName, downToLayer, newName, tempDir string) (resultImageID string, err error) {
@@ -0,0 +1,73 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/stream"
+)
+
+// EventListConfig is the deserialized object from the command-line
+type EventListConfig struct {
+	SourceID string // service or pool id; empty for all
+	Since    string // RFC3339, yyyy.mm.dd, or a duration before now
+	Limit    int
+}
+
+// GetEvents returns threshold events, newest first
+func (a *api) GetEvents(config EventListConfig) ([]event.Event, error) {
+	request := dao.EventRequest{SourceID: config.SourceID, Limit: config.Limit}
+	var err error
+	if request.Since, err = parseLogTime(config.Since, time.Now().UTC(), false); err != nil {
+		return nil, fmt.Errorf("invalid since: %s", err)
+	}
+
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []event.Event
+	if err := client.GetEvents(request, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ServiceEventConfig is the deserialized object from the command-line
+type ServiceEventConfig struct {
+	Filter stream.Filter
+	After  uint64        // id of the last event received; 0 for the recent events
+	Wait   time.Duration // how long to wait if there are no events yet
+}
+
+// GetServiceEvents returns the changes to services, instances and hosts that
+// followed config.After
+func (a *api) GetServiceEvents(config ServiceEventConfig) ([]stream.Event, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	request := dao.ServiceEventRequest{After: config.After, Filter: config.Filter, Timeout: config.Wait}
+	var events []stream.Event
+	if err := client.GetServiceEvents(request, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -16,14 +16,21 @@ package api
 import (
 	"io"
 
+	"github.com/control-center/serviced/commons/logstash"
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/manifest"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicestate"
 	template "github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/facade"
 	"github.com/control-center/serviced/script"
+	"github.com/control-center/serviced/stream"
 )
 
 // API is the intermediary between the command-line interface and the dao layer
@@ -61,6 +68,9 @@ type API interface {
 	RestartService(SchedulerConfig) (int, error)
 	StopService(SchedulerConfig) (int, error)
 	AssignIP(IPConfig) error
+	GetServiceUptime(ServiceUptimeConfig) (*healthhistory.Report, error)
+	GetServiceConfigHistory(string, string) ([]serviceconfigfile.Revision, error)
+	RevertServiceConfig(string, string, bool) error
 
 	// RunningServices (ServiceStates)
 	GetRunningServices() ([]dao.RunningService, error)
@@ -84,20 +94,47 @@ type API interface {
 	GetServiceTemplate(string) (*template.ServiceTemplate, error)
 	AddServiceTemplate(io.Reader) (*template.ServiceTemplate, error)
 	RemoveServiceTemplate(string) error
-	CompileServiceTemplate(CompileTemplateConfig) (*template.ServiceTemplate, error)
+	CompileServiceTemplate(CompileTemplateConfig) (*template.ServiceTemplate, []template.Provenance, error)
 	DeployServiceTemplate(DeployTemplateConfig) ([]service.Service, error)
+	UpgradeServiceTemplate(UpgradeTemplateConfig) (*template.UpgradePlan, error)
+	GetServiceTemplateHistory(string) ([]template.TemplateHistory, error)
+	RollbackServiceTemplate(string, string) (string, error)
+	TestTemplateLogs(TestLogsConfig) (*TestLogsResult, error)
+	LintServiceTemplate(string) ([]template.LintIssue, error)
+
+	// Secrets
+	SetSecret(name, value string) error
+	GetSecret(name string) (string, error)
+	GetSecrets() ([]secret.Info, error)
+	RemoveSecret(name string) error
+
+	// Manifests
+	ApplyManifest(ApplyConfig) (*manifest.Plan, error)
 
 	// Backup & Restore
 	Backup(string) (string, error)
 	Restore(string) error
+	ExportService(string, string) (string, error)
+	ImportService(string, string) (string, error)
 
 	// Docker
 	ResetRegistry() error
 	Squash(imageName, downToLayer, newName, tempDir string) (string, error)
 	RegistrySync() error
+	MaintainImages(maxLayers int) (*dao.ImageMaintenanceReport, error)
+	GetImageInventory(serviceID string) (*dao.ImageInventoryReport, error)
+
+	// Events
+	GetEvents(config EventListConfig) ([]event.Event, error)
+	GetServiceEvents(config ServiceEventConfig) ([]stream.Event, error)
 
 	// Logs
 	ExportLogs(config ExportLogsConfig) error
+	SearchLogs(config LogSearchConfig) (*logstash.Result, error)
+	FollowLogs(config FollowLogsConfig, stopChan chan struct{}) error
+	GetLogRetention() (*logstash.RetentionStatus, error)
+	SetLogRetention(policy logstash.RetentionPolicy) error
+	RestoreLogs(day string) error
 
 	// Metric
 	PostMetric(metricName string, metricValue string) (string, error)
@@ -0,0 +1,183 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/utils"
+	"github.com/zenoss/glog"
+)
+
+// how often to look for instances that started after following began
+var followPollInterval = 5 * time.Second
+
+// FollowLogsConfig is the deserialized object from the command-line
+type FollowLogsConfig struct {
+	ServiceID string
+	Tail      int // lines of existing output to show per instance; 0 for all
+	Output    io.Writer
+}
+
+// FollowLogs streams the docker logs of every instance of a service to the
+// output, prefixing each line with the service name and instance id, until
+// stopChan is closed.  Instances that start while following are picked up
+// as they appear; instances on other hosts are followed over ssh.
+func (a *api) FollowLogs(config FollowLogsConfig, stopChan chan struct{}) error {
+	myHostID, err := utils.HostID()
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	following := make(map[string]*exec.Cmd)
+	defer func() {
+		for id, cmd := range following {
+			if cmd.Process != nil {
+				cmd.Process.Kill()
+			}
+			delete(following, id)
+		}
+	}()
+
+	exited := make(chan string)
+	for {
+		rss, err := a.GetRunningServices()
+		if err != nil {
+			return err
+		}
+		hostIPs, err := a.hostIPs()
+		if err != nil {
+			return err
+		}
+
+		for _, rs := range rss {
+			if rs.ServiceID != config.ServiceID {
+				continue
+			} else if _, ok := following[rs.ID]; ok {
+				continue
+			}
+
+			cmd := followCommand(rs, config.Tail, myHostID, hostIPs[rs.HostID])
+			writer := &prefixWriter{mu: &mu, w: config.Output, prefix: fmt.Sprintf("%s/%d | ", rs.Name, rs.InstanceID)}
+			cmd.Stdout, cmd.Stderr = writer, writer
+
+			glog.V(1).Infof("following logs of %s/%d with: %s", rs.Name, rs.InstanceID, cmd.Args)
+			if err := cmd.Start(); err != nil {
+				glog.Warningf("Could not follow logs of %s/%d: %s", rs.Name, rs.InstanceID, err)
+				continue
+			}
+			following[rs.ID] = cmd
+
+			go func(id string, cmd *exec.Cmd, writer *prefixWriter) {
+				cmd.Wait()
+				writer.Flush()
+				select {
+				case exited <- id:
+				case <-stopChan:
+				}
+			}(rs.ID, cmd, writer)
+		}
+
+		timer := time.After(followPollInterval)
+	wait:
+		for {
+			select {
+			case id := <-exited:
+				// the instance stopped; a restarted instance gets a new id
+				delete(following, id)
+			case <-timer:
+				break wait
+			case <-stopChan:
+				return nil
+			}
+		}
+	}
+}
+
+func (a *api) hostIPs() (map[string]string, error) {
+	hosts, err := a.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+	hostIPs := make(map[string]string)
+	for _, host := range hosts {
+		hostIPs[host.ID] = host.IPAddr
+	}
+	return hostIPs, nil
+}
+
+// followCommand returns the command that follows the docker logs of an
+// instance, either locally or on the instance's host
+func followCommand(rs dao.RunningService, tail int, myHostID, hostIP string) *exec.Cmd {
+	argv := []string{"/usr/bin/docker", "logs", "--follow"}
+	if tail > 0 {
+		argv = append(argv, "--tail="+strconv.Itoa(tail))
+	}
+	argv = append(argv, rs.DockerID)
+
+	if rs.HostID != myHostID {
+		argv = append([]string{"/usr/bin/ssh", hostIP, "--"}, argv...)
+	}
+	return exec.Command(argv[0], argv[1:]...)
+}
+
+// prefixWriter writes each complete line to w with a prefix, holding on to
+// any partial line until it is completed or flushed.  Writers sharing mu do
+// not interleave their lines.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes out any partial line
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
@@ -14,23 +14,16 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
-	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/control-center/serviced/commons/logstash"
+	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain/service"
-	elastigo "github.com/zenoss/elastigo/api"
-	"github.com/zenoss/elastigo/core"
-	"github.com/zenoss/glog"
 )
 
 // ExportLogsConfig is the deserialized object from the command-line
@@ -49,10 +42,8 @@ type ExportLogsConfig struct {
 //
 // TODO: This code is racy - creating then erasing the output file does not
 // guarantee that it will be safe to write to at the end of the function
-func (a *api) ExportLogs(config ExportLogsConfig) (err error) {
+func (a *api) ExportLogs(config ExportLogsConfig) error {
 	var e error
-	files := []*os.File{}
-	fileIndex := make(map[string]map[string]int) // host => filename => index
 
 	// make sure we can write to outfile
 	if config.Outfile == "" {
@@ -95,12 +86,9 @@ func (a *api) ExportLogs(config ExportLogsConfig) (err error) {
 		}
 	}
 
-	parts := strings.Split(options.LogstashES, ":")
-	if len(parts) != 2 {
+	if parts := strings.Split(options.LogstashES, ":"); len(parts) != 2 {
 		return fmt.Errorf("invalid logstash-es host:port %s", options.LogstashES)
 	}
-	elastigo.Domain = parts[0]
-	elastigo.Port = parts[1]
 
 	query := "*"
 	if len(config.ServiceIDs) > 0 {
@@ -144,346 +132,158 @@ func (a *api) ExportLogs(config ExportLogsConfig) (err error) {
 		query = fmt.Sprintf("service:(%s)", strings.Join(queryParts, " OR "))
 	}
 
-	// Get a temporary directory
-	tempdir, e := ioutil.TempDir("", "serviced-log-export-")
-	if e != nil {
-		return fmt.Errorf("could not create temp directory: %s", e)
-	}
-	defer os.RemoveAll(tempdir)
-
 	days, e := LogstashDays()
 	if e != nil {
 		return e
 	}
 
-	// create a file to hold parse warnings
-	parseWarningsFilename := filepath.Join(tempdir, "warnings.log")
-	parseWarningsFile, e := os.Create(parseWarningsFilename)
-	if e != nil {
-		return fmt.Errorf("failed to create file %s: %s", parseWarningsFilename, e)
-	}
-	defer func() {
-		if e := parseWarningsFile.Close(); e != nil && err == nil {
-			err = fmt.Errorf("failed to close file '%s' cleanly: %s", parseWarningsFilename, e)
-		}
-	}()
-
-	glog.Infof("Starting part 1 of 3: process logstash elasticsearch results using temporary dir: %s", tempdir)
-	numWarnings := 0
-	foundIndexedDay := false
+	// Skip the indexes that are filtered out by the date range
+	var selected []string
 	for _, yyyymmdd := range days {
-		// Skip the indexes that are filtered out by the date range
 		if (config.FromDate != "" && yyyymmdd < config.FromDate) || (config.ToDate != "" && yyyymmdd > config.ToDate) {
 			continue
-		} else {
-			foundIndexedDay = true
-		}
-
-		logstashIndex := fmt.Sprintf("logstash-%s", yyyymmdd)
-		result, e := core.SearchUri(logstashIndex, "", query, "1m", 1000)
-		if e != nil {
-			return fmt.Errorf("failed to search elasticsearch: %s", e)
-		}
-		//TODO: Submit a patch to elastigo to support the "clear scroll" api. Add a "defer" here.
-		remaining := result.Hits.Total > 0
-		for remaining {
-			result, e = core.Scroll(false, result.ScrollId, "1m")
-			hits := result.Hits.Hits
-			total := len(hits)
-			for i := 0; i < total; i++ {
-				host, logfile, compactLines, warningMessage, e := parseLogSource(hits[i].Source)
-				if e != nil {
-					return e
-				}
-				if _, found := fileIndex[host]; !found {
-					fileIndex[host] = make(map[string]int)
-				}
-				if _, found := fileIndex[host][logfile]; !found {
-					index := len(files)
-					filename := filepath.Join(tempdir, fmt.Sprintf("%03d.log", index))
-					file, e := os.Create(filename)
-					if e != nil {
-						return fmt.Errorf("failed to create file %s: %s", filename, e)
-					}
-					defer func() {
-						if e := file.Close(); e != nil && err == nil {
-							err = fmt.Errorf("failed to close file '%s' cleanly: %s", filename, e)
-						}
-					}()
-					fileIndex[host][logfile] = index
-					files = append(files, file)
-				}
-				index := fileIndex[host][logfile]
-				file := files[index]
-				filename := filepath.Join(tempdir, fmt.Sprintf("%03d.log", index))
-				for _, line := range compactLines {
-					formatted := fmt.Sprintf("%016x\t%016x\t%s\n", line.Timestamp, line.Offset, line.Message)
-					if _, e := file.WriteString(formatted); e != nil {
-						return fmt.Errorf("failed writing to file %s: %s", filename, e)
-					}
-				}
-				if len(warningMessage) > 0 {
-					if _, e := parseWarningsFile.WriteString(warningMessage); e != nil {
-						return fmt.Errorf("failed writing to file %s: %s", parseWarningsFilename, e)
-					}
-					numWarnings++
-				}
-			}
-			remaining = len(hits) > 0
 		}
+		selected = append(selected, yyyymmdd)
 	}
-	if !foundIndexedDay {
+	if len(selected) == 0 {
 		return fmt.Errorf("no logstash indexes exist for the given date range %s - %s", config.FromDate, config.ToDate)
 	}
 
-	glog.Infof("Starting part 2 of 3: sort output files")
-
-	indexData := []string{}
-	for host, logfileIndex := range fileIndex {
-		for logfile, i := range logfileIndex {
-			filename := filepath.Join(tempdir, fmt.Sprintf("%03d.log", i))
-			tmpfilename := filepath.Join(tempdir, fmt.Sprintf("%03d.log.tmp", i))
-			cmd := exec.Command("sort", filename, "-uo", tmpfilename)
-			if output, e := cmd.CombinedOutput(); e != nil {
-				return fmt.Errorf("failed sorting %s, error: %v, output: %s", filename, e, output)
-			}
-			if numWarnings == 0 {
-				cmd = exec.Command("mv", tmpfilename, filename)
-				if output, e := cmd.CombinedOutput(); e != nil {
-					return fmt.Errorf("failed moving %s %s, error: %v, output: %s", tmpfilename, filename, e, output)
-				}
-			} else {
-				cmd = exec.Command("cp", tmpfilename, filename)
-				if output, e := cmd.CombinedOutput(); e != nil {
-					return fmt.Errorf("failed moving %s %s, error: %v, output: %s", tmpfilename, filename, e, output)
-				}
-			}
-			cmd = exec.Command("sed", "s/^[0-9a-f]*\\t[0-9a-f]*\\t//", "-i", filename)
-			if output, e := cmd.CombinedOutput(); e != nil {
-				return fmt.Errorf("failed stripping sort prefixes config.FromDate %s, error: %v, output: %s", filename, e, output)
-			}
-			indexData = append(indexData, fmt.Sprintf("%03d.log\t%s\t%s", i, strconv.Quote(host), strconv.Quote(logfile)))
-		}
-	}
-	sort.Strings(indexData)
-	indexData = append([]string{"INDEX OF LOG FILES", "File\tHost\tOriginal Filename"}, indexData...)
-	indexData = append(indexData, "")
-	indexFile := filepath.Join(tempdir, "index.txt")
-	e = ioutil.WriteFile(indexFile, []byte(strings.Join(indexData, "\n")), 0644)
-	if e != nil {
-		return fmt.Errorf("failed writing to %s: %s", indexFile, e)
-	}
-
-	glog.Infof("Starting part 3 of 3: generate tar file: %s", config.Outfile)
-
-	cmd := exec.Command("tar", "-czf", config.Outfile, "-C", filepath.Dir(tempdir), filepath.Base(tempdir))
-	if output, e := cmd.CombinedOutput(); e != nil {
-		return fmt.Errorf("failed to write tgz cmd:%+v, error:%v, output:%s", cmd, e, string(output))
-	}
-
-	if numWarnings != 0 {
-		glog.Warningf("warnings for log parse are included in the tar file as: %s", filepath.Join(filepath.Base(tempdir), filepath.Base(parseWarningsFilename)))
-	}
-
-	return nil
-}
-
-type logSingleLine struct {
-	Host      string    `json:"host"`
-	File      string    `json:"file"`
-	Timestamp time.Time `json:"@timestamp"`
-	Offset    string    `json:"offset"`
-	Message   string    `json:"message"`
+	return logstash.NewClient(options.LogstashES).Export(logstash.ExportOptions{
+		Query:   query,
+		Days:    selected,
+		Outfile: config.Outfile,
+	})
 }
 
-type logMultiLine struct {
-	Host      string    `json:"host"`
-	File      string    `json:"file"`
-	Timestamp time.Time `json:"@timestamp"`
-	Offset    []string  `json:"offset"`
-	Message   string    `json:"message"`
+// LogSearchConfig is the deserialized object from the command-line
+type LogSearchConfig struct {
+	ServiceID string   // includes all sub-services; empty for all services
+	Query     string   // lucene query string
+	FromTime  string   // RFC3339, yyyy.mm.dd, or a duration before now; empty for unbounded
+	ToTime    string   // RFC3339, yyyy.mm.dd, or a duration before now; empty for unbounded
+	Fields    []string // name:value
+	Offset    int
+	Limit     int
 }
 
-type compactLogLine struct {
-	Timestamp int64 //nanoseconds since the epoch, truncated at the minute to hide jitter
-	Offset    uint64
-	Message   string
-}
-
-var newline = regexp.MustCompile("\\r?\\n")
+// SearchLogs searches the logstash indices on the master for log messages
+func (a *api) SearchLogs(config LogSearchConfig) (*logstash.Result, error) {
+	request := dao.LogSearchRequest{
+		ServiceID: config.ServiceID,
+		Query:     config.Query,
+		Fields:    make(map[string]string),
+		Offset:    config.Offset,
+		Limit:     config.Limit,
+	}
 
-// convertOffsets converts a list of strings into a list of uint64s
-func convertOffsets(offsets []string) ([]uint64, error) {
-	result := make([]uint64, len(offsets))
-	for i, offsetString := range offsets {
-		offset, e := strconv.ParseUint(offsetString, 10, 64)
-		if e != nil {
-			return result, fmt.Errorf("failed to parse offset[%d] \"%s\" in \"%s\": %s", i, offsetString, offsets, e)
+	var err error
+	now := time.Now().UTC()
+	if request.From, err = parseLogTime(config.FromTime, now, false); err != nil {
+		return nil, err
+	}
+	if request.To, err = parseLogTime(config.ToTime, now, true); err != nil {
+		return nil, err
+	}
+	for _, field := range config.Fields {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("field filter must be name:value, got %q", field)
 		}
-		result[i] = offset
+		request.Fields[parts[0]] = parts[1]
 	}
 
-	return result, nil
-}
-
-// uint64sAreSorted returns true if input values are sorted in increasing order - mimics sort.IntsAreSorted()
-func uint64sAreSorted(values []uint64) bool {
-	if len(values) == 0 {
-		return true
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
 	}
 
-	previousValue := values[0]
-	for _, value := range values {
-		if value < previousValue {
-			return false
-		}
-		previousValue = value
+	var result logstash.Result
+	if err := client.SearchLogs(request, &result); err != nil {
+		return nil, err
 	}
-	return true
+	return &result, nil
 }
 
-// getMinValue returns the minimum value in an array of uint64
-func getMinValue(values []uint64) uint64 {
-	result := uint64(math.MaxUint64)
-	for _, value := range values {
-		if value < result {
-			result = value
-		}
+// GetLogRetention returns the log retention policy and the state of the
+// logstash indices
+func (a *api) GetLogRetention() (*logstash.RetentionStatus, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
 	}
-	return result
-}
 
-// generateOffsets uses the minimum offset in the array as a base returns an array of offsets where
-// each offset is the base + index
-func generateOffsets(messages []string, offsets []uint64) []uint64 {
-	result := make([]uint64, len(messages))
-	minOffset := getMinValue(offsets)
-	if minOffset == uint64(math.MaxUint64) {
-		minOffset = 0
-	}
-	for i, _ := range result {
-		result[i] = minOffset + uint64(i)
+	var status logstash.RetentionStatus
+	if err := client.GetLogRetentionStatus(0, &status); err != nil {
+		return nil, err
 	}
-	return result
+	return &status, nil
 }
 
-// return: host, file, lines, error
-func parseLogSource(source []byte) (string, string, []compactLogLine, string, error) {
-	warnings := ""
-
-	// attempt to unmarshal into singleLine
-	var line logSingleLine
-	if e := json.Unmarshal(source, &line); e == nil {
-		offset := uint64(0)
-		if len(line.Offset) != 0 {
-			var e error
-			offset, e = strconv.ParseUint(line.Offset, 10, 64)
-			if e != nil {
-				return "", "", nil, warnings, fmt.Errorf("failed to parse offset \"%s\" in \"%s\": %s", line.Offset, source, e)
-			}
-		}
-		compactLine := compactLogLine{
-			Timestamp: truncateToMinute(line.Timestamp.UnixNano()),
-			Offset:    offset,
-			Message:   line.Message,
-		}
-		return line.Host, line.File, []compactLogLine{compactLine}, warnings, nil
+// SetLogRetention replaces the log retention policy
+func (a *api) SetLogRetention(policy logstash.RetentionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
 	}
 
-	// attempt to unmarshal into multiLine
-	var multiLine logMultiLine
-	if e := json.Unmarshal(source, &multiLine); e != nil {
-		return "", "", nil, warnings, fmt.Errorf("failed to parse JSON \"%s\": %s", source, e)
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
 	}
+	return client.SetLogRetentionPolicy(policy, &unusedInt)
+}
 
-	// build offsets - list of uint64
-	offsets, e := convertOffsets(multiLine.Offset)
-	if e != nil {
-		return "", "", nil, warnings, fmt.Errorf("failed to parse JSON \"%s\": %s", source, e)
+// RestoreLogs loads an archived day (yyyy.mm.dd) back into logstash
+func (a *api) RestoreLogs(day string) error {
+	day, err := NormalizeYYYYMMDD(day)
+	if err != nil {
+		return err
 	}
 
-	// verify number of lines in message against number of offsets
-	messages := newline.Split(multiLine.Message, -1)
-	if len(offsets)+1 == len(messages) {
-		warnings += fmt.Sprintf(
-			"number of offsets for %s:%s (numLines:%d numOffsets:%d) is one less than number of lines: %s\n",
-			multiLine.Host, multiLine.File, len(messages), len(offsets), source)
-		numLines := len(messages)
-		if numLines > 1 {
-			lastOffset := uint64(len(messages[numLines-2])) + offsets[numLines-2]
-			offsets = append(offsets, lastOffset)
-		}
-	} else if len(offsets) > len(messages) {
-		warnings += fmt.Sprintf(
-			"number of offsets for %s:%s (numLines:%d numOffsets:%d) is greater than number of lines: %s\n",
-			multiLine.Host, multiLine.File, len(messages), len(multiLine.Offset), source)
-		offsets = offsets[0:len(messages)]
-	} else if len(offsets) < len(messages) {
-		warnings += fmt.Sprintf(
-			"number of offsets for %s:%s (numLines:%d numOffsets:%d) is less than number of lines: %s\n",
-			multiLine.Host, multiLine.File, len(messages), len(multiLine.Offset), source)
-		offsets = generateOffsets(messages, offsets)
-		warnings += fmt.Sprintf("new offsets: %v", offsets)
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
 	}
+	return client.RestoreLogs(day, &unusedInt)
+}
 
-	// deal with offsets that are not sorted in increasing order
-	if !uint64sAreSorted(offsets) {
-		warnings = fmt.Sprintf("offsets are not sorted: %v\n", offsets)
-		offsets = generateOffsets(messages, offsets)
-		warnings = fmt.Sprintf("new offsets: %v\n", offsets)
+// parseLogTime parses an RFC3339 timestamp, a yyyy.mm.dd date or a duration
+// before now.  A date is the start of the day, or its end if endOfDay is set.
+func parseLogTime(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
 	}
-
-	// build compactLines
-	timestamp := truncateToMinute(multiLine.Timestamp.UnixNano())
-	compactLines := make([]compactLogLine, len(messages))
-	for i, offset := range offsets {
-		compactLines = append(compactLines, compactLogLine{
-			Timestamp: timestamp,
-			Offset:    offset,
-			Message:   messages[i],
-		})
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
 	}
-
-	return multiLine.Host, multiLine.File, compactLines, warnings, nil
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			d = -d
+		}
+		return now.Add(-d), nil
+	}
+	if day, err := NormalizeYYYYMMDD(s); err == nil {
+		t, err := time.Parse(logstash.DayFormat, day)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse '%s' as a timestamp, date or duration", s)
 }
 
 // NormalizeYYYYMMDD matches optional non-digits, 4 digits, optional non-digits,
 // 2 digits, optional non-digits, 2 digits, optional non-digits
 // Returns those 8 digits formatted as "dddd.dd.dd", or error if unparseable.
 func NormalizeYYYYMMDD(s string) (string, error) {
-	match := yyyymmddMatcher.FindStringSubmatch(s)
-	if match == nil {
-		return "", fmt.Errorf("could not parse '%s' as yyyymmdd", s)
-	}
-	return fmt.Sprintf("%s.%s.%s", match[1], match[2], match[3]), nil
+	return logstash.NormalizeYYYYMMDD(s)
 }
 
-var yyyymmddMatcher = regexp.MustCompile("\\A[^0-9]*([0-9]{4})[^0-9]*([0-9]{2})[^0-9]*([0-9]{2})[^0-9]*\\z")
-
 // Returns a list of all the dates with a logstash-YYYY.MM.DD index available in ElasticSearch.
 // The strings are in YYYY.MM.DD format, and in reverse chronological order.
 var LogstashDays = func() ([]string, error) {
-	response, e := elastigo.DoCommand("GET", "/_aliases", nil)
-	if e != nil {
-		return []string{}, fmt.Errorf("couldn't fetch list of indices: %s", e)
-	}
-	var aliasMap map[string]interface{}
-	if e = json.Unmarshal(response, &aliasMap); e != nil {
-		return []string{}, fmt.Errorf("couldn't parse response (%s): %s", response, e)
-	}
-	result := make([]string, 0, len(aliasMap))
-	for index := range aliasMap {
-		if trimmed := strings.TrimPrefix(index, "logstash-"); trimmed != index {
-			if trimmed, e = NormalizeYYYYMMDD(trimmed); e != nil {
-				trimmed = ""
-			}
-			result = append(result, trimmed)
-		}
-	}
-	sort.Sort(sort.Reverse(sort.StringSlice(result)))
-	return result, nil
-}
-
-func truncateToMinute(nanos int64) int64 {
-	return nanos / int64(time.Minute) * int64(time.Minute)
+	return logstash.NewClient(options.LogstashES).Days()
 }
@@ -14,52 +14,54 @@
 package api
 
 import (
-	"math"
-	"reflect"
+	"bytes"
+	"sync"
 	"testing"
+	"time"
 )
 
-func testConvertOffsets(t *testing.T, received []string, expected []uint64) {
-	converted, err := convertOffsets(received)
-	if err != nil {
-		t.Fatalf("unexpected error converting offsets: %s", err)
+func TestLogs_ParseLogTime(t *testing.T) {
+	now := time.Date(2015, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in       string
+		endOfDay bool
+		expected time.Time
+	}{
+		{"", false, time.Time{}},
+		{"2015-01-01T10:00:00Z", false, time.Date(2015, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{"1h", false, now.Add(-time.Hour)},
+		{"-30m", false, now.Add(-30 * time.Minute)},
+		{"2015.01.01", false, time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"20150101", true, time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
 	}
-	if !reflect.DeepEqual(converted, expected) {
-		t.Fatalf("got %v expected %v", converted, expected)
+	for _, test := range tests {
+		actual, err := parseLogTime(test.in, now, test.endOfDay)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %s", test.in, err)
+		}
+		if !actual.Equal(test.expected) {
+			t.Fatalf("parsing %q: got %s expected %s", test.in, actual, test.expected)
+		}
 	}
-}
 
-func testUint64sAreSorted(t *testing.T, values []uint64, expected bool) {
-	if uint64sAreSorted(values) != expected {
-		t.Fatalf("expected %v for sortedness for values: %v", expected, values)
+	if _, err := parseLogTime("yesterday", now, false); err == nil {
+		t.Fatalf("expected error parsing %q", "yesterday")
 	}
 }
 
-func testGetMinValue(t *testing.T, values []uint64, expected uint64) {
-	if getMinValue(values) != expected {
-		t.Fatalf("expected min value %v from values: %v", expected, values)
+func TestLogs_PrefixWriter(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	w := &prefixWriter{mu: &mu, w: &out, prefix: "svc/0 | "}
+
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\npartial"))
+	if expected := "svc/0 | first line\nsvc/0 | second line\n"; out.String() != expected {
+		t.Fatalf("got %q expected %q", out.String(), expected)
 	}
-}
 
-func testGenerateOffsets(t *testing.T, inMessages []string, inOffsets, expected []uint64) {
-	converted := generateOffsets(inMessages, inOffsets)
-	if !reflect.DeepEqual(converted, expected) {
-		t.Fatalf("unexpected error generating offsets from %v:%v got %v expected %v", inMessages, inOffsets, converted, expected)
+	w.Flush()
+	if expected := "svc/0 | first line\nsvc/0 | second line\nsvc/0 | partial\n"; out.String() != expected {
+		t.Fatalf("got %q expected %q", out.String(), expected)
 	}
 }
-
-func TestLogs_Offsets(t *testing.T) {
-	testConvertOffsets(t, []string{"123", "456", "789"}, []uint64{123, 456, 789})
-	testConvertOffsets(t, []string{"456", "123", "789"}, []uint64{456, 123, 789})
-
-	testUint64sAreSorted(t, []uint64{123, 124, 125}, true)
-	testUint64sAreSorted(t, []uint64{123, 125, 124}, false)
-	testUint64sAreSorted(t, []uint64{125, 123, 124}, false)
-
-	testGetMinValue(t, []uint64{}, math.MaxUint64)
-	testGetMinValue(t, []uint64{125, 123, 124}, 123)
-
-	testGenerateOffsets(t, []string{}, []uint64{}, []uint64{})
-	testGenerateOffsets(t, []string{"abc", "def", "ghi"}, []uint64{456, 123, 789}, []uint64{123, 124, 125})
-	testGenerateOffsets(t, []string{"abc", "def", "ghi"}, []uint64{456, 124}, []uint64{124, 125, 126})
-}
\ No newline at end of file
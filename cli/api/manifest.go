@@ -0,0 +1,53 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/manifest"
+)
+
+// ApplyConfig is the configuration object to make the cluster match the
+// manifests in a directory
+type ApplyConfig struct {
+	Path   string // a directory of manifests, or one manifest
+	Prune  bool   // remove what the manifests do not declare
+	DryRun bool   // only plan the changes
+}
+
+// ApplyManifest makes the cluster match the manifests in a directory and
+// returns the changes
+func (a *api) ApplyManifest(config ApplyConfig) (*manifest.Plan, error) {
+	m, err := manifest.ReadDir(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	request := dao.ApplyRequest{
+		Manifest: *m,
+		Author:   currentUsername(),
+		Prune:    config.Prune,
+		DryRun:   config.DryRun,
+	}
+	var plan manifest.Plan
+	if err := client.ApplyManifest(request, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
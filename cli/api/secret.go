@@ -0,0 +1,67 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/secret"
+)
+
+// SetSecret sets the value of a secret
+func (a *api) SetSecret(name, value string) error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	return client.SetSecret(dao.SecretRequest{Name: name, Value: value}, &unusedInt)
+}
+
+// GetSecret returns the value of a secret
+func (a *api) GetSecret(name string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	if err := client.GetSecret(name, &value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// GetSecrets returns the names of the secrets, without their values
+func (a *api) GetSecrets() ([]secret.Info, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []secret.Info
+	if err := client.GetSecrets(0, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// RemoveSecret removes a secret
+func (a *api) RemoveSecret(name string) error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	return client.RemoveSecret(name, &unusedInt)
+}
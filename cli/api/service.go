@@ -14,14 +14,16 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 
 	"github.com/control-center/serviced/commons"
+	"github.com/control-center/serviced/commons/yaml"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicestate"
 )
@@ -189,10 +191,10 @@ func (a *api) RemoveService(id string) error {
 
 // UpdateService updates an existing service
 func (a *api) UpdateService(reader io.Reader) (*service.Service, error) {
-	// Unmarshal JSON from the reader
+	// Unmarshal YAML or JSON from the reader
 	var s service.Service
-	if err := json.NewDecoder(reader).Decode(&s); err != nil {
-		return nil, fmt.Errorf("could not unmarshal json: %s", err)
+	if err := yaml.NewDecoder(reader).Decode(&s); err != nil {
+		return nil, fmt.Errorf("could not unmarshal service: %s", err)
 	}
 
 	// Connect to the client
@@ -202,7 +204,8 @@ func (a *api) UpdateService(reader io.Reader) (*service.Service, error) {
 	}
 
 	// Update the service
-	if err := client.UpdateService(s, &unusedInt); err != nil {
+	request := dao.ServiceUpdateRequest{Service: s, Author: currentUsername()}
+	if err := client.UpdateServiceAs(request, &unusedInt); err != nil {
 		return nil, err
 	}
 
@@ -264,3 +267,66 @@ func (a *api) AssignIP(config IPConfig) error {
 
 	return nil
 }
+
+// ServiceUptimeConfig selects the windows to report the availability of a
+// service over
+type ServiceUptimeConfig struct {
+	ServiceID string
+	Windows   []string
+	History   bool
+}
+
+// GetServiceUptime reports the availability of a service from its health
+// check history
+func (a *api) GetServiceUptime(config ServiceUptimeConfig) (*healthhistory.Report, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	request := dao.ServiceUptimeRequest{
+		ServiceID: config.ServiceID,
+		Windows:   config.Windows,
+		History:   config.History,
+	}
+	var report healthhistory.Report
+	if err := client.GetServiceUptime(request, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// GetServiceConfigHistory returns the changes to the config files of a
+// service, oldest first
+func (a *api) GetServiceConfigHistory(serviceID, filename string) ([]serviceconfigfile.Revision, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	request := dao.ServiceConfigHistoryRequest{ServiceID: serviceID, Filename: filename}
+	var revisions []serviceconfigfile.Revision
+	if err := client.GetServiceConfigHistory(request, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// RevertServiceConfig restores a config file of a service to an earlier
+// revision
+func (a *api) RevertServiceConfig(serviceID, revisionID string, restart bool) error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	request := dao.ServiceConfigRevertRequest{
+		ServiceID:  serviceID,
+		RevisionID: revisionID,
+		Author:     currentUsername(),
+		Restart:    restart,
+	}
+	return client.RevertServiceConfig(request, &unusedInt)
+}
@@ -14,10 +14,14 @@
 package api
 
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/control-center/serviced/commons/yaml"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicedefinition"
@@ -30,6 +34,16 @@ type DeployTemplateConfig struct {
 	PoolID          string
 	DeploymentID    string
 	ManualAssignIPs bool
+	Params          map[string]string
+}
+
+// UpgradeTemplateConfig is the configuration object to upgrade a deployed
+// tenant to a template
+type UpgradeTemplateConfig struct {
+	ID       string
+	TenantID string
+	DryRun   bool
+	Params   map[string]string
 }
 
 // CompileTemplateConfig is the configuration object to conpile a template directory
@@ -38,6 +52,20 @@ type CompileTemplateConfig struct {
 	Map ImageMap
 }
 
+// TestLogsConfig is the configuration object to run sample log lines through
+// the parsers of a log config
+type TestLogsConfig struct {
+	Template string // template id, template file or service definition directory
+	Type     string // log type of the log config
+	Input    io.Reader
+}
+
+// TestLogsResult holds the messages logstash would index for the sample lines
+type TestLogsResult struct {
+	Messages []map[string]interface{}
+	Skipped  []string // filters that were not applied
+}
+
 // Gets all available service templates
 func (a *api) GetServiceTemplates() ([]template.ServiceTemplate, error) {
 	client, err := a.connectDAO()
@@ -83,10 +111,10 @@ func (a *api) GetServiceTemplate(id string) (*template.ServiceTemplate, error) {
 
 // Adds a new service template
 func (a *api) AddServiceTemplate(reader io.Reader) (*template.ServiceTemplate, error) {
-	// Unmarshal JSON from the reader
+	// Unmarshal YAML or JSON from the reader
 	var t template.ServiceTemplate
-	if err := json.NewDecoder(reader).Decode(&t); err != nil {
-		return nil, fmt.Errorf("could not unmarshal json: %s", err)
+	if err := yaml.NewDecoder(reader).Decode(&t); err != nil {
+		return nil, fmt.Errorf("could not unmarshal template: %s", err)
 	}
 
 	// Connect to the client
@@ -118,11 +146,17 @@ func (a *api) RemoveServiceTemplate(id string) error {
 	return nil
 }
 
-// CompileTemplate builds a template given a source path
-func (a *api) CompileServiceTemplate(config CompileTemplateConfig) (*template.ServiceTemplate, error) {
-	st, err := template.BuildFromPath(config.Dir)
+// CompileTemplate builds a template given a source path, and returns where
+// the fields of its services came from
+func (a *api) CompileServiceTemplate(config CompileTemplateConfig) (*template.ServiceTemplate, []template.Provenance, error) {
+	built, err := template.BuildFromPath(config.Dir)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	// registered templates are only looked up if the directory includes them
+	st, provenance, err := template.Compile(*built, config.Dir, a.GetServiceTemplate)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var mapImageNames func(*servicedefinition.ServiceDefinition)
@@ -137,7 +171,7 @@ func (a *api) CompileServiceTemplate(config CompileTemplateConfig) (*template.Se
 	for idx := range st.Services {
 		mapImageNames(&st.Services[idx])
 	}
-	return st, nil
+	return st, provenance, nil
 }
 
 // DeployTemplate deploys a template given its template ID
@@ -151,6 +185,7 @@ func (a *api) DeployServiceTemplate(config DeployTemplateConfig) ([]service.Serv
 		PoolID:       config.PoolID,
 		TemplateID:   config.ID,
 		DeploymentID: config.DeploymentID,
+		Params:       config.Params,
 	}
 
 	var ids []string
@@ -173,3 +208,140 @@ func (a *api) DeployServiceTemplate(config DeployTemplateConfig) ([]service.Serv
 
 	return svcs, nil
 }
+
+// GetServiceTemplateHistory returns the versions of a template, oldest first
+func (a *api) GetServiceTemplateHistory(id string) ([]template.TemplateHistory, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []template.TemplateHistory
+	if err := client.GetServiceTemplateHistory(id, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// RollbackServiceTemplate restores a previous version of a template and
+// returns its hash
+func (a *api) RollbackServiceTemplate(id, hash string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	req := dao.TemplateRollbackRequest{
+		TemplateID: id,
+		Hash:       hash,
+	}
+	if err := client.RollbackServiceTemplate(req, &hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// UpgradeServiceTemplate upgrades a deployed tenant to a template and returns
+// the changes that were made, or would be made in a dry run
+func (a *api) UpgradeServiceTemplate(config UpgradeTemplateConfig) (*template.UpgradePlan, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	req := dao.TemplateUpgradeRequest{
+		TemplateID: config.ID,
+		TenantID:   config.TenantID,
+		DryRun:     config.DryRun,
+		Params:     config.Params,
+	}
+
+	var plan template.UpgradePlan
+	if err := client.UpgradeTemplate(req, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// loadTemplate returns the compiled template of a directory of service
+// definitions, a template file or a registered template
+func (a *api) loadTemplate(name string) (*template.ServiceTemplate, error) {
+	var st *template.ServiceTemplate
+	dir := ""
+	if fi, err := os.Stat(name); err == nil && fi.IsDir() {
+		if st, err = template.BuildFromPath(name); err != nil {
+			return nil, err
+		}
+		dir = name
+	} else if err == nil {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		st = &template.ServiceTemplate{}
+		if err := yaml.NewDecoder(file).Decode(st); err != nil {
+			return nil, fmt.Errorf("could not unmarshal template: %s", err)
+		}
+		dir = filepath.Dir(name)
+	} else if st, err = a.GetServiceTemplate(name); err != nil {
+		return nil, err
+	}
+
+	compiled, _, err := template.Compile(*st, dir, a.GetServiceTemplate)
+	return compiled, err
+}
+
+// LintServiceTemplate statically checks the service definitions of a
+// template
+func (a *api) LintServiceTemplate(name string) ([]template.LintIssue, error) {
+	st, err := a.loadTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return template.Lint(st), nil
+}
+
+// TestTemplateLogs runs sample lines through the log parsers of a template
+func (a *api) TestTemplateLogs(config TestLogsConfig) (*TestLogsResult, error) {
+	st, err := a.loadTemplate(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var logConfig *servicedefinition.LogConfig
+	visit := func(sd *servicedefinition.ServiceDefinition) error {
+		for i := range sd.LogConfigs {
+			if logConfig == nil && sd.LogConfigs[i].Type == config.Type {
+				logConfig = &sd.LogConfigs[i]
+			}
+		}
+		return nil
+	}
+	for i := range st.Services {
+		servicedefinition.Walk(&st.Services[i], visit)
+	}
+	if logConfig == nil {
+		return nil, fmt.Errorf("no log config of type %s", config.Type)
+	}
+
+	var lines []string
+	reader := bufio.NewReader(config.Input)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	messages, err := logConfig.ParseLogs(lines)
+	if err != nil {
+		return nil, err
+	}
+	return &TestLogsResult{Messages: messages, Skipped: logConfig.Filters}, nil
+}
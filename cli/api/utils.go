@@ -70,6 +70,15 @@ func GetVarPath() string {
 	return path.Join(os.TempDir(), "serviced")
 }
 
+// currentUsername returns the name of the user running the command, which is
+// recorded as the author of changes
+func currentUsername() string {
+	if user, err := user.Current(); err == nil {
+		return user.Username
+	}
+	return os.Getenv("USER")
+}
+
 // GetESStartupTimeout returns the Elastic Search Startup Timeout
 func GetESStartupTimeout() int {
 	var timeout int
@@ -24,6 +24,7 @@ import (
 	"github.com/codegangsta/cli"
 	"github.com/control-center/serviced/cli/api"
 	"github.com/control-center/serviced/commons/docker"
+	"github.com/control-center/serviced/commons/layer"
 	"github.com/control-center/serviced/isvcs"
 	"github.com/control-center/serviced/rpc/rpcutils"
 	"github.com/control-center/serviced/servicedversion"
@@ -201,6 +202,7 @@ func New(driver api.API) *ServicedCli {
 		cli.IntFlag{"max-rpc-clients", configInt("MAX_RPC_CLIENTS", 3), "max number of rpc clients to an endpoint"},
 		cli.IntFlag{"rpc-dial-timeout", configInt("RPC_DIAL_TIMEOUT", 30), "timeout for creating rpc connections"},
 		cli.IntFlag{"snapshot-ttl", configInt("SNAPSHOT_TTL", 12), "snapshot TTL in hours, 0 to disable"},
+		cli.IntFlag{"max-image-layers", configInt("MAX_IMAGE_LAYERS", layer.WARN_LAYER_COUNT), "squash tenant images with more layers than this, 0 to disable"},
 
 		// Reimplementing GLOG flags :(
 		cli.BoolTFlag{"logtostderr", "log to standard error instead of files"},
@@ -209,6 +211,9 @@ func New(driver api.API) *ServicedCli {
 		cli.StringFlag{"logstash-es", configEnv("LOGSTASH_ES", "127.0.0.1:9100"), "host and port for logstash elastic search"},
 		cli.IntFlag{"logstash-max-days", configInt("LOGSTASH_MAX_DAYS", 14), "days to keep Logstash data"},
 		cli.IntFlag{"logstash-max-size", configInt("LOGSTASH_MAX_SIZE", 10), "max size of Logstash data to keep in gigabytes"},
+		cli.StringFlag{"alert-config", configEnv("ALERT_CONFIG", ""), "json file of alert notification sinks and routes"},
+		cli.StringFlag{"metrics-listen", configEnv("METRICS_LISTEN", "127.0.0.1:4980"), "address to serve prometheus metrics on (e.g. :4980 for every interface), empty to disable"},
+		cli.StringFlag{"secret-key-file", configEnv("SECRET_KEY_FILE", ""), "master key that encrypts the secrets, created if missing (default VARPATH/secrets.key)"},
 		cli.IntFlag{"v", configInt("LOG_LEVEL", 0), "log level for V logs"},
 		cli.StringFlag{"stderrthreshold", "", "logs at or above this threshold go to stderr"},
 		cli.StringFlag{"vmodule", "", "comma-separated list of pattern=N settings for file-filtered logging"},
@@ -221,8 +226,12 @@ func New(driver api.API) *ServicedCli {
 	c.initTemplate()
 	c.initService()
 	c.initSnapshot()
+	c.initSecret()
 	c.initLog()
+	c.initEvent()
+	c.initStream()
 	c.initBackup()
+	c.initManifest()
 	c.initMetric()
 	c.initDocker()
 	c.initScript()
@@ -280,6 +289,10 @@ func (c *ServicedCli) cmdInit(ctx *cli.Context) error {
 		MaxRPCClients:        ctx.GlobalInt("max-rpc-clients"),
 		RPCDialTimeout:       ctx.GlobalInt("rpc-dial-timeout"),
 		SnapshotTTL:          ctx.GlobalInt("snapshot-ttl"),
+		MaxImageLayers:       ctx.GlobalInt("max-image-layers"),
+		AlertConfig:          ctx.GlobalString("alert-config"),
+		MetricsListen:        ctx.GlobalString("metrics-listen"),
+		SecretKeyFile:        ctx.GlobalString("secret-key-file"),
 	}
 	if os.Getenv("SERVICED_MASTER") == "1" {
 		options.Master = true
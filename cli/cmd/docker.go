@@ -15,9 +15,12 @@ package cmd
 
 import (
 	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/commons/layer"
 	"github.com/zenoss/glog"
 
+	"encoding/json"
 	"fmt"
+	"os"
 )
 
 // initDocker is the initializer for serviced docker
@@ -46,6 +49,21 @@ func (c *ServicedCli) initDocker() {
 					cli.StringFlag{"endpoint", "unix:///var/run/docker.sock", "docker endpoint"},
 				},
 			},
+			{
+				Name:        "maintain",
+				Usage:       "serviced docker maintain",
+				Description: "maintain squashes tenant images with too many layers and removes unused images and registry tags",
+				Action:      c.cmdMaintainImages,
+				Flags: []cli.Flag{
+					cli.IntFlag{"max-layers", layer.WARN_LAYER_COUNT, "squash tenant images with more layers than this, 0 to disable"},
+				},
+			},
+			{
+				Name:        "inventory",
+				Usage:       "serviced docker inventory SERVICEID",
+				Description: "inventory reports the packages, layers and commit history of the images used by a service's tenant",
+				Action:      c.cmdImageInventory,
+			},
 			{
 				Name:        "reset-registry",
 				Usage:       "serviced docker reset-registry",
@@ -93,6 +111,52 @@ func (c *ServicedCli) cmdRegistrySync(ctx *cli.Context) {
 	}
 }
 
+// serviced docker maintain
+func (c *ServicedCli) cmdMaintainImages(ctx *cli.Context) {
+	report, err := c.driver.MaintainImages(ctx.Int("max-layers"))
+	if err != nil {
+		glog.Fatalf("error maintaining docker images: %s", err)
+	}
+
+	for _, image := range report.SquashedImages {
+		fmt.Printf("squashed image %s\n", image)
+	}
+	for _, image := range report.RemovedImages {
+		fmt.Printf("removed image %s\n", image)
+	}
+	for _, tag := range report.RemovedTags {
+		fmt.Printf("removed registry tag %s\n", tag)
+	}
+	fmt.Printf("reclaimed %d bytes\n", report.ReclaimedBytes)
+}
+
+// serviced docker inventory SERVICEID
+func (c *ServicedCli) cmdImageInventory(ctx *cli.Context) {
+	if len(ctx.Args()) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "inventory")
+		return
+	}
+
+	svc, err := c.searchForService(ctx.Args().First())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	report, err := c.driver.GetImageInventory(svc.ID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if jsonReport, err := json.MarshalIndent(report, " ", "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal image inventory: %s\n", err)
+	} else {
+		fmt.Println(string(jsonReport))
+	}
+}
+
 func (c *ServicedCli) cmdResetRegistry(ctx *cli.Context) {
 	if err := c.driver.ResetRegistry(); err != nil {
 		glog.Fatalf("error while resetting the registry: %s", err)
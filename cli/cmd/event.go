@@ -0,0 +1,116 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/event"
+)
+
+// initEvent is the initializer for serviced event
+func (c *ServicedCli) initEvent() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "event",
+		Usage:       "Lists threshold events",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:        "list",
+				Usage:       "Lists the events raised by monitoring profile thresholds",
+				Description: "serviced event list",
+				Action:      c.cmdEventList,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "service",
+						Value: "",
+						Usage: "service ID or name",
+					},
+					cli.StringFlag{
+						Name:  "pool",
+						Value: "",
+						Usage: "resource pool ID",
+					},
+					cli.StringFlag{
+						Name:  "since",
+						Value: "24h",
+						Usage: "RFC3339 timestamp, yyyy.mm.dd or duration ago (e.g. 1h)",
+					},
+					cli.IntFlag{
+						Name:  "limit",
+						Value: event.DefaultLimit,
+						Usage: "maximum number of events to show",
+					},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			},
+		},
+	})
+}
+
+// serviced event list [--service SERVICE|--pool POOLID] [--since TIME] [--limit N] [--verbose, -v]
+func (c *ServicedCli) cmdEventList(ctx *cli.Context) {
+	cfg := api.EventListConfig{
+		SourceID: ctx.String("pool"),
+		Since:    ctx.String("since"),
+		Limit:    ctx.Int("limit"),
+	}
+	if keyword := ctx.String("service"); keyword != "" {
+		if cfg.SourceID != "" {
+			fmt.Printf("Incorrect Usage.\n\n")
+			cli.ShowCommandHelp(ctx, "list")
+			return
+		}
+		svc, err := c.searchForService(keyword)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		cfg.SourceID = svc.ID
+	}
+
+	events, err := c.driver.GetEvents(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if len(events) == 0 {
+		fmt.Fprintln(os.Stderr, "no events found")
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonEvents, err := json.MarshalIndent(events, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal event list: %s\n", err)
+		} else {
+			fmt.Println(string(jsonEvents))
+		}
+		return
+	}
+
+	tableEvent := newtable(0, 8, 2)
+	tableEvent.printrow("TIMESTAMP", "STATUS", "SOURCE", "INSTANCE", "THRESHOLD", "METRIC", "VALUE", "SUMMARY")
+	for _, e := range events {
+		status := "violated"
+		if e.Cleared {
+			status = "cleared"
+		}
+		tableEvent.printrow(e.Timestamp.Local().Format(time.RFC3339), status, e.SourceID, e.InstanceID, e.ThresholdName, e.Metric, fmt.Sprintf("%.2f", e.Value), e.Summary)
+	}
+	tableEvent.flush()
+}
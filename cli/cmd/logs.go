@@ -14,11 +14,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/commons/logstash"
 )
 
 // Initializer for serviced log
@@ -57,6 +60,65 @@ func (c *ServicedCli) initLog() {
 					},
 				},
 			},
+			{
+				Name:        "search",
+				Usage:       "Searches the logs of services",
+				Description: "serviced log search [QUERY]",
+				Action:      c.cmdSearchLogs,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "service",
+						Value: "",
+						Usage: "service ID or name (includes all sub-services)",
+					},
+					cli.StringFlag{
+						Name:  "from",
+						Value: "",
+						Usage: "RFC3339 timestamp, yyyy.mm.dd or duration ago (e.g. 1h)",
+					},
+					cli.StringFlag{
+						Name:  "to",
+						Value: "",
+						Usage: "RFC3339 timestamp, yyyy.mm.dd or duration ago (e.g. 1h)",
+					},
+					cli.StringSliceFlag{
+						Name:  "field",
+						Value: &cli.StringSlice{},
+						Usage: "name:value; only messages whose field contains value",
+					},
+					cli.IntFlag{
+						Name:  "offset",
+						Value: 0,
+						Usage: "number of matching messages to skip",
+					},
+					cli.IntFlag{
+						Name:  "limit",
+						Value: logstash.DefaultLimit,
+						Usage: "maximum number of messages to show",
+					},
+				},
+			},
+			{
+				Name:        "retention",
+				Usage:       "Shows the log retention policy and the state of the logstash indices",
+				Description: "serviced log retention",
+				Action:      c.cmdLogRetention,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			},
+			{
+				Name:        "set-retention",
+				Usage:       "Replaces the log retention policy",
+				Description: "serviced log set-retention [FILE]",
+				Action:      c.cmdSetLogRetention,
+			},
+			{
+				Name:        "restore",
+				Usage:       "Loads an archived day of logs back into logstash",
+				Description: "serviced log restore YYYYMMDD",
+				Action:      c.cmdRestoreLogs,
+			},
 		},
 	})
 }
@@ -85,6 +147,125 @@ func (c *ServicedCli) cmdExportLogs(ctx *cli.Context) {
 	}
 }
 
+// serviced log search [QUERY]
+func (c *ServicedCli) cmdSearchLogs(ctx *cli.Context) {
+	if len(ctx.Args()) > 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "search")
+		return
+	}
+
+	cfg := api.LogSearchConfig{
+		Query:    ctx.Args().First(),
+		FromTime: ctx.String("from"),
+		ToTime:   ctx.String("to"),
+		Fields:   ctx.StringSlice("field"),
+		Offset:   ctx.Int("offset"),
+		Limit:    ctx.Int("limit"),
+	}
+	if keyword := ctx.String("service"); keyword != "" {
+		svc, err := c.searchForService(keyword)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		cfg.ServiceID = svc.ID
+	}
+
+	result, err := c.driver.SearchLogs(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	for _, msg := range result.Messages {
+		fmt.Printf("%s %s %s/%s %s: %s\n", msg.Timestamp.Format(time.RFC3339), msg.Host, msg.Service, msg.Instance, msg.File, msg.Message)
+	}
+	if shown := cfg.Offset + len(result.Messages); len(result.Messages) > 0 && shown < result.Total {
+		fmt.Fprintf(os.Stderr, "showing %d-%d of %d matching messages\n", cfg.Offset+1, shown, result.Total)
+	}
+}
+
+// serviced log retention
+func (c *ServicedCli) cmdLogRetention(ctx *cli.Context) {
+	status, err := c.driver.GetLogRetention()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonStatus, err := json.MarshalIndent(status, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal log retention status: %s\n", err)
+		} else {
+			fmt.Println(string(jsonStatus))
+		}
+		return
+	}
+
+	fmt.Printf("Default retention: %d days\n", status.Policy.DefaultDays)
+	for _, rule := range status.Policy.Rules {
+		tenant, logType := rule.TenantID, rule.Type
+		if tenant == "" {
+			tenant = "*"
+		}
+		if logType == "" {
+			logType = "*"
+		}
+		fmt.Printf("Tenant %s, type %s: %d days\n", tenant, logType, rule.Days)
+	}
+	if status.Policy.Archive {
+		fmt.Printf("Archiving to %s\n", status.ArchiveDir)
+	}
+	fmt.Println()
+
+	t := newtable(0, 8, 2)
+	t.printrow("DAY", "DOCUMENTS", "SIZE", "ARCHIVED", "RESTORED", "EXPIRES")
+	for _, index := range status.Indices {
+		t.printrow(index.Day, index.Documents, fmt.Sprintf("%.1fM", float64(index.SizeBytes)/(1024*1024)), index.Archived, index.Restored, index.Expires)
+	}
+	for _, day := range status.Archives {
+		t.printrow(day, "-", "-", true, false, "-")
+	}
+	t.flush()
+}
+
+// serviced log set-retention [FILE]
+func (c *ServicedCli) cmdSetLogRetention(ctx *cli.Context) {
+	var input *os.File
+	if filename := ctx.Args().First(); filename != "" {
+		var err error
+		if input, err = os.Open(filename); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		defer input.Close()
+	} else {
+		input = os.Stdin
+	}
+
+	var policy logstash.RetentionPolicy
+	if err := json.NewDecoder(input).Decode(&policy); err != nil {
+		fmt.Fprintf(os.Stderr, "could not parse log retention policy: %s\n", err)
+		return
+	}
+	if err := c.driver.SetLogRetention(policy); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// serviced log restore YYYYMMDD
+func (c *ServicedCli) cmdRestoreLogs(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "restore")
+		return
+	}
+	if err := c.driver.RestoreLogs(ctx.Args().First()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
 // TODO: finish this, once flag completion is supported by cli.
 // // Bash-completion command
 // func (c *ServicedCli) printLogExportCompletion(ctx *cli.Context) {
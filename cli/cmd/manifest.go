@@ -0,0 +1,103 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/manifest"
+)
+
+// Initializer for serviced apply and serviced diff
+func (c *ServicedCli) initManifest() {
+	c.app.Commands = append(
+		c.app.Commands,
+		cli.Command{
+			Name:        "apply",
+			Usage:       "Makes the pools, templates and deployments match YAML or JSON manifests",
+			Description: "serviced apply -f DIR",
+			Action:      c.cmdApply,
+			Flags: []cli.Flag{
+				cli.StringFlag{"file, f", "", "Directory of manifests, or one manifest"},
+				cli.BoolFlag{"prune", "Remove what the manifests do not declare; deployments are stopped first, and only those made by an earlier apply are removed"},
+				cli.BoolFlag{"dry-run", "Show the changes without making them"},
+			},
+		},
+		cli.Command{
+			Name:        "diff",
+			Usage:       "Shows the changes that serviced apply would make; exits with 1 if there are changes",
+			Description: "serviced diff -f DIR",
+			Action:      c.cmdDiff,
+			Flags: []cli.Flag{
+				cli.StringFlag{"file, f", "", "Directory of manifests, or one manifest"},
+				cli.BoolFlag{"prune", "Include the removals of serviced apply --prune"},
+			},
+		},
+	)
+}
+
+// serviced apply -f DIR [--prune] [--dry-run]
+func (c *ServicedCli) cmdApply(ctx *cli.Context) {
+	path := ctx.String("file")
+	if path == "" {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "apply")
+		return
+	}
+
+	config := api.ApplyConfig{Path: path, Prune: ctx.Bool("prune"), DryRun: ctx.Bool("dry-run")}
+	plan, err := c.driver.ApplyManifest(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		c.exit(1)
+		return
+	}
+	printPlan(plan)
+}
+
+// serviced diff -f DIR [--prune]
+func (c *ServicedCli) cmdDiff(ctx *cli.Context) {
+	path := ctx.String("file")
+	if path == "" {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "diff")
+		return
+	}
+
+	config := api.ApplyConfig{Path: path, Prune: ctx.Bool("prune"), DryRun: true}
+	plan, err := c.driver.ApplyManifest(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		c.exit(2)
+		return
+	}
+	printPlan(plan)
+	if !plan.Empty() {
+		c.exit(1)
+	}
+}
+
+// printPlan shows the changes of a plan, one per line
+func printPlan(plan *manifest.Plan) {
+	if plan.Empty() {
+		fmt.Println("No changes")
+		return
+	}
+	for _, change := range plan.Changes {
+		fmt.Println(change)
+	}
+}
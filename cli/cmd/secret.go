@@ -0,0 +1,231 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+// initSecret is the initializer for serviced secret
+func (c *ServicedCli) initSecret() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "secret",
+		Usage:       "Administers the secrets that services refer to",
+		Description: "The values of the secrets are encrypted with the master key in VARPATH/secrets.key, or the file given by --secret-key-file. Backups include neither the secrets nor the key; use export and import to move the secrets to another master.",
+		Subcommands: []cli.Command{
+			{
+				Name:        "list",
+				Usage:       "Lists the names of the secrets",
+				Description: "serviced secret list",
+				Action:      c.cmdSecretList,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:        "set",
+				Usage:       "Sets the value of a secret, read from stdin if it is not given",
+				Description: "serviced secret set NAME [VALUE]",
+				Action:      c.cmdSecretSet,
+			}, {
+				Name:        "get",
+				Usage:       "Shows the value of a secret",
+				Description: "serviced secret get NAME",
+				Action:      c.cmdSecretGet,
+			}, {
+				Name:        "remove",
+				ShortName:   "rm",
+				Usage:       "Removes secrets",
+				Description: "serviced secret remove NAME ...",
+				Action:      c.cmdSecretRemove,
+			}, {
+				Name:        "export",
+				Usage:       "Writes the names and values of all the secrets to a JSON file, readable only by its owner",
+				Description: "serviced secret export FILE",
+				Action:      c.cmdSecretExport,
+			}, {
+				Name:        "import",
+				Usage:       "Sets the secrets in a JSON file written by export",
+				Description: "serviced secret import FILE",
+				Action:      c.cmdSecretImport,
+			},
+		},
+	})
+}
+
+// serviced secret list
+func (c *ServicedCli) cmdSecretList(ctx *cli.Context) {
+	secrets, err := c.driver.GetSecrets()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if len(secrets) == 0 {
+		fmt.Fprintln(os.Stderr, "no secrets found")
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonSecrets, err := json.MarshalIndent(secrets, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal secrets: %s\n", err)
+		} else {
+			fmt.Println(string(jsonSecrets))
+		}
+		return
+	}
+
+	t := newtable(0, 8, 2)
+	t.printrow("NAME", "CREATED", "UPDATED")
+	for _, s := range secrets {
+		t.printrow(s.Name, s.CreatedAt.Local().Format(time.RFC3339), s.UpdatedAt.Local().Format(time.RFC3339))
+	}
+	t.flush()
+}
+
+// serviced secret set NAME [VALUE]
+func (c *ServicedCli) cmdSecretSet(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "set")
+		return
+	}
+
+	var value string
+	if len(args) > 1 {
+		value = args[1]
+	} else {
+		// keep the value out of the shell history
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read the value: %s\n", err)
+			return
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if err := c.driver.SetSecret(args[0], value); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(args[0])
+	}
+}
+
+// serviced secret get NAME
+func (c *ServicedCli) cmdSecretGet(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "get")
+		return
+	}
+
+	if value, err := c.driver.GetSecret(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(value)
+	}
+}
+
+// serviced secret remove NAME ...
+func (c *ServicedCli) cmdSecretRemove(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "remove")
+		return
+	}
+
+	for _, name := range args {
+		if err := c.driver.RemoveSecret(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		} else {
+			fmt.Println(name)
+		}
+	}
+}
+
+// serviced secret export FILE
+func (c *ServicedCli) cmdSecretExport(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "export")
+		return
+	}
+
+	secrets, err := c.driver.GetSecrets()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	values := make(map[string]string)
+	for _, s := range secrets {
+		if values[s.Name], err = c.driver.GetSecret(s.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", s.Name, err)
+			return
+		}
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal secrets: %s\n", err)
+		return
+	}
+	if err := ioutil.WriteFile(args[0], data, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Printf("Exported %d secrets to %s\n", len(values), args[0])
+}
+
+// serviced secret import FILE
+func (c *ServicedCli) cmdSecretImport(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "import")
+		return
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		fmt.Fprintf(os.Stderr, "could not read secrets from %s: %s\n", args[0], err)
+		return
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := c.driver.SetSecret(name, values[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		} else {
+			fmt.Println(name)
+		}
+	}
+}
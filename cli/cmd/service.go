@@ -19,6 +19,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
 	"strings"
 	"syscall"
@@ -30,6 +31,7 @@ import (
 	dockerclient "github.com/control-center/serviced/commons/docker"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/dfs"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/node"
@@ -74,6 +76,20 @@ func (c *ServicedCli) initService() {
 				Flags: []cli.Flag{
 					cli.BoolFlag{"ascii, a", "use ascii characters for service tree (env SERVICED_TREE_ASCII=1 will default to ascii)"},
 				},
+			}, {
+				Name:        "health",
+				Usage:       "Displays the availability of a service from its health check history",
+				Description: "serviced service health { SERVICEID | SERVICENAME | [POOL/]...PARENTNAME.../SERVICENAME }",
+				Action:      c.cmdServiceHealth,
+				Flags: []cli.Flag{
+					cli.StringSliceFlag{
+						Name:  "window",
+						Value: &cli.StringSlice{},
+						Usage: "window to report, e.g. 1h or 7d (default 1h, 24h, 7d and the SLO window)",
+					},
+					cli.BoolFlag{"history", "Show the periods each health check passed or failed"},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
 			}, {
 				Name:        "add",
 				Usage:       "Adds a new service",
@@ -99,6 +115,7 @@ func (c *ServicedCli) initService() {
 				Action:       c.cmdServiceEdit,
 				Flags: []cli.Flag{
 					cli.StringFlag{"editor, e", os.Getenv("EDITOR"), "Editor used to update the service definition"},
+					cli.StringFlag{"format", "json", "Format of the service definition in the editor (json or yaml)"},
 				},
 			}, {
 				Name:         "assign-ip",
@@ -206,6 +223,8 @@ func (c *ServicedCli) initService() {
 				Before:       c.cmdServiceLogs,
 				Flags: []cli.Flag{
 					cli.StringFlag{"endpoint", configEnv("ENDPOINT", getLocalAgentIP()), "endpoint for remote serviced (example.com:4979)"},
+					cli.BoolFlag{"follow", "follow the logs of all instances of the service"},
+					cli.IntFlag{"tail", 10, "lines of existing output to show per instance when following, 0 for all"},
 				},
 			}, {
 				Name:         "list-snapshots",
@@ -222,6 +241,47 @@ func (c *ServicedCli) initService() {
 				Flags: []cli.Flag{
 					cli.StringFlag{"description, d", "", "a description of the snapshot"},
 				},
+			}, {
+				Name:         "export",
+				Usage:        "Writes an application with its volume and images to a tgz file",
+				Description:  "serviced service export SERVICEID FILE",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdServiceExport,
+			}, {
+				Name:        "import",
+				Usage:       "Adds an application from a tgz file written by serviced service export",
+				Description: "serviced service import FILE POOLID",
+				Action:      c.cmdServiceImport,
+			}, {
+				Name:  "config",
+				Usage: "Shows and restores earlier versions of the config files of a service",
+				Subcommands: []cli.Command{
+					{
+						Name:         "history",
+						Usage:        "Lists the changes to the config files of a service",
+						Description:  "serviced service config history SERVICEID [FILENAME]",
+						BashComplete: c.printServicesFirst,
+						Action:       c.cmdServiceConfigHistory,
+						Flags: []cli.Flag{
+							cli.BoolFlag{"verbose, v", "Show JSON format"},
+						},
+					}, {
+						Name:         "diff",
+						Usage:        "Shows the differences between a revision of a config file and the current file or another revision",
+						Description:  "serviced service config diff SERVICEID REVISION [REVISION]",
+						BashComplete: c.printServicesFirst,
+						Action:       c.cmdServiceConfigDiff,
+					}, {
+						Name:         "revert",
+						Usage:        "Restores a config file of a service to an earlier revision",
+						Description:  "serviced service config revert SERVICEID REVISION",
+						BashComplete: c.printServicesFirst,
+						Action:       c.cmdServiceConfigRevert,
+						Flags: []cli.Flag{
+							cli.BoolFlag{"restart", "Restart the running instances of the service"},
+						},
+					},
+				},
 			},
 		},
 	})
@@ -497,7 +557,17 @@ func (c *ServicedCli) cmdServiceStatus(ctx *cli.Context) {
 				}
 			}
 		} else {
-			if svc.Instances > 1 {
+			// a service with quarantined instances is degraded; its
+			// instances are listed beneath it
+			degraded := false
+			for _, svcstatus := range statemap {
+				degraded = degraded || svcstatus.State.Quarantined
+			}
+			instanceParentID := svc.ParentServiceID
+			if svc.Instances > 1 && degraded {
+				lines[iid]["Status"] = "Degraded"
+				instanceParentID = svc.ID
+			} else if svc.Instances > 1 {
 				delete(lines, iid)
 			}
 
@@ -508,7 +578,7 @@ func (c *ServicedCli) cmdServiceStatus(ctx *cli.Context) {
 						"ID":        iid,
 						"ServiceID": svc.ID,
 						"Name":      fmt.Sprintf("%s/%d", svc.Name, svcstatus.State.InstanceID),
-						"ParentID":  svc.ParentServiceID,
+						"ParentID":  instanceParentID,
 					}
 				}
 				lines[iid]["Hostname"] = hostmap[svcstatus.State.HostID].Name
@@ -521,6 +591,9 @@ func (c *ServicedCli) cmdServiceStatus(ctx *cli.Context) {
 					insync = "N"
 				}
 				lines[iid]["InSync"] = insync
+				if svcstatus.State.Restarts > 0 || svcstatus.State.Quarantined {
+					lines[iid]["Reason"] = fmt.Sprintf("%d restarts; %s", svcstatus.State.Restarts, svcstatus.State.Reason)
+				}
 			}
 		}
 	}
@@ -545,10 +618,10 @@ func (c *ServicedCli) cmdServiceStatus(ctx *cli.Context) {
 
 	childMap[""] = top
 	tableService := newtable(0, 8, 2)
-	tableService.printrow("NAME", "ID", "STATUS", "UPTIME", "HOST", "IN_SYNC", "DOCKER_ID")
+	tableService.printrow("NAME", "ID", "STATUS", "UPTIME", "HOST", "IN_SYNC", "DOCKER_ID", "REASON")
 	tableService.formattree(childMap, "", func(id string) (row []interface{}) {
 		s := lines[id]
-		return append(row, s["Name"], s["ID"], s["Status"], s["Uptime"], s["Hostname"], s["InSync"], s["DockerID"])
+		return append(row, s["Name"], s["ID"], s["Status"], s["Uptime"], s["Hostname"], s["InSync"], s["DockerID"], s["Reason"])
 	}, func(row []interface{}) string {
 		return strings.ToLower(row[1].(string))
 	})
@@ -556,6 +629,72 @@ func (c *ServicedCli) cmdServiceStatus(ctx *cli.Context) {
 	return
 }
 
+// serviced service health { SERVICEID | SERVICENAME | [POOL/]...PARENTNAME.../SERVICENAME }
+func (c *ServicedCli) cmdServiceHealth(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "health")
+		return
+	}
+
+	svc, err := c.searchForService(ctx.Args().First())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	cfg := api.ServiceUptimeConfig{
+		ServiceID: svc.ID,
+		Windows:   ctx.StringSlice("window"),
+		History:   ctx.Bool("history"),
+	}
+	report, err := c.driver.GetServiceUptime(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonReport, err := json.MarshalIndent(report, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal health report: %s\n", err)
+		} else {
+			fmt.Println(string(jsonReport))
+		}
+		return
+	}
+
+	if report.SLO != nil {
+		fmt.Printf("SLO: %g%% over %s\n", report.SLO.Objective, healthhistory.FormatWindow(report.SLO.Window()))
+		if report.BudgetRemaining != nil {
+			fmt.Printf("Error budget remaining: %.1f%%\n", *report.BudgetRemaining)
+		}
+		fmt.Println()
+	}
+
+	t := newtable(0, 8, 2)
+	t.printrow("WINDOW", "AVAILABILITY", "UP", "DOWN", "BURN RATE")
+	for _, w := range report.Windows {
+		burnRate := "-"
+		if report.SLO != nil {
+			burnRate = fmt.Sprintf("%.2f", w.BurnRate)
+		}
+		up := time.Duration(w.UpSeconds) * time.Second
+		down := time.Duration(w.DownSeconds) * time.Second
+		t.printrow(w.Window, fmt.Sprintf("%.3f%%", w.Availability), up, down, burnRate)
+	}
+	t.flush()
+
+	if cfg.History {
+		fmt.Println()
+		t := newtable(0, 8, 2)
+		t.printrow("START", "END", "INSTANCE", "CHECK", "STATUS")
+		for _, r := range report.History {
+			t.printrow(r.Start.Local().Format(time.RFC3339), r.End.Local().Format(time.RFC3339), r.InstanceID, r.Name, r.Status)
+		}
+		t.flush()
+	}
+}
+
 // serviced service list [--verbose, -v] [SERVICEID]
 func (c *ServicedCli) cmdServiceList(ctx *cli.Context) {
 	if len(ctx.Args()) > 0 {
@@ -700,7 +839,7 @@ func (c *ServicedCli) cmdServiceRemove(ctx *cli.Context) {
 	}
 }
 
-// serviced service edit SERVICEID
+// serviced service edit SERVICEID [--format json|yaml]
 func (c *ServicedCli) cmdServiceEdit(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 1 {
@@ -715,14 +854,14 @@ func (c *ServicedCli) cmdServiceEdit(ctx *cli.Context) {
 		return
 	}
 
-	jsonService, err := json.MarshalIndent(service, " ", "  ")
+	data, ext, err := marshalFormat(service, ctx.String("format"))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error marshalling service: %s\n", err)
 		return
 	}
 
-	name := fmt.Sprintf("serviced_service_edit_%s", service.ID)
-	reader, err := openEditor(jsonService, name, ctx.String("editor"))
+	name := fmt.Sprintf("serviced_service_edit_%s%s", service.ID, ext)
+	reader, err := openEditor(data, name, ctx.String("editor"))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return
@@ -1246,6 +1385,10 @@ func (c *ServicedCli) cmdServiceLogs(ctx *cli.Context) error {
 		return nil
 	}
 
+	if ctx.Bool("follow") {
+		return c.followServiceLogs(ctx, args[0])
+	}
+
 	rs, err := c.searchForRunningService(args[0])
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -1291,6 +1434,34 @@ func (c *ServicedCli) cmdServiceLogs(ctx *cli.Context) error {
 	return fmt.Errorf("serviced service logs")
 }
 
+// serviced service logs --follow SERVICEID
+func (c *ServicedCli) followServiceLogs(ctx *cli.Context, keyword string) error {
+	svc, err := c.searchForService(keyword)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	stopChan := make(chan struct{})
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signalChan
+		close(stopChan)
+	}()
+
+	cfg := api.FollowLogsConfig{
+		ServiceID: svc.ID,
+		Tail:      ctx.Int("tail"),
+		Output:    os.Stdout,
+	}
+	if err := c.driver.FollowLogs(cfg, stopChan); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return fmt.Errorf("serviced service logs")
+}
+
 // serviced service list-snapshot SERVICEID
 func (c *ServicedCli) cmdServiceListSnapshots(ctx *cli.Context) {
 	if len(ctx.Args()) < 1 {
@@ -1344,3 +1515,45 @@ func (c *ServicedCli) cmdServiceSnapshot(ctx *cli.Context) {
 		fmt.Println(snapshot)
 	}
 }
+
+// serviced service export SERVICEID FILE
+func (c *ServicedCli) cmdServiceExport(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "export")
+		return
+	}
+
+	svc, err := c.searchForService(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if path, err := c.driver.ExportService(svc.ID, args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if path == "" {
+		fmt.Fprintln(os.Stderr, "received nil path to export file")
+	} else {
+		fmt.Println(path)
+	}
+}
+
+// serviced service import FILE POOLID
+func (c *ServicedCli) cmdServiceImport(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "import")
+		return
+	}
+
+	if tenantID, err := c.driver.ImportService(args[0], args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if tenantID == "" {
+		fmt.Fprintln(os.Stderr, "received nil service id")
+	} else {
+		fmt.Println(tenantID)
+	}
+}
@@ -494,6 +494,7 @@ func ExampleServicedCLI_CmdServiceEdit_usage() {
 	//
 	// OPTIONS:
 	//    --editor, -e 	Editor used to update the service definition
+	//    --format 'json'	Format of the service definition in the editor (json or yaml)
 }
 
 func ExampleServicedCLI_CmdServiceEdit_fail() {
@@ -0,0 +1,170 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
+)
+
+// shortRevisionLength is the length of the revision ids that are shown
+const shortRevisionLength = 12
+
+// serviced service config history SERVICEID [FILENAME]
+func (c *ServicedCli) cmdServiceConfigHistory(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "history")
+		return
+	}
+
+	svc, err := c.searchForService(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	filename := ""
+	if len(args) > 1 {
+		filename = args[1]
+	}
+
+	revisions, err := c.driver.GetServiceConfigHistory(svc.ID, filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if len(revisions) == 0 {
+		fmt.Fprintln(os.Stderr, "no config file changes found")
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonRevisions, err := json.MarshalIndent(revisions, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal config history: %s\n", err)
+		} else {
+			fmt.Println(string(jsonRevisions))
+		}
+		return
+	}
+
+	t := newtable(0, 8, 2)
+	t.printrow("REVISION", "CREATED", "AUTHOR", "FILENAME")
+	for _, rev := range revisions {
+		author := rev.Author
+		if author == "" {
+			author = "unknown"
+		}
+		t.printrow(shortRevision(rev.ID), rev.CreatedAt.Local().Format(time.RFC3339), author, rev.Filename)
+	}
+	t.flush()
+}
+
+// serviced service config diff SERVICEID REVISION [REVISION]
+func (c *ServicedCli) cmdServiceConfigDiff(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "diff")
+		return
+	}
+
+	svc, err := c.searchForService(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	revisions, err := c.driver.GetServiceConfigHistory(svc.ID, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	from, err := findRevision(revisions, args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	fromName := fmt.Sprintf("%s@%s", from.Filename, shortRevision(from.ID))
+	toName, to := from.Filename+"@current", ""
+	if len(args) > 2 {
+		rev, err := findRevision(revisions, args[2])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		} else if rev.Filename != from.Filename {
+			fmt.Fprintf(os.Stderr, "revisions %s and %s are of different files\n", args[1], args[2])
+			return
+		}
+		toName, to = fmt.Sprintf("%s@%s", rev.Filename, shortRevision(rev.ID)), rev.ConfFile.Content
+	} else if conf, ok := svc.ConfigFiles[from.Filename]; ok {
+		to = conf.Content
+	} else {
+		toName = "/dev/null"
+	}
+
+	fmt.Print(serviceconfigfile.Diff(fromName, toName, from.ConfFile.Content, to))
+}
+
+// serviced service config revert SERVICEID REVISION
+func (c *ServicedCli) cmdServiceConfigRevert(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "revert")
+		return
+	}
+
+	svc, err := c.searchForService(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if err := c.driver.RevertServiceConfig(svc.ID, args[1], ctx.Bool("restart")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(svc.ID)
+	}
+}
+
+// findRevision returns the revision whose id starts with prefix
+func findRevision(revisions []serviceconfigfile.Revision, prefix string) (*serviceconfigfile.Revision, error) {
+	var found *serviceconfigfile.Revision
+	for i := range revisions {
+		if strings.HasPrefix(revisions[i].ID, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("revision %s is ambiguous", prefix)
+			}
+			found = &revisions[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("revision %s not found", prefix)
+	}
+	return found, nil
+}
+
+// shortRevision returns the abbreviated id of a revision
+func shortRevision(id string) string {
+	if len(id) > shortRevisionLength {
+		return id[:shortRevisionLength]
+	}
+	return id
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/stream"
+)
+
+// how long each poll for new events waits while following
+var followEventsWait = 25 * time.Second
+
+// initStream is the initializer for serviced events
+func (c *ServicedCli) initStream() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "events",
+		Usage:       "Shows changes to services, instances and hosts",
+		Description: "serviced events [--follow] [--tenant TENANT] [--service SERVICE] [--type TYPE[,TYPE...]]",
+		Action:      c.cmdEvents,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"follow, f", "Wait for new events and show them as they happen"},
+			cli.StringFlag{
+				Name:  "tenant",
+				Value: "",
+				Usage: "tenant ID or name",
+			},
+			cli.StringFlag{
+				Name:  "service",
+				Value: "",
+				Usage: "service ID or name",
+			},
+			cli.StringFlag{
+				Name:  "type",
+				Value: "",
+				Usage: "comma separated event types, e.g. " + stream.InstanceStarted + "," + stream.InstanceStopped,
+			},
+			cli.BoolFlag{"verbose, v", "Show JSON format"},
+		},
+	})
+}
+
+// serviced events [--follow] [--tenant TENANT] [--service SERVICE] [--type TYPE[,TYPE...]] [--verbose, -v]
+func (c *ServicedCli) cmdEvents(ctx *cli.Context) {
+	if len(ctx.Args()) > 0 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "events")
+		return
+	}
+
+	var cfg api.ServiceEventConfig
+	if keyword := ctx.String("tenant"); keyword != "" {
+		svc, err := c.searchForService(keyword)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		} else if svc.ParentServiceID != "" {
+			fmt.Fprintf(os.Stderr, "service %s is not a tenant\n", svc.Name)
+			return
+		}
+		cfg.Filter.TenantID = svc.ID
+	}
+	if keyword := ctx.String("service"); keyword != "" {
+		svc, err := c.searchForService(keyword)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		cfg.Filter.ServiceID = svc.ID
+	}
+	if types := ctx.String("type"); types != "" {
+		cfg.Filter.Types = strings.Split(types, ",")
+	}
+
+	printEvent := func(e stream.Event) {
+		if ctx.Bool("verbose") {
+			if jsonEvent, err := json.Marshal(e); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to marshal event: %s\n", err)
+			} else {
+				fmt.Println(string(jsonEvent))
+			}
+			return
+		}
+		fmt.Printf("%s  %-20s  %s\n", e.Timestamp.Local().Format(time.RFC3339), e.Type, e.Summary)
+	}
+
+	if !ctx.Bool("follow") {
+		events, err := c.driver.GetServiceEvents(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		} else if len(events) == 0 {
+			fmt.Fprintln(os.Stderr, "no events found")
+			return
+		}
+		for _, e := range events {
+			printEvent(e)
+		}
+		return
+	}
+
+	// follow until interrupted
+	cfg.Wait = followEventsWait
+	for {
+		events, err := c.driver.GetServiceEvents(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		for _, e := range events {
+			printEvent(e)
+			cfg.After = e.ID
+		}
+	}
+}
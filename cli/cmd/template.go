@@ -18,10 +18,13 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/commons/yaml"
 	template "github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/servicedversion"
 )
@@ -62,7 +65,36 @@ func (c *ServicedCli) initTemplate() {
 				Action:       c.cmdTemplateDeploy,
 				Flags: []cli.Flag{
 					cli.BoolFlag{"manual-assign-ips", "Manually assign IP addresses"},
+					cli.StringSliceFlag{"param", &cli.StringSlice{}, "Set a parameter of the template (e.g. --param instances=3)"},
+					cli.StringFlag{"values", "", "JSON or YAML file with the values of the parameters of the template"},
 				},
+			}, {
+				Name:         "upgrade",
+				Usage:        "Upgrades a deployed tenant to a template, keeping the changes made by users",
+				Description:  "serviced template upgrade TEMPLATEID TENANTID",
+				BashComplete: c.printTemplatesFirst,
+				Action:       c.cmdTemplateUpgrade,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"dry-run", "Show the changes without applying them"},
+					cli.StringSliceFlag{"param", &cli.StringSlice{}, "Change a parameter of the template (e.g. --param instances=3)"},
+					cli.StringFlag{"values", "", "JSON or YAML file with the values of the parameters of the template"},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:         "history",
+				Usage:        "Lists the versions of a template and their deployments",
+				Description:  "serviced template history TEMPLATEID",
+				BashComplete: c.printTemplatesFirst,
+				Action:       c.cmdTemplateHistory,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:         "rollback",
+				Usage:        "Restores a previous version of a template",
+				Description:  "serviced template rollback TEMPLATEID HASH",
+				BashComplete: c.printTemplatesFirst,
+				Action:       c.cmdTemplateRollback,
 			}, {
 				Name:        "compile",
 				Usage:       "Convert a directory of service definitions into a template",
@@ -71,6 +103,21 @@ func (c *ServicedCli) initTemplate() {
 				Flags: []cli.Flag{
 					cli.GenericFlag{
 						"map", &api.ImageMap{}, "Map a given image name to another (e.g. -map zenoss/zenoss5x:latest,quay.io/zenoss-core:alpha2)"},
+					cli.BoolFlag{"provenance", "Show the template or directory that each field of the services came from"},
+					cli.StringFlag{"format", "json", "Format of the compiled template (json or yaml)"},
+				},
+			}, {
+				Name:        "test-logs",
+				Usage:       "Runs sample log lines through the log parsers of a template",
+				Description: "serviced template test-logs TEMPLATEID|FILE|PATH LOGTYPE [SAMPLEFILE]",
+				Action:      c.cmdTemplateTestLogs,
+			}, {
+				Name:        "lint",
+				Usage:       "Checks the service definitions of a template for problems",
+				Description: "serviced template lint TEMPLATEID|FILE|PATH",
+				Action:      c.cmdTemplateLint,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
 				},
 			},
 		},
@@ -219,7 +266,44 @@ func (c *ServicedCli) cmdTemplateRemove(ctx *cli.Context) {
 	}
 }
 
-// serviced template deploy TEMPLATEID POOLID DEPLOYMENTID [--manual-assign-ips]
+// parseTemplateParams returns the values of template parameters from the
+// --values file and the --param flags, which take precedence
+func parseTemplateParams(ctx *cli.Context) (map[string]string, error) {
+	params := make(map[string]string)
+	if filename := ctx.String("values"); filename != "" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("could not open values file: %s", err)
+		}
+		defer file.Close()
+		var values map[string]interface{}
+		if err := yaml.NewDecoder(file).Decode(&values); err != nil {
+			return nil, fmt.Errorf("could not read values file %s: %s", filename, err)
+		}
+		for name, value := range values {
+			switch v := value.(type) {
+			case string:
+				params[name] = v
+			case float64:
+				params[name] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				params[name] = strconv.FormatBool(v)
+			default:
+				return nil, fmt.Errorf("value of parameter %s in %s must be a string, number or boolean", name, filename)
+			}
+		}
+	}
+	for _, param := range ctx.StringSlice("param") {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("parameter %s is not KEY=VALUE", param)
+		}
+		params[parts[0]] = parts[1]
+	}
+	return params, nil
+}
+
+// serviced template deploy TEMPLATEID POOLID DEPLOYMENTID [--manual-assign-ips] [--param KEY=VALUE ...] [--values FILE]
 func (c *ServicedCli) cmdTemplateDeploy(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 3 {
@@ -228,11 +312,17 @@ func (c *ServicedCli) cmdTemplateDeploy(ctx *cli.Context) {
 		return
 	}
 
+	params, err := parseTemplateParams(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
 	cfg := api.DeployTemplateConfig{
 		ID:              args[0],
 		PoolID:          args[1],
 		DeploymentID:    args[2],
 		ManualAssignIPs: ctx.Bool("manual-assign-ips"),
+		Params:          params,
 	}
 
 	fmt.Fprintln(os.Stderr, "Deploying template - please wait...")
@@ -247,13 +337,143 @@ func (c *ServicedCli) cmdTemplateDeploy(ctx *cli.Context) {
 	}
 }
 
+// serviced template upgrade TEMPLATEID TENANTID [--dry-run] [--param KEY=VALUE ...] [--values FILE] [--verbose, -v]
+func (c *ServicedCli) cmdTemplateUpgrade(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "upgrade")
+		return
+	}
+
+	tenant, err := c.searchForService(args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if tenant.ParentServiceID != "" {
+		fmt.Fprintf(os.Stderr, "service %s is not a tenant\n", tenant.Name)
+		return
+	}
+
+	params, err := parseTemplateParams(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	cfg := api.UpgradeTemplateConfig{
+		ID:       args[0],
+		TenantID: tenant.ID,
+		DryRun:   ctx.Bool("dry-run"),
+		Params:   params,
+	}
+	if !cfg.DryRun {
+		fmt.Fprintln(os.Stderr, "Upgrading tenant - please wait...")
+	}
+	plan, err := c.driver.UpgradeServiceTemplate(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if plan == nil {
+		fmt.Fprintln(os.Stderr, "received nil upgrade plan")
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonPlan, err := json.MarshalIndent(plan, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal upgrade plan: %s\n", err)
+		} else {
+			fmt.Println(string(jsonPlan))
+		}
+		return
+	}
+
+	if plan.FromTemplateID == "" {
+		fmt.Fprintln(os.Stderr, "WARNING: the template that the tenant was deployed from is unknown; changes made by users may be overwritten")
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Fprintln(os.Stderr, "no changes")
+	} else {
+		t := newtable(0, 8, 2)
+		t.printrow("PATH", "ACTION", "FIELD", "DETAIL")
+		for _, change := range plan.Changes {
+			t.printrow(change.Path, change.Action, change.Field, change.Detail)
+		}
+		t.flush()
+	}
+	if conflicts := plan.Conflicts(); conflicts > 0 {
+		fmt.Fprintf(os.Stderr, "%d conflicts; the values of the users are kept\n", conflicts)
+	}
+	if plan.SnapshotID != "" {
+		fmt.Fprintf(os.Stderr, "Upgraded tenant %s to template %s; rollback to snapshot %s to undo the upgrade\n", tenant.Name, plan.ToTemplateID, plan.SnapshotID)
+	}
+}
+
+// serviced template history TEMPLATEID [--verbose, -v]
+func (c *ServicedCli) cmdTemplateHistory(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "history")
+		return
+	}
+
+	history, err := c.driver.GetServiceTemplateHistory(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if len(history) == 0 {
+		fmt.Fprintln(os.Stderr, "no versions found")
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonHistory, err := json.MarshalIndent(history, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal template history: %s\n", err)
+		} else {
+			fmt.Println(string(jsonHistory))
+		}
+		return
+	}
+
+	t := newtable(0, 8, 2)
+	t.printrow("HASH", "CREATED", "NAME", "VERSION", "CURRENT", "DEPLOYMENTS")
+	for _, h := range history {
+		created := "unknown"
+		if !h.CreatedAt.IsZero() {
+			created = h.CreatedAt.Local().Format(time.RFC3339)
+		}
+		current := ""
+		if h.Current {
+			current = "*"
+		}
+		t.printrow(h.Hash, created, h.Name, h.Version, current, strings.Join(h.Deployments, ","))
+	}
+	t.flush()
+}
+
+// serviced template rollback TEMPLATEID HASH
+func (c *ServicedCli) cmdTemplateRollback(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "rollback")
+		return
+	}
+
+	if hash, err := c.driver.RollbackServiceTemplate(args[0], args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(hash)
+	}
+}
+
 type metaTemplate struct {
 	template.ServiceTemplate
 	ServicedVersion servicedversion.ServicedVersion
 	TemplateVersion map[string]string
 }
 
-// serviced template compile DIR [[--map IMAGE,IMAGE] ...]
+// serviced template compile DIR [[--map IMAGE,IMAGE] ...] [--provenance] [--format json|yaml]
 func (c *ServicedCli) cmdTemplateCompile(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 1 {
@@ -267,10 +487,17 @@ func (c *ServicedCli) cmdTemplateCompile(ctx *cli.Context) {
 		Map: *ctx.Generic("map").(*api.ImageMap),
 	}
 
-	if template, err := c.driver.CompileServiceTemplate(cfg); err != nil {
+	if template, provenance, err := c.driver.CompileServiceTemplate(cfg); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	} else if template == nil {
 		fmt.Fprintln(os.Stderr, "received nil template")
+	} else if ctx.Bool("provenance") {
+		t := newtable(0, 8, 2)
+		t.printrow("FIELD", "SOURCE")
+		for _, p := range provenance {
+			t.printrow(p.Target, p.Source)
+		}
+		t.flush()
 	} else {
 		cmd := fmt.Sprintf("cd %s && git rev-parse HEAD", args[0])
 		commit, err := exec.Command("sh", "-c", cmd).Output()
@@ -299,11 +526,94 @@ func (c *ServicedCli) cmdTemplateCompile(ctx *cli.Context) {
 			"commit": strings.Trim(string(commit), "\n"),
 		}
 		mTemplate := metaTemplate{*template, servicedversion.GetVersion(), templateVersion}
-		jsonTemplate, err := json.MarshalIndent(mTemplate, " ", "  ")
+		data, _, err := marshalFormat(mTemplate, ctx.String("format"))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to marshal template: %s\n", err)
 		} else {
-			fmt.Println(string(jsonTemplate))
+			fmt.Println(strings.TrimSuffix(string(data), "\n"))
+		}
+	}
+}
+
+// serviced template test-logs TEMPLATEID|FILE|PATH LOGTYPE [SAMPLEFILE]
+func (c *ServicedCli) cmdTemplateTestLogs(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "test-logs")
+		return
+	}
+
+	cfg := api.TestLogsConfig{
+		Template: args[0],
+		Type:     args[1],
+		Input:    os.Stdin,
+	}
+	if len(args) > 2 {
+		input, err := os.Open(args[2])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		defer input.Close()
+		cfg.Input = input
+	}
+
+	result, err := c.driver.TestTemplateLogs(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if len(result.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "logstash filters not applied: %s\n", strings.Join(result.Skipped, ", "))
+	}
+	if jsonMessages, err := json.MarshalIndent(result.Messages, " ", "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to marshal messages: %s\n", err)
+	} else {
+		fmt.Println(string(jsonMessages))
+	}
+}
+
+// serviced template lint TEMPLATEID|FILE|PATH [--verbose, -v]
+func (c *ServicedCli) cmdTemplateLint(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "lint")
+		return
+	}
+
+	issues, err := c.driver.LintServiceTemplate(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		c.exit(1)
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if issues == nil {
+			issues = []template.LintIssue{}
+		}
+		if jsonIssues, err := json.MarshalIndent(issues, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal issues: %s\n", err)
+		} else {
+			fmt.Println(string(jsonIssues))
+		}
+	} else if len(issues) == 0 {
+		fmt.Fprintln(os.Stderr, "no problems found")
+	} else {
+		t := newtable(0, 8, 2)
+		t.printrow("SEVERITY", "CHECK", "SERVICE", "FIELD", "MESSAGE")
+		for _, issue := range issues {
+			t.printrow(issue.Severity, issue.Check, issue.Service, issue.Field, issue.Message)
+		}
+		t.flush()
+	}
+
+	for _, issue := range issues {
+		if issue.Severity == template.LintError {
+			c.exit(1)
+			return
 		}
 	}
 }
@@ -15,6 +15,7 @@ package cmd
 
 import (
 	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/commons/yaml"
 	"github.com/control-center/serviced/domain/service"
 	template "github.com/control-center/serviced/domain/servicetemplate"
 
@@ -102,17 +103,17 @@ func (t TemplateAPITest) RemoveServiceTemplate(id string) error {
 	return nil
 }
 
-func (t TemplateAPITest) CompileServiceTemplate(cfg api.CompileTemplateConfig) (*template.ServiceTemplate, error) {
+func (t TemplateAPITest) CompileServiceTemplate(cfg api.CompileTemplateConfig) (*template.ServiceTemplate, []template.Provenance, error) {
 	if t.fail {
-		return nil, ErrInvalidTemplate
+		return nil, nil, ErrInvalidTemplate
 	} else if cfg.Dir == NilTemplate {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	tpl := template.ServiceTemplate{
 		ID: fmt.Sprintf("%s-template", cfg.Dir),
 	}
-	return &tpl, nil
+	return &tpl, nil, nil
 }
 
 func (t TemplateAPITest) DeployServiceTemplate(cfg api.DeployTemplateConfig) ([]service.Service, error) {
@@ -129,6 +130,16 @@ func (t TemplateAPITest) DeployServiceTemplate(cfg api.DeployTemplateConfig) ([]
 	return []service.Service{s}, nil
 }
 
+func (t TemplateAPITest) TestTemplateLogs(cfg api.TestLogsConfig) (*api.TestLogsResult, error) {
+	if _, err := t.GetServiceTemplate(cfg.Template); err != nil {
+		return nil, err
+	}
+	messages := []map[string]interface{}{
+		{"message": "INFO started", "type": cfg.Type, "level": "INFO"},
+	}
+	return &api.TestLogsResult{Messages: messages}, nil
+}
+
 func TestServicedCLI_CmdTemplateList_one(t *testing.T) {
 	templateID := "test-template-1"
 
@@ -271,7 +282,9 @@ func ExampleServicedCLI_CmdTemplateDeploy_usage() {
 	//    serviced template deploy TEMPLATEID POOLID DEPLOYMENTID
 	//
 	// OPTIONS:
-	//    --manual-assign-ips	Manually assign IP addresses
+	//    --manual-assign-ips				Manually assign IP addresses
+	//    --param '--param option --param option'	Set a parameter of the template (e.g. --param instances=3)
+	//    --values 					JSON or YAML file with the values of the parameters of the template
 }
 
 func ExampleServicedCLI_CmdTemplateDeploy_fail() {
@@ -295,7 +308,7 @@ func ExampleServicedCLI_CmdTemplateDeploy_err() {
 func TestServicedCLI_CmdTemplateCompile(t *testing.T) {
 	dir := "/path/to/template"
 
-	expected, err := DefaultTemplateAPITest.CompileServiceTemplate(api.CompileTemplateConfig{Dir: dir})
+	expected, _, err := DefaultTemplateAPITest.CompileServiceTemplate(api.CompileTemplateConfig{Dir: dir})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -312,6 +325,25 @@ func TestServicedCLI_CmdTemplateCompile(t *testing.T) {
 	}
 }
 
+func TestServicedCLI_CmdTemplateCompile_yaml(t *testing.T) {
+	dir := "/path/to/template"
+
+	expected, _, err := DefaultTemplateAPITest.CompileServiceTemplate(api.CompileTemplateConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual template.ServiceTemplate
+	output := pipe(InitTemplateAPITest, "serviced", "template", "compile", "--format", "yaml", dir)
+	if err := yaml.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshaling resource: %s", err)
+	}
+
+	if !actual.Equals(expected) {
+		t.Fatalf("got:\n%+v\nwant:\n%+v", actual, expected)
+	}
+}
+
 func ExampleServicedCLI_CmdTemplateCompile_usage() {
 	InitTemplateAPITest("serviced", "template", "compile")
 
@@ -328,7 +360,9 @@ func ExampleServicedCLI_CmdTemplateCompile_usage() {
 	//    serviced template compile PATH
 	//
 	// OPTIONS:
-	//    --map 	`-map option -map option` Map a given image name to another (e.g. -map zenoss/zenoss5x:latest,quay.io/zenoss-core:alpha2)
+	//    --map 		`-map option -map option` Map a given image name to another (e.g. -map zenoss/zenoss5x:latest,quay.io/zenoss-core:alpha2)
+	//    --provenance		Show the template or directory that each field of the services came from
+	//    --format 'json'	Format of the compiled template (json or yaml)
 }
 
 func ExampleServicedCLI_CmdTemplateCompile_fail() {
@@ -346,3 +380,41 @@ func ExampleServicedCLI_CmdTemplateCompile_err() {
 	// Output:
 	// received nil template
 }
+
+func ExampleServicedCLI_CmdTemplateTestLogs() {
+	InitTemplateAPITest("serviced", "template", "test-logs", "test-template-1", "app")
+
+	// Output:
+	// [
+	//    {
+	//      "level": "INFO",
+	//      "message": "INFO started",
+	//      "type": "app"
+	//    }
+	//  ]
+}
+
+func ExampleServicedCLI_CmdTemplateTestLogs_usage() {
+	InitTemplateAPITest("serviced", "template", "test-logs", "test-template-1")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    test-logs - Runs sample log lines through the log parsers of a template
+	//
+	// USAGE:
+	//    command test-logs [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced template test-logs TEMPLATEID|FILE|PATH LOGTYPE [SAMPLEFILE]
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdTemplateTestLogs_err() {
+	pipeStderr(InitTemplateAPITest, "serviced", "template", "test-logs", "test-template-0", "app")
+
+	// Output:
+	// no templates found
+}
@@ -15,14 +15,17 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"syscall"
 
 	"code.google.com/p/go.crypto/ssh/terminal"
+	"github.com/control-center/serviced/commons/yaml"
 )
 
 func remove(index int, list ...interface{}) []interface{} {
@@ -44,6 +47,20 @@ func remove(index int, list ...interface{}) []interface{} {
 	return append(left, right...)
 }
 
+// marshalFormat encodes a value as indented JSON or as YAML. It returns the
+// encoded value and the file extension of the format.
+func marshalFormat(v interface{}, format string) ([]byte, string, error) {
+	switch format {
+	case "", "json":
+		data, err := json.MarshalIndent(v, " ", "  ")
+		return data, ".json", err
+	case "yaml", "yml":
+		data, err := yaml.Marshal(v)
+		return data, ".yaml", err
+	}
+	return nil, "", fmt.Errorf("unknown format %s, expected json or yaml", format)
+}
+
 var editors = []string{"vim", "vi", "nano"}
 
 func findEditor(editor string) (string, error) {
@@ -70,11 +87,16 @@ func openEditor(data []byte, name, editor string) (reader io.Reader, err error)
 			return nil, err
 		}
 
-		f, err := ioutil.TempFile("", name+"_")
+		// keep the name of the file, so that the editor knows its format
+		dir, err := ioutil.TempDir("", "serviced_edit_")
+		if err != nil {
+			return nil, fmt.Errorf("could not open tempfile: %s", err)
+		}
+		defer os.RemoveAll(dir)
+		f, err := os.Create(filepath.Join(dir, name))
 		if err != nil {
 			return nil, fmt.Errorf("could not open tempfile: %s", err)
 		}
-		defer os.Remove(f.Name())
 		defer f.Close()
 
 		if _, err := f.Write(data); err != nil {
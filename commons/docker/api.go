@@ -14,11 +14,16 @@
 package docker
 
 import (
+	"archive/tar"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
@@ -305,6 +310,34 @@ func (c *Container) Export(outfile *os.File) error {
 	return dc.ExportContainer(dockerclient.ExportContainerOptions{c.ID, outfile})
 }
 
+// ReadFile returns the contents of a regular file in the container's filesystem.
+func (c *Container) ReadFile(filename string) ([]byte, error) {
+	dc, err := getDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	if err := dc.CopyFromContainer(dockerclient.CopyFromContainerOptions{OutputStream: &buffer, Container: c.ID, Resource: filename}); err != nil {
+		return nil, err
+	}
+
+	// the resource is streamed back as a tar archive
+	tr := tar.NewReader(&buffer)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA {
+			return ioutil.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("docker: file %s not found in container %s", filename, c.ID)
+}
+
 // Kill sends a SIGKILL signal to the container. If the container is not started
 // no action is taken.
 func (c *Container) Kill() error {
@@ -610,6 +643,90 @@ func (img *Image) History() ([]*dockerclient.Image, error) {
 	return ImageHistory(img.UUID)
 }
 
+// ImageDiskUsage returns the total number of bytes used by all of the image
+// layers in the local repository
+func ImageDiskUsage() (int64, error) {
+	dc, err := getDockerClient()
+	if err != nil {
+		return 0, err
+	}
+	imgs, err := dc.ListImages(true)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, img := range imgs {
+		size += img.Size
+	}
+	return size, nil
+}
+
+// ImageDigests returns the registry content digests (REPOSITORY@sha256:...)
+// of an image in the local repository.  Images that were not pulled from or
+// pushed to a registry that reports content digests have none.
+func ImageDigests(uuid string) ([]string, error) {
+	dc, err := getDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	imgs, err := dc.ListImages(false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, img := range imgs {
+		if img.ID == uuid {
+			var digests []string
+			for _, digest := range img.RepoDigests {
+				if !strings.HasSuffix(digest, "@<none>") {
+					digests = append(digests, digest)
+				}
+			}
+			return digests, nil
+		}
+	}
+	return nil, nil
+}
+
+// RemoveUntaggedImages deletes all of the top-level images in the local
+// repository that do not have a tag and returns the UUIDs of the images that
+// were removed.  Images that are still in use by a container are skipped.
+func RemoveUntaggedImages() ([]string, error) {
+	dc, err := getDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	imgs, err := dc.ListImages(false)
+	if err != nil {
+		return nil, err
+	}
+
+	re := regexp.MustCompile("<none>:<none>")
+
+	var removed []string
+	for _, img := range imgs {
+		untagged := true
+		for _, repotag := range img.RepoTags {
+			if len(re.FindString(repotag)) == 0 {
+				untagged = false
+				break
+			}
+		}
+		if !untagged {
+			continue
+		}
+
+		glog.V(1).Infof("removing untagged image %s", img.ID)
+		if err := dc.RemoveImage(img.ID); err != nil {
+			glog.Warningf("Could not remove untagged image %s: %s", img.ID, err)
+			continue
+		}
+		removed = append(removed, img.ID)
+	}
+	return removed, nil
+}
+
 func onContainerEvent(event, id string, action ContainerActionFunc) error {
 	ec := make(chan error, 1)
 
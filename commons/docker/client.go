@@ -42,6 +42,8 @@ type Client struct {
 type ClientInterface interface {
 	CommitContainer(opts dockerclient.CommitContainerOptions) (*dockerclient.Image, error)
 
+	CopyFromContainer(opts dockerclient.CopyFromContainerOptions) error
+
 	CreateContainer(opts dockerclient.CreateContainerOptions) (*dockerclient.Container, error)
 
 	ExportContainer(opts dockerclient.ExportContainerOptions) error
@@ -94,6 +96,10 @@ func (c* Client) CommitContainer(opts dockerclient.CommitContainerOptions) (*doc
 	return c.dc.CommitContainer(opts)
 }
 
+func (c *Client) CopyFromContainer(opts dockerclient.CopyFromContainerOptions) error {
+	return c.dc.CopyFromContainer(opts)
+}
+
 func (c* Client) CreateContainer(opts dockerclient.CreateContainerOptions) (*dockerclient.Container, error) {
 	return c.dc.CreateContainer(opts)
 }
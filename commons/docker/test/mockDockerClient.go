@@ -33,6 +33,10 @@ func (mdc *MockDockerClient) CommitContainer(opts dockerclient.CommitContainerOp
 	return args.Get(0).(*dockerclient.Image), args.Error(1)
 }
 
+func (mdc *MockDockerClient) CopyFromContainer(opts dockerclient.CopyFromContainerOptions) error {
+	return mdc.Mock.Called(opts).Error(0)
+}
+
 func (mdc *MockDockerClient) CreateContainer(opts dockerclient.CreateContainerOptions) (*dockerclient.Container, error) {
 	args := mdc.Mock.Called(opts)
 	return args.Get(0).(*dockerclient.Container), args.Error(1)
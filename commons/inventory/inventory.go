@@ -0,0 +1,159 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inventory lists the operating system packages that are installed
+// inside of a docker image.
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/commons/docker"
+	"github.com/zenoss/glog"
+	dockerclient "github.com/zenoss/go-dockerclient"
+)
+
+const (
+	// Dpkg identifies packages installed by the debian package manager
+	Dpkg = "dpkg"
+	// Rpm identifies packages installed by the redhat package manager
+	Rpm = "rpm"
+
+	dpkgFile = "/tmp/serviced-inventory-dpkg"
+	rpmFile  = "/tmp/serviced-inventory-rpm"
+
+	timeout = 5 * time.Minute
+)
+
+// The script dumps the package databases to files in the container so they
+// can be copied out once the container exits.  Images without one of the
+// package managers produce an empty file.
+var script = fmt.Sprintf(
+	`{ dpkg-query -W -f='${Status}\t${Package}\t${Version}\t${Architecture}\n' 2>/dev/null || true; } > %s; `+
+		`{ rpm -qa --qf '%%{NAME}\t%%{VERSION}-%%{RELEASE}\t%%{ARCH}\n' 2>/dev/null || true; } > %s`,
+	dpkgFile, rpmFile)
+
+// Package describes an operating system package installed in an image
+type Package struct {
+	Name         string
+	Version      string
+	Architecture string
+	Manager      string // dpkg or rpm
+}
+
+type byName []Package
+
+func (p byName) Len() int      { return len(p) }
+func (p byName) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byName) Less(i, j int) bool {
+	if p[i].Name == p[j].Name {
+		return p[i].Manager < p[j].Manager
+	}
+	return p[i].Name < p[j].Name
+}
+
+// ImagePackages runs a short-lived container from the image and returns the
+// packages recorded in its dpkg and rpm databases
+func ImagePackages(imageID string) ([]Package, error) {
+	cd := &docker.ContainerDefinition{
+		dockerclient.CreateContainerOptions{
+			Config: &dockerclient.Config{
+				Entrypoint: []string{"/bin/sh", "-c"},
+				Cmd:        []string{script},
+				Image:      imageID,
+			},
+		},
+		dockerclient.HostConfig{},
+	}
+
+	ctr, err := docker.NewContainer(cd, true, timeout, nil, nil)
+	if err != nil {
+		glog.Errorf("Could not create container from image %s: %s", imageID, err)
+		return nil, err
+	}
+	defer func() {
+		if err := ctr.Delete(true); err != nil {
+			glog.Warningf("Could not remove container %s (%s): %s", ctr.ID, imageID, err)
+		}
+	}()
+
+	if rc, err := ctr.Wait(timeout); err != nil {
+		glog.Errorf("Error waiting for container %s (%s): %s", ctr.ID, imageID, err)
+		return nil, err
+	} else if rc != 0 {
+		return nil, fmt.Errorf("inventory of image %s exited with %d", imageID, rc)
+	}
+
+	dpkg, err := ctr.ReadFile(dpkgFile)
+	if err != nil {
+		glog.Errorf("Could not read dpkg inventory from container %s (%s): %s", ctr.ID, imageID, err)
+		return nil, err
+	}
+	rpm, err := ctr.ReadFile(rpmFile)
+	if err != nil {
+		glog.Errorf("Could not read rpm inventory from container %s (%s): %s", ctr.ID, imageID, err)
+		return nil, err
+	}
+
+	packages := append(ParseDpkg(dpkg), ParseRpm(rpm)...)
+	sort.Sort(byName(packages))
+	return packages, nil
+}
+
+// ParseDpkg parses the output of dpkg-query, skipping packages that are not
+// currently installed (i.e. removed packages whose config files remain)
+func ParseDpkg(data []byte) []Package {
+	var packages []Package
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 || !strings.HasSuffix(fields[0], " installed") {
+			continue
+		}
+		packages = append(packages, Package{
+			Name:         fields[1],
+			Version:      fields[2],
+			Architecture: fields[3],
+			Manager:      Dpkg,
+		})
+	}
+	return packages
+}
+
+// ParseRpm parses the output of rpm -qa
+func ParseRpm(data []byte) []Package {
+	var packages []Package
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		// gpg-pubkey entries are signing keys, not software
+		if fields[0] == "gpg-pubkey" {
+			continue
+		}
+		packages = append(packages, Package{
+			Name:         fields[0],
+			Version:      fields[1],
+			Architecture: fields[2],
+			Manager:      Rpm,
+		})
+	}
+	return packages
+}
@@ -0,0 +1,53 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDpkg(t *testing.T) {
+	data := []byte("install ok installed\tbash\t4.3-7ubuntu1\tamd64\n" +
+		"deinstall ok config-files\tvim\t2:7.4.052\tamd64\n" +
+		"garbage line\n" +
+		"install ok installed\tlibc6\t2.19-0ubuntu6\tamd64\n")
+
+	expected := []Package{
+		{"bash", "4.3-7ubuntu1", "amd64", Dpkg},
+		{"libc6", "2.19-0ubuntu6", "amd64", Dpkg},
+	}
+	if actual := ParseDpkg(data); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	if actual := ParseDpkg(nil); len(actual) != 0 {
+		t.Errorf("expected no packages, got %+v", actual)
+	}
+}
+
+func TestParseRpm(t *testing.T) {
+	data := []byte("openssl\t1.0.1e-30.el6\tx86_64\n" +
+		"gpg-pubkey\tc105b9de-4e0fd3a3\t(none)\n" +
+		"\n" +
+		"bash\t4.1.2-15.el6_5.2\tx86_64\n")
+
+	expected := []Package{
+		{"openssl", "1.0.1e-30.el6", "x86_64", Rpm},
+		{"bash", "4.1.2-15.el6_5.2", "x86_64", Rpm},
+	}
+	if actual := ParseRpm(data); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logstash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zenoss/glog"
+)
+
+// DocumentsFile is the name of the file in an export holding the documents
+// exactly as they were stored in elasticsearch, one per line
+const DocumentsFile = "documents.json"
+
+// ExportOptions selects the messages to export
+type ExportOptions struct {
+	Query     string   // lucene query string
+	Days      []string // yyyy.mm.dd days to export
+	Outfile   string   // path of the tgz file to write
+	Documents bool     // also export the original documents, so the export can be restored
+}
+
+// Export writes the messages matching the query to a tgz file.  The messages
+// of each log file on each host are sorted by timestamp and offset into a
+// separate file, listed in index.txt.
+func (c *Client) Export(options ExportOptions) (err error) {
+	var e error
+	files := []*os.File{}
+	fileIndex := make(map[string]map[string]int) // host => filename => index
+	defer func() {
+		for _, file := range files {
+			if e := file.Close(); e != nil && err == nil {
+				err = fmt.Errorf("failed to close file '%s' cleanly: %s", file.Name(), e)
+			}
+		}
+	}()
+
+	// Get a temporary directory
+	tempdir, e := ioutil.TempDir("", "serviced-log-export-")
+	if e != nil {
+		return fmt.Errorf("could not create temp directory: %s", e)
+	}
+	defer os.RemoveAll(tempdir)
+
+	// create a file to hold parse warnings
+	parseWarningsFilename := filepath.Join(tempdir, "warnings.log")
+	parseWarningsFile, e := os.Create(parseWarningsFilename)
+	if e != nil {
+		return fmt.Errorf("failed to create file %s: %s", parseWarningsFilename, e)
+	}
+	defer func() {
+		if e := parseWarningsFile.Close(); e != nil && err == nil {
+			err = fmt.Errorf("failed to close file '%s' cleanly: %s", parseWarningsFilename, e)
+		}
+	}()
+
+	// keep the documents as they were indexed, so they can be restored
+	var documents *os.File
+	documentsFilename := filepath.Join(tempdir, DocumentsFile)
+	if options.Documents {
+		if documents, e = os.Create(documentsFilename); e != nil {
+			return fmt.Errorf("failed to create file %s: %s", documentsFilename, e)
+		}
+		defer func() {
+			if e := documents.Close(); e != nil && err == nil {
+				err = fmt.Errorf("failed to close file '%s' cleanly: %s", documentsFilename, e)
+			}
+		}()
+	}
+
+	glog.Infof("Starting part 1 of 3: process logstash elasticsearch results using temporary dir: %s", tempdir)
+	numWarnings := 0
+	for _, yyyymmdd := range options.Days {
+		e := c.scan(IndexName(yyyymmdd), options.Query, func(source []byte) error {
+			if documents != nil {
+				if _, e := documents.Write(append(source, '\n')); e != nil {
+					return fmt.Errorf("failed writing to file %s: %s", documentsFilename, e)
+				}
+			}
+			host, logfile, compactLines, warningMessage, e := parseLogSource(source)
+			if e != nil {
+				return e
+			}
+			if _, found := fileIndex[host]; !found {
+				fileIndex[host] = make(map[string]int)
+			}
+			if _, found := fileIndex[host][logfile]; !found {
+				index := len(files)
+				filename := filepath.Join(tempdir, fmt.Sprintf("%03d.log", index))
+				file, e := os.Create(filename)
+				if e != nil {
+					return fmt.Errorf("failed to create file %s: %s", filename, e)
+				}
+				fileIndex[host][logfile] = index
+				files = append(files, file)
+			}
+			index := fileIndex[host][logfile]
+			file := files[index]
+			filename := filepath.Join(tempdir, fmt.Sprintf("%03d.log", index))
+			for _, line := range compactLines {
+				formatted := fmt.Sprintf("%016x\t%016x\t%s\n", line.Timestamp, line.Offset, line.Message)
+				if _, e := file.WriteString(formatted); e != nil {
+					return fmt.Errorf("failed writing to file %s: %s", filename, e)
+				}
+			}
+			if len(warningMessage) > 0 {
+				if _, e := parseWarningsFile.WriteString(warningMessage); e != nil {
+					return fmt.Errorf("failed writing to file %s: %s", parseWarningsFilename, e)
+				}
+				numWarnings++
+			}
+			return nil
+		})
+		if e != nil {
+			return e
+		}
+	}
+
+	glog.Infof("Starting part 2 of 3: sort output files")
+
+	indexData := []string{}
+	for host, logfileIndex := range fileIndex {
+		for logfile, i := range logfileIndex {
+			filename := filepath.Join(tempdir, fmt.Sprintf("%03d.log", i))
+			tmpfilename := filepath.Join(tempdir, fmt.Sprintf("%03d.log.tmp", i))
+			cmd := exec.Command("sort", filename, "-uo", tmpfilename)
+			if output, e := cmd.CombinedOutput(); e != nil {
+				return fmt.Errorf("failed sorting %s, error: %v, output: %s", filename, e, output)
+			}
+			if numWarnings == 0 {
+				cmd = exec.Command("mv", tmpfilename, filename)
+				if output, e := cmd.CombinedOutput(); e != nil {
+					return fmt.Errorf("failed moving %s %s, error: %v, output: %s", tmpfilename, filename, e, output)
+				}
+			} else {
+				cmd = exec.Command("cp", tmpfilename, filename)
+				if output, e := cmd.CombinedOutput(); e != nil {
+					return fmt.Errorf("failed moving %s %s, error: %v, output: %s", tmpfilename, filename, e, output)
+				}
+			}
+			cmd = exec.Command("sed", "s/^[0-9a-f]*\\t[0-9a-f]*\\t//", "-i", filename)
+			if output, e := cmd.CombinedOutput(); e != nil {
+				return fmt.Errorf("failed stripping sort prefixes %s, error: %v, output: %s", filename, e, output)
+			}
+			indexData = append(indexData, fmt.Sprintf("%03d.log\t%s\t%s", i, strconv.Quote(host), strconv.Quote(logfile)))
+		}
+	}
+	sort.Strings(indexData)
+	indexData = append([]string{"INDEX OF LOG FILES", "File\tHost\tOriginal Filename"}, indexData...)
+	indexData = append(indexData, "")
+	indexFile := filepath.Join(tempdir, "index.txt")
+	e = ioutil.WriteFile(indexFile, []byte(strings.Join(indexData, "\n")), 0644)
+	if e != nil {
+		return fmt.Errorf("failed writing to %s: %s", indexFile, e)
+	}
+
+	glog.Infof("Starting part 3 of 3: generate tar file: %s", options.Outfile)
+
+	cmd := exec.Command("tar", "-czf", options.Outfile, "-C", filepath.Dir(tempdir), filepath.Base(tempdir))
+	if output, e := cmd.CombinedOutput(); e != nil {
+		return fmt.Errorf("failed to write tgz cmd:%+v, error:%v, output:%s", cmd, e, string(output))
+	}
+
+	if numWarnings != 0 {
+		glog.Warningf("warnings for log parse are included in the tar file as: %s", filepath.Join(filepath.Base(tempdir), filepath.Base(parseWarningsFilename)))
+	}
+
+	return nil
+}
+
+type scrollResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Total int `json:"total"`
+		Hits  []struct {
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// scan calls fn with the source of every document in the index matching the
+// query
+func (c *Client) scan(index, query string, fn func(source []byte) error) error {
+	var response scrollResponse
+	path := fmt.Sprintf("/%s/_search?search_type=scan&scroll=1m&size=1000&q=%s", index, url.QueryEscape(query))
+	if err := c.do("GET", path, nil, &response); err != nil {
+		return fmt.Errorf("failed to search elasticsearch: %s", err)
+	}
+
+	remaining := response.Hits.Total > 0
+	for remaining {
+		scrollID := response.ScrollID
+		response = scrollResponse{}
+		if err := c.do("GET", "/_search/scroll?scroll=1m&scroll_id="+url.QueryEscape(scrollID), nil, &response); err != nil {
+			return fmt.Errorf("failed to scroll elasticsearch results: %s", err)
+		}
+		for _, hit := range response.Hits.Hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+		remaining = len(response.Hits.Hits) > 0
+	}
+	return nil
+}
+
+type logSingleLine struct {
+	Host      string    `json:"host"`
+	File      string    `json:"file"`
+	Timestamp time.Time `json:"@timestamp"`
+	Offset    string    `json:"offset"`
+	Message   string    `json:"message"`
+}
+
+type logMultiLine struct {
+	Host      string    `json:"host"`
+	File      string    `json:"file"`
+	Timestamp time.Time `json:"@timestamp"`
+	Offset    []string  `json:"offset"`
+	Message   string    `json:"message"`
+}
+
+type compactLogLine struct {
+	Timestamp int64 //nanoseconds since the epoch, truncated at the minute to hide jitter
+	Offset    uint64
+	Message   string
+}
+
+var newline = regexp.MustCompile("\\r?\\n")
+
+// convertOffsets converts a list of strings into a list of uint64s
+func convertOffsets(offsets []string) ([]uint64, error) {
+	result := make([]uint64, len(offsets))
+	for i, offsetString := range offsets {
+		offset, e := strconv.ParseUint(offsetString, 10, 64)
+		if e != nil {
+			return result, fmt.Errorf("failed to parse offset[%d] \"%s\" in \"%s\": %s", i, offsetString, offsets, e)
+		}
+		result[i] = offset
+	}
+
+	return result, nil
+}
+
+// uint64sAreSorted returns true if input values are sorted in increasing order - mimics sort.IntsAreSorted()
+func uint64sAreSorted(values []uint64) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	previousValue := values[0]
+	for _, value := range values {
+		if value < previousValue {
+			return false
+		}
+		previousValue = value
+	}
+	return true
+}
+
+// getMinValue returns the minimum value in an array of uint64
+func getMinValue(values []uint64) uint64 {
+	result := uint64(math.MaxUint64)
+	for _, value := range values {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
+
+// generateOffsets uses the minimum offset in the array as a base returns an array of offsets where
+// each offset is the base + index
+func generateOffsets(messages []string, offsets []uint64) []uint64 {
+	result := make([]uint64, len(messages))
+	minOffset := getMinValue(offsets)
+	if minOffset == uint64(math.MaxUint64) {
+		minOffset = 0
+	}
+	for i, _ := range result {
+		result[i] = minOffset + uint64(i)
+	}
+	return result
+}
+
+// return: host, file, lines, error
+func parseLogSource(source []byte) (string, string, []compactLogLine, string, error) {
+	warnings := ""
+
+	// attempt to unmarshal into singleLine
+	var line logSingleLine
+	if e := json.Unmarshal(source, &line); e == nil {
+		offset := uint64(0)
+		if len(line.Offset) != 0 {
+			var e error
+			offset, e = strconv.ParseUint(line.Offset, 10, 64)
+			if e != nil {
+				return "", "", nil, warnings, fmt.Errorf("failed to parse offset \"%s\" in \"%s\": %s", line.Offset, source, e)
+			}
+		}
+		compactLine := compactLogLine{
+			Timestamp: truncateToMinute(line.Timestamp.UnixNano()),
+			Offset:    offset,
+			Message:   line.Message,
+		}
+		return line.Host, line.File, []compactLogLine{compactLine}, warnings, nil
+	}
+
+	// attempt to unmarshal into multiLine
+	var multiLine logMultiLine
+	if e := json.Unmarshal(source, &multiLine); e != nil {
+		return "", "", nil, warnings, fmt.Errorf("failed to parse JSON \"%s\": %s", source, e)
+	}
+
+	// build offsets - list of uint64
+	offsets, e := convertOffsets(multiLine.Offset)
+	if e != nil {
+		return "", "", nil, warnings, fmt.Errorf("failed to parse JSON \"%s\": %s", source, e)
+	}
+
+	// verify number of lines in message against number of offsets
+	messages := newline.Split(multiLine.Message, -1)
+	if len(offsets)+1 == len(messages) {
+		warnings += fmt.Sprintf(
+			"number of offsets for %s:%s (numLines:%d numOffsets:%d) is one less than number of lines: %s\n",
+			multiLine.Host, multiLine.File, len(messages), len(offsets), source)
+		numLines := len(messages)
+		if numLines > 1 {
+			lastOffset := uint64(len(messages[numLines-2])) + offsets[numLines-2]
+			offsets = append(offsets, lastOffset)
+		}
+	} else if len(offsets) > len(messages) {
+		warnings += fmt.Sprintf(
+			"number of offsets for %s:%s (numLines:%d numOffsets:%d) is greater than number of lines: %s\n",
+			multiLine.Host, multiLine.File, len(messages), len(multiLine.Offset), source)
+		offsets = offsets[0:len(messages)]
+	} else if len(offsets) < len(messages) {
+		warnings += fmt.Sprintf(
+			"number of offsets for %s:%s (numLines:%d numOffsets:%d) is less than number of lines: %s\n",
+			multiLine.Host, multiLine.File, len(messages), len(multiLine.Offset), source)
+		offsets = generateOffsets(messages, offsets)
+		warnings += fmt.Sprintf("new offsets: %v", offsets)
+	}
+
+	// deal with offsets that are not sorted in increasing order
+	if !uint64sAreSorted(offsets) {
+		warnings = fmt.Sprintf("offsets are not sorted: %v\n", offsets)
+		offsets = generateOffsets(messages, offsets)
+		warnings = fmt.Sprintf("new offsets: %v\n", offsets)
+	}
+
+	// build compactLines
+	timestamp := truncateToMinute(multiLine.Timestamp.UnixNano())
+	compactLines := make([]compactLogLine, len(messages))
+	for i, offset := range offsets {
+		compactLines = append(compactLines, compactLogLine{
+			Timestamp: timestamp,
+			Offset:    offset,
+			Message:   messages[i],
+		})
+	}
+
+	return multiLine.Host, multiLine.File, compactLines, warnings, nil
+}
+
+func truncateToMinute(nanos int64) int64 {
+	return nanos / int64(time.Minute) * int64(time.Minute)
+}
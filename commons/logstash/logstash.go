@@ -0,0 +1,160 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logstash reads the daily logstash-YYYY.MM.DD indices that hold the
+// logs of service instances.
+//
+// The client talks to elasticsearch over plain http rather than through
+// elastigo, whose connection settings are global and already point at the
+// serviced datastore when running inside the master.
+package logstash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexPrefix is the prefix of the daily logstash index names
+const IndexPrefix = "logstash-"
+
+// DayFormat is the time layout of the date suffix of a logstash index
+const DayFormat = "2006.01.02"
+
+var yyyymmddMatcher = regexp.MustCompile("\\A[^0-9]*([0-9]{4})[^0-9]*([0-9]{2})[^0-9]*([0-9]{2})[^0-9]*\\z")
+
+// NormalizeYYYYMMDD matches optional non-digits, 4 digits, optional non-digits,
+// 2 digits, optional non-digits, 2 digits, optional non-digits
+// Returns those 8 digits formatted as "dddd.dd.dd", or error if unparseable.
+func NormalizeYYYYMMDD(s string) (string, error) {
+	match := yyyymmddMatcher.FindStringSubmatch(s)
+	if match == nil {
+		return "", fmt.Errorf("could not parse '%s' as yyyymmdd", s)
+	}
+	return fmt.Sprintf("%s.%s.%s", match[1], match[2], match[3]), nil
+}
+
+// IndexName returns the name of the index holding the logs of a day
+// (yyyy.mm.dd)
+func IndexName(day string) string {
+	return IndexPrefix + day
+}
+
+// Client is a connection to the logstash elasticsearch
+type Client struct {
+	address string
+}
+
+// NewClient returns a client for the logstash elasticsearch at host:port
+func NewClient(address string) *Client {
+	return &Client{address: address}
+}
+
+// Days returns all the dates with a logstash-YYYY.MM.DD index available in
+// elasticsearch.  The strings are in YYYY.MM.DD format, and in reverse
+// chronological order.
+func (c *Client) Days() ([]string, error) {
+	var aliasMap map[string]interface{}
+	if err := c.do("GET", "/_aliases", nil, &aliasMap); err != nil {
+		return []string{}, fmt.Errorf("couldn't fetch list of indices: %s", err)
+	}
+	return parseDays(aliasMap), nil
+}
+
+// DaysBetween returns the days with an index that fall between from and to,
+// inclusive, in reverse chronological order.  A zero time leaves that end of
+// the range unbounded.
+func (c *Client) DaysBetween(from, to time.Time) ([]string, error) {
+	days, err := c.Days()
+	if err != nil {
+		return nil, err
+	}
+	return filterDays(days, from, to), nil
+}
+
+func parseDays(aliasMap map[string]interface{}) []string {
+	result := make([]string, 0, len(aliasMap))
+	for index := range aliasMap {
+		if trimmed := strings.TrimPrefix(index, IndexPrefix); trimmed != index {
+			var err error
+			if trimmed, err = NormalizeYYYYMMDD(trimmed); err != nil {
+				trimmed = ""
+			}
+			result = append(result, trimmed)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(result)))
+	return result
+}
+
+func filterDays(days []string, from, to time.Time) []string {
+	var result []string
+	for _, day := range days {
+		if day == "" {
+			continue
+		} else if !from.IsZero() && day < from.UTC().Format(DayFormat) {
+			continue
+		} else if !to.IsZero() && day > to.UTC().Format(DayFormat) {
+			continue
+		}
+		result = append(result, day)
+	}
+	return result
+}
+
+// do sends a request to elasticsearch and decodes the json response into
+// result, if result is not nil
+func (c *Client) do(method, path string, body interface{}, result interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.doRaw(method, path, reqBody, result)
+}
+
+// doRaw is like do, but sends the body as is
+func (c *Client) doRaw(method, path string, reqBody []byte, result interface{}) error {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", c.address, path), bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("elasticsearch returned %s for %s %s: %s", resp.Status, method, path, respBody)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("couldn't parse response (%s): %s", respBody, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logstash
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeYYYYMMDD(t *testing.T) {
+	for _, s := range []string{"20150102", "2015.01.02", "2015-01-02", "logstash-2015.01.02"} {
+		if day, err := NormalizeYYYYMMDD(s); err != nil {
+			t.Errorf("unexpected error normalizing %s: %s", s, err)
+		} else if day != "2015.01.02" {
+			t.Errorf("expected 2015.01.02 from %s, got %s", s, day)
+		}
+	}
+	if _, err := NormalizeYYYYMMDD("201501"); err == nil {
+		t.Errorf("expected error normalizing 201501")
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	aliases := map[string]interface{}{
+		"logstash-2015.01.01": nil,
+		"logstash-2015.01.03": nil,
+		"logstash-2015.01.02": nil,
+		"kibana-int":          nil,
+	}
+	expected := []string{"2015.01.03", "2015.01.02", "2015.01.01"}
+	if days := parseDays(aliases); !reflect.DeepEqual(days, expected) {
+		t.Errorf("expected %v, got %v", expected, days)
+	}
+}
+
+func TestFilterDays(t *testing.T) {
+	days := []string{"2015.01.03", "2015.01.02", "2015.01.01"}
+	from := time.Date(2015, 1, 2, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2015, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	if actual := filterDays(days, from, to); !reflect.DeepEqual(actual, []string{"2015.01.03", "2015.01.02"}) {
+		t.Errorf("unexpected days between %s and %s: %v", from, to, actual)
+	}
+	if actual := filterDays(days, time.Time{}, from); !reflect.DeepEqual(actual, []string{"2015.01.02", "2015.01.01"}) {
+		t.Errorf("unexpected days before %s: %v", from, actual)
+	}
+	if actual := filterDays(days, time.Time{}, time.Time{}); !reflect.DeepEqual(actual, days) {
+		t.Errorf("unexpected days for unbounded range: %v", actual)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	query := buildQuery(Query{})
+	expected := `{"from":0,"query":{"match_all":{}},"size":100,"sort":[{"@timestamp":{"order":"asc"}}]}`
+	if actual, _ := json.Marshal(query); string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	query = buildQuery(Query{
+		Query:      "error",
+		From:       time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC),
+		ServiceIDs: []string{"abc-123"},
+		Fields:     map[string]string{"type": "zope"},
+		Offset:     10,
+		Limit:      5,
+	})
+	expected = `{"from":10,"query":{"filtered":{"filter":{"bool":{"must":[` +
+		`{"range":{"@timestamp":{"gte":"2015-01-02T00:00:00Z"}}},` +
+		`{"bool":{"should":[{"query":{"match":{"service":{"query":"abc-123","type":"phrase"}}}}]}},` +
+		`{"query":{"match":{"type":{"query":"zope","type":"phrase"}}}}]}},` +
+		`"query":{"query_string":{"query":"error"}}}},"size":5,"sort":[{"@timestamp":{"order":"asc"}}]}`
+	if actual, _ := json.Marshal(query); string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	source := map[string]interface{}{
+		"@timestamp": "2015-01-02T03:04:05.678Z",
+		"@version":   "1",
+		"host":       "myhost",
+		"file":       "/var/log/app.log",
+		"service":    "abc-123",
+		"instance":   "0",
+		"type":       "app",
+		"offset":     []interface{}{"0", "10"},
+		"message":    []interface{}{"line one", "line two"},
+		"level":      "ERROR",
+	}
+	expected := Message{
+		Timestamp: time.Date(2015, 1, 2, 3, 4, 5, 678000000, time.UTC),
+		Host:      "myhost",
+		File:      "/var/log/app.log",
+		Service:   "abc-123",
+		Instance:  "0",
+		Type:      "app",
+		Message:   "line one\nline two",
+		Fields:    map[string]interface{}{"level": "ERROR"},
+	}
+	if actual := parseMessage(source); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,553 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logstash
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zenoss/glog"
+)
+
+// RestoredDays is the number of days a restored index is kept before the
+// retention policy applies to it again
+const RestoredDays = 7
+
+// how many documents to send per bulk request when restoring an archive
+const restoreBatchSize = 1000
+
+// RetentionRule keeps the log messages of a tenant and/or log type for a
+// number of days
+type RetentionRule struct {
+	TenantID string // empty matches every tenant
+	Type     string // log type from the service's LogConfig; empty matches every type
+	Days     int
+}
+
+// RetentionPolicy decides how many days log messages are kept.  The first
+// rule that matches a message applies to it; messages that don't match any
+// rule are kept for DefaultDays.
+type RetentionPolicy struct {
+	DefaultDays int
+	Archive     bool // archive a day before any of its messages are deleted
+	Rules       []RetentionRule
+}
+
+// Validate checks that the policy can be enforced
+func (p RetentionPolicy) Validate() error {
+	if p.DefaultDays <= 0 {
+		return fmt.Errorf("default retention must be at least one day")
+	}
+	for i, rule := range p.Rules {
+		if rule.TenantID == "" && rule.Type == "" {
+			return fmt.Errorf("rule %d must match a tenant or a log type", i+1)
+		} else if rule.Days <= 0 {
+			return fmt.Errorf("rule %d must keep messages for at least one day", i+1)
+		}
+	}
+	return nil
+}
+
+// MaxDays returns the number of days after which every message of a day has
+// expired
+func (p RetentionPolicy) MaxDays() int {
+	max := p.DefaultDays
+	for _, rule := range p.Rules {
+		if rule.Days > max {
+			max = rule.Days
+		}
+	}
+	return max
+}
+
+// expiredQuery returns the query matching the messages of an index that has
+// reached the given age in days, or nil if no messages have expired.  all is
+// set if every message of the index has expired.
+func (p RetentionPolicy) expiredQuery(age int, tenantServices map[string][]string) (query map[string]interface{}, all bool) {
+	var expired, previous []interface{}
+	all = true
+	for _, rule := range p.Rules {
+		match := ruleQuery(rule, tenantServices[rule.TenantID])
+		if rule.Days < age {
+			expired = append(expired, boolQuery([]interface{}{match}, previous))
+		} else {
+			all = false
+		}
+		previous = append(previous, match)
+	}
+	if p.DefaultDays < age {
+		expired = append(expired, boolQuery(nil, previous))
+	} else {
+		all = false
+	}
+
+	if len(expired) == 0 {
+		return nil, false
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{"should": expired, "minimum_should_match": 1},
+	}, all
+}
+
+// ruleQuery returns the query matching the messages a rule applies to
+func ruleQuery(rule RetentionRule, serviceIDs []string) interface{} {
+	var must []interface{}
+	if rule.TenantID != "" {
+		// a tenant is the root of its service tree
+		services := []interface{}{phraseQuery("service", rule.TenantID)}
+		for _, serviceID := range serviceIDs {
+			if serviceID != rule.TenantID {
+				services = append(services, phraseQuery("service", serviceID))
+			}
+		}
+		must = append(must, map[string]interface{}{
+			"bool": map[string]interface{}{"should": services, "minimum_should_match": 1},
+		})
+	}
+	if rule.Type != "" {
+		must = append(must, phraseQuery("type", rule.Type))
+	}
+	return boolQuery(must, nil)
+}
+
+func boolQuery(must, mustNot []interface{}) map[string]interface{} {
+	if len(must) == 0 {
+		must = []interface{}{map[string]interface{}{"match_all": map[string]interface{}{}}}
+	}
+	q := map[string]interface{}{"must": must}
+	if len(mustNot) > 0 {
+		q["must_not"] = append([]interface{}{}, mustNot...)
+	}
+	return map[string]interface{}{"bool": q}
+}
+
+func phraseQuery(field, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"match": map[string]interface{}{
+			field: map[string]interface{}{"query": value, "type": "phrase"},
+		},
+	}
+}
+
+// dayAge returns the number of whole days between a day (yyyy.mm.dd) and now
+func dayAge(day string, now time.Time) (int, error) {
+	t, err := time.Parse(DayFormat, day)
+	if err != nil {
+		return 0, err
+	}
+	today, _ := time.Parse(DayFormat, now.UTC().Format(DayFormat))
+	return int(today.Sub(t).Hours() / 24), nil
+}
+
+// Retention applies a retention policy to the logstash indices, archiving
+// days to ArchiveDir before their messages are deleted
+type Retention struct {
+	Client     *Client
+	Policy     RetentionPolicy
+	ArchiveDir string
+	MaxBytes   int64 // the oldest indices are deleted beyond this size; 0 for no limit
+	// the ids of the services of each tenant named by a rule
+	TenantServices map[string][]string
+}
+
+// RetentionReport describes the changes made by enforcing a policy
+type RetentionReport struct {
+	Archived []string // days archived
+	Pruned   []string // days from which expired messages were deleted
+	Deleted  []string // days whose index was deleted
+}
+
+// IndexStatus describes a logstash index
+type IndexStatus struct {
+	Day       string
+	Documents int64
+	SizeBytes int64
+	Archived  bool
+	Restored  bool   // restored from an archive and exempt from the policy
+	Expires   string // day on which the index is deleted
+}
+
+// RetentionStatus describes the retention policy and the state of the
+// logstash indices
+type RetentionStatus struct {
+	Policy     RetentionPolicy
+	ArchiveDir string
+	Indices    []IndexStatus // newest first
+	Archives   []string      // archived days whose index has been deleted
+}
+
+// ArchivePath returns the path of the archive of a day
+func (r *Retention) ArchivePath(day string) string {
+	return filepath.Join(r.ArchiveDir, IndexName(day)+".tgz")
+}
+
+func (r *Retention) restoredPath(day string) string {
+	return filepath.Join(r.ArchiveDir, IndexName(day)+".restored")
+}
+
+func (r *Retention) isArchived(day string) bool {
+	_, err := os.Stat(r.ArchivePath(day))
+	return err == nil
+}
+
+// isRestored checks if a day was restored recently enough to be exempt from
+// the policy
+func (r *Retention) isRestored(day string, now time.Time) bool {
+	fi, err := os.Stat(r.restoredPath(day))
+	return err == nil && now.Sub(fi.ModTime()) < RestoredDays*24*time.Hour
+}
+
+// Enforce archives and deletes expired messages, then the oldest indices
+// until the indices fit in MaxBytes
+func (r *Retention) Enforce(now time.Time) (*RetentionReport, error) {
+	if err := r.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	days, err := r.Client.Days()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{}
+	for _, day := range days {
+		if day == "" {
+			continue
+		}
+		age, err := dayAge(day, now)
+		if err != nil {
+			glog.Warningf("Could not determine the age of logstash index for %s: %s", day, err)
+			continue
+		}
+		if r.isRestored(day, now) {
+			glog.V(2).Infof("Skipping restored logstash index for %s", day)
+			continue
+		}
+		os.Remove(r.restoredPath(day))
+
+		query, all := r.Policy.expiredQuery(age, r.TenantServices)
+		if query == nil {
+			continue
+		}
+
+		if all {
+			if err := r.deleteIndex(day, report); err != nil {
+				return report, err
+			}
+		} else {
+			if err := r.archiveIndex(day, report); err != nil {
+				return report, err
+			}
+			glog.Infof("Deleting expired messages from logstash index for %s", day)
+			body := map[string]interface{}{"query": query}
+			if err := r.Client.do("DELETE", "/"+IndexName(day)+"/_query", body, nil); err != nil {
+				glog.Errorf("Could not delete expired messages from logstash index for %s: %s", day, err)
+				return report, err
+			}
+			report.Pruned = append(report.Pruned, day)
+		}
+	}
+
+	if r.MaxBytes > 0 {
+		status, err := r.Status(now)
+		if err != nil {
+			glog.Errorf("Could not get the size of the logstash indices: %s", err)
+			return report, err
+		}
+		days := oversizedDays(status.Indices, r.MaxBytes, now)
+		if len(days) > 0 {
+			glog.Infof("Deleting the %d oldest logstash indices to limit disk usage to %d bytes", len(days), r.MaxBytes)
+		}
+		for _, day := range days {
+			if err := r.deleteIndex(day, report); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// archiveIndex archives a day if the policy asks for it and it is not
+// archived yet
+func (r *Retention) archiveIndex(day string, report *RetentionReport) error {
+	if !r.Policy.Archive || r.isArchived(day) {
+		return nil
+	}
+	glog.Infof("Archiving logstash index for %s to %s", day, r.ArchivePath(day))
+	if err := r.archive(day); err != nil {
+		glog.Errorf("Could not archive logstash index for %s: %s", day, err)
+		return err
+	}
+	report.Archived = append(report.Archived, day)
+	return nil
+}
+
+// deleteIndex archives a day if the policy asks for it and deletes its index
+func (r *Retention) deleteIndex(day string, report *RetentionReport) error {
+	if err := r.archiveIndex(day, report); err != nil {
+		return err
+	}
+	glog.Infof("Deleting logstash index for %s", day)
+	if err := r.Client.do("DELETE", "/"+IndexName(day), nil, nil); err != nil {
+		glog.Errorf("Could not delete logstash index for %s: %s", day, err)
+		return err
+	}
+	report.Deleted = append(report.Deleted, day)
+	return nil
+}
+
+// oversizedDays returns the days of the oldest indices to delete, oldest
+// first, so that the rest fit in maxBytes.  Today's index and restored
+// indices are never deleted.
+func oversizedDays(indices []IndexStatus, maxBytes int64, now time.Time) []string {
+	sorted := make([]IndexStatus, len(indices))
+	copy(sorted, indices)
+	sort.Sort(byDay(sorted))
+
+	var total int64
+	for _, index := range sorted {
+		total += index.SizeBytes
+	}
+
+	today := now.UTC().Format(DayFormat)
+	var days []string
+	for _, index := range sorted {
+		if total <= maxBytes {
+			break
+		} else if index.Day >= today || index.Restored {
+			continue
+		}
+		days = append(days, index.Day)
+		total -= index.SizeBytes
+	}
+	return days
+}
+
+// archive exports every message of a day, along with the documents as they
+// were indexed
+func (r *Retention) archive(day string) error {
+	if err := os.MkdirAll(r.ArchiveDir, 0755); err != nil {
+		return err
+	}
+	filename := r.ArchivePath(day)
+	tmpfilename := filename + ".tmp"
+	err := r.Client.Export(ExportOptions{
+		Query:     "*",
+		Days:      []string{day},
+		Outfile:   tmpfilename,
+		Documents: true,
+	})
+	if err != nil {
+		os.Remove(tmpfilename)
+		return err
+	}
+	return os.Rename(tmpfilename, filename)
+}
+
+// Restore loads an archived day back into elasticsearch.  The restored index
+// is kept for RestoredDays before the policy applies to it again.
+func (r *Retention) Restore(day string) error {
+	day, err := NormalizeYYYYMMDD(day)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(r.ArchivePath(day))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no archive exists for %s", day)
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return fmt.Errorf("archive for %s does not contain %s", day, DocumentsFile)
+		} else if err != nil {
+			return err
+		}
+		if filepath.Base(header.Name) == DocumentsFile {
+			break
+		}
+	}
+
+	// mark the day before loading it, so a concurrent enforcement doesn't
+	// delete a partially restored index
+	if err := touch(r.restoredPath(day)); err != nil {
+		return err
+	}
+
+	glog.Infof("Restoring logstash index for %s from %s", day, r.ArchivePath(day))
+	count := 0
+	var batch bytes.Buffer
+	reader := bufio.NewReader(archive)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if err := writeBulkIndex(&batch, IndexName(day), line); err != nil {
+				return err
+			}
+			if count++; count%restoreBatchSize == 0 {
+				if err := r.bulk(&batch); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := r.bulk(&batch); err != nil {
+		return err
+	}
+	glog.Infof("Restored %d messages to logstash index for %s", count, day)
+	return nil
+}
+
+// writeBulkIndex adds a document to a bulk request
+func writeBulkIndex(w *bytes.Buffer, index string, source []byte) error {
+	var doc struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return fmt.Errorf("could not parse document %s: %s", source, err)
+	}
+	if doc.Type == "" {
+		doc.Type = "logs"
+	}
+	action := map[string]interface{}{
+		"index": map[string]string{"_index": index, "_type": doc.Type},
+	}
+	header, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+	w.Write(header)
+	w.WriteByte('\n')
+	w.Write(source)
+	w.WriteByte('\n')
+	return nil
+}
+
+func (r *Retention) bulk(batch *bytes.Buffer) error {
+	if batch.Len() == 0 {
+		return nil
+	}
+	var response struct {
+		Errors bool `json:"errors"`
+	}
+	if err := r.Client.doRaw("POST", "/_bulk", batch.Bytes(), &response); err != nil {
+		return err
+	} else if response.Errors {
+		return fmt.Errorf("elasticsearch rejected some of the restored messages")
+	}
+	batch.Reset()
+	return nil
+}
+
+func touch(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+type indexStats struct {
+	Indices map[string]struct {
+		Primaries struct {
+			Docs struct {
+				Count int64 `json:"count"`
+			} `json:"docs"`
+			Store struct {
+				SizeInBytes int64 `json:"size_in_bytes"`
+			} `json:"store"`
+		} `json:"primaries"`
+	} `json:"indices"`
+}
+
+// Status reports the policy along with the size, archive state and
+// expiration of every logstash index
+func (r *Retention) Status(now time.Time) (*RetentionStatus, error) {
+	var stats indexStats
+	if err := r.Client.do("GET", "/"+IndexPrefix+"*/_stats/docs,store", nil, &stats); err != nil {
+		return nil, err
+	}
+
+	status := &RetentionStatus{Policy: r.Policy, ArchiveDir: r.ArchiveDir}
+	indexed := make(map[string]struct{})
+	for index, stat := range stats.Indices {
+		day, err := NormalizeYYYYMMDD(strings.TrimPrefix(index, IndexPrefix))
+		if err != nil {
+			continue
+		}
+		indexed[day] = struct{}{}
+
+		s := IndexStatus{
+			Day:       day,
+			Documents: stat.Primaries.Docs.Count,
+			SizeBytes: stat.Primaries.Store.SizeInBytes,
+			Archived:  r.isArchived(day),
+			Restored:  r.isRestored(day, now),
+		}
+		if t, err := time.Parse(DayFormat, day); err == nil {
+			s.Expires = t.AddDate(0, 0, r.Policy.MaxDays()+1).Format(DayFormat)
+		}
+		status.Indices = append(status.Indices, s)
+	}
+	sort.Sort(sort.Reverse(byDay(status.Indices)))
+
+	archives, err := filepath.Glob(filepath.Join(r.ArchiveDir, IndexPrefix+"*.tgz"))
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(archive), IndexPrefix), ".tgz")
+		if _, ok := indexed[day]; !ok {
+			status.Archives = append(status.Archives, day)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(status.Archives)))
+
+	return status, nil
+}
+
+type byDay []IndexStatus
+
+func (s byDay) Len() int           { return len(s) }
+func (s byDay) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byDay) Less(i, j int) bool { return s[i].Day < s[j].Day }
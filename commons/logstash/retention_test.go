@@ -0,0 +1,174 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logstash
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicy_Validate(t *testing.T) {
+	valid := RetentionPolicy{
+		DefaultDays: 14,
+		Rules:       []RetentionRule{{Type: "audit", Days: 365}, {TenantID: "tenant", Days: 3}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error validating %+v: %s", valid, err)
+	}
+	if max := valid.MaxDays(); max != 365 {
+		t.Errorf("expected max days 365, got %d", max)
+	}
+
+	invalid := []RetentionPolicy{
+		{},
+		{DefaultDays: 14, Rules: []RetentionRule{{Days: 3}}},
+		{DefaultDays: 14, Rules: []RetentionRule{{Type: "debug"}}},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("expected error validating %+v", policy)
+		}
+	}
+}
+
+func TestRetentionPolicy_ExpiredQuery(t *testing.T) {
+	policy := RetentionPolicy{
+		DefaultDays: 14,
+		Rules:       []RetentionRule{{Type: "audit", Days: 365}, {Type: "debug", Days: 3}},
+	}
+
+	if query, all := policy.expiredQuery(3, nil); query != nil || all {
+		t.Errorf("expected nothing to expire at 3 days, got %v (all: %t)", query, all)
+	}
+
+	// debug messages expire, unless they matched the audit rule first
+	query, all := policy.expiredQuery(4, nil)
+	if all {
+		t.Errorf("expected only some messages to expire at 4 days")
+	}
+	audit := `{"bool":{"must":[{"match":{"type":{"query":"audit","type":"phrase"}}}]}}`
+	debug := `{"bool":{"must":[{"match":{"type":{"query":"debug","type":"phrase"}}}]}}`
+	expected := `{"bool":{"minimum_should_match":1,"should":[{"bool":{"must":[` + debug + `],"must_not":[` + audit + `]}}]}}`
+	if actual, _ := json.Marshal(query); string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	// everything but audit messages expires
+	query, all = policy.expiredQuery(15, nil)
+	if all {
+		t.Errorf("expected only some messages to expire at 15 days")
+	}
+	expected = `{"bool":{"minimum_should_match":1,"should":[` +
+		`{"bool":{"must":[` + debug + `],"must_not":[` + audit + `]}},` +
+		`{"bool":{"must":[{"match_all":{}}],"must_not":[` + audit + `,` + debug + `]}}]}}`
+	if actual, _ := json.Marshal(query); string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	if _, all := policy.expiredQuery(366, nil); !all {
+		t.Errorf("expected everything to expire at 366 days")
+	}
+}
+
+func TestRetentionPolicy_TenantRule(t *testing.T) {
+	rule := RetentionRule{TenantID: "tenant", Days: 3}
+	expected := `{"bool":{"must":[{"bool":{"minimum_should_match":1,"should":[` +
+		`{"match":{"service":{"query":"tenant","type":"phrase"}}},` +
+		`{"match":{"service":{"query":"child","type":"phrase"}}}]}}]}}`
+	if actual, _ := json.Marshal(ruleQuery(rule, []string{"tenant", "child"})); string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestDayAge(t *testing.T) {
+	now := time.Date(2015, 3, 2, 23, 59, 0, 0, time.UTC)
+	for day, expected := range map[string]int{"2015.03.02": 0, "2015.03.01": 1, "2015.02.27": 3} {
+		if age, err := dayAge(day, now); err != nil {
+			t.Errorf("unexpected error for %s: %s", day, err)
+		} else if age != expected {
+			t.Errorf("expected age %d for %s, got %d", expected, day, age)
+		}
+	}
+}
+
+func TestWriteBulkIndex(t *testing.T) {
+	var buf bytes.Buffer
+	writeBulkIndex(&buf, "logstash-2015.01.02", []byte(`{"type":"zope","message":"hi"}`))
+	writeBulkIndex(&buf, "logstash-2015.01.02", []byte(`{"message":"untyped"}`))
+	expected := `{"index":{"_index":"logstash-2015.01.02","_type":"zope"}}` + "\n" +
+		`{"type":"zope","message":"hi"}` + "\n" +
+		`{"index":{"_index":"logstash-2015.01.02","_type":"logs"}}` + "\n" +
+		`{"message":"untyped"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRetention_IsRestored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logstash-retention-")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &Retention{ArchiveDir: dir}
+	now := time.Now()
+	if r.isRestored("2015.01.02", now) {
+		t.Errorf("expected day without marker not to be restored")
+	}
+	if err := touch(r.restoredPath("2015.01.02")); err != nil {
+		t.Fatalf("could not create marker: %s", err)
+	}
+	if !r.isRestored("2015.01.02", now) {
+		t.Errorf("expected recently restored day to be restored")
+	}
+	if r.isRestored("2015.01.02", now.Add((RestoredDays+1)*24*time.Hour)) {
+		t.Errorf("expected restored day to expire")
+	}
+	if filepath.Dir(r.ArchivePath("2015.01.02")) != dir {
+		t.Errorf("unexpected archive path %s", r.ArchivePath("2015.01.02"))
+	}
+}
+
+func TestOversizedDays(t *testing.T) {
+	now := time.Date(2015, 1, 5, 12, 0, 0, 0, time.UTC)
+	indices := []IndexStatus{
+		{Day: "2015.01.05", SizeBytes: 40},
+		{Day: "2015.01.02", SizeBytes: 10, Restored: true},
+		{Day: "2015.01.04", SizeBytes: 20},
+		{Day: "2015.01.01", SizeBytes: 10},
+		{Day: "2015.01.03", SizeBytes: 20},
+	}
+
+	for _, tc := range []struct {
+		maxBytes int64
+		expected []string
+	}{
+		{100, nil},
+		{90, []string{"2015.01.01"}},
+		{80, []string{"2015.01.01", "2015.01.03"}},
+		// the restored index and today's index are kept
+		{10, []string{"2015.01.01", "2015.01.03", "2015.01.04"}},
+	} {
+		actual := oversizedDays(indices, tc.maxBytes, now)
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("max %d: expected %v, got %v", tc.maxBytes, tc.expected, actual)
+		}
+	}
+}
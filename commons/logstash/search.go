@@ -0,0 +1,217 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logstash
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLimit is the number of messages returned by a search that does not
+// set a limit
+const DefaultLimit = 100
+
+// Query selects log messages from the logstash indices
+type Query struct {
+	Query      string            // lucene query string; empty matches everything
+	From       time.Time         // earliest message timestamp; zero is unbounded
+	To         time.Time         // latest message timestamp; zero is unbounded
+	ServiceIDs []string          // match messages from any of these services
+	Fields     map[string]string // match messages whose field contains the phrase
+	Offset     int               // number of matching messages to skip
+	Limit      int               // maximum number of messages to return
+}
+
+// Message is a log message found by a search
+type Message struct {
+	Timestamp time.Time
+	Host      string
+	File      string
+	Service   string
+	Instance  string
+	Type      string
+	Message   string
+	Fields    map[string]interface{} // any other fields stored with the message
+}
+
+// Result is a page of log messages matching a query, oldest first
+type Result struct {
+	Total    int // number of messages that matched the query
+	Messages []Message
+}
+
+// Search returns the messages matching the query from the indices that
+// cover its time range
+func (c *Client) Search(query Query) (*Result, error) {
+	days, err := c.DaysBetween(query.From, query.To)
+	if err != nil {
+		return nil, err
+	} else if len(days) == 0 {
+		return &Result{}, nil
+	}
+
+	indices := make([]string, len(days))
+	for i, day := range days {
+		indices[i] = IndexName(day)
+	}
+	path := fmt.Sprintf("/%s/_search?ignore_unavailable=true", url.QueryEscape(strings.Join(indices, ",")))
+
+	var response searchResponse
+	if err := c.do("POST", path, buildQuery(query), &response); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Total: response.Hits.Total}
+	for _, hit := range response.Hits.Hits {
+		result.Messages = append(result.Messages, parseMessage(hit.Source))
+	}
+	return result, nil
+}
+
+type searchResponse struct {
+	Hits struct {
+		Total int `json:"total"`
+		Hits  []struct {
+			Source map[string]interface{} `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// buildQuery converts a query into an elasticsearch request body
+func buildQuery(query Query) map[string]interface{} {
+	var match interface{}
+	if query.Query == "" {
+		match = map[string]interface{}{"match_all": map[string]interface{}{}}
+	} else {
+		match = map[string]interface{}{
+			"query_string": map[string]interface{}{"query": query.Query},
+		}
+	}
+
+	var filters []interface{}
+	if !query.From.IsZero() || !query.To.IsZero() {
+		timestamp := make(map[string]interface{})
+		if !query.From.IsZero() {
+			timestamp["gte"] = query.From.UTC().Format(time.RFC3339Nano)
+		}
+		if !query.To.IsZero() {
+			timestamp["lte"] = query.To.UTC().Format(time.RFC3339Nano)
+		}
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"@timestamp": timestamp},
+		})
+	}
+	if len(query.ServiceIDs) > 0 {
+		// string fields are analyzed, so match the ids as phrases
+		var services []interface{}
+		for _, serviceID := range query.ServiceIDs {
+			services = append(services, phraseFilter("service", serviceID))
+		}
+		filters = append(filters, map[string]interface{}{
+			"bool": map[string]interface{}{"should": services},
+		})
+	}
+	var fields []string
+	for field := range query.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		filters = append(filters, phraseFilter(field, query.Fields[field]))
+	}
+
+	if len(filters) > 0 {
+		match = map[string]interface{}{
+			"filtered": map[string]interface{}{
+				"query":  match,
+				"filter": map[string]interface{}{"bool": map[string]interface{}{"must": filters}},
+			},
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	return map[string]interface{}{
+		"query": match,
+		"sort":  []interface{}{map[string]interface{}{"@timestamp": map[string]interface{}{"order": "asc"}}},
+		"from":  query.Offset,
+		"size":  limit,
+	}
+}
+
+func phraseFilter(field, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{
+				field: map[string]interface{}{"query": value, "type": "phrase"},
+			},
+		},
+	}
+}
+
+// parseMessage converts the source of a search hit into a message.  Lines
+// joined by the multiline filter carry a list of messages.
+func parseMessage(source map[string]interface{}) Message {
+	var msg Message
+	msg.Fields = make(map[string]interface{})
+	for key, value := range source {
+		switch key {
+		case "@timestamp":
+			if s, ok := value.(string); ok {
+				msg.Timestamp, _ = time.Parse(time.RFC3339Nano, s)
+			}
+		case "host":
+			msg.Host = toString(value)
+		case "file":
+			msg.File = toString(value)
+		case "service":
+			msg.Service = toString(value)
+		case "instance":
+			msg.Instance = toString(value)
+		case "type":
+			msg.Type = toString(value)
+		case "message":
+			msg.Message = toString(value)
+		case "@version", "offset":
+		default:
+			msg.Fields[key] = value
+		}
+	}
+	return msg
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		lines := make([]string, len(v))
+		for i, line := range v {
+			lines[i] = toString(line)
+		}
+		return strings.Join(lines, "\n")
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package yaml reads and writes YAML documents that hold the JSON form of
+// values, so that a YAML file has the same fields as the JSON file it
+// replaces and the types keep their JSON decoding. YAML comments and anchors
+// are allowed, and JSON documents are read as they are.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// Extensions are the file extensions of YAML files
+var Extensions = []string{".yaml", ".yml"}
+
+// IsYAML returns true if a file name has a YAML extension
+func IsYAML(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSON returns true if a document looks like JSON rather than YAML
+func isJSON(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[')
+}
+
+// ToJSON converts a YAML or JSON document to JSON
+func ToJSON(data []byte) ([]byte, error) {
+	if isJSON(data) {
+		return data, nil
+	}
+	var doc interface{}
+	if err := goyaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	value, err := jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes a YAML or JSON document into v as encoding/json would
+// decode its JSON form
+func Unmarshal(data []byte, v interface{}) error {
+	data, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Marshal encodes the JSON form of v as YAML. Objects keep the order of their
+// fields.
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML flow syntax; decoding it as the value of an ordered map
+	// makes every object in it an ordered map
+	var doc goyaml.MapSlice
+	if err := goyaml.Unmarshal(append(append([]byte("{value: "), data...), '}'), &doc); err != nil {
+		return nil, err
+	} else if len(doc) != 1 {
+		return nil, fmt.Errorf("could not convert %s to YAML", data)
+	}
+	return goyaml.Marshal(doc[0].Value)
+}
+
+// jsonValue converts a decoded YAML value to a value that encoding/json can
+// encode, whose maps have string keys
+func jsonValue(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, elem := range value {
+			var err error
+			if m[fmt.Sprint(key)], err = jsonValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, elem := range value {
+			var err error
+			if list[i], err = jsonValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	return v, nil
+}
+
+// NewDecoder returns a decoder that reads a YAML or JSON document from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decoder reads a YAML or JSON document from a reader
+type Decoder struct {
+	r io.Reader
+}
+
+// Decode reads the document into v
+func (d *Decoder) Decode(v interface{}) error {
+	var buffer bytes.Buffer
+	if _, err := buffer.ReadFrom(d.r); err != nil {
+		return err
+	}
+	return Unmarshal(buffer.Bytes(), v)
+}
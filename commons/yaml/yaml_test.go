@@ -0,0 +1,128 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	Name      string
+	Instances int
+	Context   map[string]interface{}
+	Ports     []uint16
+}
+
+const sampleYAML = `
+# the defaults of the services
+defaults: &defaults
+  Instances: 2
+  Context:
+    db.port: 3306
+services:
+  - <<: *defaults
+    Name: web # the front end
+    Ports: [80, 443]
+`
+
+func TestUnmarshal(t *testing.T) {
+	var doc struct {
+		Services []sample `json:"services"`
+	}
+	if err := Unmarshal([]byte(sampleYAML), &doc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []sample{{
+		Name:      "web",
+		Instances: 2,
+		Context:   map[string]interface{}{"db.port": float64(3306)},
+		Ports:     []uint16{80, 443},
+	}}
+	if !reflect.DeepEqual(doc.Services, expected) {
+		t.Errorf("expected %+v, got %+v", expected, doc.Services)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var s sample
+	if err := Unmarshal([]byte("{\n\t\"Name\": \"db\",\n\t\"Instances\": 1\n}"), &s); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if s.Name != "db" || s.Instances != 1 {
+		t.Errorf("unexpected value %+v", s)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	s := sample{Name: "web", Instances: 2, Ports: []uint16{80}}
+	data, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "Name: web\nInstances: 2\nContext: null\nPorts:\n- 80\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, string(data))
+	}
+
+	var actual sample
+	if err := Unmarshal(data, &actual); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(actual, s) {
+		t.Errorf("expected %+v, got %+v", s, actual)
+	}
+}
+
+func TestMarshalOrder(t *testing.T) {
+	doc := struct {
+		Version  string
+		Services []sample
+		Labels   map[string]string
+	}{
+		Version:  "<1.0>",
+		Services: []sample{{Name: "db", Context: map[string]interface{}{"b": 1.5, "a": true}}},
+		Labels:   map[string]string{"z": "last", "a": "first"},
+	}
+	data, err := Marshal(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "Version: <1.0>\nServices:\n- Name: db\n  Instances: 0\n  Context:\n    a: true\n    b: 1.5\n  Ports: null\nLabels:\n  a: first\n  z: last\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, string(data))
+	}
+
+	data, err = Marshal([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if string(data) != "- a\n- b\n" {
+		t.Errorf("unexpected document %q", string(data))
+	}
+}
+
+func TestIsYAML(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"service.yaml": true,
+		"service.YML":  true,
+		"service.json": false,
+		"yaml":         false,
+	} {
+		if actual := IsYAML(name); actual != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, actual)
+		}
+	}
+	if strings.Join(Extensions, ",") != ".yaml,.yml" {
+		t.Errorf("unexpected extensions %v", Extensions)
+	}
+}
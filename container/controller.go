@@ -116,6 +116,11 @@ type Controller struct {
 	vhostZKPaths            []string
 	exitStatus              int
 	allowDirectConn         bool
+	readinessCheck          string    // health check gating the registration of exports
+	readiness               chan bool // receives the status of the readiness check when it changes
+	drainTimeout            time.Duration
+	preStop                 string
+	secretEnv               []string // environment variables set to secrets, which are not written to the environment file
 }
 
 // Close shuts down the controller
@@ -294,6 +299,15 @@ func NewController(options ControllerOptions) (*Controller, error) {
 	c.allowDirectConn = !service.HasEndpointsFor("import_all")
 	glog.Infof("Allow container to container connections: %t", c.allowDirectConn)
 
+	if service.ReadinessCheck != "" {
+		c.readinessCheck = service.ReadinessCheck
+		c.readiness = make(chan bool)
+		glog.Infof("Exports will be registered while health check %s passes", c.readinessCheck)
+	}
+
+	c.drainTimeout = time.Duration(service.DrainTimeout) * time.Second
+	c.preStop = service.PreStop
+
 	if service.PIDFile != "" {
 		if strings.HasPrefix(service.PIDFile, "exec ") {
 			cmd := service.PIDFile[5:len(service.PIDFile)]
@@ -308,6 +322,25 @@ func NewController(options ControllerOptions) (*Controller, error) {
 		}
 	}
 
+	// put in the secrets
+	if usesSecrets(service) {
+		values, err := getServiceSecrets(options.ServicedEndpoint, options.Service.ID)
+		if err != nil {
+			return c, fmt.Errorf("container: could not get secrets error:%s", err)
+		}
+		if err := expandConfigSecrets(service, values); err != nil {
+			glog.Errorf("Could not put secrets in config files error:%s", err)
+			return c, fmt.Errorf("container: invalid ConfigFiles error:%s", err)
+		}
+		if err := setupSecretFiles(service, values); err != nil {
+			glog.Errorf("Could not setup secret files error:%s", err)
+			return c, fmt.Errorf("container: invalid Secrets error:%s", err)
+		}
+		if c.secretEnv, err = secretEnvironment(service, values); err != nil {
+			return c, fmt.Errorf("container: invalid Secrets error:%s", err)
+		}
+	}
+
 	// create config files
 	if err := setupConfigFiles(service); err != nil {
 		glog.Errorf("Could not setup config files error:%s", err)
@@ -382,7 +415,11 @@ func NewController(options ControllerOptions) (*Controller, error) {
 		// setup network stats
 		destination := fmt.Sprintf("http://localhost%s/api/metrics/store", options.Metric.Address)
 		glog.Infof("pushing network stats to: %s", destination)
-		go statReporter(destination, time.Second*15)
+		var volumes []string
+		for _, volume := range service.Volumes {
+			volumes = append(volumes, volume.ContainerPath)
+		}
+		go statReporter(destination, time.Second*15, volumes)
 	}
 
 	// Keep a copy of the service prerequisites in the Controller object.
@@ -517,6 +554,7 @@ func (c *Controller) Run() (err error) {
 	if err := writeEnvFile(env); err != nil {
 		return err
 	}
+	env = append(env, c.secretEnv...)
 
 	args := []string{"-c", "exec " + strings.Join(c.options.Service.Command, " ")}
 
@@ -558,11 +596,17 @@ func (c *Controller) Run() (err error) {
 	healthExit := make(chan struct{})
 	defer close(healthExit)
 	c.kickOffHealthChecks(healthExit)
-	doRegisterEndpoints := true
+	readiness := c.readiness
+	ready := readiness == nil
+	registered := false
 	exited := false
 
+	var drained <-chan struct{}
+	var stopSignal os.Signal
+
 	var shutdownService = func(service *subprocess.Instance, sig os.Signal) {
 		c.options.Service.Autorestart = false
+		drained = nil
 		if sendSignal(service, sig) {
 			sigc = nil
 			prereqsPassed = nil
@@ -581,8 +625,25 @@ func (c *Controller) Run() (err error) {
 	for !exited {
 		select {
 		case sig := <-sigc:
-			glog.Infof("Notifying subprocess of signal %v", sig)
-			shutdownService(service, sig)
+			if service == nil || drained != nil {
+				glog.Infof("Notifying subprocess of signal %v", sig)
+				shutdownService(service, sig)
+				break
+			}
+			// stop taking new connections and let the open ones finish
+			// before the subprocess is signalled; another signal skips
+			// the drain
+			glog.Infof("Draining instance before notifying subprocess of signal %v", sig)
+			c.options.Service.Autorestart = false
+			prereqsPassed, startAfter = nil, nil
+			c.withdrawExportedEndpoints()
+			readiness, reregister, registered = nil, nil, false
+			stopSignal = sig
+			drained = c.drain(healthExit)
+
+		case <-drained:
+			glog.Infof("Notifying subprocess of signal %v", stopSignal)
+			shutdownService(service, stopSignal)
 
 		case <-exitAfter:
 			glog.Infof("Killing unresponsive subprocess")
@@ -615,13 +676,24 @@ func (c *Controller) Run() (err error) {
 		case <-startAfter:
 			glog.Infof("Starting service process.")
 			service, serviceExited = startService()
-			if doRegisterEndpoints {
+			if ready && !registered {
 				reregister = registerExportedEndpoints(c, rpcDead)
-				doRegisterEndpoints = false
+				registered = true
 			}
 			startAfter = nil
 		case <-reregister:
 			reregister = registerExportedEndpoints(c, rpcDead)
+		case ready = <-readiness:
+			if ready && !registered && service != nil {
+				glog.Infof("Readiness check %s passed, registering exported endpoints", c.readinessCheck)
+				reregister = registerExportedEndpoints(c, rpcDead)
+				registered = true
+			} else if !ready && registered {
+				glog.Infof("Readiness check %s failed, withdrawing exported endpoints", c.readinessCheck)
+				c.withdrawExportedEndpoints()
+				reregister = nil
+				registered = false
+			}
 		case <-rpcDead:
 			glog.Infof("RPC Server has gone away, cleaning up")
 			shutdownService(service, syscall.SIGTERM)
@@ -708,6 +780,13 @@ func (c *Controller) kickOffHealthChecks(healthExit chan struct{}) {
 	}
 	defer client.Close()
 	var healthChecks map[string]domain.HealthCheck
+	defer func() {
+		// never gate the exports on a check that will not run
+		if _, ok := healthChecks[c.readinessCheck]; c.readinessCheck != "" && !ok {
+			glog.Errorf("Readiness check %s is not running; registering exports without it", c.readinessCheck)
+			c.readinessCheck, c.readiness = "", nil
+		}
+	}()
 
 	instanceID, err := strconv.Atoi(c.options.Service.InstanceID)
 	if err != nil {
@@ -722,72 +801,67 @@ func (c *Controller) kickOffHealthChecks(healthExit chan struct{}) {
 	}
 	for key, mapping := range healthChecks {
 		glog.Infof("Kicking off health check %s.", key)
-		glog.Infof("Setting up health check: %s", mapping.Script)
-		timeout := mapping.Timeout
-		if timeout == 0 {
-			timeout = time.Second * 30
+		if mapping.Timeout == 0 {
+			mapping.Timeout = time.Second * 30
 		}
-		go c.handleHealthCheck(key, mapping.Script, mapping.Interval, timeout, healthExit)
+		var readiness chan<- bool
+		if key == c.readinessCheck {
+			readiness = c.readiness
+		}
+		go c.handleHealthCheck(key, mapping, readiness, healthExit)
 	}
 	return
 }
 
-func (c *Controller) handleHealthCheck(name string, script string, interval, timeout time.Duration, exitChannel chan struct{}) {
+// handleHealthCheck runs a health check and reports its status. If readiness
+// is set, it also receives the status whenever it changes.
+func (c *Controller) handleHealthCheck(name string, hc domain.HealthCheck, readiness chan<- bool, exitChannel chan struct{}) {
 	client, err := node.NewLBClient(c.options.ServicedEndpoint)
 	if err != nil {
 		glog.Errorf("Could not create a client to endpoint: %s, %s", c.options.ServicedEndpoint, err)
 		return
 	}
 	defer client.Close()
-	scriptFile, err := ioutil.TempFile("", name)
-	if err != nil {
-		glog.Errorf("Error creating temporary file for health check %s: %s", name, err)
-		return
-	}
-	defer scriptFile.Close()
-	defer os.Remove(scriptFile.Name())
-	err = ioutil.WriteFile(scriptFile.Name(), []byte(script), os.FileMode(0777))
+	checker, err := newHealthChecker(name, hc)
 	if err != nil {
-		glog.Errorf("Error writing script for health check %s: %s", name, err)
+		glog.Errorf("Error setting up health check %s: %s", name, err)
 		return
 	}
-	scriptFile.Close()
-	err = os.Chmod(scriptFile.Name(), os.FileMode(0777))
-	if err != nil {
-		glog.Errorf("Error setting script executable for health check %s: %s", name, err)
-		return
+	defer checker.close()
+	glog.Infof("Setting up %s health check %s", hc.Type, name)
+
+	threshold := newHealthThreshold(hc)
+	lastStatus := ""
+	delay := hc.Interval
+	if hc.InitialDelay > 0 {
+		delay = hc.InitialDelay
 	}
 	var unused int
-	sigtermTimeout := time.Second * 10
 	for {
 		select {
-		case <-time.After(interval):
-			exited := make(chan error, 1)
-			sysProcAttr := &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGTERM}
-			cmd := exec.Command("sh", "-c", scriptFile.Name())
-			cmd.SysProcAttr = sysProcAttr
-			if err := cmd.Start(); err != nil {
-				glog.Errorf("Could not run cmd %v: %s", cmd, err)
+		case <-time.After(delay):
+			delay = hc.Interval
+			err := checker.check(hc.Timeout, exitChannel)
+			if err == errHealthCheckExited {
+				return
+			} else if err == nil {
+				glog.V(4).Infof("Health check %s succeeded.", name)
+			} else {
+				glog.Warningf("Health check %s failed: %s", name, err)
+			}
+			status := threshold.observe(err)
+			if status == "" {
 				break
 			}
-			go func(c *exec.Cmd) { exited <- c.Wait() }(cmd)
-			select {
-			case err := <-exited:
-				if err == nil {
-					glog.V(4).Infof("Health check %s succeeded.", name)
-					client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, c.options.Service.InstanceID, name, time.Now().String(), "passed"}, &unused)
-				} else {
-					glog.Warningf("Health check %s failed.", name)
-					client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, c.options.Service.InstanceID, name, time.Now().String(), "failed"}, &unused)
+			client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, c.options.Service.InstanceID, name, time.Now().String(), status}, &unused)
+			if readiness != nil && status != lastStatus {
+				select {
+				case readiness <- status == "passed":
+				case <-exitChannel:
+					return
 				}
-			case <-exitChannel:
-				proc.KillGroup(cmd.Process.Pid, sigtermTimeout)
-				return
-			case <-time.After(timeout):
-				proc.KillGroup(cmd.Process.Pid, sigtermTimeout)
-				glog.Warningf("Health check %s timeout.", name)
-				client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, c.options.Service.InstanceID, name, time.Now().String(), "failed"}, &unused)
 			}
+			lastStatus = status
 		case <-exitChannel:
 			return
 		}
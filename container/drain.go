@@ -0,0 +1,166 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/domain/service"
+	"github.com/zenoss/glog"
+)
+
+// drainInterval is how often the connections of a draining instance are
+// counted
+var drainInterval = 500 * time.Millisecond
+
+// tcpEstablished is the state of an established connection in /proc/net/tcp
+const tcpEstablished = "01"
+
+// drainNetFiles list the connections of the instance over IPv4 and IPv6;
+// services listening on :: only show up in /proc/net/tcp6
+var drainNetFiles = []string{procNetFiles["tcp"], "/proc/net/tcp6"}
+
+// drain waits for the connections of the instance to finish, for at most
+// the drain timeout of the service, and then runs its pre-stop command.
+// The exports of the instance must already be withdrawn so that no new
+// connections arrive. The returned channel is closed when the service may be
+// signalled; closing cancel abandons the drain.
+//
+// The drain waits for the connections to the exports of the instance and for
+// the connections its in-flight requests open through the import proxies
+// while it drains.  Proxied connections opened before the drain, such as
+// the pooled connections to a database, are not waited for: they only close
+// when the service exits.
+func (c *Controller) drain(cancel <-chan struct{}) <-chan struct{} {
+	drained := make(chan struct{})
+	ports := c.exportedPorts()
+	setProxiesDraining(true)
+	go func() {
+		defer close(drained)
+		deadline := time.After(c.drainTimeout)
+	wait:
+		for c.drainTimeout > 0 {
+			conns := drainingProxyConnections()
+			for _, file := range drainNetFiles {
+				conns += establishedConnections(file, ports)
+			}
+			if conns == 0 {
+				glog.Infof("All connections of the instance are closed")
+				break
+			}
+			glog.V(1).Infof("Waiting for %d connections to close", conns)
+			select {
+			case <-cancel:
+				setProxiesDraining(false)
+				return
+			case <-deadline:
+				glog.Warningf("Drain timeout of %s expired with %d open connections", c.drainTimeout, conns)
+				break wait
+			case <-time.After(drainInterval):
+			}
+		}
+
+		if c.preStop == "" {
+			return
+		}
+		glog.Infof("Running pre-stop command: %s", c.preStop)
+		checker, err := newScriptChecker("prestop", c.preStop)
+		if err != nil {
+			glog.Errorf("Could not run pre-stop command: %s", err)
+			return
+		}
+		defer checker.close()
+		if err := checker.check(service.PreStopTimeout, cancel); err != nil {
+			glog.Errorf("Pre-stop command failed: %s", err)
+		}
+	}()
+	return drained
+}
+
+// exportedPorts returns the container ports of the exports of the instance
+func (c *Controller) exportedPorts() map[uint16]bool {
+	ports := make(map[uint16]bool)
+	for _, exports := range c.exportedEndpoints {
+		for _, export := range exports {
+			ports[export.endpoint.ContainerPort] = true
+		}
+	}
+	return ports
+}
+
+// setProxiesDraining starts or stops counting the connections opened
+// through the import proxies of the instance while it drains
+func setProxiesDraining(draining bool) {
+	proxiesLock.RLock()
+	defer proxiesLock.RUnlock()
+	for _, p := range proxies {
+		p.SetDraining(draining)
+	}
+}
+
+// drainingProxyConnections returns the number of connections opened while
+// draining that are being proxied to the imports of the instance
+func drainingProxyConnections() int64 {
+	proxiesLock.RLock()
+	defer proxiesLock.RUnlock()
+	var active int64
+	for _, p := range proxies {
+		active += p.DrainingConnections()
+	}
+	return active
+}
+
+// establishedConnections returns the number of established connections to
+// any of the local ports in a file formatted like /proc/net/tcp
+func establishedConnections(fileLoc string, ports map[uint16]bool) int64 {
+	if len(ports) == 0 {
+		return 0
+	}
+	file, err := os.Open(fileLoc)
+	if os.IsNotExist(err) {
+		// no IPv6 support
+		return 0
+	} else if err != nil {
+		glog.Errorf("Could not count the connections of the instance: %s", err)
+		return 0
+	}
+	defer file.Close()
+
+	var established int64
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Skip the first line of headers
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		// the local address is hex encoded as IP:PORT
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+		if ports[uint16(port)] {
+			established++
+		}
+	}
+	return established
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 14318 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0CEA 0100007F:9C40 01 00000000:00000000 00:00000000 00000000     0        0 14319 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:0CEA 0100007F:9C41 06 00000000:00000000 03:00000BB8 00000000     0        0 0 3 0000000000000000
+   3: AC11000A:1F90 AC110001:C350 01 00000000:00000000 00:00000000 00000000     0        0 14320 1 0000000000000000 20 4 30 10 -1
+   4: AC11000A:9C42 AC110002:0CEA 01 00000000:00000000 00:00000000 00000000     0        0 14321 1 0000000000000000 20 4 30 10 -1
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 15318 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00000A0011AC:1F90 0000000000000000FFFF0000010011AC:C351 01 00000000:00000000 00:00000000 00000000     0        0 15319 1 0000000000000000 20 4 30 10 -1
+`
+
+func TestEstablishedConnectionsTCP6(t *testing.T) {
+	f, err := ioutil.TempFile("", "tcp6")
+	if err != nil {
+		t.Fatalf("could not create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(procNetTCP6); err != nil {
+		t.Fatalf("could not write temporary file: %s", err)
+	}
+	f.Close()
+
+	if actual := establishedConnections(f.Name(), map[uint16]bool{8080: true}); actual != 1 {
+		t.Errorf("expected 1 established connection to 8080, got %d", actual)
+	}
+	if actual := establishedConnections(filepath.Join(os.TempDir(), "no-such-tcp6"), map[uint16]bool{8080: true}); actual != 0 {
+		t.Errorf("expected no connections without the file, got %d", actual)
+	}
+}
+
+func TestEstablishedConnections(t *testing.T) {
+	f, err := ioutil.TempFile("", "tcp")
+	if err != nil {
+		t.Fatalf("could not create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(procNetTCP); err != nil {
+		t.Fatalf("could not write temporary file: %s", err)
+	}
+	f.Close()
+
+	for _, tc := range []struct {
+		ports    map[uint16]bool
+		expected int64
+	}{
+		{nil, 0},
+		{map[uint16]bool{3306: true}, 1},
+		{map[uint16]bool{3306: true, 8080: true}, 2},
+		{map[uint16]bool{22: true}, 0},
+	} {
+		if actual := establishedConnections(f.Name(), tc.ports); actual != tc.expected {
+			t.Errorf("expected %d established connections to %v, got %d", tc.expected, tc.ports, actual)
+		}
+	}
+}
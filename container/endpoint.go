@@ -746,6 +746,15 @@ func (c *Controller) registerExportedEndpoints() error {
 	return nil
 }
 
+// withdrawExportedEndpoints removes the exports of this instance from the
+// registries while the instance keeps running
+func (c *Controller) withdrawExportedEndpoints() {
+	c.unregisterVhosts()
+	c.unregisterEndpoints()
+	c.vhostZKPaths = []string{}
+	c.exportedEndpointZKPaths = []string{}
+}
+
 func (c *Controller) unregisterVhosts() {
 	conn, err := zzk.GetLocalConnection("/")
 	if err != nil {
@@ -0,0 +1,409 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// There is no http2 or grpc client in this tree, and golang.org/x/net/http2
+// and google.golang.org/grpc need a newer Go than the one serviced builds
+// with, so grpc health checks speak just enough plaintext http2 to make one
+// unary call to grpc.health.v1.Health/Check and read the status out of the
+// response and its trailers.
+
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// http2 frame types and flags
+const (
+	frameData         = 0x0
+	frameHeaders      = 0x1
+	frameRSTStream    = 0x3
+	frameSettings     = 0x4
+	framePing         = 0x6
+	frameGoAway       = 0x7
+	frameContinuation = 0x9
+
+	flagEndStream  = 0x1
+	flagAck        = 0x1
+	flagEndHeaders = 0x4
+	flagPadded     = 0x8
+	flagPriority   = 0x20
+)
+
+// maxFrameSize bounds the frames read from a server
+const maxFrameSize = 1 << 20
+
+// grpcServingStatus names the values of HealthCheckResponse.ServingStatus
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// grpcCodes names the grpc status codes
+var grpcCodes = map[string]string{
+	"1":  "CANCELLED",
+	"2":  "UNKNOWN",
+	"3":  "INVALID_ARGUMENT",
+	"4":  "DEADLINE_EXCEEDED",
+	"5":  "NOT_FOUND",
+	"6":  "ALREADY_EXISTS",
+	"7":  "PERMISSION_DENIED",
+	"8":  "RESOURCE_EXHAUSTED",
+	"9":  "FAILED_PRECONDITION",
+	"10": "ABORTED",
+	"11": "OUT_OF_RANGE",
+	"12": "UNIMPLEMENTED",
+	"13": "INTERNAL",
+	"14": "UNAVAILABLE",
+	"15": "DATA_LOSS",
+	"16": "UNAUTHENTICATED",
+}
+
+var errNoGRPCStatus = errors.New("grpc health check returned no status")
+
+type http2Frame struct {
+	typ     byte
+	flags   byte
+	stream  uint32
+	payload []byte
+}
+
+func writeFrame(w io.Writer, typ, flags byte, stream uint32, payload []byte) error {
+	header := make([]byte, 9)
+	header[0] = byte(len(payload) >> 16)
+	header[1] = byte(len(payload) >> 8)
+	header[2] = byte(len(payload))
+	header[3] = typ
+	header[4] = flags
+	binary.BigEndian.PutUint32(header[5:], stream&0x7fffffff)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readFrame(r io.Reader) (*http2Frame, error) {
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[0])<<16 | int(header[1])<<8 | int(header[2])
+	if length > maxFrameSize {
+		return nil, fmt.Errorf("http2 frame too large: %d bytes", length)
+	}
+	f := &http2Frame{
+		typ:     header[3],
+		flags:   header[4],
+		stream:  binary.BigEndian.Uint32(header[5:]) & 0x7fffffff,
+		payload: make([]byte, length),
+	}
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// hpackInt appends an hpack integer with an n bit prefix
+func hpackInt(b []byte, first byte, n uint, i uint64) []byte {
+	max := uint64(1)<<n - 1
+	if i < max {
+		return append(b, first|byte(i))
+	}
+	b = append(b, first|byte(max))
+	for i -= max; i >= 128; i >>= 7 {
+		b = append(b, byte(i%128)|0x80)
+	}
+	return append(b, byte(i))
+}
+
+// hpackHeaders encodes headers as literals without indexing or huffman
+// coding, so the block needs no compression state
+func hpackHeaders(headers [][2]string) []byte {
+	var b []byte
+	for _, h := range headers {
+		b = append(b, 0)
+		for _, s := range h {
+			b = hpackInt(b, 0, 7, uint64(len(s)))
+			b = append(b, s...)
+		}
+	}
+	return b
+}
+
+// grpcMessage frames a protobuf message for a grpc stream
+func grpcMessage(message []byte) []byte {
+	b := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(message)))
+	return append(b, message...)
+}
+
+// healthCheckRequest encodes a HealthCheckRequest{service}
+func healthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	b := []byte{0x0a} // field 1, length delimited
+	b = appendVarint(b, uint64(len(service)))
+	return append(b, service...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for ; v >= 0x80; v >>= 7 {
+		b = append(b, byte(v)|0x80)
+	}
+	return append(b, byte(v))
+}
+
+func readVarint(b []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("invalid varint")
+}
+
+// healthCheckStatus decodes the status of a HealthCheckResponse message,
+// skipping any fields it does not know
+func healthCheckStatus(message []byte) (uint64, error) {
+	var status uint64
+	for len(message) > 0 {
+		tag, n, err := readVarint(message)
+		if err != nil {
+			return 0, err
+		}
+		message = message[n:]
+		switch tag & 0x7 {
+		case 0:
+			v, n, err := readVarint(message)
+			if err != nil {
+				return 0, err
+			}
+			if tag>>3 == 1 {
+				status = v
+			}
+			message = message[n:]
+		case 1:
+			n = 8
+		case 2:
+			l, m, err := readVarint(message)
+			if err != nil {
+				return 0, err
+			}
+			message = message[m:]
+			n = int(l)
+		case 5:
+			n = 4
+		default:
+			return 0, fmt.Errorf("invalid protobuf wire type %d", tag&0x7)
+		}
+		if tag&0x7 != 0 {
+			if n > len(message) {
+				return 0, errors.New("truncated protobuf message")
+			}
+			message = message[n:]
+		}
+	}
+	return status, nil
+}
+
+// dataPayload strips the padding from the payload of a data frame
+func dataPayload(f *http2Frame) ([]byte, error) {
+	if f.flags&flagPadded == 0 {
+		return f.payload, nil
+	}
+	if len(f.payload) == 0 || int(f.payload[0]) >= len(f.payload) {
+		return nil, errors.New("invalid http2 padding")
+	}
+	return f.payload[1 : len(f.payload)-int(f.payload[0])], nil
+}
+
+// headerBlock strips the padding and priority from the payload of a headers
+// frame
+func headerBlock(f *http2Frame) ([]byte, error) {
+	payload, err := dataPayload(f)
+	if err != nil {
+		return nil, err
+	}
+	if f.flags&flagPriority != 0 {
+		if len(payload) < 5 {
+			return nil, errors.New("invalid http2 priority")
+		}
+		payload = payload[5:]
+	}
+	return payload, nil
+}
+
+// grpcStatus returns the error of a grpc-status header that is not OK
+func grpcStatus(headers [][2]string) error {
+	var status, message string
+	for _, h := range headers {
+		switch h[0] {
+		case "grpc-status":
+			status = h[1]
+		case "grpc-message":
+			message = h[1]
+		}
+	}
+	if status == "" || status == "0" {
+		return nil
+	}
+	name, ok := grpcCodes[status]
+	if !ok {
+		name = "status " + status
+	}
+	if message != "" {
+		return fmt.Errorf("grpc health check failed: %s: %s", name, message)
+	}
+	return fmt.Errorf("grpc health check failed: %s", name)
+}
+
+// grpcHealthCheck asks the grpc server at addr whether service is serving
+func grpcHealthCheck(addr, service string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	w := bufio.NewWriter(conn)
+	if _, err := w.WriteString(http2Preface); err != nil {
+		return err
+	}
+	if err := writeFrame(w, frameSettings, 0, 0, nil); err != nil {
+		return err
+	}
+	headers := hpackHeaders([][2]string{
+		{":method", "POST"},
+		{":scheme", "http"},
+		{":path", "/grpc.health.v1.Health/Check"},
+		{":authority", addr},
+		{"content-type", "application/grpc"},
+		{"te", "trailers"},
+	})
+	if err := writeFrame(w, frameHeaders, flagEndHeaders, 1, headers); err != nil {
+		return err
+	}
+	if err := writeFrame(w, frameData, flagEndStream, 1, grpcMessage(healthCheckRequest(service))); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	decoder := newHpackDecoder()
+	var body, block []byte
+	var blockEnd bool // the header block being read ends the stream
+	var trailers [][2]string
+	for done := false; !done; {
+		f, err := readFrame(r)
+		if err != nil {
+			return err
+		}
+		if block != nil && f.typ != frameContinuation {
+			return errors.New("grpc server interrupted a header block")
+		}
+		switch f.typ {
+		case frameSettings:
+			if f.flags&flagAck == 0 {
+				if err := writeFrame(conn, frameSettings, flagAck, 0, nil); err != nil {
+					return err
+				}
+			}
+		case framePing:
+			if f.flags&flagAck == 0 {
+				if err := writeFrame(conn, framePing, flagAck, 0, f.payload); err != nil {
+					return err
+				}
+			}
+		case frameGoAway:
+			return errors.New("grpc server closed the connection")
+		case frameRSTStream:
+			if f.stream == 1 {
+				return errors.New("grpc server reset the health check")
+			}
+		case frameData:
+			if f.stream == 1 {
+				payload, err := dataPayload(f)
+				if err != nil {
+					return err
+				}
+				body = append(body, payload...)
+				done = f.flags&flagEndStream != 0
+			}
+		case frameHeaders, frameContinuation:
+			// every header block goes through the decoder, since they share
+			// its table
+			if f.typ == frameHeaders {
+				if block, err = headerBlock(f); err != nil {
+					return err
+				}
+				blockEnd = f.stream == 1 && f.flags&flagEndStream != 0
+			} else if block == nil {
+				return errors.New("unexpected http2 continuation frame")
+			} else {
+				block = append(block, f.payload...)
+			}
+			if f.flags&flagEndHeaders == 0 {
+				continue
+			}
+			decoded, err := decoder.decode(block)
+			if err != nil {
+				return err
+			}
+			block = nil
+			if blockEnd {
+				// the trailers, or the headers of a response without a body
+				trailers, done = decoded, true
+			}
+		}
+	}
+
+	if err := grpcStatus(trailers); err != nil {
+		return err
+	}
+	if len(body) < 5 {
+		return errNoGRPCStatus
+	}
+	length := binary.BigEndian.Uint32(body[1:5])
+	if body[0] != 0 || int(length) > len(body)-5 {
+		return fmt.Errorf("unexpected grpc health check response")
+	}
+	status, err := healthCheckStatus(body[5 : 5+length])
+	if err != nil {
+		return err
+	}
+	if status != 1 {
+		name, ok := grpcServingStatus[status]
+		if !ok {
+			name = fmt.Sprint(status)
+		}
+		return fmt.Errorf("grpc service %q is %s", service, name)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveGRPCHealth answers one health check on listener with status and
+// trailers, recording the request message it received. A status of 0 sends
+// the trailers without a response message.
+func serveGRPCHealth(t *testing.T, listener net.Listener, status uint64, trailers [][2]string, request chan<- []byte) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	preface := make([]byte, len(http2Preface))
+	if _, err := io.ReadFull(r, preface); err != nil || string(preface) != http2Preface {
+		t.Errorf("bad http2 preface %q: %v", preface, err)
+		return
+	}
+
+	var body []byte
+	for {
+		f, err := readFrame(r)
+		if err != nil {
+			t.Errorf("could not read frame: %s", err)
+			return
+		}
+		if f.typ == frameData && f.stream == 1 {
+			body = append(body, f.payload...)
+			if f.flags&flagEndStream != 0 {
+				break
+			}
+		}
+	}
+	request <- body
+
+	// like a grpc server, keep the connection open until the client closes it
+	defer io.Copy(ioutil.Discard, r)
+	writeFrame(conn, frameSettings, 0, 0, nil)
+	if status == 0 {
+		writeFrame(conn, frameHeaders, flagEndHeaders|flagEndStream, 1, hpackHeaders(append([][2]string{{":status", "200"}}, trailers...)))
+		return
+	}
+	// the response headers are split over a continuation frame
+	headers := hpackHeaders([][2]string{{":status", "200"}, {"content-type", "application/grpc"}})
+	writeFrame(conn, frameHeaders, 0, 1, headers[:5])
+	writeFrame(conn, frameContinuation, flagEndHeaders, 1, headers[5:])
+	response := appendVarint([]byte{0x08}, status) // field 1, varint
+	writeFrame(conn, frameData, flagPadded, 1, append([]byte{2}, append(grpcMessage(response), 0, 0)...))
+	writeFrame(conn, frameHeaders, flagEndHeaders|flagEndStream, 1, hpackHeaders(trailers))
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	ok := [][2]string{{"grpc-status", "0"}}
+	for _, tc := range []struct {
+		status   uint64
+		trailers [][2]string
+		err      string
+	}{
+		{1, ok, ""},
+		{2, ok, `grpc service "svc" is NOT_SERVING`},
+		{0, [][2]string{{"grpc-status", "5"}, {"grpc-message", "unknown service"}}, "grpc health check failed: NOT_FOUND: unknown service"},
+		{0, [][2]string{{"grpc-status", "12"}}, "grpc health check failed: UNIMPLEMENTED"},
+		{0, nil, errNoGRPCStatus.Error()},
+	} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not listen: %s", err)
+		}
+		request := make(chan []byte, 1)
+		go serveGRPCHealth(t, listener, tc.status, tc.trailers, request)
+
+		err = grpcHealthCheck(listener.Addr().String(), "svc", time.Second)
+		if tc.err == "" && err != nil {
+			t.Errorf("%v: unexpected error: %s", tc.trailers, err)
+		} else if tc.err != "" && (err == nil || err.Error() != tc.err) {
+			t.Errorf("%v: expected error %q, got %v", tc.trailers, tc.err, err)
+		}
+		if body := <-request; !bytes.Equal(body, grpcMessage(healthCheckRequest("svc"))) {
+			t.Errorf("unexpected request %v", body)
+		}
+		listener.Close()
+	}
+}
+
+func TestHealthCheckStatus(t *testing.T) {
+	// an unknown string field before the status
+	message := append([]byte{0x12, 0x02, 'h', 'i'}, 0x08, 0x02)
+	if status, err := healthCheckStatus(message); err != nil || status != 2 {
+		t.Errorf("expected status 2, got %d (%v)", status, err)
+	}
+	if _, err := healthCheckStatus([]byte{0x12, 0x05, 'h'}); err == nil {
+		t.Errorf("expected an error for a truncated message")
+	}
+	if b := hpackInt(nil, 0, 7, 300); !bytes.Equal(b, []byte{127, 173, 1}) {
+		t.Errorf("unexpected hpack integer encoding %v", b)
+	}
+}
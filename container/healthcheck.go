@@ -0,0 +1,210 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/control-center/serviced/commons/proc"
+	"github.com/control-center/serviced/domain"
+)
+
+// maxHealthBody bounds the body an http check reads to match against
+const maxHealthBody = 64 * 1024
+
+var errHealthCheckTimeout = errors.New("health check timed out")
+var errHealthCheckExited = errors.New("health check interrupted")
+
+// healthChecker runs a health check once, returning nil if it passed
+type healthChecker interface {
+	check(timeout time.Duration, exit <-chan struct{}) error
+	close()
+}
+
+// newHealthChecker creates the checker for the type of a health check. Native
+// checks connect to the port on the loopback address of the container.
+func newHealthChecker(name string, hc domain.HealthCheck) (healthChecker, error) {
+	if err := hc.Validate(); err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(hc.Port)))
+	switch hc.Type {
+	case domain.HealthCheckHTTP:
+		checker := &httpChecker{url: "http://" + addr + hc.Path, status: hc.ExpectedStatus}
+		if hc.ExpectedBody != "" {
+			checker.body = regexp.MustCompile(hc.ExpectedBody)
+		}
+		return checker, nil
+	case domain.HealthCheckTCP:
+		return tcpChecker(addr), nil
+	case domain.HealthCheckGRPC:
+		return &grpcChecker{addr: addr, service: hc.GRPCService}, nil
+	default:
+		return newScriptChecker(name, hc.Script)
+	}
+}
+
+// scriptChecker runs a shell script in the container
+type scriptChecker struct {
+	filename string
+}
+
+func newScriptChecker(name, script string) (*scriptChecker, error) {
+	scriptFile, err := ioutil.TempFile("", name)
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %s", err)
+	}
+	defer scriptFile.Close()
+	checker := &scriptChecker{filename: scriptFile.Name()}
+	if _, err := scriptFile.WriteString(script); err != nil {
+		checker.close()
+		return nil, fmt.Errorf("could not write script: %s", err)
+	}
+	if err := os.Chmod(scriptFile.Name(), os.FileMode(0777)); err != nil {
+		checker.close()
+		return nil, fmt.Errorf("could not make script executable: %s", err)
+	}
+	return checker, nil
+}
+
+func (s *scriptChecker) check(timeout time.Duration, exit <-chan struct{}) error {
+	sigtermTimeout := time.Second * 10
+	exited := make(chan error, 1)
+	cmd := exec.Command("sh", "-c", s.filename)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGTERM}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not run cmd %v: %s", cmd, err)
+	}
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-exit:
+		proc.KillGroup(cmd.Process.Pid, sigtermTimeout)
+		return errHealthCheckExited
+	case <-time.After(timeout):
+		proc.KillGroup(cmd.Process.Pid, sigtermTimeout)
+		return errHealthCheckTimeout
+	}
+}
+
+func (s *scriptChecker) close() {
+	os.Remove(s.filename)
+}
+
+// httpChecker makes a GET request and checks the status and body of the
+// response
+type httpChecker struct {
+	url    string
+	status int            // any 2xx status if 0
+	body   *regexp.Regexp // nil for any body
+}
+
+func (h *httpChecker) check(timeout time.Duration, exit <-chan struct{}) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(h.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if h.status != 0 && resp.StatusCode != h.status {
+		return fmt.Errorf("%s returned %d, expected %d", h.url, resp.StatusCode, h.status)
+	} else if h.status == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("%s returned %d", h.url, resp.StatusCode)
+	}
+	if h.body != nil {
+		body, err := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: maxHealthBody})
+		if err != nil {
+			return err
+		}
+		if !h.body.Match(body) {
+			return fmt.Errorf("%s returned a body that does not match %s", h.url, h.body)
+		}
+	}
+	return nil
+}
+
+func (h *httpChecker) close() {}
+
+// tcpChecker connects to an address
+type tcpChecker string
+
+func (t tcpChecker) check(timeout time.Duration, exit <-chan struct{}) error {
+	conn, err := net.DialTimeout("tcp", string(t), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (t tcpChecker) close() {}
+
+// grpcChecker calls the grpc health checking protocol
+type grpcChecker struct {
+	addr    string
+	service string
+}
+
+func (g *grpcChecker) check(timeout time.Duration, exit <-chan struct{}) error {
+	return grpcHealthCheck(g.addr, g.service, timeout)
+}
+
+func (g *grpcChecker) close() {}
+
+// healthThreshold debounces the results of a health check. The status is
+// unknown ("") until the check has passed successes times or failed failures
+// times in a row, and changes only when the other threshold is reached.
+type healthThreshold struct {
+	successes, failures int // thresholds
+	passed, failed      int // consecutive results
+	status              string
+}
+
+func newHealthThreshold(hc domain.HealthCheck) *healthThreshold {
+	t := &healthThreshold{successes: hc.SuccessThreshold, failures: hc.FailureThreshold}
+	if t.successes <= 0 {
+		t.successes = 1
+	}
+	if t.failures <= 0 {
+		t.failures = 1
+	}
+	return t
+}
+
+// observe records a result and returns the status to report
+func (t *healthThreshold) observe(err error) string {
+	if err == nil {
+		t.passed, t.failed = t.passed+1, 0
+		if t.passed >= t.successes {
+			t.status = "passed"
+		}
+	} else {
+		t.passed, t.failed = 0, t.failed+1
+		if t.failed >= t.failures {
+			t.status = "failed"
+		}
+	}
+	return t.status
+}
@@ -0,0 +1,123 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+func TestHealthThreshold(t *testing.T) {
+	threshold := newHealthThreshold(domain.HealthCheck{FailureThreshold: 3, SuccessThreshold: 2})
+	failed := errors.New("failed")
+	for i, step := range []struct {
+		err    error
+		status string
+	}{
+		{nil, ""},
+		{nil, "passed"},
+		{failed, "passed"},
+		{failed, "passed"},
+		{nil, "passed"},
+		{failed, "passed"},
+		{failed, "passed"},
+		{failed, "failed"},
+		{nil, "failed"},
+		{nil, "passed"},
+	} {
+		if status := threshold.observe(step.err); status != step.status {
+			t.Errorf("step %d: expected %q, got %q", i, step.status, status)
+		}
+	}
+
+	threshold = newHealthThreshold(domain.HealthCheck{})
+	if status := threshold.observe(failed); status != "failed" {
+		t.Errorf("expected a single failure to fail by default, got %q", status)
+	}
+}
+
+func TestHTTPChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"status": "ok"}`)
+	}))
+	defer server.Close()
+
+	for i, tc := range []struct {
+		checker *httpChecker
+		passes  bool
+	}{
+		{&httpChecker{url: server.URL + "/health"}, true},
+		{&httpChecker{url: server.URL + "/missing"}, false},
+		{&httpChecker{url: server.URL + "/missing", status: http.StatusNotFound}, true},
+		{&httpChecker{url: server.URL + "/health", status: http.StatusNoContent}, false},
+		{&httpChecker{url: server.URL + "/health", body: regexp.MustCompile(`"status": "ok"`)}, true},
+		{&httpChecker{url: server.URL + "/health", body: regexp.MustCompile(`"status": "down"`)}, false},
+	} {
+		if err := tc.checker.check(time.Second, nil); (err == nil) != tc.passes {
+			t.Errorf("check %d: expected pass %v, got %v", i, tc.passes, err)
+		}
+	}
+}
+
+func TestTCPChecker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	addr := listener.Addr().String()
+	if err := tcpChecker(addr).check(time.Second, nil); err != nil {
+		t.Errorf("expected tcp check to pass: %s", err)
+	}
+	listener.Close()
+	if err := tcpChecker(addr).check(time.Second, nil); err == nil {
+		t.Errorf("expected tcp check to fail after the listener closed")
+	}
+}
+
+func TestNewHealthChecker(t *testing.T) {
+	checker, err := newHealthChecker("check", domain.HealthCheck{Type: domain.HealthCheckHTTP, Port: 8080, Path: "/health", ExpectedBody: "ok"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h, ok := checker.(*httpChecker); !ok || h.url != "http://127.0.0.1:8080/health" || h.body == nil {
+		t.Errorf("unexpected checker: %+v", checker)
+	}
+	if _, err := newHealthChecker("check", domain.HealthCheck{Type: domain.HealthCheckTCP}); err == nil {
+		t.Errorf("expected an error for a tcp check without a port")
+	}
+	if _, err := newHealthChecker("check", domain.HealthCheck{Type: "udp", Port: 53}); err == nil {
+		t.Errorf("expected an error for an unknown type")
+	}
+
+	checker, err = newHealthChecker("check", domain.HealthCheck{Script: "exit 1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer checker.close()
+	if err := checker.check(5*time.Second, nil); err == nil {
+		t.Errorf("expected the script check to fail")
+	}
+}
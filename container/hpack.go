@@ -0,0 +1,326 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An hpack (RFC 7541) decoder for the header blocks of grpc health check
+// responses. The response headers and trailers of a connection share the
+// decoder, since a server may index a header in one block and refer to it in
+// the next.
+
+// hpackTableSize is the dynamic table size that a server may use before it
+// sends a table size update
+const hpackTableSize = 4096
+
+var errHpack = errors.New("invalid hpack header block")
+
+// hpackStaticTable is the static table of RFC 7541 Appendix A
+var hpackStaticTable = [][2]string{
+	{":authority", ""},
+	{":method", "GET"},
+	{":method", "POST"},
+	{":path", "/"},
+	{":path", "/index.html"},
+	{":scheme", "http"},
+	{":scheme", "https"},
+	{":status", "200"},
+	{":status", "204"},
+	{":status", "206"},
+	{":status", "304"},
+	{":status", "400"},
+	{":status", "404"},
+	{":status", "500"},
+	{"accept-charset", ""},
+	{"accept-encoding", "gzip, deflate"},
+	{"accept-language", ""},
+	{"accept-ranges", ""},
+	{"accept", ""},
+	{"access-control-allow-origin", ""},
+	{"age", ""},
+	{"allow", ""},
+	{"authorization", ""},
+	{"cache-control", ""},
+	{"content-disposition", ""},
+	{"content-encoding", ""},
+	{"content-language", ""},
+	{"content-length", ""},
+	{"content-location", ""},
+	{"content-range", ""},
+	{"content-type", ""},
+	{"cookie", ""},
+	{"date", ""},
+	{"etag", ""},
+	{"expect", ""},
+	{"expires", ""},
+	{"from", ""},
+	{"host", ""},
+	{"if-match", ""},
+	{"if-modified-since", ""},
+	{"if-none-match", ""},
+	{"if-range", ""},
+	{"if-unmodified-since", ""},
+	{"last-modified", ""},
+	{"link", ""},
+	{"location", ""},
+	{"max-forwards", ""},
+	{"proxy-authenticate", ""},
+	{"proxy-authorization", ""},
+	{"range", ""},
+	{"referer", ""},
+	{"refresh", ""},
+	{"retry-after", ""},
+	{"server", ""},
+	{"set-cookie", ""},
+	{"strict-transport-security", ""},
+	{"transfer-encoding", ""},
+	{"user-agent", ""},
+	{"vary", ""},
+	{"via", ""},
+	{"www-authenticate", ""},
+}
+
+// huffmanCodes and huffmanCodeLen are the codes of RFC 7541 Appendix B
+var huffmanCodes = [256]uint32{
+	0x1ff8, 0x7fffd8, 0xfffffe2, 0xfffffe3, 0xfffffe4, 0xfffffe5, 0xfffffe6, 0xfffffe7,
+	0xfffffe8, 0xffffea, 0x3ffffffc, 0xfffffe9, 0xfffffea, 0x3ffffffd, 0xfffffeb, 0xfffffec,
+	0xfffffed, 0xfffffee, 0xfffffef, 0xffffff0, 0xffffff1, 0xffffff2, 0x3ffffffe, 0xffffff3,
+	0xffffff4, 0xffffff5, 0xffffff6, 0xffffff7, 0xffffff8, 0xffffff9, 0xffffffa, 0xffffffb,
+	0x14, 0x3f8, 0x3f9, 0xffa, 0x1ff9, 0x15, 0xf8, 0x7fa,
+	0x3fa, 0x3fb, 0xf9, 0x7fb, 0xfa, 0x16, 0x17, 0x18,
+	0x0, 0x1, 0x2, 0x19, 0x1a, 0x1b, 0x1c, 0x1d,
+	0x1e, 0x1f, 0x5c, 0xfb, 0x7ffc, 0x20, 0xffb, 0x3fc,
+	0x1ffa, 0x21, 0x5d, 0x5e, 0x5f, 0x60, 0x61, 0x62,
+	0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a,
+	0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70, 0x71, 0x72,
+	0xfc, 0x73, 0xfd, 0x1ffb, 0x7fff0, 0x1ffc, 0x3ffc, 0x22,
+	0x7ffd, 0x3, 0x23, 0x4, 0x24, 0x5, 0x25, 0x26,
+	0x27, 0x6, 0x74, 0x75, 0x28, 0x29, 0x2a, 0x7,
+	0x2b, 0x76, 0x2c, 0x8, 0x9, 0x2d, 0x77, 0x78,
+	0x79, 0x7a, 0x7b, 0x7ffe, 0x7fc, 0x3ffd, 0x1ffd, 0xffffffc,
+	0xfffe6, 0x3fffd2, 0xfffe7, 0xfffe8, 0x3fffd3, 0x3fffd4, 0x3fffd5, 0x7fffd9,
+	0x3fffd6, 0x7fffda, 0x7fffdb, 0x7fffdc, 0x7fffdd, 0x7fffde, 0xffffeb, 0x7fffdf,
+	0xffffec, 0xffffed, 0x3fffd7, 0x7fffe0, 0xffffee, 0x7fffe1, 0x7fffe2, 0x7fffe3,
+	0x7fffe4, 0x1fffdc, 0x3fffd8, 0x7fffe5, 0x3fffd9, 0x7fffe6, 0x7fffe7, 0xffffef,
+	0x3fffda, 0x1fffdd, 0xfffe9, 0x3fffdb, 0x3fffdc, 0x7fffe8, 0x7fffe9, 0x1fffde,
+	0x7fffea, 0x3fffdd, 0x3fffde, 0xfffff0, 0x1fffdf, 0x3fffdf, 0x7fffeb, 0x7fffec,
+	0x1fffe0, 0x1fffe1, 0x3fffe0, 0x1fffe2, 0x7fffed, 0x3fffe1, 0x7fffee, 0x7fffef,
+	0xfffea, 0x3fffe2, 0x3fffe3, 0x3fffe4, 0x7ffff0, 0x3fffe5, 0x3fffe6, 0x7ffff1,
+	0x3ffffe0, 0x3ffffe1, 0xfffeb, 0x7fff1, 0x3fffe7, 0x7ffff2, 0x3fffe8, 0x1ffffec,
+	0x3ffffe2, 0x3ffffe3, 0x3ffffe4, 0x7ffffde, 0x7ffffdf, 0x3ffffe5, 0xfffff1, 0x1ffffed,
+	0x7fff2, 0x1fffe3, 0x3ffffe6, 0x7ffffe0, 0x7ffffe1, 0x3ffffe7, 0x7ffffe2, 0xfffff2,
+	0x1fffe4, 0x1fffe5, 0x3ffffe8, 0x3ffffe9, 0xffffffd, 0x7ffffe3, 0x7ffffe4, 0x7ffffe5,
+	0xfffec, 0xfffff3, 0xfffed, 0x1fffe6, 0x3fffe9, 0x1fffe7, 0x1fffe8, 0x7ffff3,
+	0x3fffea, 0x3fffeb, 0x1ffffee, 0x1ffffef, 0xfffff4, 0xfffff5, 0x3ffffea, 0x7ffff4,
+	0x3ffffeb, 0x7ffffe6, 0x3ffffec, 0x3ffffed, 0x7ffffe7, 0x7ffffe8, 0x7ffffe9, 0x7ffffea,
+	0x7ffffeb, 0xffffffe, 0x7ffffec, 0x7ffffed, 0x7ffffee, 0x7ffffef, 0x7fffff0, 0x3ffffee,
+}
+
+var huffmanCodeLen = [256]uint8{
+	13, 23, 28, 28, 28, 28, 28, 28, 28, 24, 30, 28, 28, 30, 28, 28,
+	28, 28, 28, 28, 28, 28, 30, 28, 28, 28, 28, 28, 28, 28, 28, 28,
+	6, 10, 10, 12, 13, 6, 8, 11, 10, 10, 8, 11, 8, 6, 6, 6,
+	5, 5, 5, 6, 6, 6, 6, 6, 6, 6, 7, 8, 15, 6, 12, 10,
+	13, 6, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7,
+	7, 7, 7, 7, 7, 7, 7, 7, 8, 7, 8, 13, 19, 13, 14, 6,
+	15, 5, 6, 5, 6, 5, 6, 6, 6, 5, 7, 7, 6, 6, 6, 5,
+	6, 7, 6, 5, 5, 6, 7, 7, 7, 7, 7, 15, 11, 14, 13, 28,
+	20, 22, 20, 20, 22, 22, 22, 23, 22, 23, 23, 23, 23, 23, 24, 23,
+	24, 24, 22, 23, 24, 23, 23, 23, 23, 21, 22, 23, 22, 23, 23, 24,
+	22, 21, 20, 22, 22, 23, 23, 21, 23, 22, 22, 24, 21, 22, 23, 23,
+	21, 21, 22, 21, 23, 22, 23, 23, 20, 22, 22, 22, 23, 22, 22, 23,
+	26, 26, 20, 19, 22, 23, 22, 25, 26, 26, 26, 27, 27, 26, 24, 25,
+	19, 21, 26, 27, 27, 26, 27, 24, 21, 21, 26, 26, 28, 27, 27, 27,
+	20, 24, 20, 21, 22, 21, 21, 23, 22, 22, 25, 25, 24, 24, 26, 23,
+	26, 27, 26, 26, 27, 27, 27, 27, 27, 28, 27, 27, 27, 27, 27, 26,
+}
+
+// huffmanSymbols maps the length and the bits of each code to its symbol
+var huffmanSymbols = func() map[uint64]byte {
+	symbols := make(map[uint64]byte, len(huffmanCodes))
+	for i, code := range huffmanCodes {
+		symbols[uint64(huffmanCodeLen[i])<<32|uint64(code)] = byte(i)
+	}
+	return symbols
+}()
+
+// huffmanDecode decodes a huffman coded string
+func huffmanDecode(b []byte) (string, error) {
+	var s []byte
+	var code uint32
+	var n uint8
+	for _, c := range b {
+		for bit := 7; bit >= 0; bit-- {
+			code = code<<1 | uint32(c>>uint(bit)&1)
+			if n++; n > 30 {
+				return "", errHpack
+			}
+			if symbol, ok := huffmanSymbols[uint64(n)<<32|uint64(code)]; ok {
+				s = append(s, symbol)
+				code, n = 0, 0
+			}
+		}
+	}
+	// the padding is shorter than a byte and made of the first bits of EOS
+	if n > 7 || code != 1<<n-1 {
+		return "", errHpack
+	}
+	return string(s), nil
+}
+
+// hpackReadInt reads an integer with an n bit prefix
+func hpackReadInt(b []byte, n uint) (uint64, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, errHpack
+	}
+	max := uint64(1)<<n - 1
+	i := uint64(b[0]) & max
+	b = b[1:]
+	if i < max {
+		return i, b, nil
+	}
+	for shift := uint(0); len(b) > 0; shift += 7 {
+		if shift > 56 {
+			return 0, nil, errHpack
+		}
+		c := b[0]
+		b = b[1:]
+		i += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return i, b, nil
+		}
+	}
+	return 0, nil, errHpack
+}
+
+// hpackReadString reads a string literal
+func hpackReadString(b []byte) (string, []byte, error) {
+	if len(b) == 0 {
+		return "", nil, errHpack
+	}
+	huffman := b[0]&0x80 != 0
+	length, b, err := hpackReadInt(b, 7)
+	if err != nil {
+		return "", nil, err
+	} else if length > uint64(len(b)) {
+		return "", nil, errHpack
+	}
+	s, b := b[:length], b[length:]
+	if huffman {
+		decoded, err := huffmanDecode(s)
+		return decoded, b, err
+	}
+	return string(s), b, nil
+}
+
+// hpackDecoder decodes the header blocks of a connection
+type hpackDecoder struct {
+	dynamic [][2]string // the newest entry first
+	size    int
+	maxSize int
+}
+
+func newHpackDecoder() *hpackDecoder {
+	return &hpackDecoder{maxSize: hpackTableSize}
+}
+
+// field returns the header at an index of the static and dynamic tables
+func (d *hpackDecoder) field(i uint64) ([2]string, error) {
+	if i == 0 {
+		return [2]string{}, errHpack
+	} else if i <= uint64(len(hpackStaticTable)) {
+		return hpackStaticTable[i-1], nil
+	} else if i -= uint64(len(hpackStaticTable)); i <= uint64(len(d.dynamic)) {
+		return d.dynamic[i-1], nil
+	}
+	return [2]string{}, fmt.Errorf("hpack index %d out of range", i)
+}
+
+// evict drops the oldest entries until the table fits in size
+func (d *hpackDecoder) evict(size int) {
+	for d.size > size {
+		last := d.dynamic[len(d.dynamic)-1]
+		d.dynamic = d.dynamic[:len(d.dynamic)-1]
+		d.size -= len(last[0]) + len(last[1]) + 32
+	}
+}
+
+func (d *hpackDecoder) add(h [2]string) {
+	size := len(h[0]) + len(h[1]) + 32
+	d.evict(d.maxSize - size)
+	if size <= d.maxSize {
+		d.dynamic = append([][2]string{h}, d.dynamic...)
+		d.size += size
+	}
+}
+
+// decode returns the headers of a header block
+func (d *hpackDecoder) decode(b []byte) ([][2]string, error) {
+	var headers [][2]string
+	for len(b) > 0 {
+		var err error
+		switch {
+		case b[0]&0x80 != 0: // indexed
+			var i uint64
+			if i, b, err = hpackReadInt(b, 7); err != nil {
+				return nil, err
+			}
+			h, err := d.field(i)
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, h)
+		case b[0]&0xe0 == 0x20: // dynamic table size update
+			var size uint64
+			if size, b, err = hpackReadInt(b, 5); err != nil {
+				return nil, err
+			} else if size > hpackTableSize {
+				return nil, errHpack
+			}
+			d.maxSize = int(size)
+			d.evict(d.maxSize)
+		default: // literal, with incremental indexing or without
+			indexed := b[0]&0xc0 == 0x40
+			prefix := uint(4)
+			if indexed {
+				prefix = 6
+			}
+			var i uint64
+			if i, b, err = hpackReadInt(b, prefix); err != nil {
+				return nil, err
+			}
+			var h [2]string
+			if i == 0 {
+				if h[0], b, err = hpackReadString(b); err != nil {
+					return nil, err
+				}
+			} else if named, err := d.field(i); err != nil {
+				return nil, err
+			} else {
+				h[0] = named[0]
+			}
+			if h[1], b, err = hpackReadString(b); err != nil {
+				return nil, err
+			}
+			if indexed {
+				d.add(h)
+			}
+			headers = append(headers, h)
+		}
+	}
+	return headers, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+// the request examples of RFC 7541 Appendix C.4, which use huffman coding
+// and the dynamic table
+func TestHpackDecode(t *testing.T) {
+	d := newHpackDecoder()
+	for _, tc := range []struct {
+		block   string
+		headers [][2]string
+	}{
+		{
+			"828684418cf1e3c2e5f23a6ba0ab90f4ff",
+			[][2]string{{":method", "GET"}, {":scheme", "http"}, {":path", "/"}, {":authority", "www.example.com"}},
+		}, {
+			"828684be5886a8eb10649cbf",
+			[][2]string{{":method", "GET"}, {":scheme", "http"}, {":path", "/"}, {":authority", "www.example.com"}, {"cache-control", "no-cache"}},
+		}, {
+			"828785bf408825a849e95ba97d7f8925a849e95bb8e8b4bf",
+			[][2]string{{":method", "GET"}, {":scheme", "https"}, {":path", "/index.html"}, {":authority", "www.example.com"}, {"custom-key", "custom-value"}},
+		},
+	} {
+		block, err := hex.DecodeString(tc.block)
+		if err != nil {
+			t.Fatalf("bad test block %s: %s", tc.block, err)
+		}
+		headers, err := d.decode(block)
+		if err != nil {
+			t.Fatalf("could not decode %s: %s", tc.block, err)
+		} else if !reflect.DeepEqual(headers, tc.headers) {
+			t.Errorf("expected %v, got %v", tc.headers, headers)
+		}
+	}
+	if d.size != 164 || len(d.dynamic) != 3 {
+		t.Errorf("expected 3 entries of 164 bytes, got %d of %d bytes", len(d.dynamic), d.size)
+	}
+
+	// the literals that the health check sends
+	headers := [][2]string{{"grpc-status", "5"}, {"grpc-message", "not found"}}
+	if decoded, err := d.decode(hpackHeaders(headers)); err != nil {
+		t.Errorf("could not decode literals: %s", err)
+	} else if !reflect.DeepEqual(decoded, headers) {
+		t.Errorf("expected %v, got %v", headers, decoded)
+	}
+
+	for _, block := range []string{"80", "ff00", "418cf1e3c2e5f23a6ba0ab90f4", "3fe21f"} {
+		b, _ := hex.DecodeString(block)
+		if _, err := newHpackDecoder().decode(b); err == nil {
+			t.Errorf("expected an error for %s", block)
+		}
+	}
+}
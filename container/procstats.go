@@ -0,0 +1,244 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/control-center/serviced/stats"
+	"github.com/zenoss/glog"
+)
+
+var procDir = "/proc"
+
+// processStats sums the stats of the processes with the same command name
+type processStats struct {
+	count   int64
+	utime   int64 // clock ticks
+	stime   int64 // clock ticks
+	rss     int64 // bytes
+	threads int64
+	fds     int64
+}
+
+// readProcessStats reads the stats of every process in the container.  The
+// controller runs as pid 1 of the container, so /proc only shows the
+// processes of this instance.
+func readProcessStats() (map[string]*processStats, error) {
+	dir, err := os.Open(procDir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	pagesize := int64(os.Getpagesize())
+	result := make(map[string]*processStats)
+	for _, name := range names {
+		if _, err := strconv.Atoi(name); err != nil {
+			continue
+		}
+		comm, fields, err := readPidStat(path.Join(procDir, name, "stat"))
+		if err != nil {
+			// the process exited
+			glog.V(4).Infof("Could not read stat of process %s: %s", name, err)
+			continue
+		}
+		ps, ok := result[comm]
+		if !ok {
+			ps = &processStats{}
+			result[comm] = ps
+		}
+		ps.count++
+		ps.utime += fields[0]
+		ps.stime += fields[1]
+		ps.threads += fields[2]
+		ps.rss += fields[3] * pagesize
+		if fds, err := countEntries(path.Join(procDir, name, "fd")); err == nil {
+			ps.fds += fds
+		}
+	}
+	return result, nil
+}
+
+// readPidStat parses /proc/PID/stat, returning the command name and the
+// utime, stime, num_threads and rss fields
+func readPidStat(file string) (string, []int64, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	// the command name is in parentheses and may contain spaces
+	stat := string(data)
+	start, end := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+	if start < 0 || end < start {
+		return "", nil, fmt.Errorf("could not parse %s", file)
+	}
+	comm := stat[start+1 : end]
+	fields := strings.Fields(stat[end+1:])
+	// fields[0] is the state, the third field of the file
+	indexes := []int{11, 12, 17, 21} // utime, stime, num_threads, rss
+	values := make([]int64, len(indexes))
+	for i, index := range indexes {
+		if index >= len(fields) {
+			return "", nil, fmt.Errorf("could not parse %s", file)
+		}
+		if values[i], err = strconv.ParseInt(fields[index], 10, 64); err != nil {
+			return "", nil, fmt.Errorf("could not parse %s: %s", file, err)
+		}
+	}
+	return comm, values, nil
+}
+
+func countEntries(dir string) (int64, error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	names, err := file.Readdirnames(-1)
+	return int64(len(names)), err
+}
+
+// processSamples converts process stats to samples tagged with the command
+// name
+func processSamples(now int64, processes map[string]*processStats) []stats.Sample {
+	var samples []stats.Sample
+	for comm, ps := range processes {
+		tags := map[string]string{"process": comm}
+		for metric, value := range map[string]int64{
+			"process.count":      ps.count,
+			"process.cpu.user":   ps.utime,
+			"process.cpu.system": ps.stime,
+			"process.rss":        ps.rss,
+			"process.threads":    ps.threads,
+			"process.openfiles":  ps.fds,
+		} {
+			samples = append(samples, stats.Sample{
+				Metric:    metric,
+				Value:     strconv.FormatInt(value, 10),
+				Timestamp: now,
+				Tags:      tags,
+			})
+		}
+	}
+	return samples
+}
+
+// fsUsage is the space and inodes of a filesystem
+type fsUsage struct {
+	total, used, free      int64 // bytes
+	inodesUsed, inodesFree int64
+}
+
+func statFilesystem(dir string) (*fsUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return nil, err
+	}
+	bsize := int64(st.Bsize)
+	return &fsUsage{
+		total:      int64(st.Blocks) * bsize,
+		used:       int64(st.Blocks-st.Bfree) * bsize,
+		free:       int64(st.Bavail) * bsize,
+		inodesUsed: int64(st.Files - st.Ffree),
+		inodesFree: int64(st.Ffree),
+	}, nil
+}
+
+// Walking a volume is expensive, so its size is only measured every
+// volumeSizeInterval.
+const volumeSizeInterval = 5 * time.Minute
+
+var volumeSizes = struct {
+	sync.Mutex
+	measured time.Time
+	sizes    map[string]int64
+}{sizes: make(map[string]int64)}
+
+// diskUsage returns the bytes allocated to the files under dir
+func diskUsage(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			size += st.Blocks * 512
+		} else {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// filesystemSamples reports the usage of the container's writable layer and
+// of each volume bound into it
+func filesystemSamples(now int64, t time.Time, volumes []string) []stats.Sample {
+	var samples []stats.Sample
+	add := func(metric string, value int64, tags map[string]string) {
+		samples = append(samples, stats.Sample{
+			Metric:    metric,
+			Value:     strconv.FormatInt(value, 10),
+			Timestamp: now,
+			Tags:      tags,
+		})
+	}
+
+	if fs, err := statFilesystem("/"); err != nil {
+		glog.Errorf("Could not stat the container filesystem: %s", err)
+	} else {
+		tags := map[string]string{"mount": "/"}
+		add("fs.rootfs.total", fs.total, tags)
+		add("fs.rootfs.used", fs.used, tags)
+		add("fs.rootfs.free", fs.free, tags)
+		add("fs.rootfs.inodes.used", fs.inodesUsed, tags)
+		add("fs.rootfs.inodes.free", fs.inodesFree, tags)
+	}
+
+	volumeSizes.Lock()
+	defer volumeSizes.Unlock()
+	measure := t.Sub(volumeSizes.measured) >= volumeSizeInterval
+	if measure {
+		volumeSizes.measured = t
+	}
+	for _, volume := range volumes {
+		tags := map[string]string{"mount": volume}
+		fs, err := statFilesystem(volume)
+		if err != nil {
+			glog.V(2).Infof("Could not stat volume %s: %s", volume, err)
+			continue
+		}
+		add("fs.volume.free", fs.free, tags)
+		add("fs.volume.inodes.free", fs.inodesFree, tags)
+		if measure {
+			volumeSizes.sizes[volume] = diskUsage(volume)
+		}
+		add("fs.volume.used", volumeSizes.sizes[volume], tags)
+	}
+	return samples
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReadProcessStats(t *testing.T) {
+	defer func(dir string) { procDir = dir }(procDir)
+	procDir = "testfiles/proc"
+
+	processes, err := readProcessStats()
+	if err != nil {
+		t.Fatalf("unexpected error reading process stats: %s", err)
+	}
+	if len(processes) != 2 {
+		t.Fatalf("expected 2 commands, got %d: %+v", len(processes), processes)
+	}
+
+	pagesize := int64(os.Getpagesize())
+	java, ok := processes["java"]
+	if !ok {
+		t.Fatalf("java processes not found")
+	}
+	expected := processStats{count: 2, utime: 1210, stime: 305, rss: 25500 * pagesize, threads: 50, fds: 5}
+	if *java != expected {
+		t.Errorf("expected %+v, got %+v", expected, *java)
+	}
+
+	zope, ok := processes["zope (worker) 1"]
+	if !ok {
+		t.Fatalf("command name with parentheses not parsed")
+	}
+	expected = processStats{count: 1, utime: 50, stime: 25, rss: 1000 * pagesize, threads: 3, fds: 1}
+	if *zope != expected {
+		t.Errorf("expected %+v, got %+v", expected, *zope)
+	}
+
+	samples := processSamples(100, processes)
+	if len(samples) != 12 {
+		t.Errorf("expected 12 samples, got %d", len(samples))
+	}
+	for _, sample := range samples {
+		if sample.Tags["process"] == "" || sample.Timestamp != 100 {
+			t.Errorf("unexpected sample %+v", sample)
+		}
+	}
+}
+
+func TestFilesystemSamples(t *testing.T) {
+	volume, err := ioutil.TempDir("", "procstats")
+	if err != nil {
+		t.Fatalf("could not create volume: %s", err)
+	}
+	defer os.RemoveAll(volume)
+	if err := ioutil.WriteFile(volume+"/data", make([]byte, 64*1024), 0644); err != nil {
+		t.Fatalf("could not write to volume: %s", err)
+	}
+
+	values := make(map[string]string)
+	for _, sample := range filesystemSamples(100, time.Now(), []string{volume, volume + "/missing"}) {
+		values[sample.Metric+" "+sample.Tags["mount"]] = sample.Value
+	}
+	for _, key := range []string{"fs.rootfs.used /", "fs.rootfs.free /", "fs.volume.free " + volume} {
+		if _, ok := values[key]; !ok {
+			t.Errorf("missing sample %s in %v", key, values)
+		}
+	}
+	if used := values["fs.volume.used "+volume]; used == "" || used == "0" {
+		t.Errorf("expected the volume to be measured, got %q", used)
+	}
+	if _, ok := values["fs.volume.used "+volume+"/missing"]; ok {
+		t.Errorf("unexpected sample for a missing volume")
+	}
+}
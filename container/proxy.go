@@ -21,6 +21,8 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/zenoss/glog"
@@ -84,6 +86,8 @@ type proxy struct {
 	newAddresses     chan []addressTuple // a stream of updates to the addresses
 	listener         net.Listener        // handle on the listening socket
 	allowDirectConn  bool                // allow container to container connections
+	draining         int32               // set while the instance drains
+	active           int64               // number of connections opened while draining being proxied
 }
 
 // Newproxy create a new proxy object. It starts listening on the prxy port asynchronously.
@@ -125,6 +129,22 @@ func (p *proxy) UseTLS() bool {
 	return p.useTLS
 }
 
+// SetDraining starts or stops counting the connections opened while the
+// instance drains
+func (p *proxy) SetDraining(draining bool) {
+	var flag int32
+	if draining {
+		flag = 1
+	}
+	atomic.StoreInt32(&p.draining, flag)
+}
+
+// DrainingConnections returns the number of connections opened while
+// draining that are being proxied
+func (p *proxy) DrainingConnections() int64 {
+	return atomic.LoadInt64(&p.active)
+}
+
 // Set a new Destination Address set for the prxy
 func (p *proxy) SetNewAddresses(addresses []addressTuple) {
 	// Randomize the addresses so not all instances get them in the same order
@@ -198,7 +218,17 @@ func (p *proxy) prxy(local net.Conn, address addressTuple) {
 	var (
 		remote net.Conn
 		err    error
+		done   sync.WaitGroup
 	)
+	if atomic.LoadInt32(&p.draining) == 1 {
+		atomic.AddInt64(&p.active, 1)
+		defer func() {
+			go func() {
+				done.Wait()
+				atomic.AddInt64(&p.active, -1)
+			}()
+		}()
+	}
 	glog.V(2).Infof("Setting up proxy for %#v", address)
 	isLocalContainer := false
 	localAddr := address.containerAddr
@@ -259,7 +289,9 @@ func (p *proxy) prxy(local net.Conn, address addressTuple) {
 
 	glog.V(2).Infof("Using hostAgent:%v to prxy %v<->%v<->%v<->%v",
 		remote.RemoteAddr(), local.LocalAddr(), local.RemoteAddr(), remote.LocalAddr(), address)
+	done.Add(2)
 	go func(address string) {
+		defer done.Done()
 		defer local.Close()
 		defer remote.Close()
 		io.Copy(local, remote)
@@ -267,6 +299,7 @@ func (p *proxy) prxy(local net.Conn, address addressTuple) {
 			remote.RemoteAddr(), local.LocalAddr(), local.RemoteAddr(), remote.LocalAddr(), address)
 	}(address.containerAddr)
 	go func(address string) {
+		defer done.Done()
 		defer local.Close()
 		defer remote.Close()
 		io.Copy(remote, local)
@@ -0,0 +1,147 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/control-center/serviced/domain/secret"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/node"
+	"github.com/zenoss/glog"
+)
+
+// shmSecretsDir holds the files of secrets if a tmpfs cannot be mounted on
+// servicedefinition.SecretsDir; docker mounts a tmpfs on /dev/shm
+const shmSecretsDir = "/dev/shm/serviced-secrets"
+
+// usesSecrets is true if a service puts secrets in its containers or refers
+// to them in its config files
+func usesSecrets(svc *service.Service) bool {
+	if len(svc.Secrets) > 0 {
+		return true
+	}
+	for _, config := range svc.ConfigFiles {
+		if len(secret.References(config.Content)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getServiceSecrets retrieves the values of the secrets that a service uses
+func getServiceSecrets(lbClientPort string, serviceID string) (map[string]string, error) {
+	client, err := node.NewLBClient(lbClientPort)
+	if err != nil {
+		glog.Errorf("Could not create a client to endpoint: %s, %s", lbClientPort, err)
+		return nil, err
+	}
+	defer client.Close()
+
+	var values map[string]string
+	if err := client.GetServiceSecrets(serviceID, &values); err != nil {
+		glog.Errorf("Error getting the secrets of service %s, error: %s", serviceID, err)
+		return nil, err
+	}
+	glog.V(1).Infof("getServiceSecrets: service id=%s: %d secrets", serviceID, len(values))
+	return values, nil
+}
+
+// expandConfigSecrets puts the values of the secrets that the config files of
+// a service refer to in their content
+func expandConfigSecrets(svc *service.Service, values map[string]string) error {
+	for key, config := range svc.ConfigFiles {
+		content, err := secret.Expand(config.Content, values)
+		if err != nil {
+			return fmt.Errorf("config file %s: %s", config.Filename, err)
+		}
+		config.Content = content
+		svc.ConfigFiles[key] = config
+	}
+	return nil
+}
+
+// secretEnvironment returns the environment variables that are set to the
+// values of the secrets of a service
+func secretEnvironment(svc *service.Service, values map[string]string) ([]string, error) {
+	var env []string
+	for _, s := range svc.Secrets {
+		if s.Env == "" {
+			continue
+		}
+		value, ok := values[s.Name]
+		if !ok {
+			return nil, fmt.Errorf("secret %s not found", s.Name)
+		}
+		env = append(env, fmt.Sprintf("%s=%s", s.Env, value))
+	}
+	return env, nil
+}
+
+// setupSecretFiles writes the files of the secrets of a service on a tmpfs,
+// so that their values are never written to the image of the container
+func setupSecretFiles(svc *service.Service, values map[string]string) error {
+	var files []servicedefinition.Secret
+	for _, s := range svc.Secrets {
+		if s.File != "" {
+			files = append(files, s)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	dir := servicedefinition.SecretsDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", dir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		// without CAP_SYS_ADMIN, link to the tmpfs that docker mounts on /dev/shm
+		glog.Warningf("Could not mount a tmpfs on %s (%s), using %s", dir, err, shmSecretsDir)
+		if err := os.MkdirAll(shmSecretsDir, 0755); err != nil {
+			return err
+		}
+		if err := os.Remove(dir); err != nil {
+			return fmt.Errorf("could not replace %s with a link to %s: %s", dir, shmSecretsDir, err)
+		}
+		if err := os.Symlink(shmSecretsDir, dir); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range files {
+		value, ok := values[s.Name]
+		if !ok {
+			return fmt.Errorf("secret %s not found", s.Name)
+		}
+		filename := filepath.Join(dir, s.File)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, []byte(value), 0400); err != nil {
+			glog.Errorf("Could not write secret file %s", filename)
+			return err
+		}
+		if err := chownConfFile(filename, s.Owner, s.Permissions); err != nil {
+			return err
+		}
+		glog.Infof("Wrote secret %s to %s", s.Name, filename)
+	}
+	return nil
+}
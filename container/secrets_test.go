@@ -0,0 +1,95 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/control-center/serviced/domain/secret"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func secretService() *service.Service {
+	return &service.Service{
+		Secrets: []servicedefinition.Secret{
+			{Name: "db.password", Env: "DB_PASSWORD"},
+			{Name: "tls.key", File: "tls/server.key"},
+		},
+		ConfigFiles: map[string]servicedefinition.ConfigFile{
+			"/etc/app.conf": {Filename: "/etc/app.conf", Content: "token=" + secret.Reference("api.token") + "\n"},
+			"/etc/motd":     {Filename: "/etc/motd", Content: "welcome\n"},
+		},
+	}
+}
+
+func TestUsesSecrets(t *testing.T) {
+	svc := secretService()
+	if !usesSecrets(svc) {
+		t.Errorf("expected a service with secrets to use them")
+	}
+
+	svc.Secrets = nil
+	if !usesSecrets(svc) {
+		t.Errorf("expected a service that refers to secrets in its config files to use them")
+	}
+
+	delete(svc.ConfigFiles, "/etc/app.conf")
+	if usesSecrets(svc) {
+		t.Errorf("expected a service without secrets not to use them")
+	}
+}
+
+func TestExpandConfigSecrets(t *testing.T) {
+	svc := secretService()
+	values := map[string]string{"api.token": "abc123"}
+	if err := expandConfigSecrets(svc, values); err != nil {
+		t.Fatalf("could not expand the secrets: %s", err)
+	}
+	if content := svc.ConfigFiles["/etc/app.conf"].Content; content != "token=abc123\n" {
+		t.Errorf("expected the value of the secret in the config file, got %q", content)
+	}
+	if content := svc.ConfigFiles["/etc/motd"].Content; content != "welcome\n" {
+		t.Errorf("expected the config file without secrets to be unchanged, got %q", content)
+	}
+
+	svc = secretService()
+	if err := expandConfigSecrets(svc, map[string]string{}); err == nil {
+		t.Errorf("expected an error for a missing secret")
+	}
+}
+
+func TestSecretEnvironment(t *testing.T) {
+	svc := secretService()
+	env, err := secretEnvironment(svc, map[string]string{"db.password": "hunter2", "tls.key": "KEY"})
+	if err != nil {
+		t.Fatalf("could not get the environment: %s", err)
+	}
+	if expected := []string{"DB_PASSWORD=hunter2"}; !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+
+	if _, err := secretEnvironment(svc, map[string]string{"tls.key": "KEY"}); err == nil {
+		t.Errorf("expected an error for a missing secret")
+	}
+}
+
+func TestSetupSecretFilesWithoutFiles(t *testing.T) {
+	svc := secretService()
+	svc.Secrets = svc.Secrets[:1]
+	if err := setupSecretFiles(svc, map[string]string{"db.password": "hunter2"}); err != nil {
+		t.Errorf("expected nothing to set up without secret files, got %s", err)
+	}
+}
@@ -28,13 +28,13 @@ import (
 
 // statReporter perically collects statistics at the given
 // interval until the closing channel closes
-func statReporter(statsUrl string, interval time.Duration) {
+func statReporter(statsUrl string, interval time.Duration, volumes []string) {
 
 	tick := time.Tick(interval)
 	for {
 		select {
 		case t := <-tick:
-			collect(t, statsUrl)
+			collect(t, statsUrl, volumes)
 		}
 	}
 }
@@ -46,7 +46,7 @@ var procNetFiles = map[string]string{
 	"raw": "/proc/net/raw",
 }
 
-func collect(ts time.Time, statsUrl string) {
+func collect(ts time.Time, statsUrl string, volumes []string) {
 	// TODO: At some point we can look at refactoring this to use the
 	// 'serviced metric' code
 
@@ -89,6 +89,15 @@ func collect(ts time.Time, statsUrl string) {
 		samples = append(samples, sample)
 	}
 
+	// collect the stats of the processes of the instance
+	if processes, err := readProcessStats(); err != nil {
+		glog.Errorf("Could not collect process stats: %s", err)
+	} else {
+		samples = append(samples, processSamples(now, processes)...)
+	}
+
+	// collect the disk usage of the container and its volumes
+	samples = append(samples, filesystemSamples(now, ts, volumes)...)
 
 	glog.V(4).Infof("posting samples: %+v", samples)
 	if err := stats.Post(statsUrl, samples); err != nil {
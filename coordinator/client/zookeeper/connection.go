@@ -89,6 +89,7 @@ func (c *Connection) SetOnClose(f func(int)) {
 }
 
 func (c *Connection) CreateEphemeral(path string, node client.Node) (string, error) {
+	defer timeRequest("create", time.Now())
 	if c.conn == nil {
 		return "", client.ErrConnectionClosed
 	}
@@ -124,6 +125,7 @@ func (c *Connection) CreateEphemeral(path string, node client.Node) (string, err
 
 // Create places data at the node at the given path.
 func (c *Connection) Create(path string, node client.Node) error {
+	defer timeRequest("create", time.Now())
 	if c.conn == nil {
 		return client.ErrConnectionClosed
 	}
@@ -171,6 +173,7 @@ func (c *Connection) CreateDir(path string) error {
 
 // Exists checks if a node exists at the given path.
 func (c *Connection) Exists(path string) (bool, error) {
+	defer timeRequest("exists", time.Now())
 	if c.conn == nil {
 		return false, client.ErrConnectionClosed
 	}
@@ -216,6 +219,7 @@ func toClientEvent(zkEvent <-chan zklib.Event) <-chan client.Event {
 // ChildrenW returns the children of the node at the give path and a channel of
 // events that will yield the next event at that node.
 func (c *Connection) ChildrenW(path string) (children []string, event <-chan client.Event, err error) {
+	defer timeRequest("children", time.Now())
 	if c.conn == nil {
 		return children, event, client.ErrConnectionClosed
 	}
@@ -228,6 +232,7 @@ func (c *Connection) ChildrenW(path string) (children []string, event <-chan cli
 
 // GetW gets the node at the given path and return a channel to watch for events on that node.
 func (c *Connection) GetW(path string, node client.Node) (event <-chan client.Event, err error) {
+	defer timeRequest("get", time.Now())
 	if c.conn == nil {
 		return nil, client.ErrConnectionClosed
 	}
@@ -252,6 +257,7 @@ func (c *Connection) getW(path string, node client.Node) (event <-chan client.Ev
 
 // Children returns the children of the node at the given path.
 func (c *Connection) Children(path string) (children []string, err error) {
+	defer timeRequest("children", time.Now())
 	if c.conn == nil {
 		return children, client.ErrConnectionClosed
 	}
@@ -264,6 +270,7 @@ func (c *Connection) Children(path string) (children []string, err error) {
 
 // Get returns the node at the given path.
 func (c *Connection) Get(path string, node client.Node) (err error) {
+	defer timeRequest("get", time.Now())
 	if c.conn == nil {
 		return client.ErrConnectionClosed
 	}
@@ -287,6 +294,7 @@ func (c *Connection) get(path string, node client.Node) (err error) {
 
 // Set serializes the given node and places it at the given path.
 func (c *Connection) Set(path string, node client.Node) error {
+	defer timeRequest("set", time.Now())
 	if c.conn == nil {
 		return client.ErrConnectionClosed
 	}
@@ -0,0 +1,27 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// timeRequest records the latency of a zookeeper request in
+// metrics.DefaultRegistry; call it deferred with the start time
+func timeRequest(op string, start time.Time) {
+	metrics.GetOrRegisterTimer(fmt.Sprintf("zookeeper_request_duration_seconds{op=%q}", op), metrics.DefaultRegistry).UpdateSince(start)
+}
@@ -89,6 +89,24 @@ func (this *ControlPlaneDao) AsyncRestore(filename string, unused *int) error {
 	return nil
 }
 
+// ExportService writes an application to a tgz file
+func (this *ControlPlaneDao) ExportService(request dao.ServiceExportRequest, filename *string) error {
+	this.dfs.Lock()
+	defer this.dfs.Unlock()
+	var err error
+	*filename, err = this.dfs.ExportService(request.ServiceID, request.Filename)
+	return err
+}
+
+// ImportService adds the application in a tgz file written by ExportService
+func (this *ControlPlaneDao) ImportService(request dao.ServiceImportRequest, tenantID *string) error {
+	this.dfs.Lock()
+	defer this.dfs.Unlock()
+	var err error
+	*tenantID, err = this.dfs.ImportService(request.Filename, request.PoolID)
+	return err
+}
+
 // BackupStatus monitors the status of a backup or restore
 func (this *ControlPlaneDao) BackupStatus(unused int, status *string) error {
 	message := make(chan string)
@@ -50,8 +50,11 @@ type ControlPlaneDao struct {
 	dfs            *dfs.DistributedFilesystem
 	facade         *facade.Facade
 	dockerRegistry string
+	logstashES     string
+	logstashDays   int // default days to keep log messages
 	backupLock     sync.RWMutex
 	restoreLock    sync.RWMutex
+	retentionLock  sync.Mutex
 }
 
 func serviceGetter(ctx datastore.Context, f *facade.Facade) service.GetService {
@@ -127,7 +130,7 @@ func NewControlPlaneDao(hostName string, port int) (*ControlPlaneDao, error) {
 	return dao, nil
 }
 
-func NewControlSvc(hostName string, port int, facade *facade.Facade, varpath, fsType string, maxdfstimeout time.Duration, dockerRegistry string) (*ControlPlaneDao, error) {
+func NewControlSvc(hostName string, port int, facade *facade.Facade, varpath, fsType string, maxdfstimeout time.Duration, dockerRegistry, logstashES string, logstashDays int) (*ControlPlaneDao, error) {
 	glog.V(2).Info("calling NewControlSvc()")
 	defer glog.V(2).Info("leaving NewControlSvc()")
 
@@ -141,6 +144,8 @@ func NewControlSvc(hostName string, port int, facade *facade.Facade, varpath, fs
 
 	s.varpath = varpath
 	s.fsType = fsType
+	s.logstashES = logstashES
+	s.logstashDays = logstashDays
 
 	// create the account credentials
 	if err = createSystemUser(s); err != nil {
@@ -30,6 +30,7 @@ import (
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	userdomain "github.com/control-center/serviced/domain/user"
@@ -107,7 +108,7 @@ func (dt *DaoTest) SetUpSuite(c *C) {
 		c.Fatalf("could not get zk connection %v", err)
 	}
 
-	dt.Dao, err = NewControlSvc("localhost", int(dt.Port), dt.Facade, "/tmp", "rsync", time.Minute*5, "localhost:5000")
+	dt.Dao, err = NewControlSvc("localhost", int(dt.Port), dt.Facade, "/tmp", "rsync", time.Minute*5, "localhost:5000", "localhost:9100", 14)
 	if err != nil {
 		glog.Fatalf("Could not start es container: %s", err)
 	} else {
@@ -281,6 +282,53 @@ func (dt *DaoTest) TestDao_UpdateServiceWithConfigFile(t *C) {
 
 }
 
+func (dt *DaoTest) TestDao_ServiceConfigHistory(t *C) {
+	confFile := servicedefinition.ConfigFile{Content: "original", Filename: "history.conf"}
+	svc, _ := service.NewService()
+	svc.ID = "default_history"
+	svc.Name = "history"
+	svc.PoolID = "default"
+	svc.Launch = "auto"
+	svc.DeploymentID = "deployment_id"
+	svc.OriginalConfigs = map[string]servicedefinition.ConfigFile{"history.conf": confFile}
+	err := dt.Dao.AddService(*svc, &id)
+	t.Assert(err, IsNil)
+
+	changed := servicedefinition.ConfigFile{Content: "changed", Filename: "history.conf"}
+	svc.ConfigFiles = map[string]servicedefinition.ConfigFile{"history.conf": changed}
+	err = dt.Dao.UpdateServiceAs(dao.ServiceUpdateRequest{Service: *svc, Author: "alice"}, &unused)
+	t.Assert(err, IsNil)
+
+	// the first change also records the content before it
+	var revisions []serviceconfigfile.Revision
+	err = dt.Dao.GetServiceConfigHistory(dao.ServiceConfigHistoryRequest{ServiceID: svc.ID}, &revisions)
+	t.Assert(err, IsNil)
+	t.Assert(revisions, HasLen, 2)
+	t.Assert(revisions[0].ConfFile, DeepEquals, confFile)
+	t.Assert(revisions[0].Author, Equals, "")
+	t.Assert(revisions[1].ConfFile, DeepEquals, changed)
+	t.Assert(revisions[1].Author, Equals, "alice")
+
+	// updating the service without changing the file adds nothing
+	err = dt.Dao.UpdateService(*svc, &unused)
+	t.Assert(err, IsNil)
+	err = dt.Dao.GetServiceConfigHistory(dao.ServiceConfigHistoryRequest{ServiceID: svc.ID, Filename: "history.conf"}, &revisions)
+	t.Assert(err, IsNil)
+	t.Assert(revisions, HasLen, 2)
+
+	request := dao.ServiceConfigRevertRequest{ServiceID: svc.ID, RevisionID: revisions[0].ID[:8], Author: "bob"}
+	err = dt.Dao.RevertServiceConfig(request, &unused)
+	t.Assert(err, IsNil)
+
+	var result service.Service
+	dt.Dao.GetService(svc.ID, &result)
+	t.Assert(result.ConfigFiles["history.conf"], DeepEquals, confFile)
+	err = dt.Dao.GetServiceConfigHistory(dao.ServiceConfigHistoryRequest{ServiceID: svc.ID}, &revisions)
+	t.Assert(err, IsNil)
+	t.Assert(revisions, HasLen, 3)
+	t.Assert(revisions[2].Author, Equals, "bob")
+}
+
 func (dt *DaoTest) TestDao_GetService(t *C) {
 	svc, _ := service.NewService()
 	svc.Name = "testname"
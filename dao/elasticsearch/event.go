@@ -0,0 +1,39 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/zenoss/glog"
+)
+
+// GetEvents returns the threshold events of a service or pool, or of all
+// of them, newest first
+func (this *ControlPlaneDao) GetEvents(request dao.EventRequest, events *[]event.Event) error {
+	since := request.Since
+	if since.IsZero() {
+		since = time.Now().Add(-24 * time.Hour)
+	}
+	evts, err := this.facade.GetEvents(datastore.Get(), since, request.SourceID, request.Limit)
+	if err != nil {
+		glog.Errorf("ControlPlaneDao.GetEvents: %s", err)
+		return err
+	}
+	*events = evts
+	return nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/healthhistory"
+	"github.com/zenoss/glog"
+)
+
+// GetServiceUptime reports the availability of a service over each of the
+// requested windows
+func (this *ControlPlaneDao) GetServiceUptime(request dao.ServiceUptimeRequest, report *healthhistory.Report) error {
+	r, err := this.facade.GetServiceUptime(datastore.Get(), request.ServiceID, request.Windows, request.History)
+	if err != nil {
+		glog.Errorf("ControlPlaneDao.GetServiceUptime: %s", err)
+		return err
+	}
+	*report = *r
+	return nil
+}
@@ -16,6 +16,7 @@ package elasticsearch
 
 import (
 	"github.com/control-center/serviced/commons/docker"
+	"github.com/control-center/serviced/dao"
 )
 
 func (cp *ControlPlaneDao) ImageLayerCount(imageUUID string, layers* int) error {
@@ -23,3 +24,32 @@ func (cp *ControlPlaneDao) ImageLayerCount(imageUUID string, layers* int) error
 	*layers = len(history)
 	return err
 }
+
+// MaintainImages squashes tenant images that have too many layers and
+// garbage collects images and registry tags that are no longer in use.  The
+// dfs takes its lock only while it replaces the latest images.
+func (cp *ControlPlaneDao) MaintainImages(request dao.ImageMaintenanceRequest, report *dao.ImageMaintenanceReport) error {
+	result, err := cp.dfs.MaintainImages(request.MaxLayers)
+	if err != nil {
+		return err
+	}
+	*report = *result
+	return nil
+}
+
+// GetImageInventory reports the packages, layers and commit history of the
+// images used by the tenant of the given service.  Images whose inventory is
+// still being taken are reported as pending.
+func (cp *ControlPlaneDao) GetImageInventory(serviceID string, report *dao.ImageInventoryReport) error {
+	var tenantID string
+	if err := cp.GetTenantId(serviceID, &tenantID); err != nil {
+		return err
+	}
+
+	result, err := cp.dfs.Inventory(tenantID)
+	if err != nil {
+		return err
+	}
+	*report = *result
+	return nil
+}
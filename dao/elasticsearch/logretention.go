@@ -0,0 +1,158 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/control-center/serviced/commons/logstash"
+	"github.com/zenoss/glog"
+)
+
+func (this *ControlPlaneDao) logRetentionPolicyPath() string {
+	return filepath.Join(this.varpath, "logretention.json")
+}
+
+func (this *ControlPlaneDao) logArchiveDir() string {
+	return filepath.Join(this.varpath, "logarchive")
+}
+
+// getLogRetentionPolicy reads the policy saved on the master; without one,
+// every message is kept for the days set on the command line.
+func (this *ControlPlaneDao) getLogRetentionPolicy() (logstash.RetentionPolicy, error) {
+	policy := logstash.RetentionPolicy{DefaultDays: this.logstashDays}
+	data, err := ioutil.ReadFile(this.logRetentionPolicyPath())
+	if os.IsNotExist(err) {
+		return policy, nil
+	} else if err != nil {
+		return policy, err
+	}
+	err = json.Unmarshal(data, &policy)
+	return policy, err
+}
+
+// logRetention sets up the retention of the logstash indices, looking up the
+// services of the tenants named in the policy
+func (this *ControlPlaneDao) logRetention() (*logstash.Retention, error) {
+	policy, err := this.getLogRetentionPolicy()
+	if err != nil {
+		glog.Errorf("Could not read log retention policy: %s", err)
+		return nil, err
+	}
+
+	tenantServices := make(map[string][]string)
+	for _, rule := range policy.Rules {
+		if rule.TenantID == "" {
+			continue
+		} else if _, ok := tenantServices[rule.TenantID]; ok {
+			continue
+		}
+		serviceIDs, err := this.getServiceTree(rule.TenantID)
+		if err != nil {
+			// the tenant may have been removed; its rule matches nothing
+			glog.Warningf("Could not look up services of tenant %s: %s", rule.TenantID, err)
+		}
+		tenantServices[rule.TenantID] = serviceIDs
+	}
+
+	return &logstash.Retention{
+		Client:         logstash.NewClient(this.logstashES),
+		Policy:         policy,
+		ArchiveDir:     this.logArchiveDir(),
+		TenantServices: tenantServices,
+	}, nil
+}
+
+// GetLogRetentionStatus describes the log retention policy and the state of
+// the logstash indices and archives
+func (this *ControlPlaneDao) GetLogRetentionStatus(unused int, status *logstash.RetentionStatus) error {
+	retention, err := this.logRetention()
+	if err != nil {
+		return err
+	}
+	st, err := retention.Status(time.Now())
+	if err != nil {
+		glog.Errorf("Could not get log retention status: %s", err)
+		return err
+	}
+	*status = *st
+	return nil
+}
+
+// SetLogRetentionPolicy replaces the log retention policy.  The policy is
+// enforced the next time the logstash indices are purged.
+func (this *ControlPlaneDao) SetLogRetentionPolicy(policy logstash.RetentionPolicy, unused *int) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	this.retentionLock.Lock()
+	defer this.retentionLock.Unlock()
+	if err := ioutil.WriteFile(this.logRetentionPolicyPath(), data, 0644); err != nil {
+		glog.Errorf("Could not write log retention policy: %s", err)
+		return err
+	}
+	glog.Infof("Updated log retention policy: %s", data)
+	return nil
+}
+
+// EnforceLogRetention archives and deletes the log messages that have expired
+// under the log retention policy, then archives and deletes the oldest
+// indices until they use no more than maxSize gigabytes.  A maxSize of 0
+// disables the size limit.
+func (this *ControlPlaneDao) EnforceLogRetention(maxSize int, report *logstash.RetentionReport) error {
+	this.retentionLock.Lock()
+	defer this.retentionLock.Unlock()
+
+	retention, err := this.logRetention()
+	if err != nil {
+		return err
+	}
+	retention.MaxBytes = int64(maxSize) << 30
+	if err := os.MkdirAll(retention.ArchiveDir, 0755); err != nil {
+		glog.Errorf("Could not create log archive directory %s: %s", retention.ArchiveDir, err)
+		return err
+	}
+	r, err := retention.Enforce(time.Now())
+	if err != nil {
+		glog.Errorf("Could not enforce log retention policy: %s", err)
+		return err
+	}
+	*report = *r
+	return nil
+}
+
+// RestoreLogs loads an archived day (yyyy.mm.dd) back into logstash
+func (this *ControlPlaneDao) RestoreLogs(day string, unused *int) error {
+	this.retentionLock.Lock()
+	defer this.retentionLock.Unlock()
+
+	retention, err := this.logRetention()
+	if err != nil {
+		return err
+	}
+	if err := retention.Restore(day); err != nil {
+		glog.Errorf("Could not restore logs for %s: %s", day, err)
+		return err
+	}
+	return nil
+}
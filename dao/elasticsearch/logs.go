@@ -14,7 +14,11 @@
 package elasticsearch
 
 import (
+	"fmt"
+
+	"github.com/control-center/serviced/commons/logstash"
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/rpc/agent"
 	"github.com/zenoss/glog"
@@ -74,3 +78,60 @@ func (this *ControlPlaneDao) GetServiceStateLogs(request dao.ServiceStateRequest
 	}
 	return nil
 }
+
+// SearchLogs finds messages in the logstash indices.  If the request names a
+// service, only the logs of that service and its children are searched.
+func (this *ControlPlaneDao) SearchLogs(request dao.LogSearchRequest, result *logstash.Result) error {
+	query := logstash.Query{
+		Query:  request.Query,
+		From:   request.From,
+		To:     request.To,
+		Fields: request.Fields,
+		Offset: request.Offset,
+		Limit:  request.Limit,
+	}
+
+	if request.ServiceID != "" {
+		serviceIDs, err := this.getServiceTree(request.ServiceID)
+		if err != nil {
+			glog.Errorf("Could not look up services under %s: %s", request.ServiceID, err)
+			return err
+		}
+		query.ServiceIDs = serviceIDs
+	}
+
+	res, err := logstash.NewClient(this.logstashES).Search(query)
+	if err != nil {
+		glog.Errorf("Could not search logs: %s", err)
+		return err
+	}
+	*result = *res
+	return nil
+}
+
+// getServiceTree returns the id of a service and the ids of all its
+// descendants
+func (this *ControlPlaneDao) getServiceTree(serviceID string) ([]string, error) {
+	svcs, err := this.facade.GetServices(datastore.Get(), dao.ServiceRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]string)
+	found := false
+	for _, svc := range svcs {
+		children[svc.ParentServiceID] = append(children[svc.ParentServiceID], svc.ID)
+		if svc.ID == serviceID {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("service %s not found", serviceID)
+	}
+
+	serviceIDs := []string{serviceID}
+	for i := 0; i < len(serviceIDs); i++ {
+		serviceIDs = append(serviceIDs, children[serviceIDs[i]]...)
+	}
+	return serviceIDs, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/manifest"
+	"github.com/zenoss/glog"
+)
+
+// ApplyManifest makes the cluster match a manifest and returns the changes
+func (this *ControlPlaneDao) ApplyManifest(request dao.ApplyRequest, plan *manifest.Plan) error {
+	p, err := this.facade.ApplyManifest(datastore.Get(), &request.Manifest, applyHooks{this}, request.Author, request.Prune, request.DryRun)
+	if err != nil {
+		return err
+	}
+	*plan = *p
+	return nil
+}
+
+// applyHooks creates, snapshots and cleans up the volumes of the tenants that
+// a manifest deploys, upgrades and removes, like the rpc calls that make the
+// same changes
+type applyHooks struct {
+	cp *ControlPlaneDao
+}
+
+func (h applyHooks) Deployed(tenantID string) error {
+	if _, err := h.cp.dfs.GetVolume(tenantID); err != nil {
+		glog.Warningf("Could not create volume for tenant %s: %s", tenantID, err)
+	}
+	return nil
+}
+
+func (h applyHooks) Snapshot(tenantID, description string) (string, error) {
+	var snapshotID string
+	err := h.cp.Snapshot(dao.SnapshotRequest{ServiceID: tenantID, Description: description}, &snapshotID)
+	return snapshotID, err
+}
+
+func (h applyHooks) Removed(tenantID string) error {
+	return h.cp.DeleteSnapshots(tenantID, new(int))
+}
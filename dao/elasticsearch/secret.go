@@ -0,0 +1,60 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/secret"
+)
+
+// SetSecret sets the value of a secret
+func (this *ControlPlaneDao) SetSecret(request dao.SecretRequest, unused *int) error {
+	return this.facade.SetSecret(datastore.Get(), request.Name, request.Value)
+}
+
+// GetSecret gets the value of a secret
+func (this *ControlPlaneDao) GetSecret(name string, value *string) error {
+	v, err := this.facade.GetSecret(datastore.Get(), name)
+	if err != nil {
+		return err
+	}
+	*value = v
+	return nil
+}
+
+// GetSecrets gets the names of the secrets, without their values
+func (this *ControlPlaneDao) GetSecrets(unused int, secrets *[]secret.Info) error {
+	infos, err := this.facade.GetSecrets(datastore.Get())
+	if err != nil {
+		return err
+	}
+	*secrets = infos
+	return nil
+}
+
+// RemoveSecret removes a secret
+func (this *ControlPlaneDao) RemoveSecret(name string, unused *int) error {
+	return this.facade.RemoveSecret(datastore.Get(), name)
+}
+
+// GetServiceSecrets gets the values of the secrets that a service uses
+func (this *ControlPlaneDao) GetServiceSecrets(serviceID string, values *map[string]string) error {
+	v, err := this.facade.GetServiceSecrets(datastore.Get(), serviceID)
+	if err != nil {
+		return err
+	}
+	*values = v
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/zenoss/glog"
 )
 
@@ -39,7 +40,13 @@ func (this *ControlPlaneDao) AddService(svc service.Service, serviceId *string)
 
 //
 func (this *ControlPlaneDao) UpdateService(svc service.Service, unused *int) error {
-	if err := this.facade.UpdateService(datastore.Get(), svc); err != nil {
+	return this.UpdateServiceAs(dao.ServiceUpdateRequest{Service: svc}, unused)
+}
+
+// UpdateServiceAs updates a service on behalf of a user
+func (this *ControlPlaneDao) UpdateServiceAs(request dao.ServiceUpdateRequest, unused *int) error {
+	svc := request.Service
+	if err := this.facade.UpdateServiceAs(datastore.Get(), svc, request.Author, request.Restart); err != nil {
 		return err
 	}
 
@@ -52,6 +59,22 @@ func (this *ControlPlaneDao) UpdateService(svc service.Service, unused *int) err
 	return nil
 }
 
+// GetServiceConfigHistory gets the changes to the config files of a service
+func (this *ControlPlaneDao) GetServiceConfigHistory(request dao.ServiceConfigHistoryRequest, revisions *[]serviceconfigfile.Revision) error {
+	revs, err := this.facade.GetServiceConfigHistory(datastore.Get(), request.ServiceID, request.Filename)
+	if err != nil {
+		return err
+	}
+	*revisions = revs
+	return nil
+}
+
+// RevertServiceConfig restores a config file of a service to an earlier
+// revision
+func (this *ControlPlaneDao) RevertServiceConfig(request dao.ServiceConfigRevertRequest, unused *int) error {
+	return this.facade.RevertServiceConfig(datastore.Get(), request.ServiceID, request.RevisionID, request.Author, request.Restart)
+}
+
 //
 func (this *ControlPlaneDao) RemoveService(id string, unused *int) error {
 	if err := this.facade.RemoveService(datastore.Get(), id); err != nil {
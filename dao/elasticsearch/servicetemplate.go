@@ -14,6 +14,8 @@
 package elasticsearch
 
 import (
+	"fmt"
+
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/servicetemplate"
@@ -44,9 +46,25 @@ func (this *ControlPlaneDao) GetServiceTemplates(unused int, templates *map[stri
 	return err
 }
 
+func (this *ControlPlaneDao) GetServiceTemplateHistory(templateID string, history *[]servicetemplate.TemplateHistory) error {
+	h, err := this.facade.GetServiceTemplateHistory(datastore.Get(), templateID)
+	if h != nil {
+		*history = h
+	} else {
+		*history = make([]servicetemplate.TemplateHistory, 0)
+	}
+	return err
+}
+
+func (this *ControlPlaneDao) RollbackServiceTemplate(request dao.TemplateRollbackRequest, hash *string) error {
+	var err error
+	*hash, err = this.facade.RollbackServiceTemplate(datastore.Get(), request.TemplateID, request.Hash)
+	return err
+}
+
 func (this *ControlPlaneDao) DeployTemplate(request dao.ServiceTemplateDeploymentRequest, tenantIDs *[]string) error {
 	var err error
-	*tenantIDs, err = this.facade.DeployTemplate(datastore.Get(), request.PoolID, request.TemplateID, request.DeploymentID)
+	*tenantIDs, err = this.facade.DeployTemplate(datastore.Get(), request.PoolID, request.TemplateID, request.DeploymentID, request.Params)
 
 	// Create the tenant volume
 	for _, tenantID := range *tenantIDs {
@@ -58,6 +76,33 @@ func (this *ControlPlaneDao) DeployTemplate(request dao.ServiceTemplateDeploymen
 	return err
 }
 
+func (this *ControlPlaneDao) UpgradeTemplate(request dao.TemplateUpgradeRequest, plan *servicetemplate.UpgradePlan) error {
+	p, err := this.facade.PlanTemplateUpgrade(datastore.Get(), request.TemplateID, request.TenantID, request.Params)
+	if err != nil {
+		glog.Errorf("Could not plan the upgrade of %s to template %s: %s", request.TenantID, request.TemplateID, err)
+		return err
+	}
+	if request.DryRun {
+		*plan = *p
+		return nil
+	}
+
+	// take a snapshot so that the upgrade can be rolled back
+	desc := fmt.Sprintf("before upgrade to template %s", request.TemplateID)
+	if err := this.Snapshot(dao.SnapshotRequest{ServiceID: request.TenantID, Description: desc}, &p.SnapshotID); err != nil {
+		glog.Errorf("Could not snapshot %s before the upgrade: %s", request.TenantID, err)
+		return err
+	}
+	glog.Infof("Upgrading %s to template %s (snapshot %s)", request.TenantID, request.TemplateID, p.SnapshotID)
+
+	if err := this.facade.UpgradeTemplate(datastore.Get(), p); err != nil {
+		glog.Errorf("Could not upgrade %s to template %s; rollback to snapshot %s to undo the changes: %s", request.TenantID, request.TemplateID, p.SnapshotID, err)
+		return err
+	}
+	*plan = *p
+	return nil
+}
+
 func (this *ControlPlaneDao) DeployTemplateStatus(request dao.ServiceTemplateDeploymentRequest, deployTemplateStatus *string) error {
 	var err error
 	err = this.facade.DeployTemplateStatus(request.DeploymentID, deployTemplateStatus)
@@ -0,0 +1,30 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/stream"
+)
+
+// GetServiceEvents returns the changes to services, instances and hosts that
+// followed request.After, waiting for the next one if there are none yet
+func (this *ControlPlaneDao) GetServiceEvents(request dao.ServiceEventRequest, events *[]stream.Event) error {
+	timeout := request.Timeout
+	if timeout > dao.MaxServiceEventTimeout {
+		timeout = dao.MaxServiceEventTimeout
+	}
+	*events = stream.Default.Wait(request.After, request.Filter, timeout)
+	return nil
+}
@@ -16,12 +16,20 @@ package dao
 import (
 	"time"
 
+	"github.com/control-center/serviced/commons/inventory"
+	"github.com/control-center/serviced/commons/logstash"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/healthhistory"
+	"github.com/control-center/serviced/domain/manifest"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/domain/user"
+	"github.com/control-center/serviced/stream"
 	"github.com/control-center/serviced/volume"
 )
 
@@ -87,6 +95,133 @@ type RollbackRequest struct {
 	ForceRestart bool
 }
 
+type ImageMaintenanceRequest struct {
+	MaxLayers int // squash tenant images with more layers than this; zero to disable
+}
+
+type ImageMaintenanceReport struct {
+	SquashedImages []string // tenant images whose layers were squashed
+	RemovedImages  []string // untagged images removed from the docker host
+	RemovedTags    []string // stale tags removed from the docker registry
+	ReclaimedBytes int64    // disk space reclaimed on the docker host
+}
+
+// ServiceUpdateRequest updates a service on behalf of a user
+type ServiceUpdateRequest struct {
+	Service service.Service
+	Author  string // who made the change, recorded in the history of the config files
+	Restart bool   // restart the running instances if the config files changed
+}
+
+// ServiceConfigHistoryRequest selects the changes to the config files of a
+// service
+type ServiceConfigHistoryRequest struct {
+	ServiceID string
+	Filename  string // empty for all the config files
+}
+
+// ServiceConfigRevertRequest restores a config file of a service to an
+// earlier revision
+type ServiceConfigRevertRequest struct {
+	ServiceID  string
+	RevisionID string // id or unique prefix of the revision
+	Author     string
+	Restart    bool
+}
+
+// SecretRequest sets the value of a secret
+type SecretRequest struct {
+	Name  string
+	Value string
+}
+
+// ServiceExportRequest writes an application to a file
+type ServiceExportRequest struct {
+	ServiceID string
+	Filename  string
+}
+
+// ServiceImportRequest adds the application in a file to a pool
+type ServiceImportRequest struct {
+	Filename string
+	PoolID   string
+}
+
+// ApplyRequest makes the cluster match a manifest
+type ApplyRequest struct {
+	Manifest manifest.Manifest
+	Author   string
+	Prune    bool // remove what the manifest does not declare
+	DryRun   bool // only plan the changes
+}
+
+// LogSearchRequest selects messages from the logstash indices
+type LogSearchRequest struct {
+	ServiceID string            // match logs of this service and its children; empty for all services
+	Query     string            // lucene query string
+	From      time.Time         // zero for unbounded
+	To        time.Time         // zero for unbounded
+	Fields    map[string]string // match messages whose field contains the phrase
+	Offset    int
+	Limit     int
+}
+
+// EventRequest selects threshold events
+type EventRequest struct {
+	SourceID string    // service or pool id; empty for all
+	Since    time.Time // zero for the last day
+	Limit    int
+}
+
+// ServiceUptimeRequest selects the windows to report the availability of a
+// service over
+type ServiceUptimeRequest struct {
+	ServiceID string
+	Windows   []string // e.g. 1h, 7d; empty for the default windows
+	History   bool     // include the health check results themselves
+}
+
+// ServiceEventRequest polls for the changes that followed an earlier one
+type ServiceEventRequest struct {
+	After   uint64 // id of the last event received; 0 for the buffered events
+	Filter  stream.Filter
+	Timeout time.Duration // how long to wait if there are no events yet; at most MaxServiceEventTimeout
+}
+
+// MaxServiceEventTimeout is the longest a poll for service events waits
+const MaxServiceEventTimeout = 30 * time.Second
+
+// ImageInventory describes the provenance and contents of an image used by
+// the services of a tenant
+type ImageInventory struct {
+	ImageID  string              // repotag of the image
+	UUID     string              // docker image id the repotag resolves to
+	Digests  []string            // registry content digests of the image
+	Created  time.Time           // when the image was built or last committed
+	Layers   []string            // layer ids from the top of the image down
+	Packages []inventory.Package // os packages installed in the image
+	Services []string            // ids of the services running the image
+	Pending  bool                // the inventory is still being taken
+	Error    string              // why the inventory could not be taken
+}
+
+// CommitRecord describes a container that was committed to a tenant image
+type CommitRecord struct {
+	ContainerID string
+	ImageID     string // repotag that was committed
+	ParentUUID  string // image id before the commit
+	UUID        string // image id after the commit
+	SnapshotID  string // snapshot taken after the commit
+	Committed   time.Time
+}
+
+type ImageInventoryReport struct {
+	TenantID  string
+	Generated time.Time
+	Images    []ImageInventory
+	Commits   []CommitRecord
+}
+
 // The ControlPlane interface is the API for a serviced master.
 type ControlPlane interface {
 
@@ -105,6 +240,15 @@ type ControlPlane interface {
 	// Update an existing service
 	UpdateService(service service.Service, unused *int) error
 
+	// Update an existing service on behalf of a user
+	UpdateServiceAs(request ServiceUpdateRequest, unused *int) error
+
+	// Get the changes to the config files of a service, oldest first
+	GetServiceConfigHistory(request ServiceConfigHistoryRequest, revisions *[]serviceconfigfile.Revision) error
+
+	// Restore a config file of a service to an earlier revision
+	RevertServiceConfig(request ServiceConfigRevertRequest, unused *int) error
+
 	// Remove a service definition
 	RemoveService(serviceId string, unused *int) error
 
@@ -162,6 +306,31 @@ type ControlPlane interface {
 	// Get logs for the given app
 	GetServiceStateLogs(request ServiceStateRequest, logs *string) error
 
+	// Search the logstash indices for log messages
+	SearchLogs(request LogSearchRequest, result *logstash.Result) error
+
+	// Get threshold events, newest first
+	GetEvents(request EventRequest, events *[]event.Event) error
+
+	// Get the availability of a service from its health check history
+	GetServiceUptime(request ServiceUptimeRequest, report *healthhistory.Report) error
+
+	// Wait for changes to services, instances and hosts
+	GetServiceEvents(request ServiceEventRequest, events *[]stream.Event) error
+
+	// Get the log retention policy and the state of the logstash indices
+	GetLogRetentionStatus(unused int, status *logstash.RetentionStatus) error
+
+	// Replace the log retention policy
+	SetLogRetentionPolicy(policy logstash.RetentionPolicy, unused *int) error
+
+	// Archive and delete the log messages that have expired, and the oldest
+	// indices beyond maxSize gigabytes
+	EnforceLogRetention(maxSize int, report *logstash.RetentionReport) error
+
+	// Load an archived day of logs back into logstash
+	RestoreLogs(day string, unused *int) error
+
 	// Get all running services
 	GetRunningServices(request EntityRequest, runningServices *[]RunningService) error
 
@@ -180,6 +349,9 @@ type ControlPlane interface {
 	// Deploy an application template in to production
 	DeployTemplate(request ServiceTemplateDeploymentRequest, tenantIDs *[]string) error
 
+	// Upgrade a deployed tenant to a template, keeping the changes made by users
+	UpgradeTemplate(request TemplateUpgradeRequest, plan *servicetemplate.UpgradePlan) error
+
 	// Add a new service Template
 	AddServiceTemplate(serviceTemplate servicetemplate.ServiceTemplate, templateId *string) error
 
@@ -192,6 +364,12 @@ type ControlPlane interface {
 	// Get a list of ServiceTemplates
 	GetServiceTemplates(unused int, serviceTemplates *map[string]servicetemplate.ServiceTemplate) error
 
+	// Get the versions of a ServiceTemplate, oldest first
+	GetServiceTemplateHistory(templateID string, history *[]servicetemplate.TemplateHistory) error
+
+	// Restore a previous version of a ServiceTemplate
+	RollbackServiceTemplate(request TemplateRollbackRequest, hash *string) error
+
 	//---------------------------------------------------------------------------
 	// Service CRUD
 
@@ -207,6 +385,12 @@ type ControlPlane interface {
 	// Return the number of layers in an image
 	ImageLayerCount(imageUUID string, layers *int) error
 
+	// Squash tenant images and garbage collect unused images and registry tags
+	MaintainImages(request ImageMaintenanceRequest, report *ImageMaintenanceReport) error
+
+	// Report the packages, digests and commit history of a tenant's images
+	GetImageInventory(serviceID string, report *ImageInventoryReport) error
+
 	// Volume returns a service's volume
 	GetVolume(serviceID string, volume volume.Volume) error
 
@@ -254,4 +438,34 @@ type ControlPlane interface {
 
 	// BackupStatus monitors the status of a backup or restore
 	BackupStatus(unused int, status *string) error
+
+	// ExportService writes an application with its volume and images to a tgz file
+	ExportService(request ServiceExportRequest, filename *string) error
+
+	// ImportService adds an application from a tgz file written by ExportService
+	ImportService(request ServiceImportRequest, tenantID *string) error
+
+	//---------------------------------------------------------------------------
+	// Secrets
+
+	// Set the value of a secret
+	SetSecret(request SecretRequest, unused *int) error
+
+	// Get the value of a secret
+	GetSecret(name string, value *string) error
+
+	// Get the names of the secrets, without their values
+	GetSecrets(unused int, secrets *[]secret.Info) error
+
+	// Remove a secret
+	RemoveSecret(name string, unused *int) error
+
+	// Get the values of the secrets that a service uses, when it starts
+	GetServiceSecrets(serviceID string, values *map[string]string) error
+
+	//---------------------------------------------------------------------------
+	// Manifests
+
+	// Make the cluster match a manifest and return the changes
+	ApplyManifest(request ApplyRequest, plan *manifest.Plan) error
 }
@@ -164,3 +164,42 @@ func TestWritingConfigFile(t *testing.T) {
 		t.Error("Was unable to write the logstash conf file")
 	}
 }
+
+func TestConstructingParserFilters(t *testing.T) {
+	services := []servicedefinition.ServiceDefinition{
+		servicedefinition.ServiceDefinition{
+			Name: "parsed",
+			LogConfigs: []servicedefinition.LogConfig{
+				servicedefinition.LogConfig{
+					Path: "/opt/app/log/app.log",
+					Type: "app",
+					Parsers: []servicedefinition.LogParser{
+						servicedefinition.LogParser{Type: servicedefinition.LogParserMultiline},
+						servicedefinition.LogParser{Type: servicedefinition.LogParserRegex, Pattern: `^(?P<level>[A-Z]+) "(?P<msg>.*)"`},
+						servicedefinition.LogParser{Type: servicedefinition.LogParserKeyValue, FieldSplit: "&", Target: "params"},
+					},
+				},
+			},
+		},
+	}
+	filters := getFilters(services, map[string]string{}, []string{})
+	expected := "\nif [type] == \"app\" \n {\n" +
+		"  multiline {\n" +
+		"    source => \"message\"\n" +
+		"    pattern => \"^(\\s|Caused by:)\"\n" +
+		"    what => \"previous\"\n" +
+		"    stream_identity => \"%{host}.%{file}.%{type}\"\n" +
+		"  }\n" +
+		"  grok {\n" +
+		"    match => [ \"message\", \"^(?<level>[A-Z]+) \\\"(?<msg>.*)\\\"\" ]\n" +
+		"  }\n" +
+		"  kv {\n" +
+		"    source => \"message\"\n" +
+		"    field_split => \"&\"\n" +
+		"    target => \"params\"\n" +
+		"  }\n" +
+		"}"
+	if filters != expected {
+		t.Errorf("expected filters:\n%s\ngot:\n%s", expected, filters)
+	}
+}
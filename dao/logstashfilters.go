@@ -89,12 +89,17 @@ func getFilters(services []servicedefinition.ServiceDefinition, filterDefs map[s
 	filters := ""
 	for _, service := range services {
 		for _, config := range service.LogConfigs {
+			//do not write duplicate types, logstash doesn't handle this
+			if utils.StringInSlice(config.Type, typeFilter) {
+				continue
+			}
+			body := getParserFilters(config.Parsers)
 			for _, filtName := range config.Filters {
-				//do not write duplicate types, logstash doesn't handle this
-				if !utils.StringInSlice(config.Type, typeFilter) {
-					filters += fmt.Sprintf("\nif [type] == \"%s\" \n {\n  %s \n}", config.Type, filterDefs[filtName])
-					typeFilter = append(typeFilter, config.Type)
-				}
+				body += fmt.Sprintf("  %s \n", filterDefs[filtName])
+			}
+			if body != "" {
+				filters += fmt.Sprintf("\nif [type] == \"%s\" \n {\n%s}", config.Type, body)
+				typeFilter = append(typeFilter, config.Type)
 			}
 		}
 		if len(service.Services) > 0 {
@@ -105,6 +110,63 @@ func getFilters(services []servicedefinition.ServiceDefinition, filterDefs map[s
 	return filters
 }
 
+// getParserFilters compiles the parsers of a log config into logstash
+// filters
+func getParserFilters(parsers []servicedefinition.LogParser) string {
+	filters := ""
+	for _, parser := range parsers {
+		var settings [][2]string
+		var name string
+		switch parser.Type {
+		case servicedefinition.LogParserMultiline:
+			name = "multiline"
+			settings = [][2]string{
+				{"source", parser.SourceField()},
+				{"pattern", parser.LogstashPattern()},
+				{"what", "previous"},
+				// keep the lines of different files apart
+				{"stream_identity", "%{host}.%{file}.%{type}"},
+			}
+		case servicedefinition.LogParserJSON:
+			name = "json"
+			settings = [][2]string{{"source", parser.SourceField()}}
+			if parser.Target != "" {
+				settings = append(settings, [2]string{"target", parser.Target})
+			}
+		case servicedefinition.LogParserKeyValue:
+			name = "kv"
+			settings = [][2]string{
+				{"source", parser.SourceField()},
+				{"field_split", parser.FieldSplit},
+				{"value_split", parser.ValueSplit},
+			}
+			if parser.Target != "" {
+				settings = append(settings, [2]string{"target", parser.Target})
+			}
+		case servicedefinition.LogParserRegex:
+			filters += fmt.Sprintf("  grok {\n    match => [ %s, %s ]\n  }\n", logstashString(parser.SourceField()), logstashString(parser.LogstashPattern()))
+			continue
+		default:
+			continue
+		}
+
+		filters += fmt.Sprintf("  %s {\n", name)
+		for _, setting := range settings {
+			if setting[1] != "" {
+				filters += fmt.Sprintf("    %s => %s\n", setting[0], logstashString(setting[1]))
+			}
+		}
+		filters += "  }\n"
+	}
+	return filters
+}
+
+// logstashString quotes a string for the logstash configuration, which does
+// not interpret any escapes other than quotes
+func logstashString(s string) string {
+	return "\"" + strings.Replace(s, "\"", "\\\"", -1) + "\""
+}
+
 // This method writes out the config file for logstash. It uses
 // the logstash.conf.template and does a variable replacement.
 func writeLogStashConfigFile(filters string, outputPath string) error {
@@ -59,9 +59,24 @@ type ApplicationEndpoint struct {
 
 // A request to deploy a service template
 type ServiceTemplateDeploymentRequest struct {
-	PoolID       string // Pool Id to deploy service into
-	TemplateID   string // Id of template to be deployed
-	DeploymentID string // Unique id of the instance of this template
+	PoolID       string            // Pool Id to deploy service into
+	TemplateID   string            // Id of template to be deployed
+	DeploymentID string            // Unique id of the instance of this template
+	Params       map[string]string // Values of the parameters of the template
+}
+
+// A request to restore a previous version of a template
+type TemplateRollbackRequest struct {
+	TemplateID string // Id of the template
+	Hash       string // Hash, or a unique prefix of the hash, of the version to restore
+}
+
+// A request to upgrade a deployed tenant to a template
+type TemplateUpgradeRequest struct {
+	TemplateID string            // Id of the template to upgrade to
+	TenantID   string            // Id of the tenant to upgrade
+	DryRun     bool              // Only compute the changes without applying them
+	Params     map[string]string // Values of parameters that change or that the template adds
 }
 
 // A request to deploy a service from a service definition
@@ -104,14 +119,15 @@ func (s Status) String() string {
 }
 
 var (
-	Scheduled = Status{1, "Scheduled"}
-	Starting  = Status{2, "Starting"}
-	Pausing   = Status{3, "Pausing"}
-	Paused    = Status{4, "Paused"}
-	Resuming  = Status{5, "Resuming"}
-	Running   = Status{6, "Running"}
-	Stopping  = Status{7, "Stopping"}
-	Stopped   = Status{8, "Stopped"}
+	Scheduled   = Status{1, "Scheduled"}
+	Starting    = Status{2, "Starting"}
+	Pausing     = Status{3, "Pausing"}
+	Paused      = Status{4, "Paused"}
+	Resuming    = Status{5, "Resuming"}
+	Running     = Status{6, "Running"}
+	Stopping    = Status{7, "Stopping"}
+	Stopped     = Status{8, "Stopped"}
+	Quarantined = Status{9, "Quarantined"}
 )
 
 type ServiceStatus struct {
@@ -232,6 +232,10 @@ func (dfs *DistributedFilesystem) Backup(dirpath string, last int) (string, erro
 	}
 	dfs.log("Docker image export successful")
 
+	// the values of the secrets only decrypt with the master key of this
+	// master, so they are exported on their own with serviced secret export
+	dfs.log("Secrets are not included in the backup; run serviced secret export to save them")
+
 	dfs.log("Writing backup file")
 	if err := exportTGZ(dirpath, filename); err != nil {
 		glog.Errorf("Could not write backup file %s: %s", filename, err)
@@ -400,7 +404,7 @@ func (dfs *DistributedFilesystem) Restore(filename string) error {
 	glog.V(1).Infof("Restoring services and snapshots")
 	for _, f := range snapshotFiles {
 		dfs.log("Loading %s", f)
-		if err := dfs.loadSnapshots(strings.TrimSuffix(f, ".tgz"), filepath.Join(dirpath, snapshotDir, f)); err != nil {
+		if err := dfs.loadSnapshots(strings.TrimSuffix(f, ".tgz"), filepath.Join(dirpath, snapshotDir, f), ""); err != nil {
 			glog.Errorf("Could not import snapshot from %s: %s", f, err)
 			return err
 		}
@@ -473,7 +477,7 @@ func (dfs *DistributedFilesystem) saveSnapshots(tenantID, directory string, last
 	return exportfile, snapshots, nil
 }
 
-func (dfs *DistributedFilesystem) loadSnapshots(tenantID, infile string) error {
+func (dfs *DistributedFilesystem) loadSnapshots(tenantID, infile, poolID string) error {
 	tmpdir := filepath.Join(filepath.Dir(infile), tenantID)
 
 	if err := mkdir(tmpdir); err != nil {
@@ -535,12 +539,12 @@ func (dfs *DistributedFilesystem) loadSnapshots(tenantID, infile string) error {
 	// Restore the service data
 	// This is necessary to restore services for tenants which do not exist - in that
 	//   case dfs.Rollback will fail if we don't restore first.
-	if err := dfs.restoreServices(tenantID, svcs); err != nil {
+	if err := dfs.restoreServices(tenantID, svcs, poolID); err != nil {
 		glog.Errorf("Could not restore services from %s: %s", label, err)
 		return err
 	}
 	// Rollback the snapshot
-	if err := dfs.Rollback(label, false); err != nil {
+	if err := dfs.rollback(label, false, poolID); err != nil {
 		glog.Errorf("Could not rollback to snapshot %s: %s", label, err)
 		return err
 	}
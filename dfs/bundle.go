@@ -0,0 +1,291 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/secret"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/utils"
+	"github.com/zenoss/glog"
+)
+
+const bundleJSON = "bundle.json"
+
+// bundlemeta describes the application in a service bundle
+type bundlemeta struct {
+	TenantID string
+	Name     string
+	Created  time.Time
+	Secrets  []string // the secrets the services refer to, without their values
+}
+
+// ExportService writes an application to a tgz file that can be imported on
+// another cluster. The bundle holds the services of the tenant, with their
+// config files, address assignments and virtual hosts, a snapshot of its
+// volume and the docker images it runs. The values of the secrets that the
+// services refer to are not exported; only their names are listed.
+func (dfs *DistributedFilesystem) ExportService(serviceID, filename string) (string, error) {
+	tenant, err := dfs.facade.GetService(dfs.datastoreGet(), serviceID)
+	if err != nil {
+		glog.Errorf("Could not get service %s: %s", serviceID, err)
+		return "", err
+	} else if tenant == nil {
+		return "", fmt.Errorf("service %s not found", serviceID)
+	} else if tenant.ParentServiceID != "" {
+		tenantID, err := dfs.facade.GetTenantID(dfs.datastoreGet(), serviceID)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("service %s is part of application %s; only whole applications can be exported", serviceID, tenantID)
+	}
+	dfs.log("Starting export of %s (%s)", tenant.Name, tenant.ID)
+
+	if filename = strings.TrimSpace(filename); filename == "" {
+		filename = filepath.Join(utils.BackupDir(dfs.varpath), fmt.Sprintf("%s-%s.tgz", tenant.Name, time.Now().Format("2006-01-02-150405")))
+	}
+	dirpath := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if err := mkdir(dirpath); err != nil {
+		glog.Errorf("Could neither find nor create %s: %v", dirpath, err)
+		return "", err
+	}
+
+	defer func() {
+		if err := os.RemoveAll(dirpath); err != nil {
+			glog.Errorf("Could not remove %s: %v", dirpath, err)
+		}
+	}()
+
+	if err := exportJSON(filepath.Join(dirpath, meta), &metadata{dfs.fsType}); err != nil {
+		glog.Errorf("Could not export %s: %s", meta, err)
+		return "", err
+	}
+
+	svcs, err := dfs.facade.GetServices(dfs.datastoreGet(), dao.ServiceRequest{TenantID: tenant.ID})
+	if err != nil {
+		glog.Errorf("Could not get services of %s: %s", tenant.ID, err)
+		return "", err
+	}
+
+	bundle := &bundlemeta{tenant.ID, tenant.Name, time.Now().UTC(), secretNames(svcs)}
+	if err := exportJSON(filepath.Join(dirpath, bundleJSON), bundle); err != nil {
+		glog.Errorf("Could not export %s: %s", bundleJSON, err)
+		return "", err
+	}
+	if len(bundle.Secrets) > 0 {
+		dfs.log("The values of secrets %s are not exported; set them where the export is imported", strings.Join(bundle.Secrets, ", "))
+	}
+
+	for _, dir := range []string{imageDir, snapshotDir} {
+		p := filepath.Join(dirpath, dir)
+		if err := mkdir(p); err != nil {
+			glog.Errorf("Could not create %s: %s", p, err)
+			return "", err
+		}
+	}
+
+	// the snapshot also holds the services, so that they are restored with
+	// the volume
+	dfs.log("Exporting snapshot of %s (%s)", tenant.Name, tenant.ID)
+	_, labels, err := dfs.saveSnapshots(tenant.ID, filepath.Join(dirpath, snapshotDir), 0)
+	if err != nil {
+		glog.Errorf("Could not export snapshot for %s (%s): %s", tenant.Name, tenant.ID, err)
+		return "", err
+	}
+	dfs.log("Snapshot export successful")
+
+	dfs.log("Exporting docker images")
+	imageTags, err := dfs.exportImages(filepath.Join(dirpath, imageDir), nil, svcs, labels)
+	if err != nil {
+		glog.Errorf("Could not export docker images: %s", err)
+		return "", err
+	}
+	if err := exportJSON(filepath.Join(dirpath, imageJSON), &imageTags); err != nil {
+		glog.Errorf("Could not export images: %s", err)
+		return "", err
+	}
+	dfs.log("Docker image export successful")
+
+	dfs.log("Writing export file")
+	if err := exportTGZ(dirpath, filename); err != nil {
+		glog.Errorf("Could not write export file %s: %s", filename, err)
+		return "", err
+	}
+	dfs.log("Export file created: %s", filename)
+
+	glog.Infof("Export of %s (%s) succeeded with fsType:%s saved to file:%s", tenant.Name, tenant.ID, dfs.fsType, filename)
+	return filename, nil
+}
+
+// ImportService adds the application of a bundle written by ExportService to
+// a resource pool, and returns its tenant id. The application must not exist
+// yet.
+func (dfs *DistributedFilesystem) ImportService(filename, poolID string) (string, error) {
+	pools, err := dfs.facade.GetResourcePools(dfs.datastoreGet())
+	if err != nil {
+		glog.Errorf("Could not get resource pools: %s", err)
+		return "", err
+	}
+	found := false
+	for _, pool := range pools {
+		if pool.ID == poolID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("pool %s not found", poolID)
+	}
+
+	dirpath := filepath.Join(utils.BackupDir(dfs.varpath), "import")
+	if err := os.RemoveAll(dirpath); err != nil {
+		glog.Errorf("Could not remove %s: %s", dirpath, err)
+		return "", err
+	}
+
+	if err := mkdir(dirpath); err != nil {
+		glog.Errorf("Could neither find nor create %s: %s", dirpath, err)
+		return "", err
+	}
+
+	defer func() {
+		if err := os.RemoveAll(dirpath); err != nil {
+			glog.Warningf("Could not remove %s: %s", dirpath, err)
+		}
+	}()
+
+	dfs.log("Extracting export file %s", filename)
+	if err := importTGZ(dirpath, filename); err != nil {
+		glog.Errorf("Could not expand %s to %s: %s", filename, dirpath, err)
+		return "", err
+	}
+
+	var metadata metadata
+	if err := importJSON(filepath.Join(dirpath, meta), &metadata); err != nil {
+		glog.Errorf("Could not import %s: %s", meta, err)
+		return "", err
+	} else if metadata.FSType != dfs.fsType {
+		err = fmt.Errorf("this export can only be imported on %s", metadata.FSType)
+		glog.Errorf("Could not import %s: %s", filename, err)
+		return "", err
+	}
+
+	var bundle bundlemeta
+	if err := importJSON(filepath.Join(dirpath, bundleJSON), &bundle); err != nil {
+		glog.Errorf("Could not read %s from %s: %s", bundleJSON, filename, err)
+		return "", fmt.Errorf("%s is not a service export", filename)
+	}
+
+	if tenant, err := dfs.facade.GetService(dfs.datastoreGet(), bundle.TenantID); err != nil && !datastore.IsErrNoSuchEntity(err) {
+		glog.Errorf("Could not look up service %s: %s", bundle.TenantID, err)
+		return "", err
+	} else if tenant != nil {
+		return "", fmt.Errorf("application %s (%s) already exists", tenant.Name, tenant.ID)
+	}
+	dfs.log("Starting import of %s (%s) to pool %s", bundle.Name, bundle.TenantID, poolID)
+
+	if missing, err := dfs.missingSecrets(bundle.Secrets); err != nil {
+		glog.Errorf("Could not look up secrets: %s", err)
+		return "", err
+	} else if len(missing) > 0 {
+		glog.Warningf("Application %s (%s) refers to secrets that are not set: %s", bundle.Name, bundle.TenantID, strings.Join(missing, ", "))
+		dfs.log("WARNING: set secrets %s with serviced secret set before starting %s", strings.Join(missing, ", "), bundle.Name)
+	}
+
+	dfs.log("Loading docker images")
+	var images []imagemeta
+	if err := importJSON(filepath.Join(dirpath, imageJSON), &images); err != nil {
+		glog.Errorf("Could not read images from %s: %s", filename, err)
+		return "", err
+	}
+	tenantIDs := map[string]struct{}{bundle.TenantID: struct{}{}}
+	if err := dfs.importImages(filepath.Join(dirpath, imageDir), images, tenantIDs); err != nil {
+		glog.Errorf("Could not import images from %s: %s", filename, err)
+		return "", err
+	}
+	dfs.log("Docker image load successful")
+
+	// loading the snapshot adds the services and their address assignments
+	snapshotFile := filepath.Join(dirpath, snapshotDir, fmt.Sprintf("%s.tgz", bundle.TenantID))
+	dfs.log("Loading snapshot of %s (%s)", bundle.Name, bundle.TenantID)
+	if err := dfs.loadSnapshots(bundle.TenantID, snapshotFile, poolID); err != nil {
+		glog.Errorf("Could not import snapshot from %s: %s", filename, err)
+		return "", err
+	}
+	dfs.log("Successfully loaded %s (%s)", bundle.Name, bundle.TenantID)
+
+	glog.Infof("Import of %s (%s) succeeded with fsType:%s from file:%s", bundle.Name, bundle.TenantID, dfs.fsType, filename)
+	return bundle.TenantID, nil
+}
+
+// secretNames returns the names of the secrets that services put in their
+// containers or refer to in their config files and contexts
+func secretNames(svcs []service.Service) []string {
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+	for _, svc := range svcs {
+		for _, s := range svc.Secrets {
+			add(s.Name)
+		}
+		for _, conf := range svc.ConfigFiles {
+			add(secret.References(conf.Content)...)
+		}
+		for _, value := range svc.Context {
+			if text, ok := value.(string); ok {
+				add(secret.References(text)...)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// missingSecrets returns the secrets that are not set
+func (dfs *DistributedFilesystem) missingSecrets(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	secrets, err := dfs.facade.GetSecrets(dfs.datastoreGet())
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	for _, s := range secrets {
+		set[s.Name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !set[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
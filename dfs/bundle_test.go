@@ -0,0 +1,141 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	facadetest "github.com/control-center/serviced/facade/test"
+	"github.com/stretchr/testify/mock"
+	. "gopkg.in/check.v1"
+)
+
+func (st *snapshotTest) TestBundle_ExportService_GetServiceFails(c *C) {
+	errorStub := errors.New("errorStub: GetService() failed")
+	st.mockFacade.
+		On("GetService", st.mock_datastoreGet(), testTenantID).
+		Return(nil, errorStub)
+
+	filename, err := st.dfs.ExportService(testTenantID, "/tmp/export.tgz")
+
+	c.Assert(filename, Equals, "")
+	c.Assert(err, Equals, errorStub)
+}
+
+func (st *snapshotTest) TestBundle_ExportService_NotTenant(c *C) {
+	child := &service.Service{ID: "childID", ParentServiceID: testTenantID}
+	st.mockFacade.
+		On("GetService", st.mock_datastoreGet(), "childID").
+		Return(child, nil)
+	st.mockFacade.
+		On("GetTenantID", st.mock_datastoreGet(), "childID").
+		Return(testTenantID, nil)
+
+	filename, err := st.dfs.ExportService("childID", "/tmp/export.tgz")
+
+	c.Assert(filename, Equals, "")
+	c.Assert(err, ErrorMatches, ".*only whole applications can be exported")
+}
+
+func (st *snapshotTest) TestBundle_ImportService_PoolNotFound(c *C) {
+	st.mockFacade.
+		On("GetResourcePools", st.mock_datastoreGet()).
+		Return([]pool.ResourcePool{{ID: "default"}}, nil)
+
+	tenantID, err := st.dfs.ImportService("/tmp/export.tgz", "missing")
+
+	c.Assert(tenantID, Equals, "")
+	c.Assert(err, ErrorMatches, "pool missing not found")
+}
+
+func (st *snapshotTest) TestBundle_ExportImportService(c *C) {
+	tenant := service.Service{
+		ID:           testTenantID,
+		Name:         "app",
+		DesiredState: int(service.SVCStop),
+		Context:      map[string]interface{}{"token": secret.Reference("api.token")},
+		Secrets:      []servicedefinition.Secret{{Name: "db.password", Env: "DB_PASSWORD"}},
+	}
+	child := service.Service{
+		ID:              "childID",
+		Name:            "web",
+		ParentServiceID: testTenantID,
+		DesiredState:    int(service.SVCPause),
+		ConfigFiles: map[string]servicedefinition.ConfigFile{
+			"/etc/web.conf": {Filename: "/etc/web.conf", Content: "key=" + secret.Reference("tls.key")},
+		},
+	}
+	svcs := []service.Service{tenant, child}
+
+	// export
+	mockVol := st.setupMockSnapshotVolume(c, testTenantID)
+	mockVol.On("Snapshot", mock.AnythingOfTypeArgument("string")).Return(nil)
+	mockVol.On("Snapshots").Return([]string{}, nil)
+	mockVol.On("RemoveSnapshot", mock.AnythingOfTypeArgument("string")).Return(nil)
+	mockClient := st.setupMockDockerClient()
+	mockClient.On("ListImages", false).Return(nil, nil)
+	st.mockFacade.
+		On("GetService", st.mock_datastoreGet(), testTenantID).
+		Return(&tenant, nil)
+	st.mockFacade.
+		On("GetServices", st.mock_datastoreGet(), dao.ServiceRequest{TenantID: testTenantID}).
+		Return(svcs, nil)
+	st.mockFacade.
+		On("WaitService", st.mock_datastoreGet(), service.SVCPause, st.dfs.timeout, []string{tenant.ID, child.ID}).
+		Return(nil)
+
+	exportFile := filepath.Join(st.tmpDir, "app.tgz")
+	filename, err := st.dfs.ExportService(testTenantID, exportFile)
+	c.Assert(err, IsNil)
+	c.Assert(filename, Equals, exportFile)
+
+	// the bundle lists the secrets, but not their values
+	dirpath := filepath.Join(st.tmpDir, "extracted")
+	c.Assert(importTGZ(dirpath, exportFile), IsNil)
+	var bundle bundlemeta
+	c.Assert(importJSON(filepath.Join(dirpath, bundleJSON), &bundle), IsNil)
+	c.Assert(bundle.TenantID, Equals, testTenantID)
+	c.Assert(bundle.Name, Equals, "app")
+	c.Assert(bundle.Secrets, DeepEquals, []string{"api.token", "db.password", "tls.key"})
+	_, err = os.Stat(filepath.Join(dirpath, snapshotDir, testTenantID+".tgz"))
+	c.Assert(err, IsNil)
+
+	// import on a cluster where the application does not exist yet
+	importFacade := &facadetest.MockFacade{}
+	st.dfs.facade = importFacade
+	importFacade.
+		On("GetResourcePools", st.mock_datastoreGet()).
+		Return([]pool.ResourcePool{{ID: "default"}}, nil)
+	importFacade.
+		On("GetService", st.mock_datastoreGet(), testTenantID).
+		Return(nil, nil)
+	importFacade.
+		On("GetSecrets", st.mock_datastoreGet()).
+		Return([]secret.Info{{Name: "db.password"}}, nil)
+
+	tenantID, err := st.dfs.ImportService(exportFile, "default")
+	c.Assert(err, IsNil)
+	c.Assert(tenantID, Equals, testTenantID)
+
+	missing, err := st.dfs.missingSecrets(bundle.Secrets)
+	c.Assert(err, IsNil)
+	c.Assert(missing, DeepEquals, []string{"api.token", "tls.key"})
+}
@@ -47,6 +47,9 @@ type DistributedFilesystem struct {
 	mutex sync.Mutex
 	lock  client.Lock
 
+	// inventories of images
+	inventories inventoryCache
+
 	// logging
 	logger *logger
 }
@@ -35,6 +35,7 @@ import (
 
 const (
 	DockerLatest = "latest"
+	DockerSquash = "squashing" // tag of an image while it is being squashed
 )
 
 type imagemeta struct {
@@ -154,6 +155,19 @@ func (dfs *DistributedFilesystem) Commit(dockerID string) (string, error) {
 		return "", err
 	}
 
+	// keep track of what went into the image
+	record := dao.CommitRecord{
+		ContainerID: ctr.ID,
+		ImageID:     image.ID.String(),
+		ParentUUID:  image.UUID,
+		UUID:        newImage.UUID,
+		SnapshotID:  snapshotID,
+		Committed:   time.Now().UTC(),
+	}
+	if err := dfs.recordCommit(tenantID, record); err != nil {
+		glog.Warningf("Could not record commit of %s to %s: %s", dockerID, image.ID, err)
+	}
+
 	return snapshotID, nil
 }
 
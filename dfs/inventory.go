@@ -0,0 +1,228 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dfs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/control-center/serviced/commons"
+	"github.com/control-center/serviced/commons/docker"
+	"github.com/control-center/serviced/commons/inventory"
+	"github.com/control-center/serviced/dao"
+	"github.com/zenoss/glog"
+)
+
+func (dfs *DistributedFilesystem) commitsPath(tenantID string) string {
+	return filepath.Join(dfs.varpath, "commits", tenantID+".json")
+}
+
+// recordCommit appends a commit to the tenant's commit history
+func (dfs *DistributedFilesystem) recordCommit(tenantID string, record dao.CommitRecord) error {
+	commits, err := dfs.getCommits(tenantID)
+	if err != nil {
+		return err
+	}
+
+	filename := dfs.commitsPath(tenantID)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		glog.Errorf("Could not create directory for %s: %s", filename, err)
+		return err
+	}
+	return exportJSON(filename, append(commits, record))
+}
+
+// getCommits returns the commit history of a tenant, oldest first
+func (dfs *DistributedFilesystem) getCommits(tenantID string) ([]dao.CommitRecord, error) {
+	var commits []dao.CommitRecord
+	filename := dfs.commitsPath(tenantID)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return commits, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := importJSON(filename, &commits); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// maxCachedInventories bounds the number of inventories that are kept, since
+// images are committed and replaced all the time
+var maxCachedInventories = 256
+
+// inventoryCache keeps the inventories of images by docker image id.  The
+// contents of an image never change, so an inventory is only taken once.
+// When the cache is full, the inventories that were used least recently are
+// dropped.
+type inventoryCache struct {
+	mutex  sync.Mutex
+	images map[string]*cachedInventory
+	clock  uint64 // counts the calls to get
+}
+
+type cachedInventory struct {
+	done bool
+	inv  *dao.ImageInventory
+	err  error
+	used uint64 // the clock of the last get of the inventory
+}
+
+// get returns the inventory of an image.  If the inventory was not taken yet,
+// take runs in the background and get returns false until it is done.  An
+// inventory that failed is returned once and then taken again.
+func (c *inventoryCache) get(uuid string, take func() (*dao.ImageInventory, error)) (*dao.ImageInventory, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.images == nil {
+		c.images = make(map[string]*cachedInventory)
+	}
+	c.clock++
+	if cached, ok := c.images[uuid]; ok {
+		cached.used = c.clock
+		if !cached.done {
+			return nil, false, nil
+		} else if cached.err != nil {
+			delete(c.images, uuid)
+			return nil, true, cached.err
+		}
+		return cached.inv, true, nil
+	}
+
+	c.evict(maxCachedInventories - 1)
+	cached := &cachedInventory{used: c.clock}
+	c.images[uuid] = cached
+	go func() {
+		inv, err := take()
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		cached.done, cached.inv, cached.err = true, inv, err
+	}()
+	return nil, false, nil
+}
+
+// evict drops the inventories that were used least recently until at most
+// size are left.  Inventories that are being taken are kept.
+func (c *inventoryCache) evict(size int) {
+	for len(c.images) > size {
+		oldest := ""
+		for uuid, cached := range c.images {
+			if cached.done && (oldest == "" || cached.used < c.images[oldest].used) {
+				oldest = uuid
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		delete(c.images, oldest)
+	}
+}
+
+// Inventory reports the images used by the services of a tenant, along with
+// their layers, digests, installed packages and the history of containers
+// committed to them.  Taking the inventory of an image runs containers from
+// it, so it is done in the background and the image is reported as pending
+// until it is done.
+func (dfs *DistributedFilesystem) Inventory(tenantID string) (*dao.ImageInventoryReport, error) {
+	svcs, err := dfs.facade.GetServices(dfs.datastoreGet(), dao.ServiceRequest{TenantID: tenantID})
+	if err != nil {
+		glog.Errorf("Could not get services for tenant %s: %s", tenantID, err)
+		return nil, err
+	}
+
+	// group the services by image
+	images := make(map[string][]string)
+	for _, svc := range svcs {
+		if svc.ImageID == "" {
+			continue
+		}
+		imageID, err := commons.ParseImageID(svc.ImageID)
+		if err != nil {
+			glog.Errorf("Could not parse image ID (%s) for service %s (%s): %s", svc.ImageID, svc.Name, svc.ID, err)
+			return nil, err
+		}
+		images[imageID.String()] = append(images[imageID.String()], svc.ID)
+	}
+
+	var imageIDs []string
+	for imageID := range images {
+		imageIDs = append(imageIDs, imageID)
+	}
+	sort.Strings(imageIDs)
+
+	report := &dao.ImageInventoryReport{
+		TenantID:  tenantID,
+		Generated: time.Now().UTC(),
+	}
+	for _, imageID := range imageIDs {
+		inv := dao.ImageInventory{ImageID: imageID}
+		image, err := docker.FindImage(imageID, true)
+		if err != nil {
+			glog.Errorf("Could not find image %s: %s", imageID, err)
+			inv.Error = err.Error()
+		} else if cached, done, err := dfs.inventories.get(image.UUID, func() (*dao.ImageInventory, error) {
+			return imageInventory(image)
+		}); err != nil {
+			glog.Errorf("Could not take inventory of image %s: %s", imageID, err)
+			inv.UUID, inv.Error = image.UUID, err.Error()
+		} else if !done {
+			inv.UUID, inv.Pending = image.UUID, true
+		} else {
+			inv = *cached
+			inv.ImageID = imageID
+		}
+		inv.Services = images[imageID]
+		sort.Strings(inv.Services)
+		report.Images = append(report.Images, inv)
+	}
+
+	if report.Commits, err = dfs.getCommits(tenantID); err != nil {
+		glog.Errorf("Could not get commit history for tenant %s: %s", tenantID, err)
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// imageInventory takes the inventory of an image
+func imageInventory(image *docker.Image) (*dao.ImageInventory, error) {
+	inv := &dao.ImageInventory{ImageID: image.ID.String(), UUID: image.UUID}
+
+	digests, err := docker.ImageDigests(image.UUID)
+	if err != nil {
+		return nil, err
+	}
+	inv.Digests = digests
+
+	history, err := image.History()
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range history {
+		inv.Layers = append(inv.Layers, layer.ID)
+	}
+	if len(history) > 0 {
+		inv.Created = history[0].Created
+	}
+
+	if inv.Packages, err = inventory.ImagePackages(image.UUID); err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dfs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	. "gopkg.in/check.v1"
+)
+
+type inventoryTest struct{}
+
+var _ = Suite(&inventoryTest{})
+
+// waitInventory calls get until the inventory is no longer pending
+func waitInventory(c *C, cache *inventoryCache, uuid string, take func() (*dao.ImageInventory, error)) (*dao.ImageInventory, error) {
+	for i := 0; i < 100; i++ {
+		if inv, done, err := cache.get(uuid, take); done {
+			return inv, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("inventory of %s was not taken", uuid)
+	return nil, nil
+}
+
+func (it *inventoryTest) TestInventoryCache(c *C) {
+	var cache inventoryCache
+	taken := 0
+	release := make(chan struct{})
+	take := func() (*dao.ImageInventory, error) {
+		<-release
+		taken++
+		return &dao.ImageInventory{UUID: "abc", Layers: []string{"abc", "def"}}, nil
+	}
+
+	// the inventory is taken in the background
+	inv, done, err := cache.get("abc", take)
+	c.Assert(err, IsNil)
+	c.Assert(done, Equals, false)
+	c.Assert(inv, IsNil)
+	_, done, _ = cache.get("abc", take)
+	c.Assert(done, Equals, false)
+	close(release)
+
+	inv, err = waitInventory(c, &cache, "abc", take)
+	c.Assert(err, IsNil)
+	c.Assert(inv.Layers, DeepEquals, []string{"abc", "def"})
+
+	// and only once
+	inv, err = waitInventory(c, &cache, "abc", take)
+	c.Assert(err, IsNil)
+	c.Assert(taken, Equals, 1)
+}
+
+func (it *inventoryTest) TestInventoryCacheError(c *C) {
+	var cache inventoryCache
+	fail := true
+	take := func() (*dao.ImageInventory, error) {
+		if fail {
+			return nil, errors.New("no shell in the image")
+		}
+		return &dao.ImageInventory{UUID: "abc"}, nil
+	}
+
+	_, err := waitInventory(c, &cache, "abc", take)
+	c.Assert(err, ErrorMatches, "no shell in the image")
+
+	// a failed inventory is taken again
+	fail = false
+	inv, err := waitInventory(c, &cache, "abc", take)
+	c.Assert(err, IsNil)
+	c.Assert(inv.UUID, Equals, "abc")
+}
+
+func (it *inventoryTest) TestInventoryCacheEvict(c *C) {
+	defer func(max int) { maxCachedInventories = max }(maxCachedInventories)
+	maxCachedInventories = 2
+	var cache inventoryCache
+	taken := make(map[string]int)
+	take := func(uuid string) func() (*dao.ImageInventory, error) {
+		return func() (*dao.ImageInventory, error) {
+			taken[uuid]++
+			return &dao.ImageInventory{UUID: uuid}, nil
+		}
+	}
+
+	waitInventory(c, &cache, "a", take("a"))
+	waitInventory(c, &cache, "b", take("b"))
+	// a is used after b, so b is dropped to make room for c
+	waitInventory(c, &cache, "a", take("a"))
+	waitInventory(c, &cache, "c", take("c"))
+	c.Assert(cache.images, HasLen, 2)
+
+	waitInventory(c, &cache, "a", take("a"))
+	waitInventory(c, &cache, "b", take("b"))
+	c.Assert(taken, DeepEquals, map[string]int{"a": 1, "b": 2, "c": 1})
+	c.Assert(cache.images, HasLen, 2)
+}
@@ -0,0 +1,312 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/control-center/serviced/commons/docker"
+	"github.com/control-center/serviced/commons/layer"
+	"github.com/control-center/serviced/dao"
+	"github.com/zenoss/glog"
+)
+
+// MaintainImages squashes the latest image of every tenant that has more
+// than maxLayers layers, removes untagged images from the docker host and
+// removes tenant tags from the docker registry that are no longer referenced
+// by a snapshot.  A maxLayers of zero disables squashing.  The dfs lock is
+// only held while a squashed image replaces the latest image, so snapshots and
+// deploys can run during the maintenance.
+func (dfs *DistributedFilesystem) MaintainImages(maxLayers int) (*dao.ImageMaintenanceReport, error) {
+	report := &dao.ImageMaintenanceReport{}
+	started := time.Now().UTC()
+
+	before, err := docker.ImageDiskUsage()
+	if err != nil {
+		glog.Errorf("Could not compute image disk usage: %s", err)
+		return nil, err
+	}
+
+	tenantIDs, err := dfs.getTenantIDs()
+	if err != nil {
+		glog.Errorf("Could not look up tenants: %s", err)
+		return nil, err
+	}
+
+	if maxLayers > 0 {
+		for _, tenantID := range tenantIDs {
+			squashed, err := dfs.squashImages(tenantID, maxLayers)
+			if err != nil {
+				glog.Errorf("Could not squash images for tenant %s: %s", tenantID, err)
+				return nil, err
+			}
+			report.SquashedImages = append(report.SquashedImages, squashed...)
+		}
+	}
+
+	for _, tenantID := range tenantIDs {
+		removed, err := dfs.removeStaleTags(tenantID, started)
+		if err != nil {
+			// the registry may be temporarily unavailable, so don't give up
+			// on the rest of the maintenance
+			glog.Warningf("Could not remove stale registry tags for tenant %s: %s", tenantID, err)
+			continue
+		}
+		report.RemovedTags = append(report.RemovedTags, removed...)
+	}
+
+	if report.RemovedImages, err = docker.RemoveUntaggedImages(); err != nil {
+		glog.Errorf("Could not remove untagged images: %s", err)
+		return nil, err
+	}
+
+	after, err := docker.ImageDiskUsage()
+	if err != nil {
+		glog.Errorf("Could not compute image disk usage: %s", err)
+		return nil, err
+	}
+	if after < before {
+		report.ReclaimedBytes = before - after
+	}
+
+	dfs.log("Image maintenance squashed %d images, removed %d images and %d registry tags, reclaimed %d bytes",
+		len(report.SquashedImages), len(report.RemovedImages), len(report.RemovedTags), report.ReclaimedBytes)
+	return report, nil
+}
+
+func (dfs *DistributedFilesystem) getTenantIDs() ([]string, error) {
+	svcs, err := dfs.facade.GetServices(dfs.datastoreGet(), dao.ServiceRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var tenantIDs []string
+	for _, svc := range svcs {
+		if svc.ParentServiceID == "" {
+			tenantIDs = append(tenantIDs, svc.ID)
+		}
+	}
+	return tenantIDs, nil
+}
+
+// squashImages flattens the latest images of a tenant that exceed maxLayers
+func (dfs *DistributedFilesystem) squashImages(tenantID string, maxLayers int) ([]string, error) {
+	images, err := findImages(tenantID, DockerLatest)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := docker.NewClient(fmt.Sprintf("%s:%d", dfs.dockerHost, dfs.dockerPort))
+	if err != nil {
+		return nil, err
+	}
+
+	var squashed []string
+	for _, image := range images {
+		history, err := image.History()
+		if err != nil {
+			glog.Errorf("Could not check history for image %s: %s", image.ID, err)
+			return nil, err
+		} else if len(history) <= maxLayers {
+			glog.V(2).Infof("Image %s has %d layers; not squashing", image.ID, len(history))
+			continue
+		}
+
+		// keep the bottom half of the allowed layers and flatten everything
+		// above it into a single layer, so the image config (entrypoint,
+		// environment, etc.) is inherited from the base layer
+		baseLayer := history[len(history)-squashDepth(maxLayers)].ID
+
+		// squash into a temporary tag and push it, so that its layers are in
+		// the registry before it replaces the latest image
+		squashID := image.ID
+		squashID.Tag = DockerSquash
+		dfs.log("Squashing image %s (%d layers) down to %s", image.ID, len(history), baseLayer)
+		imageID, err := layer.Squash(client, image.ID.String(), baseLayer, squashID.String(), "")
+		if err != nil {
+			glog.Errorf("Could not squash image %s: %s", image.ID, err)
+			return nil, err
+		}
+		glog.Infof("Squashed image %s into %s", image.ID, imageID)
+
+		if err := docker.PushImage(squashID.String()); err != nil {
+			glog.Errorf("Could not push squashed image %s: %s", squashID, err)
+			return nil, err
+		}
+
+		if ok, err := dfs.replaceLatest(image, squashID.String()); err != nil {
+			return nil, err
+		} else if ok {
+			squashed = append(squashed, image.ID.String())
+		}
+	}
+	return squashed, nil
+}
+
+// replaceLatest tags the squashed image as the latest image of its repository
+// and removes its temporary tag.  It returns false if the latest image was
+// committed again while it was squashed.
+func (dfs *DistributedFilesystem) replaceLatest(image *docker.Image, squashName string) (bool, error) {
+	dfs.Lock()
+	defer dfs.Unlock()
+
+	squashed, err := docker.FindImage(squashName, false)
+	if err != nil {
+		glog.Errorf("Could not find squashed image %s: %s", squashName, err)
+		return false, err
+	}
+	defer func() {
+		if err := squashed.Delete(); err != nil {
+			glog.Warningf("Could not remove tag %s: %s", squashName, err)
+		}
+	}()
+
+	latest, err := docker.FindImage(image.ID.String(), false)
+	if err != nil {
+		glog.Errorf("Could not find image %s: %s", image.ID, err)
+		return false, err
+	} else if latest.UUID != image.UUID {
+		glog.Warningf("Image %s changed while it was squashed; not replacing it", image.ID)
+		return false, nil
+	}
+
+	if _, err := squashed.Tag(image.ID.String()); err != nil {
+		glog.Errorf("Could not tag squashed image %s as %s: %s", squashName, image.ID, err)
+		return false, err
+	} else if err := docker.PushImage(image.ID.String()); err != nil {
+		glog.Errorf("Could not push squashed image %s: %s", image.ID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// squashDepth returns the number of base layers to preserve when an image
+// exceeds maxLayers
+func squashDepth(maxLayers int) int {
+	if depth := maxLayers / 2; depth > 0 {
+		return depth
+	}
+	return 1
+}
+
+// removeStaleTags deletes the registry tags of a tenant's repositories that
+// are neither latest nor referenced by an existing snapshot.  Snapshot tags
+// from since on are kept, because their snapshots may still be in progress.
+func (dfs *DistributedFilesystem) removeStaleTags(tenantID string, since time.Time) ([]string, error) {
+	snapshots, err := dfs.ListSnapshots(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[string]struct{}{DockerLatest: struct{}{}}
+	for _, snapshot := range snapshots {
+		if _, timestamp, err := parseLabel(snapshot.SnapshotID); err == nil {
+			keep[timestamp] = struct{}{}
+		}
+	}
+
+	images, err := searchImagesByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make(map[string]struct{})
+	for _, image := range images {
+		repos[fmt.Sprintf("%s/%s", image.ID.User, image.ID.Repo)] = struct{}{}
+	}
+
+	var removed []string
+	for repo := range repos {
+		tags, err := dfs.getRegistryTags(repo)
+		if err != nil {
+			return removed, err
+		}
+
+		for _, tag := range staleTags(tags, keep, since) {
+			glog.Infof("Removing stale tag %s:%s from the docker registry", repo, tag)
+			if err := dfs.deleteRegistryTag(repo, tag); err != nil {
+				return removed, err
+			}
+			removed = append(removed, fmt.Sprintf("%s:%s", repo, tag))
+		}
+	}
+	return removed, nil
+}
+
+// staleTags returns the sorted list of tags that are not in keep and are not
+// snapshot tags from since on
+func staleTags(tags map[string]string, keep map[string]struct{}, since time.Time) []string {
+	var stale []string
+	for tag := range tags {
+		if _, ok := keep[tag]; ok {
+			continue
+		} else if t, err := time.Parse(timeFormat, tag); err == nil && !t.Before(since.Truncate(time.Second)) {
+			continue
+		}
+		stale = append(stale, tag)
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+func (dfs *DistributedFilesystem) registryURL(format string, args ...interface{}) string {
+	return fmt.Sprintf("http://%s:%d", dfs.dockerHost, dfs.dockerPort) + fmt.Sprintf(format, args...)
+}
+
+// getRegistryTags returns the tags of a repository in the docker registry
+// mapped to their image ids
+func (dfs *DistributedFilesystem) getRegistryTags(repo string) (map[string]string, error) {
+	resp, err := http.Get(dfs.registryURL("/v1/repositories/%s/tags", repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// the repository was never pushed
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("registry returned %s for repository %s", resp.Status, repo)
+	}
+
+	var tags map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// deleteRegistryTag removes a tag from a repository in the docker registry
+func (dfs *DistributedFilesystem) deleteRegistryTag(repo, tag string) error {
+	req, err := http.NewRequest("DELETE", dfs.registryURL("/v1/repositories/%s/tags/%s", repo, tag), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("registry returned %s deleting %s:%s", resp.Status, repo, tag)
+	}
+	return nil
+}
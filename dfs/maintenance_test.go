@@ -0,0 +1,52 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dfs
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type maintenanceTest struct{}
+
+var _ = Suite(&maintenanceTest{})
+
+func (mt *maintenanceTest) TestStaleTags(c *C) {
+	tags := map[string]string{
+		"latest":          "abc",
+		"20150102-030405": "abc",
+		"20150101-000000": "def",
+		"devel":           "ghi",
+	}
+	keep := map[string]struct{}{
+		"latest":          struct{}{},
+		"20150102-030405": struct{}{},
+	}
+	since := time.Date(2015, 1, 3, 0, 0, 0, 0, time.UTC)
+	c.Assert(staleTags(tags, keep, since), DeepEquals, []string{"20150101-000000", "devel"})
+	c.Assert(staleTags(nil, keep, since), IsNil)
+
+	// snapshots taken during the maintenance keep their tags
+	tags["20150103-000000"] = "jkl"
+	c.Assert(staleTags(tags, keep, since), DeepEquals, []string{"20150101-000000", "devel"})
+	since = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(staleTags(tags, keep, since), DeepEquals, []string{"devel"})
+}
+
+func (mt *maintenanceTest) TestSquashDepth(c *C) {
+	c.Assert(squashDepth(109), Equals, 54)
+	c.Assert(squashDepth(2), Equals, 1)
+	c.Assert(squashDepth(1), Equals, 1)
+}
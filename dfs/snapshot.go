@@ -119,6 +119,12 @@ func (dfs *DistributedFilesystem) Snapshot(tenantID string, description string)
 
 // Rollback rolls back the dfs and docker images to the state of a given snapshot
 func (dfs *DistributedFilesystem) Rollback(snapshotID string, forceRestart bool) error {
+	return dfs.rollback(snapshotID, forceRestart, "")
+}
+
+// rollback rolls back to a snapshot, moving the restored services to poolID
+// if it is set
+func (dfs *DistributedFilesystem) rollback(snapshotID string, forceRestart bool, poolID string) error {
 	tenantID, timestamp, err := parseLabel(snapshotID)
 	if err != nil {
 		glog.Errorf("Could not rollback snapshot %s: %s", snapshotID, err)
@@ -206,7 +212,7 @@ func (dfs *DistributedFilesystem) Rollback(snapshotID string, forceRestart bool)
 		return err
 	}
 
-	if err := dfs.restoreServices(tenantID, restore); err != nil {
+	if err := dfs.restoreServices(tenantID, restore, poolID); err != nil {
 		glog.Errorf("Could not restore services from %s: %s", snapshotID, err)
 		return err
 	}
@@ -329,7 +335,7 @@ func (dfs *DistributedFilesystem) DeleteSnapshots(tenantID string) error {
 	return nil
 }
 
-func (dfs *DistributedFilesystem) restoreServices(tenantID string, svcs []*service.Service) error {
+func (dfs *DistributedFilesystem) restoreServices(tenantID string, svcs []*service.Service, poolID string) error {
 	// get the resource pools
 	pools, err := dfs.facade.GetResourcePools(dfs.datastoreGet())
 	if err != nil {
@@ -379,7 +385,9 @@ func (dfs *DistributedFilesystem) restoreServices(tenantID string, svcs []*servi
 			}
 
 			// check the pool
-			if _, ok := poolMap[svc.PoolID]; !ok {
+			if poolID != "" {
+				svc.PoolID = poolID
+			} else if _, ok := poolMap[svc.PoolID]; !ok {
 				glog.Warningf("Could not find pool %s for %s (%s). Setting pool to default.", svc.PoolID, svc.Name, svc.ID)
 				svc.PoolID = "default"
 			}
@@ -16,6 +16,7 @@ package domain
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"time"
 )
 
@@ -57,27 +58,65 @@ func (minmax *MinMax) Validate() error {
 	return nil
 }
 
+// Types of health check. A script check runs a shell script in the
+// container; the others are made by the controller itself against a port in
+// the container.
+const (
+	HealthCheckScript = "script"
+	HealthCheckHTTP   = "http"
+	HealthCheckTCP    = "tcp"
+	HealthCheckGRPC   = "grpc"
+)
+
 // HealthCheck is a health check object
 type HealthCheck struct {
-	Script   string        // A script to execute to verify the health of a service.
-	Interval time.Duration // The interval at which to execute the script.
-	Timeout  time.Duration // A timeout in which to complete the health check.
+	Type             string        // script (the default), http, tcp or grpc
+	Script           string        // A script to execute to verify the health of a service.
+	Port             uint16        // The port in the container an http, tcp or grpc check connects to.
+	Path             string        // The path an http check requests.
+	ExpectedStatus   int           // The status an http check must return; any 2xx status if 0.
+	ExpectedBody     string        // A regular expression the body of an http check must match.
+	GRPCService      string        // The service a grpc check asks about; the whole server if empty.
+	Interval         time.Duration // The interval at which to execute the script.
+	Timeout          time.Duration // A timeout in which to complete the health check.
+	InitialDelay     time.Duration // Time to wait after the service starts before the first check.
+	FailureThreshold int           // Consecutive failures before the check is reported failed; 1 if 0.
+	SuccessThreshold int           // Consecutive successes before the check is reported passed; 1 if 0.
 }
 
 type jsonHealthCheck struct {
-	Script   string
-	Interval float64 // the serialzed version will be in seconds
-	Timeout  float64
+	Type             string `json:",omitempty"`
+	Script           string
+	Port             uint16 `json:",omitempty"`
+	Path             string `json:",omitempty"`
+	ExpectedStatus   int    `json:",omitempty"`
+	ExpectedBody     string `json:",omitempty"`
+	GRPCService      string `json:",omitempty"`
+	Interval         float64 // the serialzed version will be in seconds
+	Timeout          float64
+	InitialDelay     float64 `json:",omitempty"`
+	FailureThreshold int     `json:",omitempty"`
+	SuccessThreshold int     `json:",omitempty"`
 }
 
 func (hc HealthCheck) MarshalJSON() ([]byte, error) {
 	// in json, the interval is represented in seconds
 	interval := float64(hc.Interval) / 1000000000.0
 	timeout := float64(hc.Timeout) / 1000000000.0
+	initialDelay := float64(hc.InitialDelay) / 1000000000.0
 	return json.Marshal(jsonHealthCheck{
-		Script:   hc.Script,
-		Interval: interval,
-		Timeout:  timeout,
+		Type:             hc.Type,
+		Script:           hc.Script,
+		Port:             hc.Port,
+		Path:             hc.Path,
+		ExpectedStatus:   hc.ExpectedStatus,
+		ExpectedBody:     hc.ExpectedBody,
+		GRPCService:      hc.GRPCService,
+		Interval:         interval,
+		Timeout:          timeout,
+		InitialDelay:     initialDelay,
+		FailureThreshold: hc.FailureThreshold,
+		SuccessThreshold: hc.SuccessThreshold,
 	})
 }
 
@@ -86,10 +125,44 @@ func (hc *HealthCheck) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &tempHc); err != nil {
 		return err
 	}
+	hc.Type = tempHc.Type
 	hc.Script = tempHc.Script
+	hc.Port = tempHc.Port
+	hc.Path = tempHc.Path
+	hc.ExpectedStatus = tempHc.ExpectedStatus
+	hc.ExpectedBody = tempHc.ExpectedBody
+	hc.GRPCService = tempHc.GRPCService
 	// interval in js is in seconds, convert to nanoseconds, then duration
 	hc.Interval = time.Duration(tempHc.Interval * 1000000000.0)
 	hc.Timeout = time.Duration(tempHc.Timeout * 1000000000.0)
+	hc.InitialDelay = time.Duration(tempHc.InitialDelay * 1000000000.0)
+	hc.FailureThreshold = tempHc.FailureThreshold
+	hc.SuccessThreshold = tempHc.SuccessThreshold
+	return nil
+}
+
+// Validate ensures a health check has what its type needs
+func (hc HealthCheck) Validate() error {
+	switch hc.Type {
+	case "", HealthCheckScript:
+	case HealthCheckHTTP, HealthCheckTCP, HealthCheckGRPC:
+		if hc.Port == 0 {
+			return fmt.Errorf("%s health check requires a port", hc.Type)
+		}
+	default:
+		return fmt.Errorf("unknown health check type: %s", hc.Type)
+	}
+	if hc.ExpectedBody != "" {
+		if _, err := regexp.Compile(hc.ExpectedBody); err != nil {
+			return fmt.Errorf("invalid expected body: %s", err)
+		}
+	}
+	if hc.ExpectedStatus != 0 && (hc.ExpectedStatus < 100 || hc.ExpectedStatus > 599) {
+		return fmt.Errorf("invalid expected status: %d", hc.ExpectedStatus)
+	}
+	if hc.InitialDelay < 0 || hc.FailureThreshold < 0 || hc.SuccessThreshold < 0 {
+		return fmt.Errorf("initial delay and thresholds cannot be negative")
+	}
 	return nil
 }
 
@@ -44,3 +44,42 @@ func TestHealthCheck(t *testing.T) {
 	}
 
 }
+
+func TestHealthCheckValidate(t *testing.T) {
+	for i, tc := range []struct {
+		hc    HealthCheck
+		valid bool
+	}{
+		{HealthCheck{Script: "true"}, true},
+		{HealthCheck{Type: HealthCheckHTTP, Port: 8080, Path: "/health", ExpectedStatus: 200, ExpectedBody: "ok"}, true},
+		{HealthCheck{Type: HealthCheckTCP, Port: 22}, true},
+		{HealthCheck{Type: HealthCheckGRPC}, false},
+		{HealthCheck{Type: HealthCheckHTTP, Port: 8080, ExpectedBody: "("}, false},
+		{HealthCheck{Type: HealthCheckHTTP, Port: 8080, ExpectedStatus: 42}, false},
+		{HealthCheck{Type: "udp", Port: 53}, false},
+		{HealthCheck{Script: "true", FailureThreshold: -1}, false},
+	} {
+		if err := tc.hc.Validate(); (err == nil) != tc.valid {
+			t.Errorf("check %d: expected valid %v, got %v", i, tc.valid, err)
+		}
+	}
+}
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	policy := RestartPolicy{BackoffSeconds: 10, MaxBackoffSeconds: 60}
+	for n, expected := range map[int]time.Duration{
+		1: 0,
+		2: 10 * time.Second,
+		3: 20 * time.Second,
+		4: 40 * time.Second,
+		5: 60 * time.Second,
+		9: 60 * time.Second,
+	} {
+		if delay := policy.Backoff(n); delay != expected {
+			t.Errorf("restart %d: expected %s, got %s", n, expected, delay)
+		}
+	}
+	if err := (RestartPolicy{MaxRestarts: 3}).Validate(); err == nil {
+		t.Errorf("expected max restarts without a window to be invalid")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/utils"
+
+	"time"
+)
+
+// Source types of an event
+const (
+	SourceService = "service"
+	SourcePool    = "pool"
+)
+
+// Event records a threshold of a monitoring profile being violated or cleared
+type Event struct {
+	ID            string
+	ThresholdID   string
+	ThresholdName string
+	ThresholdType string // MinMax, Duration, ValueChange or HoltWinters
+	SourceType    string // service or pool
+	SourceID      string // id of the service or pool whose profile holds the threshold
+	InstanceID    string // instance of the service, for thresholds applied to running services
+	MetricSource  string
+	Metric        string
+	Value         float64
+	Predicted     *float64 `json:"Predicted,omitempty"` // value expected by a HoltWinters threshold
+	Cleared       bool     // the threshold is no longer violated
+	Summary       string
+	Tags          map[string]interface{} // EventTags of the threshold
+	Timestamp     time.Time
+	datastore.VersionedEntity
+}
+
+// New creates an Event
+func New(sourceType, sourceID, thresholdID string) (*Event, error) {
+	uuid, err := utils.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &Event{ID: uuid, SourceType: sourceType, SourceID: sourceID, ThresholdID: thresholdID, Timestamp: time.Now().UTC()}, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"github.com/control-center/serviced/datastore/elastic"
+	"github.com/zenoss/glog"
+)
+
+var (
+	mappingString = `
+{
+	"event": {
+	  "properties": {
+		"ID" :          {"type": "string", "index":"not_analyzed"},
+		"ThresholdID":  {"type": "string", "index":"not_analyzed"},
+		"SourceType":   {"type": "string", "index":"not_analyzed"},
+		"SourceID":     {"type": "string", "index":"not_analyzed"},
+		"InstanceID":   {"type": "string", "index":"not_analyzed"},
+		"MetricSource": {"type": "string", "index":"not_analyzed"},
+		"Metric":       {"type": "string", "index":"not_analyzed"},
+		"Timestamp":    {"type": "date", "format" : "dateOptionalTime"},
+		"Tags":         {"type": "object", "enabled": false}
+	  }
+	}
+}
+`
+	//MAPPING is the elastic mapping for an event
+	MAPPING, mappingError = elastic.NewMapping(mappingString)
+)
+
+func init() {
+	if mappingError != nil {
+		glog.Fatalf("error creating event mapping: %v", mappingError)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/zenoss/elastigo/search"
+
+	"strconv"
+	"time"
+)
+
+// DefaultLimit is the number of events returned when no limit is given
+const DefaultLimit = 100
+
+// NewStore creates an Event store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Store type for interacting with Event persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+// GetEvents returns the most recent events since a time, newest first. If
+// sourceID is set, only the events of that service or pool are returned.
+func (s *Store) GetEvents(ctx datastore.Context, since time.Time, sourceID string, limit int) ([]Event, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	queryString := "_exists_:ID"
+	if sourceID != "" {
+		queryString = "SourceID:" + strconv.Quote(sourceID)
+	}
+	query := search.Query().Range(search.Range().Field("Timestamp").From(since.UTC().Format(time.RFC3339))).Search(queryString)
+	search := search.Search("controlplane").Type(kind).Size(strconv.Itoa(limit)).Sort(search.Sort("Timestamp").Desc()).Query(query)
+
+	q := datastore.NewQuery(ctx)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	return convert(results)
+}
+
+func convert(results datastore.Results) ([]Event, error) {
+	events := make([]Event, results.Len())
+	for idx := range events {
+		if err := results.Get(idx, &events[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// Key creates a Key suitable for getting, putting and deleting Events
+func Key(id string) datastore.Key {
+	return datastore.NewKey(kind, id)
+}
+
+var kind = "event"
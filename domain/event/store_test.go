@@ -0,0 +1,89 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/datastore/elastic"
+	. "gopkg.in/check.v1"
+
+	"testing"
+	"time"
+)
+
+// This plumbs gocheck into testing
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{
+	ElasticTest: elastic.ElasticTest{
+		Index:    "controlplane",
+		Mappings: []elastic.Mapping{MAPPING},
+	}})
+
+type S struct {
+	elastic.ElasticTest
+	ctx datastore.Context
+	es  *Store
+}
+
+func (s *S) SetUpTest(c *C) {
+	s.ElasticTest.SetUpTest(c)
+	datastore.Register(s.Driver())
+	s.ctx = datastore.Get()
+	s.es = NewStore()
+}
+
+func (s *S) Test_EventCRUD(c *C) {
+	evt, err := New(SourceService, "service_id", "threshold_id")
+	c.Assert(err, IsNil)
+	evt.Value = 42
+	c.Assert(s.es.Put(s.ctx, Key(evt.ID), evt), IsNil)
+
+	var evt2 Event
+	c.Assert(s.es.Get(s.ctx, Key(evt.ID), &evt2), IsNil)
+	c.Assert(evt2.Value, Equals, float64(42))
+
+	c.Assert(s.es.Delete(s.ctx, Key(evt.ID)), IsNil)
+	err = s.es.Get(s.ctx, Key(evt.ID), &evt2)
+	c.Assert(datastore.IsErrNoSuchEntity(err), Equals, true)
+}
+
+func (s *S) Test_GetEvents(c *C) {
+	now := time.Now().UTC()
+	for i, sourceID := range []string{"a", "b", "a"} {
+		evt, err := New(SourceService, sourceID, "threshold_id")
+		c.Assert(err, IsNil)
+		evt.Timestamp = now.Add(time.Duration(i-3) * time.Hour)
+		c.Assert(s.es.Put(s.ctx, Key(evt.ID), evt), IsNil)
+	}
+
+	events, err := s.es.GetEvents(s.ctx, now.Add(-24*time.Hour), "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(events), Equals, 3)
+	c.Assert(events[0].Timestamp.After(events[1].Timestamp), Equals, true)
+
+	events, err = s.es.GetEvents(s.ctx, now.Add(-24*time.Hour), "a", 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(events), Equals, 2)
+
+	events, err = s.es.GetEvents(s.ctx, now.Add(-90*time.Minute), "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(len(events), Equals, 1)
+
+	events, err = s.es.GetEvents(s.ctx, now.Add(-24*time.Hour), "", 2)
+	c.Assert(err, IsNil)
+	c.Assert(len(events), Equals, 2)
+}
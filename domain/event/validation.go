@@ -0,0 +1,35 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"github.com/control-center/serviced/validation"
+)
+
+// ValidEntity check if fields are valid
+func (e Event) ValidEntity() error {
+	vErr := validation.NewValidationError()
+	vErr.Add(validation.NotEmpty("ID", e.ID))
+	vErr.Add(validation.NotEmpty("ThresholdID", e.ThresholdID))
+	vErr.Add(validation.NotEmpty("SourceID", e.SourceID))
+	vErr.Add(validation.StringIn(e.SourceType, SourceService, SourcePool))
+	if e.Timestamp.IsZero() {
+		vErr.AddViolation("field Timestamp must be set")
+	}
+
+	if vErr.HasError() {
+		return vErr
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthhistory
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/utils"
+
+	"time"
+)
+
+// Statuses of a health check result
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed"
+)
+
+// Result records a period during which a health check of a service instance
+// kept the same status
+type Result struct {
+	ID         string
+	ServiceID  string
+	InstanceID string
+	Name       string // name of the health check
+	Status     string // passed or failed
+	Start      time.Time
+	End        time.Time
+	datastore.VersionedEntity
+}
+
+// NewResult creates a Result
+func NewResult(serviceID, instanceID, name, status string, start, end time.Time) (*Result, error) {
+	uuid, err := utils.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		ID:         uuid,
+		ServiceID:  serviceID,
+		InstanceID: instanceID,
+		Name:       name,
+		Status:     status,
+		Start:      start.UTC(),
+		End:        end.UTC(),
+	}, nil
+}
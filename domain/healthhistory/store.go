@@ -0,0 +1,88 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthhistory
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/zenoss/elastigo/search"
+
+	"strconv"
+	"time"
+)
+
+// pageSize is the number of results fetched by each search request
+var pageSize = 1000
+
+// NewStore creates a Result store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Store type for interacting with Result persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+// GetResults returns the results of a service that ended at or after since,
+// oldest first
+func (s *Store) GetResults(ctx datastore.Context, serviceID string, since time.Time) ([]Result, error) {
+	queryString := "ServiceID:" + strconv.Quote(serviceID)
+	query := search.Query().Range(search.Range().Field("End").From(since.UTC().Format(time.RFC3339))).Search(queryString)
+	return s.search(ctx, query)
+}
+
+// GetResultsBefore returns the results of all services that ended before a
+// time
+func (s *Store) GetResultsBefore(ctx datastore.Context, before time.Time) ([]Result, error) {
+	query := search.Query().Range(search.Range().Field("End").To(before.UTC().Format(time.RFC3339))).Search("_exists_:ID")
+	return s.search(ctx, query)
+}
+
+// search pages through every result matching the query, oldest first
+func (s *Store) search(ctx datastore.Context, query *search.QueryDsl) ([]Result, error) {
+	var history []Result
+	q := datastore.NewQuery(ctx)
+	for from := 0; ; from += pageSize {
+		search := search.Search("controlplane").Type(kind).From(strconv.Itoa(from)).Size(strconv.Itoa(pageSize)).Sort(search.Sort("Start"), search.Sort("ID")).Query(query)
+		results, err := q.Execute(search)
+		if err != nil {
+			return nil, err
+		}
+		page, err := convert(results)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, page...)
+		if len(page) < pageSize {
+			return history, nil
+		}
+	}
+}
+
+func convert(results datastore.Results) ([]Result, error) {
+	history := make([]Result, results.Len())
+	for idx := range history {
+		if err := results.Get(idx, &history[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// Key creates a Key suitable for getting, putting and deleting Results
+func Key(id string) datastore.Key {
+	return datastore.NewKey(kind, id)
+}
+
+var kind = "healthresult"
@@ -0,0 +1,95 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthhistory
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/datastore/elastic"
+	. "gopkg.in/check.v1"
+
+	"testing"
+	"time"
+)
+
+// This plumbs gocheck into testing
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{
+	ElasticTest: elastic.ElasticTest{
+		Index:    "controlplane",
+		Mappings: []elastic.Mapping{MAPPING},
+	}})
+
+type S struct {
+	elastic.ElasticTest
+	ctx datastore.Context
+	rs  *Store
+}
+
+func (s *S) SetUpTest(c *C) {
+	s.ElasticTest.SetUpTest(c)
+	datastore.Register(s.Driver())
+	s.ctx = datastore.Get()
+	s.rs = NewStore()
+}
+
+func (s *S) Test_GetResults(c *C) {
+	now := time.Now().UTC()
+	for i, serviceID := range []string{"a", "b", "a"} {
+		start := now.Add(time.Duration(i-3) * time.Hour)
+		result, err := NewResult(serviceID, "0", "running", StatusPassed, start, start.Add(30*time.Minute))
+		c.Assert(err, IsNil)
+		c.Assert(s.rs.Put(s.ctx, Key(result.ID), result), IsNil)
+	}
+
+	results, err := s.rs.GetResults(s.ctx, "a", now.Add(-24*time.Hour))
+	c.Assert(err, IsNil)
+	c.Assert(len(results), Equals, 2)
+	c.Assert(results[0].Start.Before(results[1].Start), Equals, true)
+
+	results, err = s.rs.GetResults(s.ctx, "a", now.Add(-90*time.Minute))
+	c.Assert(err, IsNil)
+	c.Assert(len(results), Equals, 1)
+
+	results, err = s.rs.GetResultsBefore(s.ctx, now.Add(-150*time.Minute))
+	c.Assert(err, IsNil)
+	c.Assert(len(results), Equals, 1)
+	c.Assert(results[0].ServiceID, Equals, "a")
+}
+
+func (s *S) Test_GetResultsPaged(c *C) {
+	defer func(size int) { pageSize = size }(pageSize)
+	pageSize = 2
+
+	now := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		start := now.Add(time.Duration(i-5) * time.Hour)
+		result, err := NewResult("a", "0", "running", StatusPassed, start, start.Add(30*time.Minute))
+		c.Assert(err, IsNil)
+		c.Assert(s.rs.Put(s.ctx, Key(result.ID), result), IsNil)
+	}
+
+	results, err := s.rs.GetResults(s.ctx, "a", now.Add(-24*time.Hour))
+	c.Assert(err, IsNil)
+	c.Assert(len(results), Equals, 5)
+	for i := 1; i < len(results); i++ {
+		c.Assert(results[i-1].Start.Before(results[i].Start), Equals, true)
+	}
+
+	results, err = s.rs.GetResultsBefore(s.ctx, now)
+	c.Assert(err, IsNil)
+	c.Assert(len(results), Equals, 5)
+}
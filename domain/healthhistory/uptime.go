@@ -0,0 +1,212 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthhistory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+// DefaultWindows are the windows reported when none are requested
+var DefaultWindows = []string{"1h", "24h", "7d"}
+
+// WindowReport is the availability of a service over a window of time
+type WindowReport struct {
+	Window       string
+	Start        time.Time
+	End          time.Time
+	UpSeconds    float64 // time during which every instance passed its checks
+	DownSeconds  float64 // time during which any instance failed a check
+	Availability float64 // percent of the measured time the service was up; 100 if nothing was measured
+	BurnRate     float64 // rate the SLO error budget is being spent; 1 spends it exactly over the SLO window
+}
+
+// Report is the availability of a service over several windows
+type Report struct {
+	ServiceID       string
+	SLO             *domain.SLO `json:",omitempty"`
+	Windows         []WindowReport
+	BudgetRemaining *float64 `json:",omitempty"` // percent of the SLO error budget left over the SLO window
+	History         []Result `json:",omitempty"`
+}
+
+// ParseWindow parses a window such as 1h, 90m or 7d
+func ParseWindow(window string) (time.Duration, error) {
+	if strings.HasSuffix(window, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid window: %s", window)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window: %s", window)
+	}
+	return d, nil
+}
+
+// FormatWindow formats a window the way ParseWindow reads it, using days
+// when the window is a whole number of them
+func FormatWindow(d time.Duration) string {
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// NewReport measures the availability of a service from its results over
+// each window ending at now. The SLO window is added to the windows if the
+// service has an SLO, and only the checks named by the SLO are counted.
+func NewReport(serviceID string, slo *domain.SLO, results []Result, windows []string, now time.Time) (*Report, error) {
+	if len(windows) == 0 {
+		windows = DefaultWindows
+	}
+	durations := make([]time.Duration, 0, len(windows)+1)
+	for _, window := range windows {
+		d, err := ParseWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, d)
+	}
+
+	var checks []string
+	if slo != nil {
+		checks = slo.HealthChecks
+		found := false
+		for _, d := range durations {
+			if d == slo.Window() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			durations = append(durations, slo.Window())
+		}
+	}
+
+	report := &Report{ServiceID: serviceID, SLO: slo}
+	for _, d := range durations {
+		start := now.Add(-d)
+		up, down := measure(results, checks, start, now)
+		wr := WindowReport{
+			Window:       FormatWindow(d),
+			Start:        start,
+			End:          now,
+			UpSeconds:    up.Seconds(),
+			DownSeconds:  down.Seconds(),
+			Availability: availability(up, down),
+		}
+		if slo != nil {
+			if budget := slo.ErrorBudget(); budget > 0 {
+				wr.BurnRate = (1 - wr.Availability/100) / budget
+				if d == slo.Window() {
+					remaining := 100 * (1 - wr.BurnRate)
+					report.BudgetRemaining = &remaining
+				}
+			}
+		}
+		report.Windows = append(report.Windows, wr)
+	}
+	return report, nil
+}
+
+func availability(up, down time.Duration) float64 {
+	if up+down == 0 {
+		return 100
+	}
+	return 100 * float64(up) / float64(up+down)
+}
+
+// edge is the start or end of a result, clipped to a window
+type edge struct {
+	at     time.Time
+	failed int // change in the number of failing checks
+	known  int // change in the number of checks reporting
+}
+
+type edges []edge
+
+func (e edges) Len() int           { return len(e) }
+func (e edges) Less(i, j int) bool { return e[i].at.Before(e[j].at) }
+func (e edges) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// measure sweeps the results that overlap a window. The service is down
+// while any check of any instance is failing, up while checks are reporting
+// and all of them pass, and unmeasured while nothing reports.
+func measure(results []Result, checks []string, start, end time.Time) (up, down time.Duration) {
+	var points edges
+	for _, r := range results {
+		if !counts(r.Name, checks) {
+			continue
+		}
+		from, to := r.Start, r.End
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if !from.Before(to) {
+			continue
+		}
+		failed := 0
+		if r.Status == StatusFailed {
+			failed = 1
+		}
+		points = append(points, edge{from, failed, 1}, edge{to, -failed, -1})
+	}
+	sort.Sort(points)
+
+	failing, reporting := 0, 0
+	for i, p := range points {
+		if i > 0 && reporting > 0 {
+			span := p.at.Sub(points[i-1].at)
+			if failing > 0 {
+				down += span
+			} else {
+				up += span
+			}
+		}
+		failing += p.failed
+		reporting += p.known
+	}
+	return up, down
+}
+
+func counts(name string, checks []string) bool {
+	if len(checks) == 0 {
+		return true
+	}
+	for _, check := range checks {
+		if check == name {
+			return true
+		}
+	}
+	return false
+}
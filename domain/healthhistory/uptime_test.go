@@ -0,0 +1,98 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthhistory
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+func TestParseWindow(t *testing.T) {
+	for window, expected := range map[string]time.Duration{
+		"1h":    time.Hour,
+		"1h30m": 90 * time.Minute,
+		"7d":    7 * 24 * time.Hour,
+	} {
+		if d, err := ParseWindow(window); err != nil || d != expected {
+			t.Errorf("window %s: expected %s, got %s (%v)", window, expected, d, err)
+		}
+		if formatted := FormatWindow(expected); formatted != window {
+			t.Errorf("expected %s, got %s", window, formatted)
+		}
+	}
+	for _, window := range []string{"", "d", "-1d", "0h", "abc"} {
+		if _, err := ParseWindow(window); err == nil {
+			t.Errorf("expected an error parsing %q", window)
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	now := time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return now.Add(time.Duration(minutes) * time.Minute) }
+	results := []Result{
+		// instance 0 passes for the whole hour except 10 minutes
+		{InstanceID: "0", Name: "running", Status: StatusPassed, Start: at(-120), End: at(-20)},
+		{InstanceID: "0", Name: "running", Status: StatusFailed, Start: at(-20), End: at(-10)},
+		{InstanceID: "0", Name: "running", Status: StatusPassed, Start: at(-10), End: at(0)},
+		// instance 1 overlaps the failure of instance 0 and fails a check the SLO ignores
+		{InstanceID: "1", Name: "running", Status: StatusPassed, Start: at(-30), End: at(0)},
+		{InstanceID: "1", Name: "ready", Status: StatusFailed, Start: at(-5), End: at(0)},
+	}
+
+	up, down := measure(results, nil, at(-60), now)
+	if up != 45*time.Minute || down != 15*time.Minute {
+		t.Errorf("expected 45m up and 15m down, got %s and %s", up, down)
+	}
+	up, down = measure(results, []string{"running"}, at(-60), now)
+	if up != 50*time.Minute || down != 10*time.Minute {
+		t.Errorf("expected 50m up and 10m down, got %s and %s", up, down)
+	}
+	// nothing reported before the first result
+	up, down = measure(results, nil, at(-180), at(-100))
+	if up != 20*time.Minute || down != 0 {
+		t.Errorf("expected 20m up and no downtime, got %s and %s", up, down)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	now := time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)
+	slo := &domain.SLO{Objective: 99, WindowDays: 1}
+	results := []Result{
+		{Name: "running", Status: StatusPassed, Start: now.Add(-24 * time.Hour), End: now.Add(-6 * time.Minute)},
+		{Name: "running", Status: StatusFailed, Start: now.Add(-6 * time.Minute), End: now},
+	}
+
+	report, err := NewReport("svc", slo, results, []string{"1h"}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(report.Windows) != 2 || report.Windows[1].Window != "1d" {
+		t.Fatalf("expected the 1h and SLO windows, got %+v", report.Windows)
+	}
+	hour := report.Windows[0]
+	if math.Abs(hour.Availability-90) > 1e-9 || math.Abs(hour.BurnRate-10) > 1e-9 {
+		t.Errorf("expected 90%% available with a burn rate of 10, got %v and %v", hour.Availability, hour.BurnRate)
+	}
+	if report.BudgetRemaining == nil || math.Abs(*report.BudgetRemaining-100*(1-(6.0/1440)/0.01)) > 1e-6 {
+		t.Errorf("expected 58%% of the budget left, got %v", report.BudgetRemaining)
+	}
+
+	if _, err := NewReport("svc", nil, results, []string{"1x"}, now); err == nil {
+		t.Errorf("expected an error for an invalid window")
+	}
+}
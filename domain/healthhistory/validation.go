@@ -0,0 +1,37 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthhistory
+
+import (
+	"github.com/control-center/serviced/validation"
+)
+
+// ValidEntity check if fields are valid
+func (r Result) ValidEntity() error {
+	vErr := validation.NewValidationError()
+	vErr.Add(validation.NotEmpty("ID", r.ID))
+	vErr.Add(validation.NotEmpty("ServiceID", r.ServiceID))
+	vErr.Add(validation.NotEmpty("Name", r.Name))
+	vErr.Add(validation.StringIn(r.Status, StatusPassed, StatusFailed))
+	if r.Start.IsZero() || r.End.IsZero() {
+		vErr.AddViolation("fields Start and End must be set")
+	} else if r.End.Before(r.Start) {
+		vErr.AddViolation("field End must not be before Start")
+	}
+
+	if vErr.HasError() {
+		return vErr
+	}
+	return nil
+}
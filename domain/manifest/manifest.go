@@ -0,0 +1,120 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifest describes the desired state of a cluster in files that can
+// be kept in version control: its resource pools with their virtual ips, its
+// service templates, the deployments of the templates and the changes made to
+// the deployed services. serviced apply makes the cluster match its manifests.
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/control-center/serviced/commons/yaml"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/servicetemplate"
+)
+
+// Manifest is the desired state of a cluster
+type Manifest struct {
+	Pools       []pool.ResourcePool               // pools with their virtual ips
+	Templates   []servicetemplate.ServiceTemplate // matched by ID, or by Name if they have no ID
+	Deployments []Deployment
+	Services    []ServiceOverride
+}
+
+// Deployment is a template deployed to a pool
+type Deployment struct {
+	ID       string            // the deployment id of the services
+	Template string            // the id or the name of the template
+	PoolID   string            // the pool that the services run in
+	Params   map[string]string // the values of the parameters of the template
+}
+
+// ServiceOverride sets fields of a deployed service. The fields that are not
+// set keep their values.
+type ServiceOverride struct {
+	Deployment  string                 // the deployment id of the service
+	Path        string                 // the names of the services from the tenant down, separated by /
+	Instances   *int                   // the number of instances to run
+	Launch      string                 // auto or manual
+	Context     map[string]interface{} // keys set in the context of the service
+	ConfigFiles map[string]string      // the content of config files, by file name
+}
+
+// Add adds the contents of another manifest
+func (m *Manifest) Add(other *Manifest) {
+	m.Pools = append(m.Pools, other.Pools...)
+	m.Templates = append(m.Templates, other.Templates...)
+	m.Deployments = append(m.Deployments, other.Deployments...)
+	m.Services = append(m.Services, other.Services...)
+}
+
+// IsManifest returns true if a file name has the extension of a manifest
+func IsManifest(filename string) bool {
+	return yaml.IsYAML(filename) || strings.ToLower(filepath.Ext(filename)) == ".json"
+}
+
+// ReadFile reads a YAML or JSON manifest
+func ReadFile(filename string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("could not read manifest %s: %s", filename, err)
+	}
+	return &m, nil
+}
+
+// ReadDir reads the manifests in a directory and its subdirectories in the
+// order of their paths, and adds them to one manifest. A path to a file reads
+// the one manifest.
+func ReadDir(dirpath string) (*Manifest, error) {
+	var filenames []string
+	err := filepath.Walk(dirpath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dirpath && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == dirpath || IsManifest(path) {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+
+	m := &Manifest{}
+	for _, filename := range filenames {
+		next, err := ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		m.Add(next)
+	}
+	return m, nil
+}
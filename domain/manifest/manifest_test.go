@@ -0,0 +1,126 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const poolsYAML = `
+# the pools of the cluster
+Pools:
+  - ID: prod
+    Description: production
+    VirtualIPs:
+      - {IP: 10.0.0.5, Netmask: 255.255.255.0, BindInterface: eth0}
+Deployments:
+  - ID: acme
+    Template: app
+    PoolID: prod
+    Params: {tenant: acme}
+`
+
+const servicesJSON = `{
+	"Services": [
+		{"Deployment": "acme", "Path": "app/web", "Instances": 3, "Context": {"workers": 4}}
+	]
+}`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "manifest-")
+	if err != nil {
+		t.Fatalf("could not create directory: %s", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("could not create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write %s: %s", path, err)
+		}
+	}
+	return dir
+}
+
+func TestReadDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"pools.yaml":            poolsYAML,
+		"tenants/services.json": servicesJSON,
+		"README.md":             "not a manifest",
+		".git/config.json":      "not a manifest",
+	})
+	defer os.RemoveAll(dir)
+
+	m, err := ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(m.Pools) != 1 || m.Pools[0].ID != "prod" || len(m.Pools[0].VirtualIPs) != 1 || m.Pools[0].VirtualIPs[0].IP != "10.0.0.5" {
+		t.Errorf("unexpected pools %+v", m.Pools)
+	}
+	if len(m.Deployments) != 1 || m.Deployments[0].Params["tenant"] != "acme" {
+		t.Errorf("unexpected deployments %+v", m.Deployments)
+	}
+	if len(m.Services) != 1 || *m.Services[0].Instances != 3 || m.Services[0].Context["workers"] != float64(4) {
+		t.Errorf("unexpected services %+v", m.Services)
+	}
+	if err := m.ValidEntity(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// one manifest
+	m, err = ReadDir(filepath.Join(dir, "pools.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(m.Pools) != 1 || len(m.Services) != 0 {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+}
+
+func TestValidEntity(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.yaml": poolsYAML, "b.yaml": poolsYAML})
+	defer os.RemoveAll(dir)
+
+	m, err := ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := m.ValidEntity(); err == nil {
+		t.Errorf("expected an error for the pool and deployment declared twice")
+	}
+
+	m = &Manifest{Deployments: []Deployment{{ID: "acme"}}}
+	if err := m.ValidEntity(); err == nil {
+		t.Errorf("expected an error for the deployment without a template and pool")
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	for _, test := range []struct {
+		change   Change
+		expected string
+	}{
+		{Change{ActionAdd, KindPool, "prod", ""}, "+ pool prod"},
+		{Change{ActionUpdate, KindService, "acme/app/web", "Instances"}, "~ service acme/app/web: Instances"},
+		{Change{ActionRemove, KindDeployment, "old", ""}, "- deployment old"},
+	} {
+		if actual := test.change.String(); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
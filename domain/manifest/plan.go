@@ -0,0 +1,69 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"fmt"
+)
+
+// Kinds of the things that a plan changes
+const (
+	KindPool       = "pool"
+	KindVirtualIP  = "virtualip"
+	KindTemplate   = "template"
+	KindDeployment = "deployment"
+	KindService    = "service"
+)
+
+// Actions of the changes of a plan
+const (
+	ActionAdd    = "add"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+// Change is one change that makes the cluster match its manifests
+type Change struct {
+	Action string
+	Kind   string
+	Name   string // the id of the pool, template or deployment, or the path of the service
+	Detail string
+}
+
+// String describes the change on one line, with a leading +, ~ or -
+func (c Change) String() string {
+	sign := "~"
+	switch c.Action {
+	case ActionAdd:
+		sign = "+"
+	case ActionRemove:
+		sign = "-"
+	}
+	if c.Detail == "" {
+		return fmt.Sprintf("%s %s %s", sign, c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s %s: %s", sign, c.Kind, c.Name, c.Detail)
+}
+
+// Plan lists the changes that make the cluster match its manifests, in the
+// order they are made
+type Plan struct {
+	Changes []Change
+	Applied bool // the changes were made
+}
+
+// Empty returns true if the cluster already matches its manifests
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
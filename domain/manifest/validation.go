@@ -0,0 +1,95 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/control-center/serviced/validation"
+)
+
+// ValidEntity checks that the manifest declares everything once and that the
+// required fields are set
+func (m *Manifest) ValidEntity() error {
+	violations := validation.NewValidationError()
+
+	pools := make(map[string]bool)
+	for _, p := range m.Pools {
+		if p.ID == "" {
+			violations.AddViolation("pools must have an ID")
+		} else if pools[p.ID] {
+			violations.AddViolation(fmt.Sprintf("pool %s is declared twice", p.ID))
+		}
+		pools[p.ID] = true
+
+		ips := make(map[string]bool)
+		for _, vip := range p.VirtualIPs {
+			if vip.PoolID != "" && vip.PoolID != p.ID {
+				violations.AddViolation(fmt.Sprintf("virtual ip %s of pool %s is in pool %s", vip.IP, p.ID, vip.PoolID))
+			} else if ips[vip.IP] {
+				violations.AddViolation(fmt.Sprintf("virtual ip %s of pool %s is declared twice", vip.IP, p.ID))
+			}
+			ips[vip.IP] = true
+			violations.Add(validation.IsIP(vip.IP))
+		}
+	}
+
+	templates := make(map[string]bool)
+	for _, t := range m.Templates {
+		key := t.ID
+		if key == "" {
+			key = t.Name
+		}
+		if key == "" {
+			violations.AddViolation("templates must have a Name or an ID")
+		} else if templates[key] {
+			violations.AddViolation(fmt.Sprintf("template %s is declared twice", key))
+		}
+		templates[key] = true
+	}
+
+	deployments := make(map[string]bool)
+	for _, d := range m.Deployments {
+		if d.ID == "" {
+			violations.AddViolation("deployments must have an ID")
+		} else if deployments[d.ID] {
+			violations.AddViolation(fmt.Sprintf("deployment %s is declared twice", d.ID))
+		}
+		deployments[d.ID] = true
+		if d.Template == "" {
+			violations.AddViolation(fmt.Sprintf("deployment %s has no Template", d.ID))
+		}
+		if d.PoolID == "" {
+			violations.AddViolation(fmt.Sprintf("deployment %s has no PoolID", d.ID))
+		}
+	}
+
+	for _, s := range m.Services {
+		if s.Deployment == "" || s.Path == "" {
+			violations.AddViolation("services must have a Deployment and a Path")
+			continue
+		}
+		if s.Launch != "" {
+			violations.Add(validation.StringIn(s.Launch, "auto", "manual"))
+		}
+		if s.Instances != nil && *s.Instances < 0 {
+			violations.AddViolation(fmt.Sprintf("service %s/%s cannot have %d instances", s.Deployment, s.Path, *s.Instances))
+		}
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
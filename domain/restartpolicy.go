@@ -0,0 +1,75 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRestartPolicy applies to services without a restart policy: an
+// instance that keeps exiting is restarted with a growing delay, but is
+// never restarted for failing health checks and never quarantined.
+var DefaultRestartPolicy = RestartPolicy{
+	BackoffSeconds:    10,
+	MaxBackoffSeconds: 300,
+	WindowSeconds:     600,
+}
+
+// RestartPolicy decides when the agent restarts an instance of a service and
+// when it gives up on it
+type RestartPolicy struct {
+	FailureThreshold  int // consecutive failures of a health check before the instance is restarted; never if 0
+	BackoffSeconds    int // delay before the second restart within the window, doubled for each restart after it
+	MaxBackoffSeconds int // longest delay before a restart
+	MaxRestarts       int // restarts within the window before the instance is quarantined; never if 0
+	WindowSeconds     int // period restarts are counted over
+}
+
+// Window returns the period restarts are counted over
+func (p RestartPolicy) Window() time.Duration {
+	return time.Duration(p.WindowSeconds) * time.Second
+}
+
+// Backoff returns the delay before the nth restart within the window. The
+// first restart is immediate.
+func (p RestartPolicy) Backoff(n int) time.Duration {
+	if n <= 1 || p.BackoffSeconds <= 0 {
+		return 0
+	}
+	delay := time.Duration(p.BackoffSeconds) * time.Second
+	max := time.Duration(p.MaxBackoffSeconds) * time.Second
+	for i := 2; i < n; i++ {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
+
+// Validate ensures none of the settings are negative and that restarts are
+// counted over a window if they are limited
+func (p RestartPolicy) Validate() error {
+	if p.FailureThreshold < 0 || p.BackoffSeconds < 0 || p.MaxBackoffSeconds < 0 || p.MaxRestarts < 0 || p.WindowSeconds < 0 {
+		return fmt.Errorf("restart policy settings cannot be negative")
+	}
+	if p.MaxRestarts > 0 && p.WindowSeconds == 0 {
+		return fmt.Errorf("restart policy with max restarts requires a window")
+	}
+	return nil
+}
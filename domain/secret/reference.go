@@ -0,0 +1,68 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// a reference is the call of the secret template function, which evaluates
+// to the call itself so that the value is only put in when a container starts
+var referencePattern = regexp.MustCompile(`\{\{-?\s*secret\s+"((?:[^"\\]|\\.)+)"\s*-?\}\}`)
+
+// Reference returns the text that refers to a secret in a service definition
+func Reference(name string) string {
+	return fmt.Sprintf("{{secret %s}}", strconv.Quote(name))
+}
+
+// References returns the names of the secrets referred to in a text, sorted
+// and without duplicates
+func References(text string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range referencePattern.FindAllStringSubmatch(text, -1) {
+		name, err := strconv.Unquote(`"` + match[1] + `"`)
+		if err != nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand replaces the references to secrets in a text with their values
+func Expand(text string, values map[string]string) (string, error) {
+	var missing error
+	expanded := referencePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		match := referencePattern.FindStringSubmatch(ref)
+		name, err := strconv.Unquote(`"` + match[1] + `"`)
+		if err != nil {
+			return ref
+		}
+		value, ok := values[name]
+		if !ok {
+			if missing == nil {
+				missing = fmt.Errorf("secret %s not found", name)
+			}
+			return ref
+		}
+		return value
+	})
+	return expanded, missing
+}
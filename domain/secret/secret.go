@@ -0,0 +1,67 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secret keeps passwords and other values that must not be stored in
+// plain text in service definitions. The values are encrypted with a master
+// key and are only decrypted when the containers that reference them start.
+package secret
+
+import (
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+)
+
+// Secret is a named value encrypted with the master key
+type Secret struct {
+	Name       string
+	Ciphertext []byte
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	datastore.VersionedEntity
+}
+
+// Info describes a secret without its value
+type Info struct {
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// New creates a secret with the value encrypted by cipher
+func New(name, value string, cipher *Cipher) (*Secret, error) {
+	ciphertext, err := cipher.Encrypt([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	s := &Secret{Name: name, Ciphertext: ciphertext, CreatedAt: now, UpdatedAt: now}
+	if err := s.ValidEntity(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Value decrypts the value of the secret
+func (s *Secret) Value(cipher *Cipher) (string, error) {
+	plaintext, err := cipher.Decrypt(s.Ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Info returns the description of the secret
+func (s *Secret) Info() Info {
+	return Info{Name: s.Name, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testCipher(t *testing.T) *Cipher {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, KeySize))
+	if err != nil {
+		t.Fatalf("Could not create cipher: %s", err)
+	}
+	return c
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c := testCipher(t)
+	s, err := New("db/password", "hunter2", c)
+	if err != nil {
+		t.Fatalf("Could not create secret: %s", err)
+	}
+	if bytes.Contains(s.Ciphertext, []byte("hunter2")) {
+		t.Errorf("Ciphertext contains the value")
+	}
+	if value, err := s.Value(c); err != nil || value != "hunter2" {
+		t.Errorf("Expected hunter2, got %q (%v)", value, err)
+	}
+
+	// the same value is encrypted differently every time
+	other, _ := New("db/password", "hunter2", c)
+	if bytes.Equal(s.Ciphertext, other.Ciphertext) {
+		t.Errorf("Expected a random nonce")
+	}
+
+	wrong, _ := NewCipher(bytes.Repeat([]byte{8}, KeySize))
+	if _, err := s.Value(wrong); err != ErrDecrypt {
+		t.Errorf("Expected ErrDecrypt with another key, got %v", err)
+	}
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Errorf("Expected an error for a short key")
+	}
+	if _, err := New("bad name", "x", c); err == nil {
+		t.Errorf("Expected an error for an invalid name")
+	}
+}
+
+func TestLoadCipher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "keys", "secrets.key")
+
+	c, err := LoadCipher(filename)
+	if err != nil {
+		t.Fatalf("Could not create key: %s", err)
+	}
+	if fi, err := os.Stat(filename); err != nil {
+		t.Fatalf("Key was not written: %s", err)
+	} else if fi.Mode().Perm() != 0600 || fi.Size() != KeySize {
+		t.Errorf("Unexpected key file %s %d", fi.Mode(), fi.Size())
+	}
+
+	ciphertext, _ := c.Encrypt([]byte("value"))
+	again, err := LoadCipher(filename)
+	if err != nil {
+		t.Fatalf("Could not load key: %s", err)
+	}
+	if plaintext, err := again.Decrypt(ciphertext); err != nil || string(plaintext) != "value" {
+		t.Errorf("Expected value, got %q (%v)", plaintext, err)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	text := "user=admin\npassword=" + Reference("db/password") + "\nkey={{ secret \"api-key\" }}\nagain={{secret \"db/password\"}}\n"
+	if names := References(text); !reflect.DeepEqual(names, []string{"api-key", "db/password"}) {
+		t.Errorf("Unexpected references %v", names)
+	}
+
+	expanded, err := Expand(text, map[string]string{"db/password": "hunter2", "api-key": "abc"})
+	if err != nil {
+		t.Fatalf("Could not expand: %s", err)
+	}
+	if expected := "user=admin\npassword=hunter2\nkey=abc\nagain=hunter2\n"; expanded != expected {
+		t.Errorf("Expected %q, got %q", expected, expanded)
+	}
+
+	if _, err := Expand(text, map[string]string{"api-key": "abc"}); err == nil {
+		t.Errorf("Expected an error for a missing secret")
+	}
+	if names := References("{{getContext . \"secret\"}}"); len(names) != 0 {
+		t.Errorf("Unexpected references %v", names)
+	}
+}
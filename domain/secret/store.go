@@ -0,0 +1,59 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"strconv"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/zenoss/elastigo/search"
+)
+
+// maxResults is the most secrets that are listed
+const maxResults = 10000
+
+// NewStore creates a Secret store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Store type for interacting with Secret persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+// GetSecrets returns all the secrets, sorted by name
+func (s *Store) GetSecrets(ctx datastore.Context) ([]Secret, error) {
+	search := search.Search("controlplane").Type(kind).Size(strconv.Itoa(maxResults)).Sort(search.Sort("Name")).Query(search.Query().Search("_exists_:Name"))
+
+	q := datastore.NewQuery(ctx)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]Secret, results.Len())
+	for idx := range secrets {
+		if err := results.Get(idx, &secrets[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+// Key creates a Key suitable for getting, putting and deleting Secrets
+func Key(name string) datastore.Key {
+	return datastore.NewKey(kind, name)
+}
+
+var kind = "secret"
@@ -0,0 +1,49 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/control-center/serviced/validation"
+)
+
+// names may contain slashes so that the secrets of a tenant can be grouped
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.\-/]+$`)
+
+// ValidName checks that a secret can be referenced by name
+func ValidName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: only letters, digits and _.-/ are allowed", name)
+	}
+	return nil
+}
+
+// ValidEntity validates Secret fields
+func (s Secret) ValidEntity() error {
+	vErr := validation.NewValidationError()
+	vErr.Add(validation.NotEmpty("Name", s.Name))
+	if s.Name != "" {
+		vErr.Add(ValidName(s.Name))
+	}
+	if len(s.Ciphertext) == 0 {
+		vErr.AddViolation("field Ciphertext must be set")
+	}
+
+	if vErr.HasError() {
+		return vErr
+	}
+	return nil
+}
@@ -14,6 +14,7 @@
 package service
 
 import (
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/zenoss/glog"
 
 	"bytes"
@@ -163,6 +164,29 @@ func (service *Service) EvaluateRunsTemplate(gs GetService, fc FindChildService)
 	return
 }
 
+// templateFunctions returns the functions of the templates that are evaluated
+// with a service as their context
+func templateFunctions(gs GetService, fc FindChildService) template.FuncMap {
+	return template.FuncMap{
+		"parent":        parent(gs),
+		"child":         child(fc),
+		"context":       context(gs),
+		"getContext":    getContext(gs),
+		"contextFilter": contextFilter(gs),
+		"percentScale":  percentScale,
+		"bytesToMB":     bytesToMB,
+		"plus":          plus,
+		"each":          each,
+		"secret":        secret.Reference,
+	}
+}
+
+// ParseTemplate parses a template that is evaluated with a service as its
+// context, such as a config file or a command, without evaluating it
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("ServiceDefinitionTemplate").Funcs(templateFunctions(nil, nil)).Parse(text)
+}
+
 // evaluateTemplate takes a control center client and template string and evaluates
 // the template using the service as the context. If the template is invalid or there is an error
 // then an empty string is returned.
@@ -177,20 +201,8 @@ func (service *Service) evaluateTemplate(gs GetService, fc FindChildService, ins
 		}
 	}()
 
-	functions := template.FuncMap{
-		"parent":        parent(gs),
-		"child":         child(fc),
-		"context":       context(gs),
-		"getContext":    getContext(gs),
-		"contextFilter": contextFilter(gs),
-		"percentScale":  percentScale,
-		"bytesToMB":     bytesToMB,
-		"plus":          plus,
-		"each":          each,
-	}
-
 	// parse the template
-	t := template.Must(template.New("ServiceDefinitionTemplate").Funcs(functions).Parse(serviceTemplate))
+	t := template.Must(template.New("ServiceDefinitionTemplate").Funcs(templateFunctions(gs, fc)).Parse(serviceTemplate))
 
 	// evaluate it
 	var buffer bytes.Buffer
@@ -52,6 +52,9 @@ const (
 	SVCPause   = DesiredState(2)
 )
 
+// PreStopTimeout bounds how long the pre-stop command of an instance may run
+const PreStopTimeout = 30 * time.Second
+
 // Service A Service that can run in serviced.
 type Service struct {
 	ID                string
@@ -89,11 +92,17 @@ type Service struct {
 	CPUCommitment     uint64
 	Actions           map[string]string
 	HealthChecks      map[string]domain.HealthCheck // A health check for the service.
+	ReadinessCheck    string                        // The health check that must pass before the exports of an instance are registered
+	SLO               *domain.SLO                   // An optional availability objective measured with the health checks
+	RestartPolicy     *domain.RestartPolicy         // When to restart or quarantine unhealthy instances; domain.DefaultRestartPolicy if nil
 	Prereqs           []domain.Prereq               // Optional list of scripts that must be successfully run before kicking off the service command.
 	MonitoringProfile domain.MonitorProfile
 	MemoryLimit       float64
 	CPUShares         int64
 	PIDFile           string
+	DrainTimeout      int
+	PreStop           string
+	Secrets           []servicedefinition.Secret
 	datastore.VersionedEntity
 }
 
@@ -181,8 +190,14 @@ func BuildService(sd servicedefinition.ServiceDefinition, parentServiceID string
 	svc.Runs = sd.Runs
 	svc.Actions = sd.Actions
 	svc.HealthChecks = sd.HealthChecks
+	svc.ReadinessCheck = sd.ReadinessCheck
+	svc.SLO = sd.SLO
+	svc.RestartPolicy = sd.RestartPolicy
 	svc.Prereqs = sd.Prereqs
 	svc.PIDFile = sd.PIDFile
+	svc.DrainTimeout = sd.DrainTimeout
+	svc.PreStop = sd.PreStop
+	svc.Secrets = sd.Secrets
 
 	svc.Endpoints = make([]ServiceEndpoint, 0)
 	for _, ep := range sd.Endpoints {
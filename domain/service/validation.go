@@ -43,6 +43,27 @@ func (s *Service) ValidEntity() error {
 		}
 	}
 
+	for name, hc := range s.HealthChecks {
+		if err := hc.Validate(); err != nil {
+			vErr.AddViolation(fmt.Sprintf("health check %s: %s", name, err))
+		}
+	}
+	if _, ok := s.HealthChecks[s.ReadinessCheck]; s.ReadinessCheck != "" && !ok {
+		vErr.AddViolation(fmt.Sprintf("readiness check %s is not a health check", s.ReadinessCheck))
+	}
+	if s.RestartPolicy != nil {
+		vErr.Add(s.RestartPolicy.Validate())
+	}
+	if s.SLO != nil {
+		vErr.Add(s.SLO.Validate())
+	}
+	if s.DrainTimeout < 0 {
+		vErr.AddViolation(fmt.Sprintf("drain timeout %d is negative", s.DrainTimeout))
+	}
+	for _, secret := range s.Secrets {
+		vErr.Add(secret.ValidEntity())
+	}
+
 	if vErr.HasError() {
 		return vErr
 	}
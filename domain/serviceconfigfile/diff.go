@@ -0,0 +1,137 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfigfile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// lines of context around the changes of a diff
+const diffContext = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+	a, b int // line numbers in the old and the new content, from 0
+}
+
+// Diff returns the changes from one content of a config file to another as a
+// unified diff, or an empty string if they are the same
+func Diff(fromName, toName, from, to string) string {
+	a, b := splitLines(from), splitLines(to)
+	lines := diffLines(a, b)
+
+	var buffer bytes.Buffer
+	for start := 0; start < len(lines); {
+		// find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// extend the hunk until there are more unchanged lines than the
+		// context of two hunks
+		end, unchanged := start, 0
+		for i := start; i < len(lines) && unchanged <= 2*diffContext; i++ {
+			if lines[i].op == ' ' {
+				unchanged++
+			} else {
+				unchanged, end = 0, i+1
+			}
+		}
+		first, last := start-diffContext, end+diffContext
+		if first < 0 {
+			first = 0
+		}
+		if last > len(lines) {
+			last = len(lines)
+		}
+
+		if buffer.Len() == 0 {
+			fmt.Fprintf(&buffer, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		aStart, aCount, bStart, bCount := lines[first].a, 0, lines[first].b, 0
+		for _, line := range lines[first:last] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&buffer, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, line := range lines[first:last] {
+			fmt.Fprintf(&buffer, "%c%s\n", line.op, line.text)
+		}
+		start = last
+	}
+	return buffer.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns the lines of both contents, marked by whether they were
+// removed, added or kept, using their longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	// common[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || common[i][j+1] > common[i+1][j]):
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		default:
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		}
+	}
+	return lines
+}
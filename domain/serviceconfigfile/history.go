@@ -0,0 +1,129 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfigfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/utils"
+	"github.com/zenoss/elastigo/search"
+)
+
+// Revision is a version of a config file of a service in a tenant, kept in
+// the history of the config file
+type Revision struct {
+	ID              string
+	ServiceTenantID string
+	ServicePath     string
+	Filename        string
+	ConfFile        servicedefinition.ConfigFile
+	Author          string // the user that made the change; empty if not known
+	CreatedAt       time.Time
+	datastore.VersionedEntity
+}
+
+// NewRevision creates a Revision of a config file
+func NewRevision(tenantID, svcPath string, conf servicedefinition.ConfigFile, author string) (*Revision, error) {
+	uuid, err := utils.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	rev := &Revision{
+		ID:              uuid,
+		ServiceTenantID: tenantID,
+		ServicePath:     svcPath,
+		Filename:        conf.Filename,
+		ConfFile:        conf,
+		Author:          author,
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := rev.ValidEntity(); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// PutRevision adds a revision to the history of a config file
+func (s *Store) PutRevision(ctx datastore.Context, rev *Revision) error {
+	return s.Put(ctx, RevisionKey(rev.ID), rev)
+}
+
+// GetRevisions returns the history of the config files of a service in a
+// tenant, oldest first. If filename is set, only the history of that file is
+// returned.
+func (s *Store) GetRevisions(ctx datastore.Context, tenantID, svcPath, filename string) ([]Revision, error) {
+	filters := []interface{}{
+		"and",
+		search.Filter().Terms("ServiceTenantID", tenantID),
+		search.Filter().Terms("ServicePath", svcPath),
+	}
+	if filename != "" {
+		filters = append(filters, search.Filter().Terms("Filename", filename))
+	}
+	search := search.Search("controlplane").Type(revisionKind).Size("50000").Filter(filters...)
+
+	q := datastore.NewQuery(ctx)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	revs := make([]Revision, results.Len())
+	for idx := range revs {
+		if err := results.Get(idx, &revs[idx]); err != nil {
+			return nil, err
+		}
+	}
+	sort.Sort(revisionsByCreatedAt(revs))
+	return revs, nil
+}
+
+// FindRevision returns the revision of a config file of a service whose id
+// starts with the given prefix
+func (s *Store) FindRevision(ctx datastore.Context, tenantID, svcPath, prefix string) (*Revision, error) {
+	revs, err := s.GetRevisions(ctx, tenantID, svcPath, "")
+	if err != nil {
+		return nil, err
+	}
+	var found *Revision
+	for i := range revs {
+		if strings.HasPrefix(revs[i].ID, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("revision %s is ambiguous", prefix)
+			}
+			found = &revs[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("revision %s not found", prefix)
+	}
+	return found, nil
+}
+
+// RevisionKey creates a Key suitable for getting and putting Revisions
+func RevisionKey(id string) datastore.Key {
+	return datastore.NewKey(revisionKind, id)
+}
+
+type revisionsByCreatedAt []Revision
+
+func (r revisionsByCreatedAt) Len() int           { return len(r) }
+func (r revisionsByCreatedAt) Less(i, j int) bool { return r[i].CreatedAt.Before(r[j].CreatedAt) }
+func (r revisionsByCreatedAt) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+var revisionKind = "svcconfigfilerevision"
@@ -32,10 +32,31 @@ var (
 `
 	//MAPPING is the elastic mapping for a service
 	MAPPING, mappingError = elastic.NewMapping(mappingString)
+
+	revisionMappingString = `
+{
+	"svcconfigfilerevision": {
+	  "properties": {
+		"ID" :             {"type": "string", "index":"not_analyzed"},
+		"ServiceTenantID": {"type": "string", "index":"not_analyzed"},
+		"ServicePath":     {"type": "string", "index":"not_analyzed"},
+		"Filename":        {"type": "string", "index":"not_analyzed"},
+		"Author":          {"type": "string", "index":"not_analyzed"},
+		"CreatedAt":       {"type": "date", "format": "dateOptionalTime"},
+		"ConfFile":        {"type": "object", "enabled": false}
+	  }
+	}
+}
+`
+	//RevisionMapping is the elastic mapping for the history of config files
+	RevisionMapping, revisionMappingError = elastic.NewMapping(revisionMappingString)
 )
 
 func init() {
 	if mappingError != nil {
 		glog.Fatalf("error creating svcconfigfile mapping: %v", mappingError)
 	}
+	if revisionMappingError != nil {
+		glog.Fatalf("error creating svcconfigfilerevision mapping: %v", revisionMappingError)
+	}
 }
@@ -38,3 +38,17 @@ func (scf SvcConfigFile) ValidEntity() error {
 	}
 	return nil
 }
+
+//ValidEntity check if the fields of a Revision are valid
+func (rev Revision) ValidEntity() error {
+	vErr := validation.NewValidationError()
+	vErr.Add(validation.NotEmpty("ID", rev.ID))
+	vErr.Add(validation.NotEmpty("ServiceTenantID", rev.ServiceTenantID))
+	vErr.Add(validation.NotEmpty("ServicePath", rev.ServicePath))
+	vErr.Add(validation.NotEmpty("Filename", rev.Filename))
+
+	if vErr.HasError() {
+		return vErr
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicedefinition
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Log parser types
+const (
+	LogParserJSON      = "json"      // each message is a json object
+	LogParserMultiline = "multiline" // lines matching Pattern are joined to the previous message
+	LogParserKeyValue  = "kv"        // the message is a list of key=value pairs
+	LogParserRegex     = "regex"     // the named captures of Pattern become fields
+)
+
+// DefaultMultilinePattern matches the lines that continue a java stack trace
+const DefaultMultilinePattern = `^(\s|Caused by:)`
+
+// LogParser parses the messages of a logfile into fields.  Logstash runs the
+// parsers of a LogConfig in order; a multiline parser must come first.
+type LogParser struct {
+	Type       string // json, multiline, kv or regex
+	Source     string // field to parse; defaults to message
+	Target     string // json and kv: field to put the parsed values under; empty for the top level
+	Pattern    string // multiline: regexp of the lines that continue a message (defaults to java stack traces); regex: regexp with named captures
+	FieldSplit string // kv: characters separating the pairs; defaults to a space
+	ValueSplit string // kv: characters separating a key from its value; defaults to =
+}
+
+// Tags added to messages that a parser could not parse, as logstash does
+const (
+	jsonParseFailure = "_jsonparsefailure"
+	grokParseFailure = "_grokparsefailure"
+)
+
+var onigurumaCapture = regexp.MustCompile(`\(\?<([A-Za-z_][A-Za-z0-9_]*)>`)
+
+// GoPattern returns the regexp of a multiline or regex parser in go syntax.
+// Named captures may be written (?<name>...), as logstash expects, or
+// (?P<name>...).
+func (p LogParser) GoPattern() string {
+	pattern := p.Pattern
+	if pattern == "" && p.Type == LogParserMultiline {
+		pattern = DefaultMultilinePattern
+	}
+	return onigurumaCapture.ReplaceAllString(pattern, "(?P<$1>")
+}
+
+// LogstashPattern returns the regexp of a multiline or regex parser in the
+// syntax of logstash
+func (p LogParser) LogstashPattern() string {
+	return strings.Replace(p.GoPattern(), "(?P<", "(?<", -1)
+}
+
+// SourceField returns the field parsed by the parser
+func (p LogParser) SourceField() string {
+	if p.Source == "" {
+		return "message"
+	}
+	return p.Source
+}
+
+func (p LogParser) fieldSplit() string {
+	if p.FieldSplit == "" {
+		return " "
+	}
+	return p.FieldSplit
+}
+
+func (p LogParser) valueSplit() string {
+	if p.ValueSplit == "" {
+		return "="
+	}
+	return p.ValueSplit
+}
+
+// ParseLogs runs sample lines of a logfile through the parsers of the log
+// config, returning the messages logstash would index.  Filters are raw
+// logstash configuration and are not applied.
+func (lc LogConfig) ParseLogs(lines []string) ([]map[string]interface{}, error) {
+	if err := lc.ValidEntity(); err != nil {
+		return nil, err
+	}
+
+	messages := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		message := map[string]interface{}{"message": line, "type": lc.Type}
+		for _, tag := range lc.LogTags {
+			message[tag.Name] = tag.Value
+		}
+		messages = append(messages, message)
+	}
+
+	for _, parser := range lc.Parsers {
+		var err error
+		switch parser.Type {
+		case LogParserMultiline:
+			messages, err = parseMultiline(parser, messages)
+		case LogParserJSON:
+			for _, message := range messages {
+				parseJSON(parser, message)
+			}
+		case LogParserKeyValue:
+			for _, message := range messages {
+				parseKeyValue(parser, message)
+			}
+		case LogParserRegex:
+			for _, message := range messages {
+				if err = parseRegex(parser, message); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+func parseMultiline(p LogParser, messages []map[string]interface{}) ([]map[string]interface{}, error) {
+	re, err := regexp.Compile(p.GoPattern())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	for _, message := range messages {
+		line, _ := message[p.SourceField()].(string)
+		if last := len(result) - 1; last >= 0 && re.MatchString(line) {
+			previous, _ := result[last][p.SourceField()].(string)
+			result[last][p.SourceField()] = previous + "\n" + line
+			addTag(result[last], "multiline")
+			continue
+		}
+		result = append(result, message)
+	}
+	return result, nil
+}
+
+func parseJSON(p LogParser, message map[string]interface{}) {
+	source, _ := message[p.SourceField()].(string)
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(source), &fields); err != nil {
+		addTag(message, jsonParseFailure)
+		return
+	}
+	setFields(message, p.Target, fields)
+}
+
+func parseKeyValue(p LogParser, message map[string]interface{}) {
+	// the same expression as the kv filter of logstash
+	fieldSplit := regexp.QuoteMeta(p.fieldSplit())
+	valueSplit := regexp.QuoteMeta(p.valueSplit())
+	re := regexp.MustCompile(fmt.Sprintf(`((?:\\ |[^%s%s])+)[%s](?:"([^"]*)"|'([^']*)'|((?:\\ |[^%s])+))`, fieldSplit, valueSplit, valueSplit, fieldSplit))
+
+	source, _ := message[p.SourceField()].(string)
+	fields := make(map[string]interface{})
+	for _, match := range re.FindAllStringSubmatch(source, -1) {
+		fields[match[1]] = match[2] + match[3] + match[4]
+	}
+	setFields(message, p.Target, fields)
+}
+
+func parseRegex(p LogParser, message map[string]interface{}) error {
+	re, err := regexp.Compile(p.GoPattern())
+	if err != nil {
+		return err
+	}
+
+	source, _ := message[p.SourceField()].(string)
+	match := re.FindStringSubmatch(source)
+	if match == nil {
+		addTag(message, grokParseFailure)
+		return nil
+	}
+	for i, name := range re.SubexpNames() {
+		// like grok, empty captures are not kept
+		if name != "" && match[i] != "" {
+			message[name] = match[i]
+		}
+	}
+	return nil
+}
+
+func setFields(message map[string]interface{}, target string, fields map[string]interface{}) {
+	if target != "" {
+		message[target] = fields
+		return
+	}
+	for name, value := range fields {
+		message[name] = value
+	}
+}
+
+func addTag(message map[string]interface{}, tag string) {
+	tags, _ := message["tags"].([]string)
+	for _, t := range tags {
+		if t == tag {
+			return
+		}
+	}
+	message["tags"] = append(tags, tag)
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicedefinition_test
+
+import (
+	. "github.com/control-center/serviced/domain/servicedefinition"
+
+	"reflect"
+	"testing"
+)
+
+func TestLogParserValidate(t *testing.T) {
+	valid := []LogParser{
+		{Type: LogParserJSON, Target: "data"},
+		{Type: LogParserMultiline},
+		{Type: LogParserMultiline, Pattern: `^\s`},
+		{Type: LogParserKeyValue, FieldSplit: "&", ValueSplit: ":"},
+		{Type: LogParserRegex, Pattern: `^(?P<level>\w+)`},
+		{Type: LogParserRegex, Pattern: `^(?<level>\w+)`},
+	}
+	for _, p := range valid {
+		if err := p.ValidEntity(); err != nil {
+			t.Errorf("unexpected error validating %+v: %s", p, err)
+		}
+	}
+
+	invalid := []LogParser{
+		{},
+		{Type: "grok"},
+		{Type: LogParserJSON, Pattern: "x"},
+		{Type: LogParserMultiline, Pattern: "("},
+		{Type: LogParserRegex, Pattern: `^(\w+)`},
+		{Type: LogParserRegex, Pattern: `^(?P<level>\w+)`, Target: "data"},
+		{Type: LogParserJSON, FieldSplit: "&"},
+	}
+	for _, p := range invalid {
+		if err := p.ValidEntity(); err == nil {
+			t.Errorf("expected error validating %+v", p)
+		}
+	}
+
+	lc := LogConfig{Type: "app", Parsers: []LogParser{{Type: LogParserJSON}, {Type: LogParserMultiline}}}
+	if err := lc.ValidEntity(); err == nil {
+		t.Errorf("expected error validating multiline parser after json parser")
+	}
+}
+
+func TestLogParserPatterns(t *testing.T) {
+	p := LogParser{Type: LogParserRegex, Pattern: `(?<a>x)(?P<b>y)(?:z)`}
+	if actual := p.GoPattern(); actual != `(?P<a>x)(?P<b>y)(?:z)` {
+		t.Errorf("unexpected go pattern %s", actual)
+	}
+	if actual := p.LogstashPattern(); actual != `(?<a>x)(?<b>y)(?:z)` {
+		t.Errorf("unexpected logstash pattern %s", actual)
+	}
+}
+
+func TestParseLogs(t *testing.T) {
+	lc := LogConfig{
+		Type:    "app",
+		LogTags: []LogTag{{Name: "tier", Value: "web"}},
+		Parsers: []LogParser{
+			{Type: LogParserMultiline},
+			{Type: LogParserRegex, Pattern: `^(?<level>[A-Z]+) (?<body>.*)`},
+			{Type: LogParserKeyValue, Source: "body", Target: "kv"},
+		},
+	}
+	lines := []string{
+		`ERROR user=bob action="log in" failed`,
+		`java.lang.NullPointerException`,
+		`	at Foo.bar(Foo.java:10)`,
+		`not parsed`,
+	}
+	expected := []map[string]interface{}{
+		{
+			"message": `ERROR user=bob action="log in" failed`,
+			"type":    "app",
+			"tier":    "web",
+			"level":   "ERROR",
+			"body":    `user=bob action="log in" failed`,
+			"kv":      map[string]interface{}{"user": "bob", "action": "log in"},
+		},
+		{
+			"message": "java.lang.NullPointerException\n\tat Foo.bar(Foo.java:10)",
+			"type":    "app",
+			"tier":    "web",
+			"tags":    []string{"multiline", "_grokparsefailure"},
+			"kv":      map[string]interface{}{},
+		},
+		{
+			"message": "not parsed",
+			"type":    "app",
+			"tier":    "web",
+			"tags":    []string{"_grokparsefailure"},
+			"kv":      map[string]interface{}{},
+		},
+	}
+
+	actual, err := lc.ParseLogs(lines)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestParseLogsJSON(t *testing.T) {
+	lc := LogConfig{Type: "app", Parsers: []LogParser{{Type: LogParserJSON}}}
+	actual, err := lc.ParseLogs([]string{`{"level":"INFO","count":2}`, `{`})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []map[string]interface{}{
+		{"message": `{"level":"INFO","count":2}`, "type": "app", "level": "INFO", "count": float64(2)},
+		{"message": "{", "type": "app", "tags": []string{"_jsonparsefailure"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -51,11 +51,17 @@ type ServiceDefinition struct {
 	Runs              map[string]string             // Map of commands that can be executed with 'serviced run ...'
 	Actions           map[string]string             // Map of commands that can be executed with 'serviced action ...'
 	HealthChecks      map[string]domain.HealthCheck // HealthChecks for a service.
+	ReadinessCheck    string                        // The health check that must pass before the exports of an instance are registered
+	SLO               *domain.SLO                   // An optional availability objective measured with the health checks
+	RestartPolicy     *domain.RestartPolicy         // When to restart or quarantine unhealthy instances; domain.DefaultRestartPolicy if nil
 	Prereqs           []domain.Prereq               // Optional list of scripts that must be successfully run before kicking off the service command.
 	MonitoringProfile domain.MonitorProfile         // An optional list of queryable metrics, graphs, and thresholds
 	MemoryLimit       float64
 	CPUShares         int64
-	PIDFile           string // An optional path or command to generate a path for a PID file to which signals are relayed.
+	PIDFile           string   // An optional path or command to generate a path for a PID file to which signals are relayed.
+	DrainTimeout      int      // Seconds to wait for the connections of an instance to finish before it is signalled to stop
+	PreStop           string   // An optional command run in the container after draining and before the service is signalled to stop
+	Secrets           []Secret // Secrets put in the containers when they start
 }
 
 // SnapshotCommands commands to be called during and after a snapshot
@@ -105,6 +111,20 @@ type ConfigFile struct {
 	Content     string // content of config file
 }
 
+// SecretsDir is the tmpfs in the containers that holds the files of secrets
+const SecretsDir = "/run/secrets"
+
+// Secret puts the value of a secret from the secret store in the containers
+// of a service when they start, in an environment variable or in a file on a
+// tmpfs so that it is never written to the image
+type Secret struct {
+	Name        string // name of the secret in the secret store
+	Env         string // environment variable that is set to the value
+	File        string // file under SecretsDir that contains the value
+	Owner       string // owner of the file, what you would pass to chown
+	Permissions string // permissions of the file, 0400 if empty
+}
+
 //AddressResourceConfig defines an external facing port for a service definition
 type AddressResourceConfig struct {
 	Port     uint16
@@ -113,10 +133,11 @@ type AddressResourceConfig struct {
 
 // LogConfig represents the configuration for a logfile for a service.
 type LogConfig struct {
-	Path    string   // The location on the container's filesystem of the log, can be a directory
-	Type    string   // Arbitrary string that identifies the "types" of logs that come from this source. This will be
-	Filters []string // A list of filters that must be contained in either the LogFilters or a parent's LogFilter,
-	LogTags []LogTag // Key value pair of tags that are sent to logstash for all entries coming out of this logfile
+	Path    string      // The location on the container's filesystem of the log, can be a directory
+	Type    string      // Arbitrary string that identifies the "types" of logs that come from this source. This will be
+	Filters []string    // A list of filters that must be contained in either the LogFilters or a parent's LogFilter,
+	LogTags []LogTag    // Key value pair of tags that are sent to logstash for all entries coming out of this logfile
+	Parsers []LogParser // Parse the messages of this logfile into fields, in order, before the Filters are applied
 }
 
 // LogTag  no clue what this is. Maybe someone actually reads this
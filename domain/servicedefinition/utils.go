@@ -14,9 +14,9 @@
 package servicedefinition
 
 import (
+	"github.com/control-center/serviced/commons/yaml"
 	"github.com/zenoss/glog"
 
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -24,6 +24,42 @@ import (
 	"strings"
 )
 
+// ServiceFiles are the names that the file of a service definition may have in
+// its directory, in JSON or in YAML
+var ServiceFiles = []string{"service.json", "service.yaml", "service.yml"}
+
+// isServiceFile returns true if a file name is one of the ServiceFiles
+func isServiceFile(name string) bool {
+	for _, f := range ServiceFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// findServiceFile returns the path of the one service definition file of a
+// directory
+func findServiceFile(path string) (string, error) {
+	found := ""
+	for _, f := range ServiceFiles {
+		serviceFile := filepath.Join(path, f)
+		if _, err := os.Stat(serviceFile); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		if found != "" {
+			return "", fmt.Errorf("%s has both %s and %s", path, filepath.Base(found), f)
+		}
+		found = serviceFile
+	}
+	if found == "" {
+		return "", fmt.Errorf("no %s in %s", strings.Join(ServiceFiles, ", "), path)
+	}
+	return found, nil
+}
+
 func getServiceDefinition(path string) (serviceDef *ServiceDefinition, err error) {
 
 	// is path a dir
@@ -35,8 +71,11 @@ func getServiceDefinition(path string) (serviceDef *ServiceDefinition, err error
 		return nil, fmt.Errorf("given path is not a directory")
 	}
 
-	// look for service.json
-	serviceFile := fmt.Sprintf("%s/service.json", path)
+	// look for service.json, service.yaml or service.yml
+	serviceFile, err := findServiceFile(path)
+	if err != nil {
+		return nil, err
+	}
 	blob, err := ioutil.ReadFile(serviceFile)
 	if err != nil {
 		return nil, err
@@ -44,7 +83,7 @@ func getServiceDefinition(path string) (serviceDef *ServiceDefinition, err error
 
 	// load blob
 	svc := ServiceDefinition{}
-	err = json.Unmarshal(blob, &svc)
+	err = yaml.Unmarshal(blob, &svc)
 	if err != nil {
 		glog.Errorf("Could not unmarshal service at %s", path)
 		return nil, err
@@ -64,7 +103,7 @@ func getServiceDefinition(path string) (serviceDef *ServiceDefinition, err error
 	}
 	for _, subpath := range subpaths {
 		switch {
-		case subpath.Name() == "service.json":
+		case isServiceFile(subpath.Name()):
 			continue
 		case subpath.Name() == "makefile": // ignoring makefiles present in service defs
 			continue
@@ -19,6 +19,7 @@ import (
 	"github.com/control-center/serviced/validation"
 
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 )
@@ -62,7 +63,37 @@ func (sd *ServiceDefinition) validate(context *validationContext) error {
 		}
 		names[trimName] = struct{}{}
 	}
-	//TODO: validate LogConfigs
+	for _, lc := range sd.LogConfigs {
+		if err := lc.ValidEntity(); err != nil {
+			return fmt.Errorf("service definition %v: %v", sd.Name, err)
+		}
+	}
+	for name, hc := range sd.HealthChecks {
+		if err := hc.Validate(); err != nil {
+			return fmt.Errorf("service definition %v: health check %s: %v", sd.Name, name, err)
+		}
+	}
+	if _, ok := sd.HealthChecks[sd.ReadinessCheck]; sd.ReadinessCheck != "" && !ok {
+		return fmt.Errorf("service definition %v: readiness check %s is not a health check", sd.Name, sd.ReadinessCheck)
+	}
+	if sd.RestartPolicy != nil {
+		if err := sd.RestartPolicy.Validate(); err != nil {
+			return fmt.Errorf("service definition %v: %v", sd.Name, err)
+		}
+	}
+	if sd.SLO != nil {
+		if err := sd.SLO.Validate(); err != nil {
+			return fmt.Errorf("service definition %v: %v", sd.Name, err)
+		}
+	}
+	if sd.DrainTimeout < 0 {
+		return fmt.Errorf("service definition %v: drain timeout %d is negative", sd.Name, sd.DrainTimeout)
+	}
+	for _, secret := range sd.Secrets {
+		if err := secret.ValidEntity(); err != nil {
+			return fmt.Errorf("service definition %v: %v", sd.Name, err)
+		}
+	}
 
 	return validServiceDefinitions(&sd.Services, context)
 }
@@ -130,6 +161,70 @@ func applicationValidation(application string) error {
 	return err
 }
 
+//ValidEntity makes sure a Secret names the secret and where it is put
+func (s Secret) ValidEntity() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("secret must have a name")
+	}
+	if s.Env == "" && s.File == "" {
+		return fmt.Errorf("secret %s: an environment variable or a file must be set", s.Name)
+	}
+	if s.File != "" {
+		if path.IsAbs(s.File) || path.Clean(s.File) != s.File || strings.HasPrefix(s.File, "../") || s.File == ".." {
+			return fmt.Errorf("secret %s: file %s must be a path relative to %s", s.Name, s.File, SecretsDir)
+		}
+	}
+	return nil
+}
+
+//ValidEntity makes sure the parsers of a LogConfig can be compiled into the logstash configuration
+func (lc LogConfig) ValidEntity() error {
+	for i, parser := range lc.Parsers {
+		if err := parser.ValidEntity(); err != nil {
+			return fmt.Errorf("log config %s: parser %d: %v", lc.Type, i, err)
+		}
+		if parser.Type == LogParserMultiline && i > 0 {
+			return fmt.Errorf("log config %s: parser %d: a multiline parser must come first", lc.Type, i)
+		}
+	}
+	return nil
+}
+
+//ValidEntity makes sure a LogParser is in a valid state
+func (p LogParser) ValidEntity() error {
+	switch p.Type {
+	case LogParserJSON, LogParserKeyValue:
+		if p.Pattern != "" {
+			return fmt.Errorf("%s parser does not take a pattern", p.Type)
+		}
+	case LogParserMultiline:
+		if _, err := regexp.Compile(p.GoPattern()); err != nil {
+			return fmt.Errorf("illegal multiline pattern %s", err)
+		}
+	case LogParserRegex:
+		re, err := regexp.Compile(p.GoPattern())
+		if err != nil {
+			return fmt.Errorf("illegal regex pattern %s", err)
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			named = named || name != ""
+		}
+		if !named {
+			return fmt.Errorf("regex pattern %s has no named captures", p.Pattern)
+		}
+	default:
+		return fmt.Errorf("unknown parser type %q", p.Type)
+	}
+	if p.Type != LogParserKeyValue && (p.FieldSplit != "" || p.ValueSplit != "") {
+		return fmt.Errorf("%s parser does not take field or value separators", p.Type)
+	}
+	if (p.Type == LogParserMultiline || p.Type == LogParserRegex) && p.Target != "" {
+		return fmt.Errorf("%s parser does not take a target", p.Type)
+	}
+	return nil
+}
+
 //ValidEntity used to make sure AddressResourceConfig is in a valid state
 func (arc AddressResourceConfig) ValidEntity() error {
 	//check if protocol set or port not 0
@@ -15,6 +15,7 @@ package servicedefinition_test
 
 import (
 	"github.com/control-center/serviced/commons"
+	"github.com/control-center/serviced/domain"
 	. "github.com/control-center/serviced/domain/servicedefinition"
 	. "github.com/control-center/serviced/domain/servicedefinition/testutils"
 
@@ -135,3 +136,20 @@ func TestServiceDefinitionEmptyEndpointName(t *testing.T) {
 		t.Errorf("Unexpected Error %v", err)
 	}
 }
+
+func TestServiceDefinitionReadinessCheck(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].ReadinessCheck = "ready"
+
+	err := sd.ValidEntity()
+	if err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "readiness check ready is not a health check") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	sd.Services[0].HealthChecks = map[string]domain.HealthCheck{"ready": {Type: domain.HealthCheckTCP, Port: 8080}}
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
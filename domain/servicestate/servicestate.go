@@ -50,10 +50,13 @@ type ServiceState struct {
 	PortMapping map[string][]domain.HostIPAndPort // protocol -> container port (internal) -> host port (external)
 	// remove list?  PortMapping:map[6379/tcp:[{HostIP:0.0.0.0 HostPort:49195}]]
 	//  i.e. redis:  PortMapping:map[6379/tcp: {HostIP:0.0.0.0 HostPort:49195} ]
-	Endpoints  []service.ServiceEndpoint
-	HostIP     string
-	InstanceID int
-	InSync     bool
+	Endpoints   []service.ServiceEndpoint
+	HostIP      string
+	InstanceID  int
+	InSync      bool
+	Restarts    int    // times the agent restarted the instance
+	Quarantined bool   // the agent stopped the instance and will not restart it
+	Reason      string // why the instance was last restarted or quarantined
 }
 
 // IsRunning returns true when a service is currently running
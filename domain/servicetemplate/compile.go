@@ -0,0 +1,367 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+// Include adds the services of another template or directory to a template
+type Include struct {
+	Template  string                 // ID of a registered template
+	Path      string                 // directory of service definitions, relative to the directory of the template
+	Into      string                 // SERVICEPATH of the service that gets the included services; the top of the template if empty
+	Overrides map[string]interface{} // values of fields of the included services, by SERVICEPATH:FIELD relative to the included services
+}
+
+// Provenance tells where a field of a compiled template came from
+type Provenance struct {
+	Target string // SERVICEPATH:FIELD
+	Source string
+}
+
+// TemplateSource returns the registered template with the given id
+type TemplateSource func(templateID string) (*ServiceTemplate, error)
+
+// String describes what an include refers to
+func (inc Include) String() string {
+	if inc.Template != "" {
+		return "template " + inc.Template
+	}
+	return "directory " + inc.Path
+}
+
+// Compile resolves the template that a template extends and the templates and
+// directories that it includes, and returns the template with all of its
+// services inline and where each of their fields came from. Directories are
+// relative to dir, and can only be included if dir is set. Registered
+// templates are looked up with source, and can only be included if source is
+// set.
+func Compile(st ServiceTemplate, dir string, source TemplateSource) (*ServiceTemplate, []Provenance, error) {
+	origin := "template " + st.ID
+	if dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		dir = abs
+		origin = "directory " + dir
+	} else if st.ID == "" {
+		origin = "template " + st.Name
+	}
+
+	c := &compiler{source: source}
+	compiled, provenance, err := c.compile(st, origin, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := compiled.validateParameters(); err != nil {
+		return nil, nil, err
+	}
+
+	// later sources override earlier ones
+	byTarget := make(map[string]int)
+	result := make([]Provenance, 0, len(provenance))
+	for _, p := range provenance {
+		if i, ok := byTarget[p.Target]; ok {
+			result[i] = p
+			continue
+		}
+		byTarget[p.Target] = len(result)
+		result = append(result, p)
+	}
+	sort.Sort(provenanceByTarget(result))
+	return compiled, result, nil
+}
+
+// hasIncludes is true if the template must be compiled before it is deployed
+func (st *ServiceTemplate) hasIncludes() bool {
+	return st.Extends != nil || len(st.Includes) > 0
+}
+
+// TemplateIDs returns the ids of the registered templates that a template
+// extends and includes
+func (st *ServiceTemplate) TemplateIDs() []string {
+	var ids []string
+	if st.Extends != nil && st.Extends.Template != "" {
+		ids = append(ids, st.Extends.Template)
+	}
+	for _, inc := range st.Includes {
+		if inc.Template != "" {
+			ids = append(ids, inc.Template)
+		}
+	}
+	return ids
+}
+
+type compiler struct {
+	source TemplateSource
+	stack  []string // the templates and directories being compiled
+}
+
+// compile resolves the includes of a template whose services come from
+// origin. The targets of the provenance are relative to the template.
+func (c *compiler) compile(st ServiceTemplate, origin, dir string) (*ServiceTemplate, []Provenance, error) {
+	for _, o := range c.stack {
+		if o == origin {
+			return nil, nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(c.stack, " -> "), origin)
+		}
+	}
+	c.stack = append(c.stack, origin)
+	defer func() { c.stack = c.stack[:len(c.stack)-1] }()
+
+	var provenance []Provenance
+	own := st.Services
+	params := st.Parameters
+	configFiles := st.ConfigFiles
+	st.Services, st.Parameters, st.ConfigFiles = nil, nil, nil
+
+	if st.Extends != nil {
+		if st.Extends.Into != "" {
+			return nil, nil, fmt.Errorf("%s: a template cannot extend %s into a service", origin, st.Extends)
+		}
+		base, baseProvenance, err := c.include(*st.Extends, origin, dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		st.Services = base.Services
+		st.Parameters = base.Parameters
+		st.ConfigFiles = base.ConfigFiles
+		if st.Version == "" {
+			st.Version = base.Version
+		}
+		if st.Description == "" {
+			st.Description = base.Description
+		}
+		provenance = append(provenance, baseProvenance...)
+	}
+
+	for _, sd := range own {
+		if findService(st.Services, sd.Name) != nil {
+			return nil, nil, fmt.Errorf("%s: service %s is already defined", origin, sd.Name)
+		}
+		st.Services = append(st.Services, sd)
+		provenance = append(provenance, fieldProvenance(sd, "", origin)...)
+	}
+	if err := addParameters(&st, params, "", origin); err != nil {
+		return nil, nil, err
+	}
+	if len(configFiles) > 0 && st.ConfigFiles == nil {
+		st.ConfigFiles = make(map[string]servicedefinition.ConfigFile)
+	}
+	for filename, configFile := range configFiles {
+		st.ConfigFiles[filename] = configFile
+	}
+
+	for _, inc := range st.Includes {
+		included, includedProvenance, err := c.include(inc, origin, dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		prefix := ""
+		if inc.Into != "" {
+			prefix = inc.Into + "/"
+		}
+		children, err := serviceChildren(&st.Services, inc.Into)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: cannot include %s: %s", origin, inc, err)
+		}
+		for _, sd := range included.Services {
+			if findService(*children, sd.Name) != nil {
+				return nil, nil, fmt.Errorf("%s: service %s%s included from %s is already defined", origin, prefix, sd.Name, inc)
+			}
+			*children = append(*children, sd)
+		}
+		if err := addParameters(&st, included.Parameters, prefix, inc.String()); err != nil {
+			return nil, nil, err
+		}
+		for _, p := range includedProvenance {
+			provenance = append(provenance, Provenance{Target: prefix + p.Target, Source: p.Source})
+		}
+	}
+
+	st.Extends, st.Includes = nil, nil
+	return &st, provenance, nil
+}
+
+// include compiles the template that inc refers to and applies its overrides
+func (c *compiler) include(inc Include, origin, dir string) (*ServiceTemplate, []Provenance, error) {
+	var st *ServiceTemplate
+	var includedOrigin, includedDir string
+	switch {
+	case inc.Template != "" && inc.Path != "":
+		return nil, nil, fmt.Errorf("%s: an include must have either a template or a path", origin)
+	case inc.Template != "":
+		if c.source == nil {
+			return nil, nil, fmt.Errorf("%s: cannot include template %s here", origin, inc.Template)
+		}
+		var err error
+		if st, err = c.source(inc.Template); err != nil {
+			return nil, nil, fmt.Errorf("%s: could not load template %s: %s", origin, inc.Template, err)
+		}
+		includedOrigin = "template " + inc.Template
+	case inc.Path != "":
+		if dir == "" {
+			return nil, nil, fmt.Errorf("%s: directory %s can only be included when compiling a directory", origin, inc.Path)
+		}
+		includedDir = inc.Path
+		if !filepath.IsAbs(includedDir) {
+			includedDir = filepath.Join(dir, includedDir)
+		}
+		var err error
+		if st, err = BuildFromPath(includedDir); err != nil {
+			return nil, nil, fmt.Errorf("%s: could not load directory %s: %s", origin, inc.Path, err)
+		}
+		includedOrigin = "directory " + includedDir
+	default:
+		return nil, nil, fmt.Errorf("%s: an include must have either a template or a path", origin)
+	}
+
+	compiled, provenance, err := c.compile(*st, includedOrigin, includedDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(inc.Overrides) == 0 {
+		return compiled, provenance, nil
+	}
+
+	targets := make([]string, 0, len(inc.Overrides))
+	for target := range inc.Overrides {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	services, err := editServices(compiled.Services, func(services []interface{}) error {
+		for _, target := range targets {
+			if err := setTarget(services, target, inc.Overrides[target]); err != nil {
+				return fmt.Errorf("%s: override of %s: %s", origin, inc, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	compiled.Services = services
+	for _, target := range targets {
+		provenance = append(provenance, Provenance{Target: target, Source: "override of " + inc.String() + " in " + origin})
+	}
+	return compiled, provenance, nil
+}
+
+// addParameters adds the parameters of an included template, whose targets
+// are relative to prefix
+func addParameters(st *ServiceTemplate, params []Parameter, prefix, origin string) error {
+	for _, p := range params {
+		for _, q := range st.Parameters {
+			if q.Name == p.Name {
+				return fmt.Errorf("parameter %s of %s is already defined", p.Name, origin)
+			}
+		}
+		if prefix != "" {
+			targets := make([]string, len(p.Targets))
+			for i, target := range p.Targets {
+				targets[i] = prefix + target
+			}
+			p.Targets = targets
+		}
+		st.Parameters = append(st.Parameters, p)
+	}
+	return nil
+}
+
+// fieldProvenance records that the fields that are set in a service and its
+// children came from origin
+func fieldProvenance(sd servicedefinition.ServiceDefinition, prefix, origin string) []Provenance {
+	path := prefix + sd.Name
+	var provenance []Provenance
+	fields, _ := toJSON(sd)
+	if fields, ok := fields.(map[string]interface{}); ok {
+		names := make([]string, 0, len(fields))
+		for name, value := range fields {
+			if name != "Name" && name != "Services" && !isZeroJSON(value) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			provenance = append(provenance, Provenance{Target: path + ":" + name, Source: origin})
+		}
+	}
+	for _, child := range sd.Services {
+		provenance = append(provenance, fieldProvenance(child, path+"/", origin)...)
+	}
+	return provenance
+}
+
+// isZeroJSON is true if a JSON value is empty
+func isZeroJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		for _, field := range v {
+			if !isZeroJSON(field) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// findService returns the service with the given name
+func findService(services []servicedefinition.ServiceDefinition, name string) *servicedefinition.ServiceDefinition {
+	for i := range services {
+		if services[i].Name == name {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+// serviceChildren returns the list of the children of the service at path,
+// or the list of services itself if path is empty
+func serviceChildren(services *[]servicedefinition.ServiceDefinition, path string) (*[]servicedefinition.ServiceDefinition, error) {
+	children := services
+	if path == "" {
+		return children, nil
+	}
+	for _, name := range strings.Split(path, "/") {
+		sd := findService(*children, name)
+		if sd == nil {
+			return nil, fmt.Errorf("service %s not found", path)
+		}
+		children = &sd.Services
+	}
+	return children, nil
+}
+
+type provenanceByTarget []Provenance
+
+func (p provenanceByTarget) Len() int           { return len(p) }
+func (p provenanceByTarget) Less(i, j int) bool { return p[i].Target < p[j].Target }
+func (p provenanceByTarget) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
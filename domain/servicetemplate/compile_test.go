@@ -0,0 +1,260 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func compileSource(templates map[string]ServiceTemplate) TemplateSource {
+	return func(id string) (*ServiceTemplate, error) {
+		if st, ok := templates[id]; ok {
+			st.ID = id
+			return &st, nil
+		}
+		return nil, fmt.Errorf("no template %s", id)
+	}
+}
+
+func baseTemplates() map[string]ServiceTemplate {
+	return map[string]ServiceTemplate{
+		"infra": ServiceTemplate{
+			Name:    "infra",
+			Version: "1.0",
+			Services: []servicedefinition.ServiceDefinition{
+				{Name: "zookeeper", Command: "run zk", Instances: domain.MinMax{Min: 1, Default: 1}},
+				{Name: "redis", Command: "run redis"},
+			},
+			Parameters: []Parameter{
+				{Name: "zkinstances", Type: ParamInt, Targets: []string{"zookeeper:Instances.Default"}},
+			},
+		},
+	}
+}
+
+func TestCompileInclude(t *testing.T) {
+	st := ServiceTemplate{
+		ID:   "app",
+		Name: "app",
+		Services: []servicedefinition.ServiceDefinition{
+			{Name: "app", Services: []servicedefinition.ServiceDefinition{{Name: "web", Command: "run web"}}},
+		},
+		Includes: []Include{
+			{Template: "infra", Into: "app", Overrides: map[string]interface{}{"redis:Command": "run redis --fast"}},
+		},
+	}
+
+	compiled, provenance, err := Compile(st, "", compileSource(baseTemplates()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if compiled.Includes != nil || compiled.Extends != nil {
+		t.Errorf("includes were not resolved: %+v", compiled)
+	}
+	app := compiled.Services[0]
+	if len(app.Services) != 3 || app.Services[1].Name != "zookeeper" || app.Services[2].Command != "run redis --fast" {
+		t.Fatalf("unexpected services %+v", app.Services)
+	}
+	if len(compiled.Parameters) != 1 || compiled.Parameters[0].Targets[0] != "app/zookeeper:Instances.Default" {
+		t.Errorf("unexpected parameters %+v", compiled.Parameters)
+	}
+
+	sources := make(map[string]string)
+	for _, p := range provenance {
+		sources[p.Target] = p.Source
+	}
+	for target, source := range map[string]string{
+		"app/web:Command":       "template app",
+		"app/zookeeper:Command": "template infra",
+		"app/redis:Command":     "override of template infra in template app",
+	} {
+		if sources[target] != source {
+			t.Errorf("expected %s from %s, got %q", target, source, sources[target])
+		}
+	}
+}
+
+func TestCompileExtends(t *testing.T) {
+	st := ServiceTemplate{
+		ID:       "custom",
+		Name:     "custom",
+		Extends:  &Include{Template: "infra", Overrides: map[string]interface{}{"zookeeper:Instances.Default": 3}},
+		Services: []servicedefinition.ServiceDefinition{{Name: "app", Command: "run app"}},
+	}
+
+	compiled, _, err := Compile(st, "", compileSource(baseTemplates()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if compiled.Name != "custom" || compiled.Version != "1.0" {
+		t.Errorf("unexpected template %s %s", compiled.Name, compiled.Version)
+	}
+	if len(compiled.Services) != 3 || compiled.Services[0].Instances.Default != 3 || compiled.Services[2].Name != "app" {
+		t.Errorf("unexpected services %+v", compiled.Services)
+	}
+	if len(compiled.Parameters) != 1 {
+		t.Errorf("expected the parameters of the base template, got %+v", compiled.Parameters)
+	}
+
+	st.Services = []servicedefinition.ServiceDefinition{{Name: "redis"}}
+	if _, _, err := Compile(st, "", compileSource(baseTemplates())); err == nil {
+		t.Errorf("expected an error for a service that is already defined")
+	}
+}
+
+func TestTemplateIDs(t *testing.T) {
+	st := ServiceTemplate{
+		Extends:  &Include{Template: "base"},
+		Includes: []Include{{Path: "services"}, {Template: "db", Into: "app"}},
+	}
+	if ids := st.TemplateIDs(); !reflect.DeepEqual(ids, []string{"base", "db"}) {
+		t.Errorf("expected [base db], got %v", ids)
+	}
+	if ids := (&ServiceTemplate{}).TemplateIDs(); len(ids) != 0 {
+		t.Errorf("expected no ids, got %v", ids)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	templates := baseTemplates()
+	templates["a"] = ServiceTemplate{Name: "a", Includes: []Include{{Template: "b"}}}
+	templates["b"] = ServiceTemplate{Name: "b", Extends: &Include{Template: "a"}}
+	source := compileSource(templates)
+
+	_, _, err := Compile(ServiceTemplate{ID: "a", Includes: []Include{{Template: "b"}}}, "", source)
+	if err == nil || !strings.Contains(err.Error(), "include cycle: template a -> template b -> template a") {
+		t.Errorf("expected an include cycle, got %v", err)
+	}
+
+	for _, st := range []ServiceTemplate{
+		{Includes: []Include{{Template: "missing"}}},
+		{Includes: []Include{{Template: "infra", Into: "missing"}}},
+		{Includes: []Include{{Template: "infra", Overrides: map[string]interface{}{"redis:Missing": 1}}}},
+		{Includes: []Include{{Path: "relative"}}},
+		{Includes: []Include{{}}},
+		{Includes: []Include{{Template: "infra"}, {Template: "infra"}}},
+	} {
+		if _, _, err := Compile(st, "", source); err == nil {
+			t.Errorf("expected an error for %+v", st.Includes)
+		}
+	}
+
+	if _, _, err := Compile(ServiceTemplate{Includes: []Include{{Template: "infra"}}}, "", nil); err == nil {
+		t.Errorf("expected an error without a template source")
+	}
+}
+
+func writeServiceDir(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompileDirectories(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "servicetemplate-compile-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	writeServiceDir(t, tmp, map[string]string{
+		"app/service.json":          `{"Command": ""}`,
+		"app/template.json":         `{"Includes": [{"Path": "../shared", "Into": "app"}]}`,
+		"app/web/service.json":      `{"Command": "run web"}`,
+		"shared/service.json":       `{"Command": ""}`,
+		"shared/cache/service.json": `{"Command": "run cache"}`,
+	})
+
+	st, err := BuildFromPath(filepath.Join(tmp, "app"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	compiled, provenance, err := Compile(*st, filepath.Join(tmp, "app"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	shared := findService(compiled.Services[0].Services, "shared")
+	if shared == nil || len(shared.Services) != 1 || shared.Services[0].Command != "run cache" {
+		t.Fatalf("unexpected services %+v", compiled.Services)
+	}
+	found := false
+	for _, p := range provenance {
+		if p.Target == "app/shared/cache:Command" && p.Source == "directory "+filepath.Join(tmp, "shared") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no provenance of the included directory in %+v", provenance)
+	}
+
+	// the shared directory includes the application back
+	writeServiceDir(t, tmp, map[string]string{
+		"shared/template.json": `{"Includes": [{"Path": "../app"}]}`,
+	})
+	if _, _, err := Compile(*st, filepath.Join(tmp, "app"), nil); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("expected an include cycle, got %v", err)
+	}
+}
+
+func TestBuildFromPathYAML(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "servicetemplate-yaml-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	writeServiceDir(t, tmp, map[string]string{
+		"app/service.yaml":     "Description: the application\nCommand: ''\n",
+		"app/template.yml":     "# deployed with the number of web instances\nParameters:\n- Name: instances\n  Type: int\n  Targets: ['app/web:Instances.Default']\n",
+		"app/web/service.json": `{"Command": "run web"}`,
+		"app/db/service.yml":   "Command: run db # the database\nContext:\n  db.port: 3306\n",
+	})
+
+	st, err := BuildFromPath(filepath.Join(tmp, "app"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if st.Description != "the application" || len(st.Parameters) != 1 || st.Parameters[0].Name != "instances" {
+		t.Errorf("unexpected template %+v", st)
+	}
+	if web := findService(st.Services[0].Services, "web"); web == nil || web.Command != "run web" {
+		t.Errorf("unexpected services %+v", st.Services[0].Services)
+	}
+	if db := findService(st.Services[0].Services, "db"); db == nil || db.Command != "run db" || db.Context["db.port"] != float64(3306) {
+		t.Errorf("unexpected services %+v", st.Services[0].Services)
+	}
+
+	// a service cannot be defined twice
+	writeServiceDir(t, tmp, map[string]string{
+		"app/web/service.yaml": "Command: run web\n",
+	})
+	if _, err := BuildFromPath(filepath.Join(tmp, "app")); err == nil || !strings.Contains(err.Error(), "has both") {
+		t.Errorf("expected an error for two service files, got %v", err)
+	}
+}
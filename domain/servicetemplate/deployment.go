@@ -0,0 +1,94 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+)
+
+// Deployment records the template that the services with a deployment id
+// were deployed or last upgraded from, so that later upgrades can tell the
+// changes made by the template from the changes made by users
+type Deployment struct {
+	ID           string // the deployment id of the services
+	TemplateID   string
+	TemplateHash string            // ContentHash of the template as it was deployed
+	Data         string            // JSON encoded template as it was deployed
+	Params       map[string]string // the values of the parameters of the template
+	DeployedAt   time.Time
+	Managed      bool // set when serviced apply manages the deployment
+	datastore.VersionedEntity
+}
+
+// NewDeployment records that deploymentID was deployed from st with the given
+// parameters
+func NewDeployment(deploymentID string, st ServiceTemplate, params map[string]string) (*Deployment, error) {
+	hash, err := ContentHash(st)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	return &Deployment{
+		ID:           deploymentID,
+		TemplateID:   st.ID,
+		TemplateHash: hash,
+		Data:         string(data),
+		Params:       params,
+		DeployedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// Template returns the template as it was deployed, before its parameters
+// were applied
+func (d *Deployment) Template() (*ServiceTemplate, error) {
+	return FromJSON(d.Data)
+}
+
+// Resolved returns the template as it was deployed, with its parameters
+// applied
+func (d *Deployment) Resolved() (*ServiceTemplate, error) {
+	st, err := d.Template()
+	if err != nil {
+		return nil, err
+	}
+	return st.Apply(st.DeclaredValues(d.Params))
+}
+
+// PutDeployment adds or updates the record of a deployment
+func (s *Store) PutDeployment(ctx datastore.Context, d *Deployment) error {
+	return s.ds.Put(ctx, DeploymentKey(d.ID), d)
+}
+
+// GetDeployment returns the record of a deployment. Return ErrNoSuchEntity if
+// not found
+func (s *Store) GetDeployment(ctx datastore.Context, deploymentID string) (*Deployment, error) {
+	var d Deployment
+	if err := s.ds.Get(ctx, DeploymentKey(deploymentID), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeploymentKey creates a Key suitable for getting and putting Deployments
+func DeploymentKey(deploymentID string) datastore.Key {
+	return datastore.NewKey(deploymentKind, deploymentID)
+}
+
+var deploymentKind = "servicetemplatedeployment"
@@ -0,0 +1,185 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/zenoss/elastigo/search"
+	"github.com/zenoss/glog"
+)
+
+// TemplateVersion is a version of a template kept in its history
+type TemplateVersion struct {
+	ID         string // TemplateID-Hash
+	TemplateID string
+	Hash       string // ContentHash of the template at this version
+	Name       string
+	Version    string
+	Data       string // JSON encoded template
+	CreatedAt  time.Time
+	datastore.VersionedEntity
+}
+
+// TemplateHistory describes a version of a template and the deployments that
+// were deployed from it or upgraded to it
+type TemplateHistory struct {
+	Hash        string
+	Name        string
+	Version     string
+	CreatedAt   time.Time
+	Current     bool // the template is at this version
+	Deployments []string
+}
+
+// ContentHash identifies the content of a template, regardless of its id
+func ContentHash(st ServiceTemplate) (string, error) {
+	st.ID = ""
+	st.DatabaseVersion = 0
+	return st.Hash()
+}
+
+// Template returns the template at this version
+func (v *TemplateVersion) Template() (*ServiceTemplate, error) {
+	st, err := FromJSON(v.Data)
+	if err != nil {
+		return nil, err
+	}
+	st.ID = v.TemplateID
+	return st, nil
+}
+
+// putVersion adds the current content of a template to its history, unless
+// the template had the same content before
+func (s *Store) putVersion(ctx datastore.Context, st ServiceTemplate) error {
+	hash, err := ContentHash(st)
+	if err != nil {
+		return err
+	}
+	if _, err := s.GetVersion(ctx, st.ID, hash); err == nil {
+		return nil
+	} else if !datastore.IsErrNoSuchEntity(err) {
+		return err
+	}
+
+	st.DatabaseVersion = 0
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	version := &TemplateVersion{
+		ID:         versionID(st.ID, hash),
+		TemplateID: st.ID,
+		Hash:       hash,
+		Name:       st.Name,
+		Version:    st.Version,
+		Data:       string(data),
+		CreatedAt:  time.Now().UTC(),
+	}
+	glog.V(2).Infof("Adding version %s of template %s", hash, st.ID)
+	return s.ds.Put(ctx, VersionKey(st.ID, hash), version)
+}
+
+// GetVersion returns a version of a template. Return ErrNoSuchEntity if not
+// found
+func (s *Store) GetVersion(ctx datastore.Context, templateID, hash string) (*TemplateVersion, error) {
+	var version TemplateVersion
+	if err := s.ds.Get(ctx, VersionKey(templateID, hash), &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetVersions returns the versions of a template, oldest first
+func (s *Store) GetVersions(ctx datastore.Context, templateID string) ([]TemplateVersion, error) {
+	glog.V(3).Infof("Store.GetVersions %s", templateID)
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Term("TemplateID", templateID)
+	search := search.Search("controlplane").Type(versionKind).Size("50000").Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]TemplateVersion, results.Len())
+	for idx := range versions {
+		if err := results.Get(idx, &versions[idx]); err != nil {
+			return nil, err
+		}
+	}
+	sort.Sort(versionsByCreatedAt(versions))
+	return versions, nil
+}
+
+// FindVersion returns the version of a template whose hash starts with the
+// given prefix
+func (s *Store) FindVersion(ctx datastore.Context, templateID, prefix string) (*TemplateVersion, error) {
+	versions, err := s.GetVersions(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	var found *TemplateVersion
+	for i := range versions {
+		if strings.HasPrefix(versions[i].Hash, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("version %s of template %s is ambiguous", prefix, templateID)
+			}
+			found = &versions[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("template %s has no version %s", templateID, prefix)
+	}
+	return found, nil
+}
+
+// GetDeploymentsByTemplate returns the records of the deployments of a
+// template
+func (s *Store) GetDeploymentsByTemplate(ctx datastore.Context, templateID string) ([]Deployment, error) {
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Term("TemplateID", templateID)
+	search := search.Search("controlplane").Type(deploymentKind).Size("50000").Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	deployments := make([]Deployment, results.Len())
+	for idx := range deployments {
+		if err := results.Get(idx, &deployments[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return deployments, nil
+}
+
+// VersionKey creates a Key suitable for getting and putting TemplateVersions
+func VersionKey(templateID, hash string) datastore.Key {
+	return datastore.NewKey(versionKind, versionID(templateID, hash))
+}
+
+func versionID(templateID, hash string) string {
+	return templateID + "-" + hash
+}
+
+type versionsByCreatedAt []TemplateVersion
+
+func (v versionsByCreatedAt) Len() int           { return len(v) }
+func (v versionsByCreatedAt) Less(i, j int) bool { return v[i].CreatedAt.Before(v[j].CreatedAt) }
+func (v versionsByCreatedAt) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+
+var versionKind = "servicetemplateversion"
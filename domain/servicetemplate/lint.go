@@ -0,0 +1,443 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+// Severities of lint issues
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// Checks of the linter
+const (
+	LintDefinition = "definition" // the service definition is not valid
+	LintImports    = "imports"    // an import has no matching export
+	LintPorts      = "ports"      // endpoints use the same port or virtual address
+	LintTemplates  = "templates"  // a field does not parse as a template
+	LintContext    = "context"    // a template refers to a context key that is not defined
+	LintVolumes    = "volumes"    // volumes are mounted at overlapping paths
+	LintCommands   = "commands"   // runs or actions cannot be executed
+	LintHealth     = "health"     // health checks have nothing to run or request
+)
+
+// LintIssue is a problem found in the service definitions of a template
+type LintIssue struct {
+	Severity string
+	Check    string
+	Service  string // SERVICEPATH
+	Field    string // e.g. Endpoints.mysql or ConfigFiles./etc/my.cnf
+	Message  string
+}
+
+// portFunctions are the functions of PortTemplate and VirtualAddress
+var portFunctions = template.FuncMap{
+	"plus": func(a, b int) int {
+		return a + b
+	},
+}
+
+// lintService is a service definition with its path in the template and the
+// context it inherits from its parents
+type lintService struct {
+	path    string
+	sd      *servicedefinition.ServiceDefinition
+	context map[string]interface{}
+}
+
+// Lint statically checks the service definitions of a compiled template for
+// problems that are only found when the services are deployed or run
+func Lint(st *ServiceTemplate) []LintIssue {
+	var issues []LintIssue
+	report := func(severity, check string, svc lintService, field, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{
+			Severity: severity,
+			Check:    check,
+			Service:  svc.path,
+			Field:    field,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for i := range st.Services {
+		app := &st.Services[i]
+		if err := app.ValidEntity(); err != nil {
+			report(LintError, LintDefinition, lintService{path: app.Name}, "", "%s", err)
+		}
+
+		// endpoints are imported from the services of the same tenant
+		services := lintServices(app, "", nil)
+		lintImports(services, report)
+		lintAddresses(services, report)
+		for _, svc := range services {
+			lintPorts(svc, report)
+			lintTemplates(svc, report)
+			lintVolumes(svc, report)
+			lintCommands(svc, report)
+			lintHealthChecks(svc, report)
+		}
+	}
+
+	sort.Stable(lintIssues(issues))
+	return issues
+}
+
+type reportFunc func(severity, check string, svc lintService, field, format string, args ...interface{})
+
+// lintServices returns a service definition and its children with the
+// contexts that they inherit
+func lintServices(sd *servicedefinition.ServiceDefinition, prefix string, inherited map[string]interface{}) []lintService {
+	context := make(map[string]interface{})
+	for key, value := range inherited {
+		context[key] = value
+	}
+	for key, value := range sd.Context {
+		context[key] = value
+	}
+
+	services := []lintService{{path: prefix + sd.Name, sd: sd, context: context}}
+	for i := range sd.Services {
+		services = append(services, lintServices(&sd.Services[i], prefix+sd.Name+"/", context)...)
+	}
+	return services
+}
+
+// applicationTemplate returns the application of an endpoint as it is
+// defined, before it is evaluated
+func applicationTemplate(ep servicedefinition.EndpointDefinition) string {
+	if ep.ApplicationTemplate != "" {
+		return ep.ApplicationTemplate
+	}
+	return ep.Application
+}
+
+// templateActions matches the actions of a template
+var templateActions = regexp.MustCompile(`{{.*?}}`)
+
+// lintImports checks that every import of a tenant matches an export
+func lintImports(services []lintService, report reportFunc) {
+	type export struct {
+		name    string
+		pattern *regexp.Regexp // if the name is a template
+	}
+	var exports []export
+	for _, svc := range services {
+		for _, ep := range svc.sd.Endpoints {
+			if ep.Purpose != "export" {
+				continue
+			}
+			name := applicationTemplate(ep)
+			e := export{name: name}
+			if strings.Contains(name, "{{") {
+				// the evaluated name may be anything in place of the actions
+				parts := templateActions.Split(name, -1)
+				for i := range parts {
+					parts[i] = regexp.QuoteMeta(parts[i])
+				}
+				e.pattern = regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+			}
+			exports = append(exports, e)
+		}
+	}
+
+	for _, svc := range services {
+		for _, ep := range svc.sd.Endpoints {
+			if ep.Purpose != "import" && ep.Purpose != "import_all" {
+				continue
+			}
+			name := applicationTemplate(ep)
+			if strings.Contains(name, "{{") {
+				// cannot be known until the template is evaluated
+				continue
+			}
+			re, err := regexp.Compile("^" + name + "$")
+			if err != nil {
+				report(LintError, LintImports, svc, "Endpoints."+ep.Name, "application %s is not a valid regular expression: %s", name, err)
+				continue
+			}
+			found := false
+			for _, e := range exports {
+				if re.MatchString(e.name) || (e.pattern != nil && e.pattern.MatchString(name)) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				report(LintError, LintImports, svc, "Endpoints."+ep.Name, "no service of the tenant exports %s", name)
+			}
+		}
+	}
+}
+
+// lintAddresses checks that endpoints do not ask for the same external port
+func lintAddresses(services []lintService, report reportFunc) {
+	ports := make(map[uint16]string)
+	for _, svc := range services {
+		for _, ep := range svc.sd.Endpoints {
+			port := ep.AddressConfig.Port
+			if port == 0 {
+				continue
+			}
+			if other, ok := ports[port]; ok {
+				report(LintWarning, LintPorts, svc, "Endpoints."+ep.Name, "address port %d is also assigned to %s; they need different IP addresses", port, other)
+				continue
+			}
+			ports[port] = svc.path + " " + ep.Name
+		}
+	}
+}
+
+// lintPorts checks that the endpoints of a service that listen in its
+// containers do not use the same port or virtual address
+func lintPorts(svc lintService, report reportFunc) {
+	ports := make(map[uint16]servicedefinition.EndpointDefinition)
+	addresses := make(map[string]string)
+	for _, ep := range svc.sd.Endpoints {
+		if ep.PortNumber != 0 && ep.PortTemplate == "" {
+			if other, ok := ports[ep.PortNumber]; ok && (ep.Purpose != "export" || other.Purpose != "export") {
+				report(LintError, LintPorts, svc, "Endpoints."+ep.Name, "port %d is also used by endpoint %s", ep.PortNumber, other.Name)
+			} else if !ok {
+				ports[ep.PortNumber] = ep
+			}
+		}
+		if ep.VirtualAddress != "" {
+			if other, ok := addresses[ep.VirtualAddress]; ok {
+				report(LintError, LintPorts, svc, "Endpoints."+ep.Name, "virtual address %s is also used by endpoint %s", ep.VirtualAddress, other)
+			} else {
+				addresses[ep.VirtualAddress] = ep.Name
+			}
+		}
+	}
+}
+
+// lintTemplates checks that the fields of a service that are evaluated as
+// templates parse, and that they refer to context keys that are defined
+func lintTemplates(svc lintService, report reportFunc) {
+	check := func(field, text string) {
+		if !strings.Contains(text, "{{") {
+			return
+		}
+		t, err := service.ParseTemplate(text)
+		if err != nil {
+			report(LintError, LintTemplates, svc, field, "%s", err)
+			return
+		}
+		for _, key := range contextKeys(t.Tree.Root) {
+			if _, ok := svc.context[key]; !ok {
+				report(LintWarning, LintContext, svc, field, "context key %s is not defined by the service or its parents", key)
+			}
+		}
+	}
+	checkPort := func(field, text string) {
+		if _, err := template.New(field).Funcs(portFunctions).Parse(text); err != nil {
+			report(LintError, LintTemplates, svc, field, "%s", err)
+		}
+	}
+
+	sd := svc.sd
+	check("Command", sd.Command)
+	check("Hostname", sd.Hostname)
+	filenames := make([]string, 0, len(sd.ConfigFiles))
+	for filename := range sd.ConfigFiles {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		check("ConfigFiles."+filename, sd.ConfigFiles[filename].Content)
+	}
+	for _, name := range sortedKeys(sd.Runs) {
+		check("Runs."+name, sd.Runs[name])
+	}
+	for _, name := range sortedKeys(sd.Actions) {
+		check("Actions."+name, sd.Actions[name])
+	}
+	for _, ep := range sd.Endpoints {
+		check("Endpoints."+ep.Name+".ApplicationTemplate", applicationTemplate(ep))
+		checkPort("Endpoints."+ep.Name+".PortTemplate", ep.PortTemplate)
+		checkPort("Endpoints."+ep.Name+".VirtualAddress", ep.VirtualAddress)
+	}
+	for _, vol := range sd.Volumes {
+		check("Volumes."+vol.ContainerPath, vol.ResourcePath)
+	}
+	for _, name := range sortedHealthChecks(sd.HealthChecks) {
+		check("HealthChecks."+name, sd.HealthChecks[name].Script)
+	}
+	for _, lc := range sd.LogConfigs {
+		check("LogConfigs."+lc.Path, lc.Path)
+		check("LogConfigs."+lc.Path+".Type", lc.Type)
+		for _, tag := range lc.LogTags {
+			check("LogConfigs."+lc.Path+".LogTags."+tag.Name, tag.Value)
+		}
+	}
+}
+
+// contextKeys returns the keys that a template looks up in the context of its
+// service, with {{getContext . "key"}} or {{(context .).key}}
+func contextKeys(node parse.Node) []string {
+	var keys []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			if len(n.Args) == 3 && isCall(n.Args[0], "getContext") {
+				if _, ok := n.Args[1].(*parse.DotNode); ok {
+					if key, ok := n.Args[2].(*parse.StringNode); ok {
+						keys = append(keys, key.Text)
+					}
+				}
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			if pipe, ok := n.Node.(*parse.PipeNode); ok && len(n.Field) > 0 && len(pipe.Cmds) == 1 {
+				cmd := pipe.Cmds[0]
+				if len(cmd.Args) == 2 && isCall(cmd.Args[0], "context") {
+					if _, ok := cmd.Args[1].(*parse.DotNode); ok {
+						keys = append(keys, n.Field[0])
+					}
+				}
+			}
+			walk(n.Node)
+		}
+	}
+	walk(node)
+	return keys
+}
+
+func isCall(node parse.Node, name string) bool {
+	ident, ok := node.(*parse.IdentifierNode)
+	return ok && ident.Ident == name
+}
+
+// lintVolumes checks that the volumes of a service are not mounted at the same
+// or nested paths
+func lintVolumes(svc lintService, report reportFunc) {
+	vols := svc.sd.Volumes
+	for i := range vols {
+		a := path.Clean(vols[i].ContainerPath)
+		for j := i + 1; j < len(vols); j++ {
+			b := path.Clean(vols[j].ContainerPath)
+			switch {
+			case a == b:
+				report(LintError, LintVolumes, svc, "Volumes."+vols[j].ContainerPath, "volumes %s and %s are mounted at the same path", vols[i].ResourcePath, vols[j].ResourcePath)
+			case strings.HasPrefix(b, a+"/"), strings.HasPrefix(a, b+"/"):
+				report(LintWarning, LintVolumes, svc, "Volumes."+vols[j].ContainerPath, "volume is mounted inside or over volume %s", vols[i].ContainerPath)
+			}
+		}
+	}
+}
+
+// lintCommands checks that the runs and actions of a service have containers
+// to be executed in
+func lintCommands(svc lintService, report reportFunc) {
+	sd := svc.sd
+	for _, name := range sortedKeys(sd.Runs) {
+		if sd.ImageID == "" {
+			report(LintError, LintCommands, svc, "Runs."+name, "the service has no image to run %s in", name)
+		}
+	}
+	for _, name := range sortedKeys(sd.Actions) {
+		if sd.ImageID == "" || sd.Command == "" {
+			report(LintError, LintCommands, svc, "Actions."+name, "the service has no instances to run action %s in", name)
+		}
+	}
+}
+
+// lintHealthChecks checks that script health checks have a script to run and
+// that http health checks request an absolute path
+func lintHealthChecks(svc lintService, report reportFunc) {
+	for _, name := range sortedHealthChecks(svc.sd.HealthChecks) {
+		hc := svc.sd.HealthChecks[name]
+		switch hc.Type {
+		case "", domain.HealthCheckScript:
+			if strings.TrimSpace(hc.Script) == "" {
+				report(LintError, LintHealth, svc, "HealthChecks."+name, "health check %s has no script", name)
+			}
+		case domain.HealthCheckHTTP:
+			if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+				report(LintError, LintHealth, svc, "HealthChecks."+name, "path %s of health check %s does not start with /", hc.Path, name)
+			}
+		}
+	}
+}
+
+// sortedHealthChecks returns the names of the health checks in order
+func sortedHealthChecks(m map[string]domain.HealthCheck) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type lintIssues []LintIssue
+
+func (l lintIssues) Len() int           { return len(l) }
+func (l lintIssues) Less(i, j int) bool { return l[i].Service < l[j].Service }
+func (l lintIssues) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
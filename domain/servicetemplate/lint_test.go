@@ -0,0 +1,123 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"testing"
+
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func lintTemplate() *ServiceTemplate {
+	return &ServiceTemplate{
+		Name: "app",
+		Services: []servicedefinition.ServiceDefinition{
+			{
+				Name:    "app",
+				Launch:  "auto",
+				Context: map[string]interface{}{"db.user": "zenoss", "tenant": "acme"},
+				Services: []servicedefinition.ServiceDefinition{
+					{
+						Name:      "db",
+						Command:   "run db",
+						ImageID:   "vendor/db",
+						Launch:    "auto",
+						Instances: domain.MinMax{Min: 1},
+						Endpoints: []servicedefinition.EndpointDefinition{
+							{Name: "mysql", Purpose: "export", Application: "mysql", PortNumber: 3306, Protocol: "tcp"},
+							{Name: "queue", Purpose: "export", ApplicationTemplate: "{{(context .).tenant}}_queue", PortNumber: 5672, Protocol: "tcp"},
+						},
+					}, {
+						Name:      "web",
+						Command:   `run web --user {{getContext . "db.user"}}`,
+						ImageID:   "vendor/web",
+						Launch:    "auto",
+						Instances: domain.MinMax{Min: 1},
+						Endpoints: []servicedefinition.EndpointDefinition{
+							{Name: "mysql", Purpose: "import", Application: "mysql", PortNumber: 3306, Protocol: "tcp"},
+							{Name: "queue", Purpose: "import", Application: "acme_queue", PortNumber: 5672, Protocol: "tcp"},
+						},
+						ConfigFiles: map[string]servicedefinition.ConfigFile{
+							"/etc/web.conf": {Filename: "/etc/web.conf", Content: `user={{getContext . "db.user"}} tenant={{(context .).tenant}}`},
+						},
+						Volumes: []servicedefinition.Volume{
+							{ResourcePath: "data", ContainerPath: "/var/data"},
+						},
+						Runs:    map[string]string{"migrate": "run migrate"},
+						Actions: map[string]string{"flush": "run flush"},
+						HealthChecks: map[string]domain.HealthCheck{
+							"running":   {Script: `test -f /var/data/{{(context .).tenant}}.pid`},
+							"answering": {Type: domain.HealthCheckHTTP, Port: 8080, Path: "/health"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	if issues := Lint(lintTemplate()); len(issues) != 0 {
+		t.Errorf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestLintIssues(t *testing.T) {
+	st := lintTemplate()
+	app := &st.Services[0]
+	app.Runs = map[string]string{"setup": "run setup"}
+	web := &app.Services[1]
+	web.Command = `run web --user {{(context .).dbuser}}`
+	web.Endpoints = append(web.Endpoints,
+		servicedefinition.EndpointDefinition{Name: "redis", Purpose: "import", Application: "redis", PortNumber: 6379, Protocol: "tcp"},
+		servicedefinition.EndpointDefinition{Name: "cache", Purpose: "import", Application: "mysql", PortNumber: 3306, PortTemplate: "{{plus 1 .InstanceID", Protocol: "tcp"},
+		servicedefinition.EndpointDefinition{Name: "mysql2", Purpose: "import", Application: "mysql", PortNumber: 3306, Protocol: "tcp"},
+	)
+	web.ConfigFiles["/etc/broken.conf"] = servicedefinition.ConfigFile{Filename: "/etc/broken.conf", Content: "{{if .Name}}"}
+	web.Volumes = append(web.Volumes, servicedefinition.Volume{ResourcePath: "logs", ContainerPath: "/var/data/logs"})
+	web.HealthChecks["running"] = domain.HealthCheck{Script: "  "}
+	web.HealthChecks["answering"] = domain.HealthCheck{Type: domain.HealthCheckHTTP, Port: 8080, Path: "health"}
+	web.HealthChecks["ready"] = domain.HealthCheck{Script: `test -f {{(context .).pidfile}}`}
+
+	expected := []struct {
+		severity, check, service, field string
+	}{
+		{LintError, LintCommands, "app", "Runs.setup"},
+		{LintError, LintImports, "app/web", "Endpoints.redis"},
+		{LintError, LintPorts, "app/web", "Endpoints.mysql2"},
+		{LintWarning, LintContext, "app/web", "Command"},
+		{LintError, LintTemplates, "app/web", "ConfigFiles./etc/broken.conf"},
+		{LintError, LintTemplates, "app/web", "Endpoints.cache.PortTemplate"},
+		{LintWarning, LintVolumes, "app/web", "Volumes./var/data/logs"},
+		{LintError, LintHealth, "app/web", "HealthChecks.running"},
+		{LintError, LintHealth, "app/web", "HealthChecks.answering"},
+		{LintWarning, LintContext, "app/web", "HealthChecks.ready"},
+	}
+	issues := Lint(st)
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %+v", len(expected), issues)
+	}
+	for _, e := range expected {
+		found := false
+		for _, issue := range issues {
+			if issue.Severity == e.severity && issue.Check == e.check && issue.Service == e.service && issue.Field == e.field {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %+v in %+v", e, issues)
+		}
+	}
+}
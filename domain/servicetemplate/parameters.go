@@ -0,0 +1,319 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+// Types of template parameters
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamFloat  = "float"
+	ParamBool   = "bool"
+)
+
+// Parameter is a value of a template that is chosen when the template is
+// deployed, so that one template can serve several environments
+type Parameter struct {
+	Name        string
+	Type        string // string (the default), int, float or bool
+	Description string
+	Default     string   // if there is no default, the targets keep the values of the template
+	Required    bool     // a value must be given when the template is deployed
+	Allowed     []string // optional list of the allowed values
+	Min         *float64 // optional bounds of int and float values
+	Max         *float64
+	Targets     []string // the fields that are set to the value, as SERVICEPATH:FIELD, e.g. "app/web:Instances.Default"
+}
+
+// Validate checks that a value fits the parameter
+func (p *Parameter) Validate(value string) error {
+	if len(p.Allowed) > 0 {
+		allowed := false
+		for _, a := range p.Allowed {
+			if a == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("parameter %s must be one of %s", p.Name, strings.Join(p.Allowed, ", "))
+		}
+	}
+
+	var number float64
+	switch p.Type {
+	case "", ParamString:
+		return nil
+	case ParamBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("parameter %s must be true or false", p.Name)
+		}
+		return nil
+	case ParamInt:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parameter %s must be an integer", p.Name)
+		}
+		number = float64(i)
+	case ParamFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("parameter %s must be a number", p.Name)
+		}
+		number = f
+	default:
+		return fmt.Errorf("parameter %s has unknown type %s", p.Name, p.Type)
+	}
+	if p.Min != nil && number < *p.Min {
+		return fmt.Errorf("parameter %s must be at least %g", p.Name, *p.Min)
+	}
+	if p.Max != nil && number > *p.Max {
+		return fmt.Errorf("parameter %s must be at most %g", p.Name, *p.Max)
+	}
+	return nil
+}
+
+// jsonValue converts a valid value to the JSON type of the parameter
+func (p *Parameter) jsonValue(value string) interface{} {
+	switch p.Type {
+	case ParamBool:
+		b, _ := strconv.ParseBool(value)
+		return b
+	case ParamInt:
+		i, _ := strconv.ParseInt(value, 10, 64)
+		return i
+	case ParamFloat:
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	}
+	return value
+}
+
+// sampleValue returns a valid value used to check the targets of a parameter
+func (p *Parameter) sampleValue() string {
+	switch {
+	case len(p.Allowed) > 0:
+		return p.Allowed[0]
+	case p.Min != nil:
+		return strconv.FormatFloat(*p.Min, 'f', -1, 64)
+	case p.Type == ParamBool:
+		return "false"
+	case p.Type == ParamInt, p.Type == ParamFloat:
+		return "0"
+	}
+	return ""
+}
+
+// validateParameters checks the declarations of the parameters and that
+// their targets exist
+func (st *ServiceTemplate) validateParameters() error {
+	seen := make(map[string]bool)
+	values := make(map[string]string)
+	for i := range st.Parameters {
+		p := &st.Parameters[i]
+		if p.Name == "" {
+			return fmt.Errorf("template parameters must have a name")
+		} else if seen[p.Name] {
+			return fmt.Errorf("duplicate template parameter %s", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Default != "" {
+			if err := p.Validate(p.Default); err != nil {
+				return fmt.Errorf("invalid default: %s", err)
+			}
+		} else {
+			values[p.Name] = p.sampleValue()
+		}
+	}
+	// the targets may be in services that are not included yet
+	if len(st.Parameters) == 0 || st.hasIncludes() {
+		return nil
+	}
+	_, err := st.Apply(values)
+	return err
+}
+
+// Apply returns a copy of the template with the values of its parameters set
+// in their targets. Parameters without a value take their default.
+func (st ServiceTemplate) Apply(values map[string]string) (*ServiceTemplate, error) {
+	declared := make(map[string]*Parameter)
+	for i := range st.Parameters {
+		declared[st.Parameters[i].Name] = &st.Parameters[i]
+	}
+	for name := range values {
+		if declared[name] == nil {
+			return nil, fmt.Errorf("template %s has no parameter %s", st.Name, name)
+		}
+	}
+
+	applied, err := editServices(st.Services, func(services []interface{}) error {
+		for _, p := range st.Parameters {
+			value, ok := values[p.Name]
+			if !ok {
+				if p.Required {
+					return fmt.Errorf("parameter %s is required", p.Name)
+				} else if p.Default == "" {
+					continue
+				}
+				value = p.Default
+			}
+			if err := p.Validate(value); err != nil {
+				return err
+			}
+			for _, target := range p.Targets {
+				if err := setTarget(services, target, p.jsonValue(value)); err != nil {
+					return fmt.Errorf("parameter %s: %s", p.Name, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	st.Services = applied
+	return &st, nil
+}
+
+// DeclaredValues returns the values of the parameters that the template
+// declares
+func (st *ServiceTemplate) DeclaredValues(values map[string]string) map[string]string {
+	declared := make(map[string]string)
+	for _, p := range st.Parameters {
+		if value, ok := values[p.Name]; ok {
+			declared[p.Name] = value
+		}
+	}
+	return declared
+}
+
+// editServices returns a copy of service definitions that are edited in
+// their JSON form, which does not depend on the types of the fields
+func editServices(sds []servicedefinition.ServiceDefinition, edit func(services []interface{}) error) ([]servicedefinition.ServiceDefinition, error) {
+	services, err := toJSON(sds)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := services.([]interface{})
+	if err := edit(list); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var edited []servicedefinition.ServiceDefinition
+	if err := json.Unmarshal(data, &edited); err != nil {
+		return nil, fmt.Errorf("invalid service definitions: %s", err)
+	}
+	return edited, nil
+}
+
+// toJSON returns the generic JSON form of a value
+func toJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// setTarget sets a field of the JSON form of a service definition. The target
+// is SERVICEPATH:FIELD, where SERVICEPATH is the names of the services from
+// the top of the template separated by /, and FIELD is the path to the field
+// separated by dots. Elements of lists are found by their name or index, and
+// keys of maps may contain dots.
+func setTarget(services []interface{}, target string, value interface{}) error {
+	parts := strings.SplitN(target, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("target %s is not SERVICEPATH:FIELD", target)
+	}
+
+	var svc map[string]interface{}
+	children := services
+	for _, name := range strings.Split(parts[0], "/") {
+		svc = nil
+		for _, child := range children {
+			if c, ok := child.(map[string]interface{}); ok && c["Name"] == name {
+				svc = c
+				break
+			}
+		}
+		if svc == nil {
+			return fmt.Errorf("target %s: service %s not found", target, name)
+		}
+		children, _ = svc["Services"].([]interface{})
+	}
+
+	// all the fields of a service definition are in its JSON form
+	field := strings.SplitN(parts[1], ".", 2)[0]
+	if _, ok := svc[field]; !ok {
+		return fmt.Errorf("target %s: field %s not found", target, field)
+	}
+	if err := setField(svc, parts[1], value); err != nil {
+		return fmt.Errorf("target %s: %s", target, err)
+	}
+	return nil
+}
+
+// setField sets the value at a dotted path in a JSON object
+func setField(node interface{}, path string, value interface{}) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		// the longest key that the path starts with, so that keys may
+		// contain dots
+		key := ""
+		for k := range n {
+			if (path == k || strings.HasPrefix(path, k+".")) && len(k) > len(key) {
+				key = k
+			}
+		}
+		if key == "" {
+			key = strings.SplitN(path, ".", 2)[0]
+			if key != path {
+				return fmt.Errorf("field %s not found", key)
+			}
+		}
+		if key == path {
+			n[key] = value
+			return nil
+		}
+		return setField(n[key], path[len(key)+1:], value)
+	case []interface{}:
+		parts := strings.SplitN(path, ".", 2)
+		for i, elem := range n {
+			if e, ok := elem.(map[string]interface{}); ok && e["Name"] == parts[0] || strconv.Itoa(i) == parts[0] {
+				if len(parts) == 1 {
+					n[i] = value
+					return nil
+				}
+				return setField(elem, parts[1], value)
+			}
+		}
+		return fmt.Errorf("element %s not found", parts[0])
+	}
+	return fmt.Errorf("field %s not found", path)
+}
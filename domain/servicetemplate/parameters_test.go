@@ -0,0 +1,137 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"testing"
+
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func parameterTemplate() ServiceTemplate {
+	one := float64(1)
+	return ServiceTemplate{
+		Name: "app",
+		Services: []servicedefinition.ServiceDefinition{
+			{
+				Name: "app",
+				Services: []servicedefinition.ServiceDefinition{
+					{
+						Name:      "web",
+						Command:   "run web",
+						Instances: domain.MinMax{Min: 1, Default: 1},
+						ConfigFiles: map[string]servicedefinition.ConfigFile{
+							"/etc/web.conf": servicedefinition.ConfigFile{Filename: "/etc/web.conf", Content: "debug"},
+						},
+					},
+				},
+			},
+		},
+		Parameters: []Parameter{
+			{Name: "instances", Type: ParamInt, Min: &one, Default: "2", Targets: []string{"app/web:Instances.Default"}},
+			{Name: "config", Targets: []string{"app/web:ConfigFiles./etc/web.conf.Content"}},
+			{Name: "mode", Allowed: []string{"fast", "slow"}, Targets: []string{"app/web:Command"}},
+		},
+	}
+}
+
+func TestApplyParameters(t *testing.T) {
+	st := parameterTemplate()
+
+	applied, err := st.Apply(map[string]string{"config": "verbose", "mode": "fast"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	web := applied.Services[0].Services[0]
+	if web.Instances.Default != 2 {
+		t.Errorf("expected the default of 2 instances, got %d", web.Instances.Default)
+	}
+	if web.ConfigFiles["/etc/web.conf"].Content != "verbose" {
+		t.Errorf("unexpected config %+v", web.ConfigFiles)
+	}
+	if web.Command != "fast" {
+		t.Errorf("unexpected command %s", web.Command)
+	}
+
+	// the template itself is unchanged
+	if st.Services[0].Services[0].Instances.Default != 1 || st.Services[0].Services[0].Command != "run web" {
+		t.Errorf("template was changed: %+v", st.Services[0].Services[0])
+	}
+
+	// parameters without a value or default keep the values of the template
+	applied, err = st.Apply(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if web := applied.Services[0].Services[0]; web.Command != "run web" || web.ConfigFiles["/etc/web.conf"].Content != "debug" {
+		t.Errorf("unexpected service %+v", web)
+	}
+}
+
+func TestApplyParametersErrors(t *testing.T) {
+	st := parameterTemplate()
+	for _, values := range []map[string]string{
+		{"unknown": "1"},
+		{"instances": "two"},
+		{"instances": "0"},
+		{"mode": "medium"},
+	} {
+		if _, err := st.Apply(values); err == nil {
+			t.Errorf("expected an error for %v", values)
+		}
+	}
+
+	st.Parameters[2].Required = true
+	if _, err := st.Apply(nil); err == nil {
+		t.Errorf("expected an error for a missing required parameter")
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	st := parameterTemplate()
+	if err := st.validateParameters(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	st = parameterTemplate()
+	st.Parameters = append(st.Parameters, Parameter{Name: "mode"})
+	if err := st.validateParameters(); err == nil {
+		t.Errorf("expected an error for a duplicate parameter")
+	}
+
+	st = parameterTemplate()
+	st.Parameters[0].Default = "0"
+	if err := st.validateParameters(); err == nil {
+		t.Errorf("expected an error for an invalid default")
+	}
+
+	st = parameterTemplate()
+	st.Parameters[1].Targets = []string{"app/db:Command"}
+	if err := st.validateParameters(); err == nil {
+		t.Errorf("expected an error for a missing service")
+	}
+
+	st = parameterTemplate()
+	st.Parameters[1].Targets = []string{"app/web:Nothing.Here"}
+	if err := st.validateParameters(); err == nil {
+		t.Errorf("expected an error for a missing field")
+	}
+
+	st = parameterTemplate()
+	st.Parameters[1].Type = ParamBool
+	if err := st.validateParameters(); err == nil {
+		t.Errorf("expected an error for a target of the wrong type")
+	}
+}
@@ -17,8 +17,12 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 
+	"github.com/control-center/serviced/commons/yaml"
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/servicedefinition"
 )
@@ -31,6 +35,9 @@ type ServiceTemplate struct {
 	Description string                                  // Meaningful description of service
 	Services    []servicedefinition.ServiceDefinition   // Child services
 	ConfigFiles map[string]servicedefinition.ConfigFile // Config file templates
+	Parameters  []Parameter                             // Values chosen when the template is deployed
+	Extends     *Include                                // Template whose services this template starts from
+	Includes    []Include                               // Templates whose services are added to this template
 	datastore.VersionedEntity
 }
 
@@ -54,6 +61,15 @@ func (a *ServiceTemplate) Equals(b *ServiceTemplate) bool {
 	if !reflect.DeepEqual(a.ConfigFiles, b.ConfigFiles) {
 		return false
 	}
+	if !reflect.DeepEqual(a.Parameters, b.Parameters) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Extends, b.Extends) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Includes, b.Includes) {
+		return false
+	}
 	return true
 }
 
@@ -73,6 +89,13 @@ func FromJSON(data string) (*ServiceTemplate, error) {
 	return &st, err
 }
 
+//FromYAML creates a ServiceTemplate from a YAML or JSON string
+func FromYAML(data string) (*ServiceTemplate, error) {
+	var st ServiceTemplate
+	err := yaml.Unmarshal([]byte(data), &st)
+	return &st, err
+}
+
 // ServiceTemplateWrapper type for storing ServiceTemplates  TODO: no need to be public when CRUD moves hers
 type serviceTemplateWrapper struct {
 	ID              string // Primary-key - Should match ServiceTemplate.ID
@@ -103,17 +126,44 @@ func newWrapper(st ServiceTemplate) (*serviceTemplateWrapper, error) {
 
 }
 
+// TemplateFiles are the names that the optional file at the top of a
+// directory of service definitions may have. It holds the fields of the
+// template, such as its parameters and includes, in JSON or in YAML.
+var TemplateFiles = []string{"template.json", "template.yaml", "template.yml"}
+
 //BuildFromPath given a path will create a ServiceDefintion
 func BuildFromPath(path string) (*ServiceTemplate, error) {
 	sd, err := servicedefinition.BuildFromPath(path)
 	if err != nil {
 		return nil, err
 	}
-	st := ServiceTemplate{
-		Services:    []servicedefinition.ServiceDefinition{*sd},
-		Name:        sd.Name,
-		Version:     sd.Version,
-		Description: sd.Description,
+	st := ServiceTemplate{}
+	templateFile := ""
+	for _, name := range TemplateFiles {
+		filename := filepath.Join(path, name)
+		data, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if templateFile != "" {
+			return nil, fmt.Errorf("%s has both %s and %s", path, templateFile, name)
+		}
+		templateFile = name
+		if err := yaml.Unmarshal(data, &st); err != nil {
+			return nil, fmt.Errorf("could not read %s: %s", filename, err)
+		}
+	}
+	st.Services = []servicedefinition.ServiceDefinition{*sd}
+	if st.Name == "" {
+		st.Name = sd.Name
+	}
+	if st.Version == "" {
+		st.Version = sd.Version
+	}
+	if st.Description == "" {
+		st.Description = sd.Description
 	}
 	return &st, nil
 }
@@ -57,10 +57,93 @@ var (
 `
 	//MAPPING is the elastic mapping for a service template
 	MAPPING, mappingError = elastic.NewMapping(mappingString)
+
+	deploymentMappingString = `
+{
+  "servicetemplatedeployment" : {
+    "properties" : {
+      "ID" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "TemplateID" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "TemplateHash" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "Data" : {
+        "type"  : "string",
+        "index" : "no"
+      },
+      "Params" : {
+        "type"    : "object",
+        "enabled" : false
+      },
+      "DeployedAt" : {
+        "type"  : "date",
+        "format" : "dateOptionalTime"
+      },
+      "Managed" : {
+        "type"  : "boolean"
+      }
+    }
+  }
+}
+`
+	//DeploymentMapping is the elastic mapping for the record of a deployment
+	DeploymentMapping, deploymentMappingError = elastic.NewMapping(deploymentMappingString)
+
+	versionMappingString = `
+{
+  "servicetemplateversion" : {
+    "properties" : {
+      "ID" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "TemplateID" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "Hash" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "Name" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "Version" : {
+        "type"  : "string",
+        "index" : "not_analyzed"
+      },
+      "Data" : {
+        "type"  : "string",
+        "index" : "no"
+      },
+      "CreatedAt" : {
+        "type"  : "date",
+        "format" : "dateOptionalTime"
+      }
+    }
+  }
+}
+`
+	//VersionMapping is the elastic mapping for the versions of a template
+	VersionMapping, versionMappingError = elastic.NewMapping(versionMappingString)
 )
 
 func init() {
 	if mappingError != nil {
 		glog.Fatalf("error creating host mapping: %v", mappingError)
 	}
+	if deploymentMappingError != nil {
+		glog.Fatalf("error creating deployment mapping: %v", deploymentMappingError)
+	}
+	if versionMappingError != nil {
+		glog.Fatalf("error creating template version mapping: %v", versionMappingError)
+	}
 }
@@ -31,7 +31,7 @@ type Store struct {
 	ds datastore.DataStore
 }
 
-// Put adds or updates a ServiceTemplate
+// Put adds or updates a ServiceTemplate and keeps its previous versions
 func (s *Store) Put(ctx datastore.Context, st ServiceTemplate) error {
 	if err := st.ValidEntity(); err != nil {
 		return fmt.Errorf("error validating template: %v", err)
@@ -40,7 +40,10 @@ func (s *Store) Put(ctx datastore.Context, st ServiceTemplate) error {
 	if err != nil {
 		return err
 	}
-	return s.ds.Put(ctx, Key(wrapper.ID), wrapper)
+	if err := s.ds.Put(ctx, Key(wrapper.ID), wrapper); err != nil {
+		return err
+	}
+	return s.putVersion(ctx, st)
 }
 
 // Get a ServiceTemplate by id. Return ErrNoSuchEntity if not found
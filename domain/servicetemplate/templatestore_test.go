@@ -31,7 +31,7 @@ func Test(t *testing.T) {
 var _ = Suite(&S{
 	ElasticTest: elastic.ElasticTest{
 		Index:    "controlplane",
-		Mappings: []elastic.Mapping{MAPPING},
+		Mappings: []elastic.Mapping{MAPPING, DeploymentMapping, VersionMapping},
 	}})
 
 type S struct {
@@ -105,3 +105,74 @@ func (s *S) Test_GetServiceTemplates(t *C) {
 	t.Assert(len(servicetemplates), Equals, 2)
 
 }
+
+func (s *S) Test_DeploymentCRUD(t *C) {
+	_, err := s.store.GetDeployment(s.ctx, "dep_test_id")
+	t.Assert(datastore.IsErrNoSuchEntity(err), Equals, true)
+
+	st := ServiceTemplate{ID: "st_test_id", Name: testutils.ValidSvcDef.Name}
+	st.Services = []servicedefinition.ServiceDefinition{*testutils.ValidSvcDef}
+	d, err := NewDeployment("dep_test_id", st, nil)
+	t.Assert(err, IsNil)
+	err = s.store.PutDeployment(s.ctx, d)
+	t.Assert(err, IsNil)
+
+	d2, err := s.store.GetDeployment(s.ctx, "dep_test_id")
+	t.Assert(err, IsNil)
+	t.Assert(d2.TemplateID, Equals, st.ID)
+	st2, err := d2.Template()
+	t.Assert(err, IsNil)
+	t.Assert(st2.Equals(&st), Equals, true)
+
+	// an upgrade replaces the record
+	st.ID = "st_test_id2"
+	d, err = NewDeployment("dep_test_id", st, nil)
+	t.Assert(err, IsNil)
+	err = s.store.PutDeployment(s.ctx, d)
+	t.Assert(err, IsNil)
+	d2, err = s.store.GetDeployment(s.ctx, "dep_test_id")
+	t.Assert(err, IsNil)
+	t.Assert(d2.TemplateID, Equals, "st_test_id2")
+}
+
+func (s *S) Test_TemplateHistory(t *C) {
+	st := ServiceTemplate{ID: "st_history_id", Name: testutils.ValidSvcDef.Name}
+	st.Services = []servicedefinition.ServiceDefinition{*testutils.ValidSvcDef}
+	err := s.store.Put(s.ctx, st)
+	t.Assert(err, IsNil)
+	hash1, err := ContentHash(st)
+	t.Assert(err, IsNil)
+
+	// the content hash does not depend on the id
+	other := st
+	other.ID = "other"
+	otherHash, err := ContentHash(other)
+	t.Assert(err, IsNil)
+	t.Assert(otherHash, Equals, hash1)
+
+	st.Services[0].Command = "blam"
+	err = s.store.Put(s.ctx, st)
+	t.Assert(err, IsNil)
+	hash2, err := ContentHash(st)
+	t.Assert(err, IsNil)
+
+	// putting the same content again does not add a version
+	err = s.store.Put(s.ctx, st)
+	t.Assert(err, IsNil)
+
+	versions, err := s.store.GetVersions(s.ctx, st.ID)
+	t.Assert(err, IsNil)
+	t.Assert(len(versions), Equals, 2)
+	t.Assert(versions[0].Hash, Equals, hash1)
+	t.Assert(versions[1].Hash, Equals, hash2)
+
+	v, err := s.store.FindVersion(s.ctx, st.ID, hash1[:8])
+	t.Assert(err, IsNil)
+	st1, err := v.Template()
+	t.Assert(err, IsNil)
+	t.Assert(st1.ID, Equals, st.ID)
+	t.Assert(st1.Services[0].Command, Equals, testutils.ValidSvcDef.Command)
+
+	_, err = s.store.FindVersion(s.ctx, st.ID, "nosuchhash")
+	t.Assert(err, NotNil)
+}
@@ -0,0 +1,400 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+// Actions of the changes in an upgrade plan
+const (
+	UpgradeAdd      = "add"      // the new template adds a service
+	UpgradeRemove   = "remove"   // the new template drops a service
+	UpgradeUpdate   = "update"   // the new template changes a value the users did not edit
+	UpgradeConflict = "conflict" // the new template and the users changed the same value; the users' value is kept
+)
+
+// UpgradeChange is one change that an upgrade makes to the services of a
+// tenant
+type UpgradeChange struct {
+	Action    string
+	Path      string // the names of the services from the tenant down, separated by /
+	ServiceID string // empty for the services that are added
+	Field     string // the changed field of the service, or ConfigFiles/FILENAME
+	Detail    string
+}
+
+// UpgradePlan lists the changes that upgrade a tenant to a new template
+type UpgradePlan struct {
+	TenantID       string
+	DeploymentID   string
+	FromTemplateID string // empty if the deployment of the tenant was not recorded
+	ToTemplateID   string
+	SnapshotID     string            // the snapshot taken before the plan was applied
+	Params         map[string]string // the values of the parameters of the new template
+	Changes        []UpgradeChange
+
+	// The services to update, add and remove; these are only used on the
+	// master and are not sent to clients
+	Update []service.Service `json:"-"`
+	Add    []UpgradeAddition `json:"-"`
+	Remove []string          `json:"-"`
+}
+
+// UpgradeAddition is a service definition to deploy under an existing service
+type UpgradeAddition struct {
+	ParentID   string
+	Definition servicedefinition.ServiceDefinition
+}
+
+// Conflicts returns the number of changes that are conflicts
+func (p *UpgradePlan) Conflicts() int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == UpgradeConflict {
+			count++
+		}
+	}
+	return count
+}
+
+// the fields of a service that are not set by its definition or that are
+// merged separately
+var upgradeSkipFields = map[string]bool{
+	"ID":              true,
+	"Name":            true,
+	"PoolID":          true,
+	"DesiredState":    true,
+	"ParentServiceID": true,
+	"CreatedAt":       true,
+	"UpdatedAt":       true,
+	"DeploymentID":    true,
+	"OriginalConfigs": true,
+	"ConfigFiles":     true,
+	"ImageID":         true,
+	"Endpoints":       true,
+	"VersionedEntity": true,
+}
+
+// PlanUpgrade computes the changes that upgrade the live services of a tenant
+// from the service definition that the tenant was deployed from (base) to a
+// new one (next). The changes made by users since the tenant was deployed,
+// including their edits of config files, are kept. If base is nil because the
+// deployment was not recorded, the values of the new definition are taken for
+// everything but the config files, which are merged with the configs that the
+// services were deployed with.
+func PlanUpgrade(tenantID string, base, next *servicedefinition.ServiceDefinition, live []service.Service) (*UpgradePlan, error) {
+	var tenant *service.Service
+	children := make(map[string][]*service.Service)
+	for i := range live {
+		svc := &live[i]
+		if svc.ID == tenantID {
+			tenant = svc
+		}
+		children[svc.ParentServiceID] = append(children[svc.ParentServiceID], svc)
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	p := &upgradePlanner{
+		plan:     &UpgradePlan{TenantID: tenantID, DeploymentID: tenant.DeploymentID},
+		children: children,
+		recorded: base != nil,
+	}
+	if err := p.walk(tenant.Name, base, next, tenant, tenant.ParentServiceID); err != nil {
+		return nil, err
+	}
+	return p.plan, nil
+}
+
+type upgradePlanner struct {
+	plan     *UpgradePlan
+	children map[string][]*service.Service
+	recorded bool
+}
+
+func (p *upgradePlanner) change(action, path, serviceID, field, detail string) {
+	p.plan.Changes = append(p.plan.Changes, UpgradeChange{
+		Action:    action,
+		Path:      path,
+		ServiceID: serviceID,
+		Field:     field,
+		Detail:    detail,
+	})
+}
+
+// walk plans the upgrade of a service and its children
+func (p *upgradePlanner) walk(path string, base, next *servicedefinition.ServiceDefinition, live *service.Service, parentID string) error {
+	switch {
+	case next == nil && live == nil:
+		return nil
+	case live == nil:
+		if base != nil {
+			p.change(UpgradeConflict, path, "", "", "removed since it was deployed; not added back")
+			return nil
+		}
+		p.plan.Add = append(p.plan.Add, UpgradeAddition{ParentID: parentID, Definition: *next})
+		p.change(UpgradeAdd, path, "", "", "")
+		return nil
+	case next == nil:
+		if base != nil {
+			p.plan.Remove = append(p.plan.Remove, live.ID)
+			p.change(UpgradeRemove, path, live.ID, "", "")
+		} else if !p.recorded {
+			p.change(UpgradeConflict, path, live.ID, "", "not in the template; kept")
+		}
+		// otherwise the service was added by a user
+		return nil
+	}
+
+	if err := p.merge(path, base, next, live); err != nil {
+		return err
+	}
+
+	// match the children by name, in the order of the new definition
+	var names []string
+	seen := make(map[string]bool)
+	addName := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, sd := range next.Services {
+		addName(sd.Name)
+	}
+	if base != nil {
+		for _, sd := range base.Services {
+			addName(sd.Name)
+		}
+	}
+	liveChildren := make(map[string]*service.Service)
+	for _, child := range p.children[live.ID] {
+		liveChildren[child.Name] = child
+		addName(child.Name)
+	}
+	for _, name := range names {
+		var baseChild *servicedefinition.ServiceDefinition
+		if base != nil {
+			baseChild = findDefinition(base.Services, name)
+		}
+		if err := p.walk(path+"/"+name, baseChild, findDefinition(next.Services, name), liveChildren[name], live.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge plans the changes to the fields of a live service
+func (p *upgradePlanner) merge(path string, base, next *servicedefinition.ServiceDefinition, live *service.Service) error {
+	nextSvc, err := buildLike(next, live)
+	if err != nil {
+		return err
+	}
+	baseSvc := live
+	if base != nil {
+		if baseSvc, err = buildLike(base, live); err != nil {
+			return err
+		}
+	}
+
+	merged := *live
+	changed := false
+	mergeValue := func(field string, b, n, l interface{}) bool {
+		switch {
+		case jsonEqual(b, n), jsonEqual(l, n):
+			return false
+		case jsonEqual(l, b):
+			p.change(UpgradeUpdate, path, live.ID, field, "")
+			changed = true
+			return true
+		default:
+			p.change(UpgradeConflict, path, live.ID, field, "edited since it was deployed; kept")
+			return false
+		}
+	}
+
+	mv := reflect.ValueOf(&merged).Elem()
+	bv, nv, lv := reflect.ValueOf(baseSvc).Elem(), reflect.ValueOf(nextSvc).Elem(), reflect.ValueOf(live).Elem()
+	for i := 0; i < mv.NumField(); i++ {
+		field := mv.Type().Field(i).Name
+		if upgradeSkipFields[field] {
+			continue
+		}
+		if mergeValue(field, bv.Field(i).Interface(), nv.Field(i).Interface(), lv.Field(i).Interface()) {
+			mv.Field(i).Set(nv.Field(i))
+		}
+	}
+
+	// live images are renamed into the registry of the tenant, so without a
+	// record of the deployment only the names of the images can be compared
+	imageChanged := imageName(next.ImageID) != imageName(live.ImageID)
+	if base != nil {
+		imageChanged = next.ImageID != base.ImageID
+	}
+	if imageChanged {
+		p.change(UpgradeUpdate, path, live.ID, "ImageID", next.ImageID)
+		merged.ImageID = next.ImageID
+		changed = true
+	}
+
+	// endpoint applications are evaluated and addresses are assigned
+	if mergeValue("Endpoints", endpointDefinitions(baseSvc.Endpoints), endpointDefinitions(nextSvc.Endpoints), endpointDefinitions(live.Endpoints)) {
+		assignments := make(map[string]service.ServiceEndpoint)
+		for _, ep := range live.Endpoints {
+			assignments[ep.Name] = ep
+		}
+		merged.Endpoints = make([]service.ServiceEndpoint, len(nextSvc.Endpoints))
+		for i, ep := range nextSvc.Endpoints {
+			ep.AddressAssignment = assignments[ep.Name].AddressAssignment
+			merged.Endpoints[i] = ep
+		}
+	}
+
+	// config files are merged one by one, so that the edits of users are kept
+	baseConfigs := live.OriginalConfigs
+	if base != nil {
+		baseConfigs = base.ConfigFiles
+	}
+	var filenames []string
+	for _, configs := range []map[string]servicedefinition.ConfigFile{baseConfigs, next.ConfigFiles, live.ConfigFiles} {
+		for filename := range configs {
+			if !containsString(filenames, filename) {
+				filenames = append(filenames, filename)
+			}
+		}
+	}
+	sort.Strings(filenames)
+	merged.ConfigFiles = make(map[string]servicedefinition.ConfigFile)
+	for filename, conf := range live.ConfigFiles {
+		merged.ConfigFiles[filename] = conf
+	}
+	for _, filename := range filenames {
+		b, bok := baseConfigs[filename]
+		n, nok := next.ConfigFiles[filename]
+		l, lok := live.ConfigFiles[filename]
+		if mergeValue("ConfigFiles/"+filename, configValue(b, bok), configValue(n, nok), configValue(l, lok)) {
+			if nok {
+				merged.ConfigFiles[filename] = n
+			} else {
+				delete(merged.ConfigFiles, filename)
+			}
+		}
+	}
+	merged.OriginalConfigs = next.ConfigFiles
+	if !jsonEqual(merged.OriginalConfigs, live.OriginalConfigs) {
+		changed = true
+	}
+
+	if changed {
+		p.plan.Update = append(p.plan.Update, merged)
+	}
+	return nil
+}
+
+// buildLike builds the service of a definition with the ids of a live service
+func buildLike(sd *servicedefinition.ServiceDefinition, live *service.Service) (*service.Service, error) {
+	svc, err := service.BuildService(*sd, live.ParentServiceID, live.PoolID, live.DesiredState, live.DeploymentID)
+	if err != nil {
+		return nil, err
+	}
+	svc.ID = live.ID
+	profile, err := sd.MonitoringProfile.ReBuild("1h-ago", map[string][]string{"controlplane_service_id": []string{live.ID}})
+	if err != nil {
+		return nil, err
+	}
+	svc.MonitoringProfile = *profile
+	return svc, nil
+}
+
+func findDefinition(sds []servicedefinition.ServiceDefinition, name string) *servicedefinition.ServiceDefinition {
+	for i := range sds {
+		if sds[i].Name == name {
+			return &sds[i]
+		}
+	}
+	return nil
+}
+
+// endpointDefinitions returns the endpoints as they are defined, without
+// their evaluated applications and address assignments
+func endpointDefinitions(endpoints []service.ServiceEndpoint) []servicedefinition.EndpointDefinition {
+	defs := make([]servicedefinition.EndpointDefinition, len(endpoints))
+	for i, ep := range endpoints {
+		def := ep.EndpointDefinition
+		if def.ApplicationTemplate == "" {
+			def.ApplicationTemplate = def.Application
+		}
+		def.Application = ""
+		defs[i] = def
+	}
+	return defs
+}
+
+// imageName returns the name of an image without its registry, namespace and
+// tag
+func imageName(imageID string) string {
+	if i := strings.LastIndex(imageID, "/"); i >= 0 {
+		imageID = imageID[i+1:]
+	}
+	if i := strings.Index(imageID, ":"); i >= 0 {
+		imageID = imageID[:i]
+	}
+	return imageID
+}
+
+func configValue(conf servicedefinition.ConfigFile, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	return conf
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual compares values as they are stored, so that nil and empty slices
+// and maps are equal
+func jsonEqual(a, b interface{}) bool {
+	aj, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	empty := func(data []byte) bool {
+		s := string(data)
+		return s == "null" || s == "[]" || s == "{}"
+	}
+	if empty(aj) && empty(bj) {
+		return true
+	}
+	return string(aj) == string(bj)
+}
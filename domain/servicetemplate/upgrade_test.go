@@ -0,0 +1,183 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicetemplate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func webConfig(content string) map[string]servicedefinition.ConfigFile {
+	return map[string]servicedefinition.ConfigFile{
+		"/etc/web.conf": servicedefinition.ConfigFile{Filename: "/etc/web.conf", Owner: "root:root", Permissions: "0644", Content: content},
+	}
+}
+
+// deploys base and returns the live services as a user would have edited them
+func upgradeFixture(t *testing.T, base *servicedefinition.ServiceDefinition) []service.Service {
+	var live []service.Service
+	var deploy func(sd servicedefinition.ServiceDefinition, parentID string)
+	deploy = func(sd servicedefinition.ServiceDefinition, parentID string) {
+		svc, err := service.BuildService(sd, parentID, "default", 0, "deployment")
+		if err != nil {
+			t.Fatalf("could not build service %s: %s", sd.Name, err)
+		}
+		if sd.ImageID != "" {
+			svc.ImageID = "localhost:5000/tenant/" + imageName(sd.ImageID)
+		}
+		live = append(live, *svc)
+		for _, child := range sd.Services {
+			deploy(child, svc.ID)
+		}
+	}
+	deploy(*base, "")
+
+	for i := range live {
+		if live[i].Name == "web" {
+			live[i].Instances = 3
+			live[i].ConfigFiles = webConfig("edited")
+			live = append(live, service.Service{ID: "extra", Name: "extra", ParentServiceID: live[i].ParentServiceID})
+			break
+		}
+	}
+	return live
+}
+
+func upgradeDefinitions() (base, next *servicedefinition.ServiceDefinition) {
+	base = &servicedefinition.ServiceDefinition{
+		Name: "app",
+		Services: []servicedefinition.ServiceDefinition{
+			{
+				Name:        "web",
+				Command:     "run web",
+				ImageID:     "vendor/web:1",
+				Instances:   domain.MinMax{Min: 1},
+				ConfigFiles: webConfig("original"),
+			}, {
+				Name:    "db",
+				Command: "run db",
+			},
+		},
+	}
+	next = &servicedefinition.ServiceDefinition{
+		Name: "app",
+		Services: []servicedefinition.ServiceDefinition{
+			{
+				Name:        "web",
+				Command:     "run web --fast",
+				ImageID:     "vendor/web:2",
+				Instances:   domain.MinMax{Min: 1, Default: 2},
+				ConfigFiles: webConfig("upgraded"),
+			}, {
+				Name:    "cache",
+				Command: "run cache",
+			},
+		},
+	}
+	return base, next
+}
+
+func TestPlanUpgrade(t *testing.T) {
+	base, next := upgradeDefinitions()
+	live := upgradeFixture(t, base)
+	tenantID := live[0].ID
+
+	plan, err := PlanUpgrade(tenantID, base, next, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	webID := live[1].ID
+	expected := []UpgradeChange{
+		{UpgradeUpdate, "app/web", webID, "Startup", ""},
+		{UpgradeConflict, "app/web", webID, "Instances", "edited since it was deployed; kept"},
+		{UpgradeUpdate, "app/web", webID, "InstanceLimits", ""},
+		{UpgradeUpdate, "app/web", webID, "ImageID", "vendor/web:2"},
+		{UpgradeConflict, "app/web", webID, "ConfigFiles//etc/web.conf", "edited since it was deployed; kept"},
+		{UpgradeAdd, "app/cache", "", "", ""},
+		{UpgradeRemove, "app/db", live[2].ID, "", ""},
+	}
+	if !reflect.DeepEqual(plan.Changes, expected) {
+		t.Fatalf("expected changes\n%+v\ngot\n%+v", expected, plan.Changes)
+	}
+	if plan.Conflicts() != 2 {
+		t.Errorf("expected 2 conflicts, got %d", plan.Conflicts())
+	}
+
+	if len(plan.Update) != 1 {
+		t.Fatalf("expected 1 service to update, got %+v", plan.Update)
+	}
+	web := plan.Update[0]
+	if web.ID != webID || web.Startup != "run web --fast" || web.Instances != 3 || web.ImageID != "vendor/web:2" {
+		t.Errorf("unexpected update %+v", web)
+	}
+	if !reflect.DeepEqual(web.ConfigFiles, webConfig("edited")) || !reflect.DeepEqual(web.OriginalConfigs, webConfig("upgraded")) {
+		t.Errorf("unexpected configs %+v, originals %+v", web.ConfigFiles, web.OriginalConfigs)
+	}
+	if len(plan.Add) != 1 || plan.Add[0].ParentID != tenantID || plan.Add[0].Definition.Name != "cache" {
+		t.Errorf("unexpected additions %+v", plan.Add)
+	}
+	if !reflect.DeepEqual(plan.Remove, []string{live[2].ID}) {
+		t.Errorf("unexpected removals %+v", plan.Remove)
+	}
+}
+
+func TestPlanUpgradeWithoutDeployment(t *testing.T) {
+	base, next := upgradeDefinitions()
+	live := upgradeFixture(t, base)
+	tenantID := live[0].ID
+
+	plan, err := PlanUpgrade(tenantID, nil, next, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	webID := live[1].ID
+	expected := []UpgradeChange{
+		{UpgradeUpdate, "app/web", webID, "Startup", ""},
+		{UpgradeUpdate, "app/web", webID, "Instances", ""},
+		{UpgradeUpdate, "app/web", webID, "InstanceLimits", ""},
+		{UpgradeConflict, "app/web", webID, "ConfigFiles//etc/web.conf", "edited since it was deployed; kept"},
+		{UpgradeAdd, "app/cache", "", "", ""},
+		{UpgradeConflict, "app/db", live[2].ID, "", "not in the template; kept"},
+		{UpgradeConflict, "app/extra", "extra", "", "not in the template; kept"},
+	}
+	if !reflect.DeepEqual(plan.Changes, expected) {
+		t.Fatalf("expected changes\n%+v\ngot\n%+v", expected, plan.Changes)
+	}
+	if len(plan.Remove) != 0 {
+		t.Errorf("expected no removals, got %+v", plan.Remove)
+	}
+}
+
+func TestPlanUpgradeNoChanges(t *testing.T) {
+	base, _ := upgradeDefinitions()
+	live := upgradeFixture(t, base)
+
+	plan, err := PlanUpgrade(live[0].ID, base, base, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(plan.Changes) != 0 || len(plan.Update) != 0 {
+		t.Errorf("expected no changes, got %+v", plan.Changes)
+	}
+
+	if _, err := PlanUpgrade("unknown", base, base, live); err == nil {
+		t.Errorf("expected an error for an unknown tenant")
+	}
+}
@@ -58,6 +58,8 @@ func (st *ServiceTemplate) ValidEntity() error {
 		violations.Add(servicedefinition.Walk(&sd, visit))
 	}
 
+	violations.Add(st.validateParameters())
+
 	if len(violations.Errors) > 0 {
 		return violations
 	}
@@ -76,3 +78,28 @@ func (st *serviceTemplateWrapper) ValidEntity() error {
 	}
 	return nil
 }
+
+//ValidEntity makes sure the record of a Deployment has non-empty values
+func (d *Deployment) ValidEntity() error {
+	v := validation.NewValidationError()
+	v.Add(validation.NotEmpty("ID", d.ID))
+	v.Add(validation.NotEmpty("TemplateID", d.TemplateID))
+	v.Add(validation.NotEmpty("Data", d.Data))
+	if v.HasError() {
+		return v
+	}
+	return nil
+}
+
+//ValidEntity makes sure a TemplateVersion has non-empty values
+func (v *TemplateVersion) ValidEntity() error {
+	violations := validation.NewValidationError()
+	violations.Add(validation.NotEmpty("ID", v.ID))
+	violations.Add(validation.NotEmpty("TemplateID", v.TemplateID))
+	violations.Add(validation.NotEmpty("Hash", v.Hash))
+	violations.Add(validation.NotEmpty("Data", v.Data))
+	if violations.HasError() {
+		return violations
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultSLOWindowDays is the number of days an SLO is measured over when
+// the SLO does not say
+const DefaultSLOWindowDays = 30
+
+// SLO is a service level objective for the availability of a service: the
+// percent of the time the health checks of its instances should pass
+type SLO struct {
+	Objective    float64  // percent, e.g. 99.9
+	WindowDays   int      // days the objective is measured over; defaults to DefaultSLOWindowDays
+	HealthChecks []string // names of the health checks that count; empty for all
+}
+
+// Window returns the period the objective is measured over
+func (slo SLO) Window() time.Duration {
+	days := slo.WindowDays
+	if days <= 0 {
+		days = DefaultSLOWindowDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ErrorBudget returns the fraction of the time the service may be down
+func (slo SLO) ErrorBudget() float64 {
+	return 1 - slo.Objective/100
+}
+
+// Validate ensures that the objective is a percent below 100 and the window
+// is not negative
+func (slo SLO) Validate() error {
+	if slo.Objective <= 0 || slo.Objective >= 100 {
+		return fmt.Errorf("SLO objective must be a percent between 0 and 100, exclusive: %v", slo.Objective)
+	}
+	if slo.WindowDays < 0 {
+		return fmt.Errorf("SLO window cannot be negative: %d days", slo.WindowDays)
+	}
+	return nil
+}
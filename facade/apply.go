@@ -0,0 +1,828 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/manifest"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicetemplate"
+	"github.com/zenoss/glog"
+)
+
+// applyStopTimeout is how long a pruned deployment is given to stop
+const applyStopTimeout = 5 * time.Minute
+
+// ApplyHooks looks after the volumes of the tenants that a manifest deploys,
+// upgrades and removes, which the facade has no access to
+type ApplyHooks interface {
+	// Deployed creates the volume of a deployed tenant
+	Deployed(tenantID string) error
+	// Snapshot snapshots a tenant before it is upgraded and returns the id
+	// of the snapshot
+	Snapshot(tenantID, description string) (string, error)
+	// Removed deletes the snapshots of a removed tenant
+	Removed(tenantID string) error
+}
+
+// ApplyManifest makes the cluster match a manifest and returns the changes
+// that were made. Applying the same manifest again changes nothing. If
+// dryRun is set, the changes are planned but not made. If prune is set, the
+// pools, virtual ips and templates that the manifest does not declare and
+// nothing that is kept uses are removed, and so are the deployments that an
+// earlier apply managed and the manifest no longer declares; their services
+// are stopped first. Changes to config files are recorded as made by author.
+func (f *Facade) ApplyManifest(ctx datastore.Context, m *manifest.Manifest, hooks ApplyHooks, author string, prune, dryRun bool) (*manifest.Plan, error) {
+	if err := m.ValidEntity(); err != nil {
+		return nil, err
+	}
+
+	p := &applyPlanner{f: f, ctx: ctx, m: m, hooks: hooks, author: author, prune: prune}
+	if err := p.plan(); err != nil {
+		return nil, err
+	}
+
+	plan := &manifest.Plan{Changes: make([]manifest.Change, 0, len(p.steps))}
+	for _, step := range p.steps {
+		plan.Changes = append(plan.Changes, step.change)
+	}
+	if dryRun {
+		return plan, nil
+	}
+
+	for _, step := range p.steps {
+		if step.apply == nil {
+			continue
+		}
+		glog.Infof("Applying manifest: %s", step.change)
+		if err := step.apply(); err != nil {
+			glog.Errorf("Could not %s %s %s: %s", step.change.Action, step.change.Kind, step.change.Name, err)
+			return nil, fmt.Errorf("could not %s %s %s: %s", step.change.Action, step.change.Kind, step.change.Name, err)
+		}
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// applyStep is a change of a plan with the function that makes it. Changes
+// that are made by the function of an earlier step have no function.
+type applyStep struct {
+	change manifest.Change
+	apply  func() error
+}
+
+// applyPlanner compares a manifest with the state of the cluster
+type applyPlanner struct {
+	f      *Facade
+	ctx    datastore.Context
+	m      *manifest.Manifest
+	hooks  ApplyHooks
+	author string
+	prune  bool
+
+	steps  []applyStep
+	prunes []applyStep // removals are made after the other changes
+
+	templateIDs   map[string]string // the ids of the templates of the manifest, by id and name
+	templateNames map[string]string // the names of the templates by id
+	deployed      map[string]bool   // the deployments that are added
+	pruned        map[string]bool   // the deployments that are removed
+}
+
+func (p *applyPlanner) add(action, kind, name, detail string, apply func() error) {
+	p.steps = append(p.steps, applyStep{manifest.Change{Action: action, Kind: kind, Name: name, Detail: detail}, apply})
+}
+
+func (p *applyPlanner) remove(kind, name, detail string, apply func() error) {
+	p.prunes = append(p.prunes, applyStep{manifest.Change{Action: manifest.ActionRemove, Kind: kind, Name: name, Detail: detail}, apply})
+}
+
+func (p *applyPlanner) plan() error {
+	if err := p.planPools(); err != nil {
+		return err
+	}
+	if err := p.planTemplates(); err != nil {
+		return err
+	}
+	if err := p.planDeployments(); err != nil {
+		return err
+	}
+	if err := p.planServices(); err != nil {
+		return err
+	}
+	// templates and pools are removed after the deployments that use them
+	if err := p.pruneTemplates(); err != nil {
+		return err
+	}
+	if err := p.prunePools(); err != nil {
+		return err
+	}
+	p.steps = append(p.steps, p.prunes...)
+	return nil
+}
+
+// planPools adds and updates pools and their virtual ips
+func (p *applyPlanner) planPools() error {
+	pools, err := p.f.GetResourcePools(p.ctx)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]pool.ResourcePool)
+	for _, rp := range pools {
+		current[rp.ID] = rp
+	}
+
+	for i := range p.m.Pools {
+		desired := p.m.Pools[i]
+		for j := range desired.VirtualIPs {
+			desired.VirtualIPs[j].PoolID = desired.ID
+		}
+
+		existing, found := current[desired.ID]
+		if !found {
+			entity := desired
+			p.add(manifest.ActionAdd, manifest.KindPool, desired.ID, "", func() error {
+				return p.f.AddResourcePool(p.ctx, &entity)
+			})
+			for _, vip := range desired.VirtualIPs {
+				p.add(manifest.ActionAdd, manifest.KindVirtualIP, vip.IP, "in pool "+desired.ID, nil)
+			}
+			continue
+		}
+
+		entity := existing
+		var fields []string
+		if desired.Realm != "" && desired.Realm != existing.Realm {
+			entity.Realm = desired.Realm
+			fields = append(fields, "Realm")
+		}
+		if desired.Description != existing.Description {
+			entity.Description = desired.Description
+			fields = append(fields, "Description")
+		}
+		if desired.CoreLimit != existing.CoreLimit {
+			entity.CoreLimit = desired.CoreLimit
+			fields = append(fields, "CoreLimit")
+		}
+		if desired.MemoryLimit != existing.MemoryLimit {
+			entity.MemoryLimit = desired.MemoryLimit
+			fields = append(fields, "MemoryLimit")
+		}
+
+		existingIPs := make(map[string]bool)
+		for _, vip := range existing.VirtualIPs {
+			existingIPs[vip.IP] = true
+		}
+		var changes []manifest.Change
+		declared := make(map[string]bool)
+		entity.VirtualIPs = nil
+		for _, vip := range desired.VirtualIPs {
+			declared[vip.IP] = true
+			entity.VirtualIPs = append(entity.VirtualIPs, vip)
+			if !existingIPs[vip.IP] {
+				changes = append(changes, manifest.Change{Action: manifest.ActionAdd, Kind: manifest.KindVirtualIP, Name: vip.IP, Detail: "in pool " + desired.ID})
+			}
+		}
+		for _, vip := range existing.VirtualIPs {
+			if declared[vip.IP] {
+				continue
+			} else if p.prune {
+				changes = append(changes, manifest.Change{Action: manifest.ActionRemove, Kind: manifest.KindVirtualIP, Name: vip.IP, Detail: "in pool " + desired.ID})
+			} else {
+				entity.VirtualIPs = append(entity.VirtualIPs, vip)
+			}
+		}
+
+		if len(fields) == 0 && len(changes) == 0 {
+			continue
+		}
+		apply := func() error {
+			return p.f.UpdateResourcePool(p.ctx, &entity)
+		}
+		if len(fields) > 0 {
+			p.add(manifest.ActionUpdate, manifest.KindPool, desired.ID, strings.Join(fields, ", "), apply)
+			apply = nil
+		}
+		for _, change := range changes {
+			p.steps = append(p.steps, applyStep{change, apply})
+			apply = nil
+		}
+	}
+	return nil
+}
+
+// prunePools removes the pools that are not declared, unless they still have
+// hosts or services that are kept
+func (p *applyPlanner) prunePools() error {
+	if !p.prune {
+		return nil
+	}
+	declared := make(map[string]bool)
+	for _, rp := range p.m.Pools {
+		declared[rp.ID] = true
+	}
+	for _, d := range p.m.Deployments {
+		declared[d.PoolID] = true
+	}
+
+	pools, err := p.f.GetResourcePools(p.ctx)
+	if err != nil {
+		return err
+	}
+	for _, rp := range pools {
+		if declared[rp.ID] {
+			continue
+		}
+		if hosts, err := p.f.FindHostsInPool(p.ctx, rp.ID); err != nil {
+			return err
+		} else if len(hosts) > 0 {
+			glog.Warningf("Not removing pool %s: it has %d hosts", rp.ID, len(hosts))
+			continue
+		}
+		svcs, err := p.f.GetServicesByPool(p.ctx, rp.ID)
+		if err != nil {
+			return err
+		}
+		kept := 0
+		for _, svc := range svcs {
+			if !p.pruned[svc.DeploymentID] {
+				kept++
+			}
+		}
+		if kept > 0 {
+			glog.Warningf("Not removing pool %s: it has %d services", rp.ID, kept)
+			continue
+		}
+		poolID := rp.ID
+		p.remove(manifest.KindPool, poolID, "", func() error {
+			return p.f.RemoveResourcePool(p.ctx, poolID)
+		})
+	}
+	return nil
+}
+
+// planTemplates adds and updates templates. Templates are matched by their
+// id, or by their name if the manifest does not give their id.
+func (p *applyPlanner) planTemplates() error {
+	templates, err := p.f.GetServiceTemplates(p.ctx)
+	if err != nil {
+		return err
+	}
+	p.templateIDs = make(map[string]string)
+	p.templateNames = make(map[string]string)
+
+	for i := range p.m.Templates {
+		desired := p.m.Templates[i]
+		hash, err := servicetemplate.ContentHash(desired)
+		if err != nil {
+			return err
+		}
+
+		var existing *servicetemplate.ServiceTemplate
+		if desired.ID != "" {
+			if st, ok := templates[desired.ID]; ok {
+				existing = &st
+			}
+		} else {
+			var named []servicetemplate.ServiceTemplate
+			for _, st := range templates {
+				if st.Name != desired.Name {
+					continue
+				}
+				named = append(named, st)
+				if h, err := servicetemplate.ContentHash(st); err != nil {
+					return err
+				} else if h == hash {
+					// a template with the same content
+					named = []servicetemplate.ServiceTemplate{st}
+					break
+				}
+			}
+			if len(named) > 1 {
+				return fmt.Errorf("there are %d templates named %s; set the ID of the template in the manifest", len(named), desired.Name)
+			} else if len(named) == 1 {
+				existing = &named[0]
+			}
+		}
+
+		var templateID string
+		if existing == nil {
+			if templateID = desired.ID; templateID == "" {
+				// the id that AddServiceTemplate gives the template
+				if templateID, err = desired.Hash(); err != nil {
+					return err
+				}
+			}
+			entity := desired
+			p.add(manifest.ActionAdd, manifest.KindTemplate, templateName(desired.Name, templateID), desired.Version, func() error {
+				if entity.ID != "" {
+					return p.f.UpdateServiceTemplate(p.ctx, entity)
+				}
+				_, err := p.f.AddServiceTemplate(p.ctx, entity)
+				return err
+			})
+		} else {
+			templateID = existing.ID
+			if h, err := servicetemplate.ContentHash(*existing); err != nil {
+				return err
+			} else if h != hash {
+				entity := desired
+				entity.ID = existing.ID
+				entity.DatabaseVersion = existing.DatabaseVersion
+				detail := "content changed"
+				if existing.Version != desired.Version {
+					detail = fmt.Sprintf("version %s to %s", existing.Version, desired.Version)
+				}
+				p.add(manifest.ActionUpdate, manifest.KindTemplate, templateName(desired.Name, templateID), detail, func() error {
+					return p.f.UpdateServiceTemplate(p.ctx, entity)
+				})
+			}
+		}
+		p.templateIDs[templateID] = templateID
+		if desired.Name != "" {
+			p.templateIDs[desired.Name] = templateID
+		}
+		p.templateNames[templateID] = desired.Name
+	}
+	return nil
+}
+
+// pruneTemplates removes the templates that the manifest does not declare,
+// unless a deployment that is kept runs them or a kept template extends or
+// includes them
+func (p *applyPlanner) pruneTemplates() error {
+	if !p.prune {
+		return nil
+	}
+	templates, err := p.f.GetServiceTemplates(p.ctx)
+	if err != nil {
+		return err
+	}
+	kept := make(map[string]bool)
+	for _, id := range p.templateIDs {
+		kept[id] = true
+	}
+	for _, st := range p.m.Templates {
+		for _, id := range st.TemplateIDs() {
+			kept[id] = true
+		}
+	}
+	for _, d := range p.m.Deployments {
+		if id, err := p.resolveTemplate(d.Template, templates); err == nil {
+			kept[id] = true
+		}
+	}
+	for id := range templates {
+		if kept[id] {
+			continue
+		}
+		records, err := p.f.templateStore.GetDeploymentsByTemplate(p.ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if p.pruned[record.ID] {
+				continue
+			}
+			if tenants, err := p.tenants(record.ID); err != nil {
+				return err
+			} else if len(tenants) > 0 {
+				kept[id] = true
+				break
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for id := range kept {
+			st, ok := templates[id]
+			if !ok {
+				continue
+			}
+			for _, included := range st.TemplateIDs() {
+				if !kept[included] {
+					kept[included] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(templates))
+	for id := range templates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if kept[id] {
+			continue
+		}
+		templateID := id
+		p.remove(manifest.KindTemplate, templateName(templates[id].Name, id), "", func() error {
+			return p.f.RemoveServiceTemplate(p.ctx, templateID)
+		})
+	}
+	return nil
+}
+
+// resolveTemplate returns the id of the template that a deployment names
+func (p *applyPlanner) resolveTemplate(name string, templates map[string]servicetemplate.ServiceTemplate) (string, error) {
+	if id, ok := p.templateIDs[name]; ok {
+		return id, nil
+	}
+	if _, ok := templates[name]; ok {
+		return name, nil
+	}
+	var ids []string
+	for id, st := range templates {
+		if st.Name == name {
+			ids = append(ids, id)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("template %s not found", name)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("there are %d templates named %s; use the ID of the template", len(ids), name)
+}
+
+// templateHash returns the content hash of a template as it will be after
+// the templates of the manifest are applied
+func (p *applyPlanner) templateHash(templateID string, templates map[string]servicetemplate.ServiceTemplate) (string, error) {
+	for _, st := range p.m.Templates {
+		key := st.ID
+		if key == "" {
+			key = st.Name
+		}
+		if p.templateIDs[key] == templateID {
+			return servicetemplate.ContentHash(st)
+		}
+	}
+	return servicetemplate.ContentHash(templates[templateID])
+}
+
+// planDeployments deploys templates and upgrades the deployments whose
+// template or parameters changed. The deployments are recorded as managed by
+// manifests, and only those are pruned.
+func (p *applyPlanner) planDeployments() error {
+	templates, err := p.f.GetServiceTemplates(p.ctx)
+	if err != nil {
+		return err
+	}
+	p.deployed = make(map[string]bool)
+	p.pruned = make(map[string]bool)
+	declared := make(map[string]bool)
+
+	for i := range p.m.Deployments {
+		d := p.m.Deployments[i]
+		declared[d.ID] = true
+		templateID, err := p.resolveTemplate(d.Template, templates)
+		if err != nil {
+			return fmt.Errorf("deployment %s: %s", d.ID, err)
+		}
+
+		tenants, err := p.tenants(d.ID)
+		if err != nil {
+			return err
+		}
+		if len(tenants) == 0 {
+			p.deployed[d.ID] = true
+			p.add(manifest.ActionAdd, manifest.KindDeployment, d.ID, fmt.Sprintf("template %s to pool %s", templateName(p.templateNames[templateID], templateID), d.PoolID), func() error {
+				tenantIDs, err := p.f.DeployTemplate(p.ctx, d.PoolID, templateID, d.ID, d.Params)
+				if err != nil {
+					return err
+				}
+				for _, tenantID := range tenantIDs {
+					if err := p.hooks.Deployed(tenantID); err != nil {
+						return err
+					}
+				}
+				return p.manage(d.ID)
+			})
+			continue
+		}
+		for _, tenant := range tenants {
+			if tenant.PoolID != d.PoolID {
+				return fmt.Errorf("deployment %s runs in pool %s; moving deployments to another pool is not supported", d.ID, tenant.PoolID)
+			}
+		}
+
+		hash, err := p.templateHash(templateID, templates)
+		if err != nil {
+			return err
+		}
+		var reasons []string
+		record, err := p.f.templateStore.GetDeployment(p.ctx, d.ID)
+		if datastore.IsErrNoSuchEntity(err) {
+			reasons = append(reasons, "the deployed template was not recorded")
+		} else if err != nil {
+			return err
+		} else {
+			if record.TemplateHash != hash {
+				reasons = append(reasons, "template "+templateName(p.templateNames[templateID], templateID))
+			}
+			for _, name := range changedParams(record.Params, d.Params) {
+				reasons = append(reasons, "parameter "+name)
+			}
+		}
+		if len(reasons) == 0 {
+			if !record.Managed {
+				p.add(manifest.ActionUpdate, manifest.KindDeployment, d.ID, "managed by the manifest", func() error {
+					return p.manage(d.ID)
+				})
+			}
+			continue
+		}
+		p.add(manifest.ActionUpdate, manifest.KindDeployment, d.ID, "upgrade for "+strings.Join(reasons, ", "), func() error {
+			for _, tenant := range tenants {
+				if err := p.upgrade(templateID, tenant.ID, d.ID, d.Params); err != nil {
+					return err
+				}
+			}
+			return p.manage(d.ID)
+		})
+	}
+
+	if !p.prune {
+		return nil
+	}
+	svcs, err := p.f.getServices(p.ctx)
+	if err != nil {
+		return err
+	}
+	for _, svc := range svcs {
+		if svc.ParentServiceID != "" || svc.DeploymentID == "" || declared[svc.DeploymentID] {
+			continue
+		}
+		// leave the deployments that were not made by a manifest alone
+		if record, err := p.f.templateStore.GetDeployment(p.ctx, svc.DeploymentID); datastore.IsErrNoSuchEntity(err) {
+			continue
+		} else if err != nil {
+			return err
+		} else if !record.Managed {
+			continue
+		}
+		p.pruned[svc.DeploymentID] = true
+		tenantID := svc.ID
+		p.remove(manifest.KindDeployment, svc.DeploymentID, fmt.Sprintf("application %s (%s)", svc.Name, svc.ID), func() error {
+			return p.removeTenant(tenantID)
+		})
+	}
+	return nil
+}
+
+// manage records that a deployment is managed by manifests, so that it is
+// pruned once no manifest declares it
+func (p *applyPlanner) manage(deploymentID string) error {
+	record, err := p.f.templateStore.GetDeployment(p.ctx, deploymentID)
+	if err != nil {
+		return err
+	} else if record.Managed {
+		return nil
+	}
+	record.Managed = true
+	return p.f.templateStore.PutDeployment(p.ctx, record)
+}
+
+// upgrade snapshots a tenant and upgrades it to a template
+func (p *applyPlanner) upgrade(templateID, tenantID, deploymentID string, params map[string]string) error {
+	plan, err := p.f.PlanTemplateUpgrade(p.ctx, templateID, tenantID, params)
+	if err != nil {
+		return err
+	}
+	plan.DeploymentID = deploymentID
+
+	// take a snapshot so that the upgrade can be rolled back
+	desc := fmt.Sprintf("before upgrade to template %s", templateID)
+	if plan.SnapshotID, err = p.hooks.Snapshot(tenantID, desc); err != nil {
+		glog.Errorf("Could not snapshot %s before the upgrade: %s", tenantID, err)
+		return err
+	}
+	glog.Infof("Upgrading %s to template %s (snapshot %s)", tenantID, templateID, plan.SnapshotID)
+
+	if err := p.f.UpgradeTemplate(p.ctx, plan); err != nil {
+		glog.Errorf("Could not upgrade %s to template %s; rollback to snapshot %s to undo the changes: %s", tenantID, templateID, plan.SnapshotID, err)
+		return err
+	}
+	return nil
+}
+
+// removeTenant stops the services of a tenant, waits for them to stop, and
+// removes them with their snapshots
+func (p *applyPlanner) removeTenant(tenantID string) error {
+	var serviceIDs []string
+	if err := p.f.walkServices(p.ctx, tenantID, true, func(svc *service.Service) error {
+		if svc.DesiredState != int(service.SVCStop) {
+			if _, err := p.f.ScheduleService(p.ctx, svc.ID, false, service.SVCStop); err != nil {
+				glog.Errorf("Could not stop service %s (%s): %s", svc.Name, svc.ID, err)
+				return err
+			}
+		}
+		serviceIDs = append(serviceIDs, svc.ID)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := p.f.WaitService(p.ctx, service.SVCStop, applyStopTimeout, serviceIDs...); err != nil {
+		glog.Errorf("Could not wait for the services of %s to stop: %s", tenantID, err)
+		return err
+	}
+	if err := p.f.RemoveService(p.ctx, tenantID); err != nil {
+		return err
+	}
+	return p.hooks.Removed(tenantID)
+}
+
+// changedParams returns the names of the parameters that are set, changed or
+// unset, in which case they go back to their defaults
+func changedParams(current, desired map[string]string) []string {
+	var names []string
+	for name, value := range desired {
+		if old, ok := current[name]; !ok || old != value {
+			names = append(names, name)
+		}
+	}
+	for name := range current {
+		if _, ok := desired[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tenants returns the top level services of a deployment
+func (p *applyPlanner) tenants(deploymentID string) ([]service.Service, error) {
+	svcs, err := p.f.serviceStore.GetServicesByDeployment(p.ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []service.Service
+	for _, svc := range svcs {
+		if svc.ParentServiceID == "" {
+			tenants = append(tenants, svc)
+		}
+	}
+	return tenants, nil
+}
+
+// planServices sets the fields of deployed services
+func (p *applyPlanner) planServices() error {
+	for i := range p.m.Services {
+		o := p.m.Services[i]
+		name := o.Deployment + "/" + o.Path
+		apply := func() error {
+			svc, err := p.findService(o.Deployment, o.Path)
+			if err != nil {
+				return err
+			} else if svc == nil {
+				return fmt.Errorf("service not found")
+			}
+			if fields, err := overrideService(svc, &o); err != nil {
+				return err
+			} else if len(fields) == 0 {
+				return nil
+			}
+			return p.f.UpdateServiceAs(p.ctx, *svc, p.author, false)
+		}
+
+		if p.deployed[o.Deployment] {
+			p.add(manifest.ActionUpdate, manifest.KindService, name, "once deployed", apply)
+			continue
+		}
+		svc, err := p.findService(o.Deployment, o.Path)
+		if err != nil {
+			return err
+		} else if svc == nil {
+			return fmt.Errorf("service %s not found", name)
+		}
+		fields, err := overrideService(svc, &o)
+		if err != nil {
+			return fmt.Errorf("service %s: %s", name, err)
+		} else if len(fields) > 0 {
+			p.add(manifest.ActionUpdate, manifest.KindService, name, strings.Join(fields, ", "), apply)
+		}
+	}
+	return nil
+}
+
+// findService returns the service at a path of a deployment, or nil if there
+// is none
+func (p *applyPlanner) findService(deploymentID, path string) (*service.Service, error) {
+	names := strings.Split(strings.Trim(path, "/"), "/")
+	tenants, err := p.tenants(deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	var svc *service.Service
+	for i := range tenants {
+		if tenants[i].Name == names[0] {
+			svc = &tenants[i]
+			break
+		}
+	}
+	for _, name := range names[1:] {
+		if svc == nil {
+			break
+		}
+		if svc, err = p.f.serviceStore.FindChildService(p.ctx, deploymentID, svc.ID, name); err != nil {
+			return nil, err
+		}
+	}
+	if svc == nil {
+		return nil, nil
+	}
+	// load the config files of the service
+	return p.f.GetService(p.ctx, svc.ID)
+}
+
+// overrideService sets the fields of a service that an override changes, and
+// returns the names of the fields
+func overrideService(svc *service.Service, o *manifest.ServiceOverride) ([]string, error) {
+	var fields []string
+	if o.Instances != nil && *o.Instances != svc.Instances {
+		svc.Instances = *o.Instances
+		fields = append(fields, "Instances")
+	}
+	if o.Launch != "" && o.Launch != svc.Launch {
+		svc.Launch = o.Launch
+		fields = append(fields, "Launch")
+	}
+
+	keys := make([]string, 0, len(o.Context))
+	for key := range o.Context {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if current, ok := svc.Context[key]; ok && jsonEqual(current, o.Context[key]) {
+			continue
+		}
+		if svc.Context == nil {
+			svc.Context = make(map[string]interface{})
+		}
+		svc.Context[key] = o.Context[key]
+		fields = append(fields, "Context."+key)
+	}
+
+	filenames := make([]string, 0, len(o.ConfigFiles))
+	for filename := range o.ConfigFiles {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		conf, ok := svc.ConfigFiles[filename]
+		if !ok {
+			return nil, fmt.Errorf("service %s has no config file %s", svc.Name, filename)
+		} else if conf.Content == o.ConfigFiles[filename] {
+			continue
+		}
+		conf.Content = o.ConfigFiles[filename]
+		svc.ConfigFiles[filename] = conf
+		fields = append(fields, "ConfigFiles/"+filename)
+	}
+	return fields, nil
+}
+
+// jsonEqual compares values by their JSON form, so that numbers that were
+// stored and read back are equal to the numbers of a manifest
+func jsonEqual(a, b interface{}) bool {
+	da, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	db, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(da, db)
+}
+
+// templateName describes a template by its name and id
+func templateName(name, id string) string {
+	if name == "" || name == id {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", name, id)
+}
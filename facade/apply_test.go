@@ -0,0 +1,354 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/domain/manifest"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/domain/servicetemplate"
+	. "gopkg.in/check.v1"
+)
+
+// applyHooksMock records the tenants that ApplyManifest passes to its hooks
+type applyHooksMock struct {
+	deployed  []string
+	snapshots []string
+	removed   []string
+}
+
+func (h *applyHooksMock) Deployed(tenantID string) error {
+	h.deployed = append(h.deployed, tenantID)
+	return nil
+}
+
+func (h *applyHooksMock) Snapshot(tenantID, description string) (string, error) {
+	h.snapshots = append(h.snapshots, tenantID)
+	return "snapshot-" + tenantID, nil
+}
+
+func (h *applyHooksMock) Removed(tenantID string) error {
+	h.removed = append(h.removed, tenantID)
+	return nil
+}
+
+// applyTenants returns the ids of the tenants of a deployment
+func (ft *FacadeTest) applyTenants(t *C, deploymentID string) []string {
+	svcs, err := ft.Facade.serviceStore.GetServicesByDeployment(ft.CTX, deploymentID)
+	t.Assert(err, IsNil)
+	var tenantIDs []string
+	for _, svc := range svcs {
+		if svc.ParentServiceID == "" {
+			tenantIDs = append(tenantIDs, svc.ID)
+		}
+	}
+	return tenantIDs
+}
+
+// removeTenants removes the services of a deployment
+func (ft *FacadeTest) removeTenants(deploymentID string) {
+	svcs, err := ft.Facade.serviceStore.GetServicesByDeployment(ft.CTX, deploymentID)
+	if err != nil {
+		return
+	}
+	for _, svc := range svcs {
+		if svc.ParentServiceID == "" {
+			ft.Facade.RemoveService(ft.CTX, svc.ID)
+		}
+	}
+}
+
+// applyTemplate is a template with an application that has a child service
+func applyTemplate(name string) servicetemplate.ServiceTemplate {
+	return servicetemplate.ServiceTemplate{
+		Name:    name,
+		Version: "1.0",
+		Services: []servicedefinition.ServiceDefinition{{
+			Name:     "app",
+			Launch:   "manual",
+			Services: []servicedefinition.ServiceDefinition{{Name: "child", Launch: "manual"}},
+		}},
+	}
+}
+
+func (ft *FacadeTest) Test_ApplyManifest(t *C) {
+	poolID := "Test_ApplyManifest"
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolID)
+
+	m := &manifest.Manifest{
+		Pools: []pool.ResourcePool{{ID: poolID, Description: "applied"}},
+		Templates: []servicetemplate.ServiceTemplate{{
+			Name:     "Test_ApplyManifest",
+			Version:  "1.0",
+			Services: []servicedefinition.ServiceDefinition{{Name: "app", Launch: "manual"}},
+		}},
+	}
+
+	// a dry run plans the changes without making them
+	plan, err := ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, true)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Applied, Equals, false)
+	t.Assert(plan.Changes, HasLen, 2)
+	t.Assert(plan.Changes[0].Action, Equals, manifest.ActionAdd)
+	t.Assert(plan.Changes[0].Kind, Equals, manifest.KindPool)
+	t.Assert(plan.Changes[1].Kind, Equals, manifest.KindTemplate)
+	rp, err := ft.Facade.GetResourcePool(ft.CTX, poolID)
+	t.Assert(err, IsNil)
+	t.Assert(rp, IsNil)
+
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Applied, Equals, true)
+	t.Assert(plan.Changes, HasLen, 2)
+	rp, err = ft.Facade.GetResourcePool(ft.CTX, poolID)
+	t.Assert(err, IsNil)
+	t.Assert(rp.Description, Equals, "applied")
+
+	templateID, err := m.Templates[0].Hash()
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, templateID)
+
+	// applying the same manifest again changes nothing
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Empty(), Equals, true)
+
+	// a changed template is updated under the same id
+	m.Pools[0].Description = "changed"
+	m.Templates[0].Version = "1.1"
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, DeepEquals, []manifest.Change{
+		{Action: manifest.ActionUpdate, Kind: manifest.KindPool, Name: poolID, Detail: "Description"},
+		{Action: manifest.ActionUpdate, Kind: manifest.KindTemplate, Name: "Test_ApplyManifest (" + templateID + ")", Detail: "version 1.0 to 1.1"},
+	})
+	st, err := ft.Facade.templateStore.Get(ft.CTX, templateID)
+	t.Assert(err, IsNil)
+	t.Assert(st.Version, Equals, "1.1")
+}
+
+func (ft *FacadeTest) Test_ApplyManifestDeployments(t *C) {
+	poolID := "Test_ApplyManifestDeployments"
+	deploymentID := "Test_ApplyManifestDeployments"
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolID)
+
+	m := &manifest.Manifest{
+		Pools:       []pool.ResourcePool{{ID: poolID}},
+		Templates:   []servicetemplate.ServiceTemplate{applyTemplate("Test_ApplyManifestDeployments")},
+		Deployments: []manifest.Deployment{{ID: deploymentID, Template: "Test_ApplyManifestDeployments", PoolID: poolID}},
+	}
+	templateID, err := m.Templates[0].Hash()
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, templateID)
+	defer ft.removeTenants(deploymentID)
+
+	// the template is deployed and its tenant gets a volume
+	hooks := &applyHooksMock{}
+	plan, err := ft.Facade.ApplyManifest(ft.CTX, m, hooks, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, HasLen, 3)
+	t.Assert(plan.Changes[2], DeepEquals, manifest.Change{
+		Action: manifest.ActionAdd,
+		Kind:   manifest.KindDeployment,
+		Name:   deploymentID,
+		Detail: "template Test_ApplyManifestDeployments (" + templateID + ") to pool " + poolID,
+	})
+	tenantIDs := ft.applyTenants(t, deploymentID)
+	t.Assert(tenantIDs, HasLen, 1)
+	t.Assert(hooks.deployed, DeepEquals, tenantIDs)
+	record, err := ft.Facade.templateStore.GetDeployment(ft.CTX, deploymentID)
+	t.Assert(err, IsNil)
+	t.Assert(record.Managed, Equals, true)
+
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, hooks, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Empty(), Equals, true)
+
+	// a changed template upgrades the deployment after a snapshot
+	m.Templates[0].Version = "1.1"
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, hooks, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, HasLen, 2)
+	t.Assert(plan.Changes[1], DeepEquals, manifest.Change{
+		Action: manifest.ActionUpdate,
+		Kind:   manifest.KindDeployment,
+		Name:   deploymentID,
+		Detail: "upgrade for template Test_ApplyManifestDeployments (" + templateID + ")",
+	})
+	t.Assert(hooks.snapshots, DeepEquals, tenantIDs)
+	record, err = ft.Facade.templateStore.GetDeployment(ft.CTX, deploymentID)
+	t.Assert(err, IsNil)
+	t.Assert(record.Managed, Equals, true)
+
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, hooks, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Empty(), Equals, true)
+}
+
+func (ft *FacadeTest) Test_ChangedParams(t *C) {
+	current := map[string]string{"kept": "1", "changed": "1", "unset": "1"}
+	desired := map[string]string{"kept": "1", "changed": "2", "added": "1"}
+	t.Assert(changedParams(current, desired), DeepEquals, []string{"added", "changed", "unset"})
+	t.Assert(changedParams(current, current), HasLen, 0)
+	t.Assert(changedParams(nil, nil), HasLen, 0)
+}
+
+func (ft *FacadeTest) Test_ApplyManifestServices(t *C) {
+	poolID := "Test_ApplyManifestServices"
+	deploymentID := "Test_ApplyManifestServices"
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolID)
+
+	m := &manifest.Manifest{
+		Pools:       []pool.ResourcePool{{ID: poolID}},
+		Templates:   []servicetemplate.ServiceTemplate{applyTemplate("Test_ApplyManifestServices")},
+		Deployments: []manifest.Deployment{{ID: deploymentID, Template: "Test_ApplyManifestServices", PoolID: poolID}},
+		Services: []manifest.ServiceOverride{{
+			Deployment: deploymentID,
+			Path:       "app/child",
+			Launch:     "auto",
+			Context:    map[string]interface{}{"key": "value"},
+		}},
+	}
+	templateID, err := m.Templates[0].Hash()
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, templateID)
+	defer ft.removeTenants(deploymentID)
+
+	// the services of a new deployment are set once it is deployed
+	plan, err := ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, HasLen, 4)
+	t.Assert(plan.Changes[3], DeepEquals, manifest.Change{Action: manifest.ActionUpdate, Kind: manifest.KindService, Name: deploymentID + "/app/child", Detail: "once deployed"})
+	tenantIDs := ft.applyTenants(t, deploymentID)
+	t.Assert(tenantIDs, HasLen, 1)
+	child, err := ft.Facade.FindChildService(ft.CTX, tenantIDs[0], "child")
+	t.Assert(err, IsNil)
+	t.Assert(child.Launch, Equals, "auto")
+	t.Assert(child.Context["key"], Equals, "value")
+
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Empty(), Equals, true)
+
+	// the fields that a user changed are set back
+	child.Context["key"] = "edited"
+	err = ft.Facade.UpdateService(ft.CTX, *child)
+	t.Assert(err, IsNil)
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, DeepEquals, []manifest.Change{
+		{Action: manifest.ActionUpdate, Kind: manifest.KindService, Name: deploymentID + "/app/child", Detail: "Context.key"},
+	})
+	child, err = ft.Facade.FindChildService(ft.CTX, tenantIDs[0], "child")
+	t.Assert(err, IsNil)
+	t.Assert(child.Context["key"], Equals, "value")
+
+	// services that do not exist are reported
+	m.Services[0].Path = "app/missing"
+	_, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, true)
+	t.Assert(err, NotNil)
+}
+
+func (ft *FacadeTest) Test_ApplyManifestPrune(t *C) {
+	poolID := "Test_ApplyManifestPrune"
+	managedID := "Test_ApplyManifestPrune_managed"
+	manualID := "Test_ApplyManifestPrune_manual"
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolID)
+
+	m := &manifest.Manifest{
+		Pools:       []pool.ResourcePool{{ID: poolID}},
+		Templates:   []servicetemplate.ServiceTemplate{applyTemplate("Test_ApplyManifestPrune")},
+		Deployments: []manifest.Deployment{{ID: managedID, Template: "Test_ApplyManifestPrune", PoolID: poolID}},
+	}
+	templateID, err := m.Templates[0].Hash()
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, templateID)
+	defer ft.removeTenants(managedID)
+	defer ft.removeTenants(manualID)
+
+	_, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", false, false)
+	t.Assert(err, IsNil)
+	managedTenants := ft.applyTenants(t, managedID)
+	t.Assert(managedTenants, HasLen, 1)
+
+	// a deployment that was not made by a manifest
+	manualTenants, err := ft.Facade.DeployTemplate(ft.CTX, poolID, templateID, manualID, nil)
+	t.Assert(err, IsNil)
+	t.Assert(manualTenants, HasLen, 1)
+
+	// only the deployment that the manifest managed is pruned, and the
+	// template stays for the other deployment
+	m.Templates, m.Deployments = nil, nil
+	plan, err := ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", true, true)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, HasLen, 1)
+	t.Assert(plan.Changes[0].Action, Equals, manifest.ActionRemove)
+	t.Assert(plan.Changes[0].Name, Equals, managedID)
+
+	hooks := &applyHooksMock{}
+	_, err = ft.Facade.ApplyManifest(ft.CTX, m, hooks, "tester", true, false)
+	t.Assert(err, IsNil)
+	t.Assert(hooks.removed, DeepEquals, managedTenants)
+	t.Assert(ft.applyTenants(t, managedID), HasLen, 0)
+	t.Assert(ft.applyTenants(t, manualID), DeepEquals, manualTenants)
+
+	// declaring a deployment puts it under the management of the manifest
+	m.Deployments = []manifest.Deployment{{ID: manualID, Template: templateID, PoolID: poolID}}
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", true, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, DeepEquals, []manifest.Change{
+		{Action: manifest.ActionUpdate, Kind: manifest.KindDeployment, Name: manualID, Detail: "managed by the manifest"},
+	})
+
+	// the template goes with the last deployment that runs it
+	m.Deployments = nil
+	plan, err = ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", true, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, HasLen, 2)
+	t.Assert(plan.Changes[0].Name, Equals, manualID)
+	t.Assert(plan.Changes[1], DeepEquals, manifest.Change{Action: manifest.ActionRemove, Kind: manifest.KindTemplate, Name: "Test_ApplyManifestPrune (" + templateID + ")"})
+	t.Assert(ft.applyTenants(t, manualID), HasLen, 0)
+}
+
+func (ft *FacadeTest) Test_ApplyManifestPruneIncludes(t *C) {
+	poolID := "Test_ApplyManifestPruneIncludes"
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolID)
+	baseID, err := ft.Facade.AddServiceTemplate(ft.CTX, applyTemplate("Test_ApplyManifestPruneIncludes_base"))
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, baseID)
+	orphanID, err := ft.Facade.AddServiceTemplate(ft.CTX, applyTemplate("Test_ApplyManifestPruneIncludes_orphan"))
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, orphanID)
+
+	m := &manifest.Manifest{
+		Pools: []pool.ResourcePool{{ID: poolID}},
+		Templates: []servicetemplate.ServiceTemplate{{
+			ID:      "Test_ApplyManifestPruneIncludes",
+			Name:    "Test_ApplyManifestPruneIncludes",
+			Extends: &servicetemplate.Include{Template: baseID},
+		}},
+	}
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, "Test_ApplyManifestPruneIncludes")
+
+	// the template that a declared template extends is kept
+	plan, err := ft.Facade.ApplyManifest(ft.CTX, m, &applyHooksMock{}, "tester", true, false)
+	t.Assert(err, IsNil)
+	t.Assert(plan.Changes, DeepEquals, []manifest.Change{
+		{Action: manifest.ActionAdd, Kind: manifest.KindPool, Name: poolID},
+		{Action: manifest.ActionAdd, Kind: manifest.KindTemplate, Name: "Test_ApplyManifestPruneIncludes"},
+		{Action: manifest.ActionRemove, Kind: manifest.KindTemplate, Name: "Test_ApplyManifestPruneIncludes_orphan (" + orphanID + ")"},
+	})
+	_, err = ft.Facade.templateStore.Get(ft.CTX, baseID)
+	t.Assert(err, IsNil)
+}
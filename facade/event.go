@@ -0,0 +1,38 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/zenoss/glog"
+
+	"time"
+)
+
+// AddEvent records a threshold event
+func (f *Facade) AddEvent(ctx datastore.Context, evt *event.Event) error {
+	glog.V(2).Infof("Facade.AddEvent: %+v", evt)
+	if err := evt.ValidEntity(); err != nil {
+		return err
+	}
+	return f.eventStore.Put(ctx, event.Key(evt.ID), evt)
+}
+
+// GetEvents returns the most recent events since a time, newest first,
+// optionally only those of a service or pool
+func (f *Facade) GetEvents(ctx datastore.Context, since time.Time, sourceID string, limit int) ([]event.Event, error) {
+	glog.V(3).Infof("Facade.GetEvents: since=%s, sourceID=%s, limit=%d", since, sourceID, limit)
+	return f.eventStore.GetEvents(ctx, since, sourceID, limit)
+}
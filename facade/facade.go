@@ -14,8 +14,11 @@
 package facade
 
 import (
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicetemplate"
 )
@@ -26,9 +29,12 @@ var _ FacadeInterface = &Facade{}
 // New creates an initialized Facade instance
 func New(dockerRegistry string) *Facade {
 	return &Facade{
+		eventStore:     event.NewStore(),
+		healthStore:    healthhistory.NewStore(),
 		hostStore:      host.NewStore(),
 		poolStore:      pool.NewStore(),
 		serviceStore:   service.NewStore(),
+		secretStore:    secret.NewStore(),
 		templateStore:  servicetemplate.NewStore(),
 		dockerRegistry: dockerRegistry,
 	}
@@ -36,9 +42,13 @@ func New(dockerRegistry string) *Facade {
 
 // Facade is an entrypoint to available controlplane methods
 type Facade struct {
+	eventStore     *event.Store
+	healthStore    *healthhistory.Store
 	hostStore      *host.HostStore
 	poolStore      *pool.Store
 	templateStore  *servicetemplate.Store
 	serviceStore   *service.Store
+	secretStore    *secret.Store
+	secretCipher   *secret.Cipher
 	dockerRegistry string
 }
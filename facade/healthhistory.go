@@ -0,0 +1,94 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/healthhistory"
+	"github.com/zenoss/glog"
+
+	"time"
+)
+
+// SaveHealthResult records a period of a health check of a service instance
+func (f *Facade) SaveHealthResult(ctx datastore.Context, result *healthhistory.Result) error {
+	glog.V(2).Infof("Facade.SaveHealthResult: %+v", result)
+	if err := result.ValidEntity(); err != nil {
+		return err
+	}
+	return f.healthStore.Put(ctx, healthhistory.Key(result.ID), result)
+}
+
+// GetHealthResults returns the health check results of a service that ended
+// at or after since, oldest first
+func (f *Facade) GetHealthResults(ctx datastore.Context, serviceID string, since time.Time) ([]healthhistory.Result, error) {
+	glog.V(3).Infof("Facade.GetHealthResults: serviceID=%s, since=%s", serviceID, since)
+	return f.healthStore.GetResults(ctx, serviceID, since)
+}
+
+// PurgeHealthResults deletes the health check results that ended before a
+// time and returns the number deleted
+func (f *Facade) PurgeHealthResults(ctx datastore.Context, before time.Time) (int, error) {
+	results, err := f.healthStore.GetResultsBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	for _, result := range results {
+		if err := f.healthStore.Delete(ctx, healthhistory.Key(result.ID)); err != nil {
+			return 0, err
+		}
+	}
+	return len(results), nil
+}
+
+// GetServiceUptime reports the availability of a service over each window
+// ending now, measured against the SLO of the service if it has one. The
+// results themselves are included if history is set.
+func (f *Facade) GetServiceUptime(ctx datastore.Context, serviceID string, windows []string, history bool) (*healthhistory.Report, error) {
+	svc, err := f.GetService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	longest := time.Duration(0)
+	if svc.SLO != nil {
+		longest = svc.SLO.Window()
+	}
+	if len(windows) == 0 {
+		windows = healthhistory.DefaultWindows
+	}
+	for _, window := range windows {
+		d, err := healthhistory.ParseWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		if d > longest {
+			longest = d
+		}
+	}
+
+	results, err := f.healthStore.GetResults(ctx, serviceID, now.Add(-longest))
+	if err != nil {
+		return nil, err
+	}
+	report, err := healthhistory.NewReport(serviceID, svc.SLO, results, windows, now)
+	if err != nil {
+		return nil, err
+	}
+	if history {
+		report.History = results
+	}
+	return report, nil
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/domain/servicetemplate"
@@ -65,4 +66,6 @@ type FacadeInterface interface {
  	HasIP(ctx datastore.Context, poolID string, ipAddr string) (bool, error)
 
 	UpdateResourcePool(ctx datastore.Context, entity *pool.ResourcePool) error
+
+	GetSecrets(ctx datastore.Context) ([]secret.Info, error)
 }
@@ -0,0 +1,153 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/secret"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/zenoss/glog"
+)
+
+// ErrNoMasterKey is returned if secrets are used before the master key is
+// loaded
+var ErrNoMasterKey = errors.New("facade: the master key of the secrets is not loaded")
+
+// SetSecretCipher sets the cipher that encrypts the values of the secrets
+// with the master key
+func (f *Facade) SetSecretCipher(cipher *secret.Cipher) {
+	f.secretCipher = cipher
+}
+
+// SetSecret adds a secret or replaces its value
+func (f *Facade) SetSecret(ctx datastore.Context, name, value string) error {
+	if f.secretCipher == nil {
+		return ErrNoMasterKey
+	}
+	s, err := secret.New(name, value, f.secretCipher)
+	if err != nil {
+		return err
+	}
+
+	var existing secret.Secret
+	if err := f.secretStore.Get(ctx, secret.Key(name), &existing); err == nil {
+		s.CreatedAt = existing.CreatedAt
+		s.DatabaseVersion = existing.DatabaseVersion
+	} else if !datastore.IsErrNoSuchEntity(err) {
+		return err
+	}
+
+	glog.Infof("Setting secret %s", name)
+	return f.secretStore.Put(ctx, secret.Key(name), s)
+}
+
+// GetSecret returns the value of a secret
+func (f *Facade) GetSecret(ctx datastore.Context, name string) (string, error) {
+	if f.secretCipher == nil {
+		return "", ErrNoMasterKey
+	}
+	var s secret.Secret
+	if err := f.secretStore.Get(ctx, secret.Key(name), &s); err != nil {
+		return "", err
+	}
+	return s.Value(f.secretCipher)
+}
+
+// GetSecrets describes all the secrets, without their values
+func (f *Facade) GetSecrets(ctx datastore.Context) ([]secret.Info, error) {
+	secrets, err := f.secretStore.GetSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]secret.Info, len(secrets))
+	for i := range secrets {
+		infos[i] = secrets[i].Info()
+	}
+	return infos, nil
+}
+
+// RemoveSecret removes a secret
+func (f *Facade) RemoveSecret(ctx datastore.Context, name string) error {
+	glog.Infof("Removing secret %s", name)
+	return f.secretStore.Delete(ctx, secret.Key(name))
+}
+
+// GetServiceSecrets returns the values of the secrets that a service puts in
+// its containers or refers to in its config files and in the context of the
+// service and its parents. It is called when an instance of the service
+// starts.
+func (f *Facade) GetServiceSecrets(ctx datastore.Context, serviceID string) (map[string]string, error) {
+	svc, err := f.GetService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.serviceSecretNames(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for _, name := range names {
+		value, err := f.GetSecret(ctx, name)
+		if datastore.IsErrNoSuchEntity(err) {
+			glog.Errorf("Service %s (%s) refers to secret %s, which is not set", svc.Name, svc.ID, name)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// serviceSecretNames returns the names of the secrets that a service uses
+func (f *Facade) serviceSecretNames(ctx datastore.Context, svc *service.Service) ([]string, error) {
+	seen := make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	for _, s := range svc.Secrets {
+		add(s.Name)
+	}
+	for _, conf := range svc.ConfigFiles {
+		add(secret.References(conf.Content)...)
+	}
+	for parent := *svc; ; {
+		for _, value := range parent.Context {
+			if text, ok := value.(string); ok {
+				add(secret.References(text)...)
+			}
+		}
+		if parent.ParentServiceID == "" {
+			break
+		}
+		var err error
+		if parent, err = f.getService(ctx, parent.ParentServiceID); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
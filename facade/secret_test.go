@@ -0,0 +1,74 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/domain/secret"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	. "gopkg.in/check.v1"
+)
+
+func (ft *FacadeTest) Test_GetServiceSecrets(t *C) {
+	values := map[string]string{
+		"db.password": "hunter2",
+		"api.token":   "abc123",
+		"tls.key":     "KEY",
+		"unused":      "nobody refers to this",
+	}
+	for name, value := range values {
+		t.Assert(ft.Facade.SetSecret(ft.CTX, name, value), IsNil)
+		defer ft.Facade.RemoveSecret(ft.CTX, name)
+	}
+
+	parent, err := service.NewService()
+	t.Assert(err, IsNil)
+	parent.Name = "Test_GetServiceSecrets"
+	parent.PoolID = "default"
+	parent.DeploymentID = "Test_GetServiceSecrets"
+	parent.Launch = "manual"
+	parent.Context = map[string]interface{}{"token": secret.Reference("api.token")}
+	t.Assert(ft.Facade.AddService(ft.CTX, *parent), IsNil)
+	defer ft.Facade.RemoveService(ft.CTX, parent.ID)
+
+	child, err := service.NewService()
+	t.Assert(err, IsNil)
+	child.Name = "child"
+	child.PoolID = "default"
+	child.DeploymentID = "Test_GetServiceSecrets"
+	child.ParentServiceID = parent.ID
+	child.Launch = "manual"
+	child.Secrets = []servicedefinition.Secret{{Name: "db.password", Env: "DB_PASSWORD"}}
+	child.ConfigFiles = map[string]servicedefinition.ConfigFile{
+		"/etc/app.conf": {Filename: "/etc/app.conf", Content: "key=" + secret.Reference("tls.key") + " other=" + secret.Reference("not.set")},
+	}
+	t.Assert(ft.Facade.AddService(ft.CTX, *child), IsNil)
+	defer ft.Facade.RemoveService(ft.CTX, child.ID)
+
+	// secrets that are not set are left out
+	actual, err := ft.Facade.GetServiceSecrets(ft.CTX, child.ID)
+	t.Assert(err, IsNil)
+	t.Assert(actual, DeepEquals, map[string]string{
+		"db.password": "hunter2",
+		"api.token":   "abc123",
+		"tls.key":     "KEY",
+	})
+
+	actual, err = ft.Facade.GetServiceSecrets(ft.CTX, parent.ID)
+	t.Assert(err, IsNil)
+	t.Assert(actual, DeepEquals, map[string]string{"api.token": "abc123"})
+
+	_, err = New("localhost:5000").GetServiceSecrets(ft.CTX, child.ID)
+	t.Assert(err, Equals, ErrNoMasterKey)
+}
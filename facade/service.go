@@ -26,6 +26,7 @@ import (
 	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/stream"
 
 	"github.com/control-center/serviced/commons/docker"
 )
@@ -82,39 +83,34 @@ func (f *Facade) AddService(ctx datastore.Context, svc service.Service) error {
 		for key, _ := range svcCopy.OriginalConfigs {
 			glog.V(2).Infof("Facade.AddService: calling updateService for %s due to OriginalConfigs of %+v", svc.Name, key)
 		}
-		return f.updateService(ctx, &svcCopy)
+		if err := f.updateService(ctx, &svcCopy); err != nil {
+			return err
+		}
+	} else {
+		glog.V(2).Infof("Facade.AddService: calling zk.updateService for %s %d ConfigFiles", svc.Name, len(svc.ConfigFiles))
+		if err := zkAPI(f).UpdateService(&svc); err != nil {
+			return err
+		}
 	}
 
-	glog.V(2).Infof("Facade.AddService: calling zk.updateService for %s %d ConfigFiles", svc.Name, len(svc.ConfigFiles))
-	return zkAPI(f).UpdateService(&svc)
+	stream.Publish(stream.Event{Type: stream.ServiceAdded, ServiceID: svc.ID, Summary: fmt.Sprintf("service %s added", svc.Name)})
+	return nil
 }
 
 //
 func (f *Facade) UpdateService(ctx datastore.Context, svc service.Service) error {
-	glog.V(2).Infof("Facade.UpdateService: %+v", svc)
-	//cannot update service without validating it.
-	if svc.DesiredState != int(service.SVCStop) {
-		if err := f.validateServicesForStarting(ctx, &svc); err != nil {
-			glog.Warningf("Could not validate service %s (%s) for starting: %s", svc.Name, svc.ID, err)
-			svc.DesiredState = int(service.SVCStop)
-		}
-
-		for _, ep := range svc.GetServiceVHosts() {
-			for _, vh := range ep.VHosts {
-				//check that vhosts aren't already started elsewhere
-				if err := zkAPI(f).CheckRunningVHost(vh, svc.ID); err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	return f.updateService(ctx, &svc)
+	return f.UpdateServiceAs(ctx, svc, "", false)
 }
 
 func (f *Facade) RemoveService(ctx datastore.Context, id string) error {
 	store := f.serviceStore
 
+	// look up the tenant while the services are still there
+	tenantID, err := f.GetTenantID(ctx, id)
+	if err != nil {
+		glog.Warningf("Could not look up the tenant of service %s: %s", id, err)
+	}
+
 	return f.walkServices(ctx, id, true, func(svc *service.Service) error {
 		// remove all address assignments
 		for _, endpoint := range svc.Endpoints {
@@ -140,6 +136,7 @@ func (f *Facade) RemoveService(ctx datastore.Context, id string) error {
 			return err
 		}
 
+		stream.Publish(stream.Event{Type: stream.ServiceRemoved, TenantID: tenantID, ServiceID: svc.ID, Summary: fmt.Sprintf("service %s removed", svc.Name)})
 		return nil
 	})
 }
@@ -1066,6 +1063,12 @@ func (f *Facade) fillServiceAddr(ctx datastore.Context, svc *service.Service) er
 
 // updateService internal method to use when service has been validated
 func (f *Facade) updateService(ctx datastore.Context, svc *service.Service) error {
+	return f.updateServiceAs(ctx, svc, "")
+}
+
+// updateServiceAs updates a service that has been validated and records the
+// changes to its config files as made by author
+func (f *Facade) updateServiceAs(ctx datastore.Context, svc *service.Service, author string) error {
 	id := strings.TrimSpace(svc.ID)
 	if id == "" {
 		return errors.New("empty Service.ID not allowed")
@@ -1097,8 +1100,13 @@ func (f *Facade) updateService(ctx datastore.Context, svc *service.Service) erro
 	//For now always make sure originalConfigs stay the same, essentially they are immutable
 	svc.OriginalConfigs = oldSvc.OriginalConfigs
 
+	//the config files as they were, with the stored changes
+	if err := f.fillServiceConfigs(ctx, oldSvc); err != nil {
+		return err
+	}
+
 	//check if config files haven't changed
-	if !reflect.DeepEqual(oldSvc.OriginalConfigs, svc.ConfigFiles) {
+	if !reflect.DeepEqual(oldSvc.ConfigFiles, svc.ConfigFiles) {
 		//lets validate Service before doing more work....
 		if err := svc.ValidEntity(); err != nil {
 			return err
@@ -1146,6 +1154,10 @@ func (f *Facade) updateService(ctx datastore.Context, svc *service.Service) erro
 		for _, confToDelete := range foundConfs {
 			configStore.Delete(ctx, serviceconfigfile.Key(confToDelete.ID))
 		}
+
+		if err := f.recordConfigRevisions(ctx, tenantID, servicePath, oldSvc, svc.ConfigFiles, author); err != nil {
+			glog.Warningf("Could not record the history of the config files of service %s (%s): %s", svc.Name, svc.ID, err)
+		}
 	}
 
 	svc.UpdatedAt = time.Now()
@@ -0,0 +1,192 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package facade
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/stream"
+	"github.com/zenoss/glog"
+)
+
+// UpdateServiceAs updates a service and records the changes to its config
+// files as made by author. If restart is set and the config files changed,
+// the running instances of the service are restarted to pick them up.
+func (f *Facade) UpdateServiceAs(ctx datastore.Context, svc service.Service, author string, restart bool) error {
+	glog.V(2).Infof("Facade.UpdateServiceAs: %+v", svc)
+	//cannot update service without validating it.
+	if svc.DesiredState != int(service.SVCStop) {
+		if err := f.validateServicesForStarting(ctx, &svc); err != nil {
+			glog.Warningf("Could not validate service %s (%s) for starting: %s", svc.Name, svc.ID, err)
+			svc.DesiredState = int(service.SVCStop)
+		}
+
+		for _, ep := range svc.GetServiceVHosts() {
+			for _, vh := range ep.VHosts {
+				//check that vhosts aren't already started elsewhere
+				if err := zkAPI(f).CheckRunningVHost(vh, svc.ID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	old, err := f.GetService(ctx, svc.ID)
+	if err != nil {
+		glog.Errorf("Could not load service %s: %s", svc.ID, err)
+		return err
+	}
+	changed := len(changedConfigFiles(old, svc.ConfigFiles)) > 0
+
+	if err := f.updateServiceAs(ctx, &svc, author); err != nil {
+		return err
+	}
+	stream.Publish(stream.Event{Type: stream.ServiceUpdated, ServiceID: svc.ID, Summary: fmt.Sprintf("service %s updated", svc.Name)})
+
+	if restart && changed {
+		if _, err := f.restartServiceInstances(ctx, &svc); err != nil {
+			glog.Errorf("Could not restart the instances of service %s (%s): %s", svc.Name, svc.ID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetServiceConfigHistory returns the changes to the config files of a
+// service, oldest first. If filename is set, only the changes to that file are
+// returned.
+func (f *Facade) GetServiceConfigHistory(ctx datastore.Context, serviceID, filename string) ([]serviceconfigfile.Revision, error) {
+	svc, err := f.getService(ctx, serviceID)
+	if err != nil {
+		glog.Errorf("Could not load service %s: %s", serviceID, err)
+		return nil, err
+	}
+	tenantID, servicePath, err := f.getTenantIDAndPath(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	return serviceconfigfile.NewStore().GetRevisions(ctx, tenantID, servicePath, filename)
+}
+
+// RevertServiceConfig restores a config file of a service to the content it
+// had at a revision, as a change made by author. If restart is set, the
+// running instances of the service are restarted to pick it up.
+func (f *Facade) RevertServiceConfig(ctx datastore.Context, serviceID, revisionID, author string, restart bool) error {
+	svc, err := f.GetService(ctx, serviceID)
+	if err != nil {
+		glog.Errorf("Could not load service %s: %s", serviceID, err)
+		return err
+	}
+	tenantID, servicePath, err := f.getTenantIDAndPath(ctx, *svc)
+	if err != nil {
+		return err
+	}
+	rev, err := serviceconfigfile.NewStore().FindRevision(ctx, tenantID, servicePath, revisionID)
+	if err != nil {
+		return err
+	}
+	if _, ok := svc.ConfigFiles[rev.Filename]; !ok {
+		return fmt.Errorf("service %s has no config file %s", svc.Name, rev.Filename)
+	}
+
+	glog.Infof("Reverting config file %s of service %s (%s) to revision %s", rev.Filename, svc.Name, svc.ID, rev.ID)
+	svc.ConfigFiles[rev.Filename] = rev.ConfFile
+	return f.UpdateServiceAs(ctx, *svc, author, restart)
+}
+
+// recordConfigRevisions adds the config files of a service that changed to
+// their history. The first change of a file also records the content it had
+// before, so that the change can be reverted.
+func (f *Facade) recordConfigRevisions(ctx datastore.Context, tenantID, servicePath string, old *service.Service, configFiles map[string]servicedefinition.ConfigFile, author string) error {
+	store := serviceconfigfile.NewStore()
+	changed := changedConfigFiles(old, configFiles)
+	filenames := make([]string, 0, len(changed))
+	for filename := range changed {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		revs, err := store.GetRevisions(ctx, tenantID, servicePath, filename)
+		if err != nil {
+			return err
+		}
+		if previous, found := old.ConfigFiles[filename]; found && len(revs) == 0 {
+			baseline, err := serviceconfigfile.NewRevision(tenantID, servicePath, previous, "")
+			if err != nil {
+				return err
+			}
+			if !old.UpdatedAt.IsZero() {
+				baseline.CreatedAt = old.UpdatedAt.UTC()
+			}
+			if err := store.PutRevision(ctx, baseline); err != nil {
+				return err
+			}
+		}
+		rev, err := serviceconfigfile.NewRevision(tenantID, servicePath, changed[filename], author)
+		if err != nil {
+			return err
+		}
+		glog.V(2).Infof("Recording revision %s of config file %s of %s", rev.ID, filename, servicePath)
+		if err := store.PutRevision(ctx, rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changedConfigFiles returns the config files of a service whose content
+// changes when its config files are updated to configFiles. Only the files
+// that the service was deployed with are kept, and the files that are left
+// out go back to their original content.
+func changedConfigFiles(old *service.Service, configFiles map[string]servicedefinition.ConfigFile) map[string]servicedefinition.ConfigFile {
+	changed := make(map[string]servicedefinition.ConfigFile)
+	for filename, original := range old.OriginalConfigs {
+		conf, found := configFiles[filename]
+		if !found {
+			conf = original
+		}
+		if !reflect.DeepEqual(old.ConfigFiles[filename], conf) {
+			changed[filename] = conf
+		}
+	}
+	return changed
+}
+
+// restartServiceInstances stops the running instances of a service, which
+// are started again because the service is still meant to run. Returns the
+// number of instances that were restarted.
+func (f *Facade) restartServiceInstances(ctx datastore.Context, svc *service.Service) (int, error) {
+	if svc.DesiredState != int(service.SVCRun) {
+		return 0, nil
+	}
+	var states []servicestate.ServiceState
+	if err := zkAPI(f).GetServiceStates(svc.PoolID, &states, svc.ID); err != nil {
+		return 0, err
+	}
+	for _, state := range states {
+		glog.Infof("Restarting instance %d of service %s (%s) for its new config files", state.InstanceID, svc.Name, svc.ID)
+		if err := zkAPI(f).StopServiceInstance(svc.PoolID, state.HostID, state.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(states), nil
+}
@@ -17,6 +17,8 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/pkg/parsers"
@@ -53,6 +55,11 @@ func (f *Facade) AddServiceTemplate(ctx datastore.Context, serviceTemplate servi
 		return hash, nil
 	}
 
+	if _, _, err := servicetemplate.Compile(serviceTemplate, "", f.templateSource(ctx)); err != nil {
+		glog.Errorf("Could not resolve the includes of template %s: %s", hash, err)
+		return "", err
+	}
+
 	if err = f.templateStore.Put(ctx, serviceTemplate); err != nil {
 		return "", err
 	}
@@ -64,6 +71,10 @@ func (f *Facade) AddServiceTemplate(ctx datastore.Context, serviceTemplate servi
 
 //UpdateServiceTemplate updates a service template
 func (f *Facade) UpdateServiceTemplate(ctx datastore.Context, template servicetemplate.ServiceTemplate) error {
+	if _, _, err := servicetemplate.Compile(template, "", f.templateSource(ctx)); err != nil {
+		glog.Errorf("Could not resolve the includes of template %s: %s", template.ID, err)
+		return err
+	}
 	if err := f.templateStore.Put(ctx, template); err != nil {
 		return err
 	}
@@ -77,6 +88,14 @@ func (f *Facade) RemoveServiceTemplate(ctx datastore.Context, id string) error {
 		return fmt.Errorf("Unable to find template: %s", id)
 	}
 
+	// templates that extend or include the template cannot be compiled
+	// without it
+	if dependents, err := f.templateDependents(ctx, id); err != nil {
+		return fmt.Errorf("could not verify the templates that include %s: %s", id, err)
+	} else if count := len(dependents); count > 0 {
+		return fmt.Errorf("cannot delete template %s: found %d templates that extend or include it: %s", id, count, strings.Join(dependents, ", "))
+	}
+
 	glog.V(2).Infof("Facade.RemoveServiceTemplate: %s", id)
 	if err := f.templateStore.Delete(ctx, id); err != nil {
 		return err
@@ -86,6 +105,154 @@ func (f *Facade) RemoveServiceTemplate(ctx datastore.Context, id string) error {
 	return nil
 }
 
+// GetServiceTemplateHistory returns the versions of a template, oldest first,
+// with the deployments of each version
+func (f *Facade) GetServiceTemplateHistory(ctx datastore.Context, templateID string) ([]servicetemplate.TemplateHistory, error) {
+	versions, err := f.templateStore.GetVersions(ctx, templateID)
+	if err != nil {
+		glog.Errorf("Could not look up the versions of template %s: %s", templateID, err)
+		return nil, err
+	}
+
+	current, err := f.templateStore.Get(ctx, templateID)
+	if err != nil && !datastore.IsErrNoSuchEntity(err) {
+		glog.Errorf("Could not load template %s: %s", templateID, err)
+		return nil, err
+	} else if current == nil && len(versions) == 0 {
+		return nil, fmt.Errorf("template %s not found", templateID)
+	}
+	currentHash := ""
+	if current != nil {
+		if currentHash, err = servicetemplate.ContentHash(*current); err != nil {
+			return nil, err
+		}
+	}
+
+	deployments, err := f.templateStore.GetDeploymentsByTemplate(ctx, templateID)
+	if err != nil {
+		glog.Errorf("Could not look up the deployments of template %s: %s", templateID, err)
+		return nil, err
+	}
+	deployed := make(map[string][]string)
+	for _, d := range deployments {
+		deployed[d.TemplateHash] = append(deployed[d.TemplateHash], d.ID)
+	}
+
+	history := make([]servicetemplate.TemplateHistory, 0, len(versions)+1)
+	found := false
+	for _, v := range versions {
+		history = append(history, servicetemplate.TemplateHistory{
+			Hash:        v.Hash,
+			Name:        v.Name,
+			Version:     v.Version,
+			CreatedAt:   v.CreatedAt,
+			Current:     v.Hash == currentHash,
+			Deployments: deployed[v.Hash],
+		})
+		found = found || v.Hash == currentHash
+	}
+	// templates added before their history was kept have no versions yet
+	if current != nil && !found {
+		history = append(history, servicetemplate.TemplateHistory{
+			Hash:        currentHash,
+			Name:        current.Name,
+			Version:     current.Version,
+			Current:     true,
+			Deployments: deployed[currentHash],
+		})
+	}
+	return history, nil
+}
+
+// RollbackServiceTemplate restores the version of a template whose hash
+// starts with the given prefix. Returns the hash of the version.
+func (f *Facade) RollbackServiceTemplate(ctx datastore.Context, templateID, hash string) (string, error) {
+	version, err := f.templateStore.FindVersion(ctx, templateID, hash)
+	if err != nil {
+		glog.Errorf("Could not find version %s of template %s: %s", hash, templateID, err)
+		return "", err
+	}
+	template, err := version.Template()
+	if err != nil {
+		glog.Errorf("Could not load version %s of template %s: %s", version.Hash, templateID, err)
+		return "", err
+	}
+	glog.Infof("Rolling back template %s to version %s", templateID, version.Hash)
+	if err := f.UpdateServiceTemplate(ctx, *template); err != nil {
+		return "", err
+	}
+	return version.Hash, nil
+}
+
+// templateSource looks up the templates that other templates include
+func (f *Facade) templateSource(ctx datastore.Context) servicetemplate.TemplateSource {
+	return func(templateID string) (*servicetemplate.ServiceTemplate, error) {
+		return f.templateStore.Get(ctx, templateID)
+	}
+}
+
+// templateDependents returns the ids of the templates that extend or include
+// a template
+func (f *Facade) templateDependents(ctx datastore.Context, templateID string) ([]string, error) {
+	templates, err := f.templateStore.GetServiceTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var dependents []string
+	for _, st := range templates {
+		for _, id := range st.TemplateIDs() {
+			if id == templateID && st.ID != templateID {
+				dependents = append(dependents, st.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents, nil
+}
+
+// compileTemplate loads a template with the templates that it extends and
+// includes resolved
+func (f *Facade) compileTemplate(ctx datastore.Context, templateID string) (*servicetemplate.ServiceTemplate, error) {
+	template, err := f.templateStore.Get(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	compiled, _, err := servicetemplate.Compile(*template, "", f.templateSource(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// recordDeployment records the compiled template that a deployment was
+// deployed or upgraded from, under the hash of the template as it is stored
+// so that the history of the template lists the deployment. An upgrade keeps
+// whether serviced apply manages the deployment.
+func (f *Facade) recordDeployment(ctx datastore.Context, deploymentID string, template *servicetemplate.ServiceTemplate, params map[string]string, upgrade bool) error {
+	stored, err := f.templateStore.Get(ctx, template.ID)
+	if err != nil {
+		return err
+	}
+	hash, err := servicetemplate.ContentHash(*stored)
+	if err != nil {
+		return err
+	}
+	deployment, err := servicetemplate.NewDeployment(deploymentID, *template, params)
+	if err != nil {
+		return err
+	}
+	deployment.TemplateHash = hash
+	if upgrade {
+		if previous, err := f.templateStore.GetDeployment(ctx, deploymentID); err == nil {
+			deployment.Managed = previous.Managed
+		} else if !datastore.IsErrNoSuchEntity(err) {
+			return err
+		}
+	}
+	return f.templateStore.PutDeployment(ctx, deployment)
+}
+
 func (f *Facade) GetServiceTemplates(ctx datastore.Context) (map[string]servicetemplate.ServiceTemplate, error) {
 	glog.V(2).Infof("Facade.GetServiceTemplates")
 	results, err := f.templateStore.GetServiceTemplates(ctx)
@@ -176,8 +343,9 @@ func (f *Facade) DeployTemplateStatus(deploymentID string, status *string) error
 	return nil
 }
 
-//DeployTemplate creates and deployes a service to the pool and returns the tenant id of the newly deployed service
-func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID string, deploymentID string) ([]string, error) {
+//DeployTemplate creates and deployes a service to the pool and returns the tenant id of the newly deployed service.
+//The values of the parameters of the template are set in the service definitions before the services are built.
+func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID string, deploymentID string, params map[string]string) ([]string, error) {
 	// add an entry for reporting status
 	deployments[deploymentID] = map[string]string{
 		"TemplateID":   templateID,
@@ -189,7 +357,7 @@ func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID
 	defer delete(deployments, deploymentID)
 
 	UpdateDeployTemplateStatus(deploymentID, "deploy_loading_template|"+templateID)
-	template, err := f.templateStore.Get(ctx, templateID)
+	template, err := f.compileTemplate(ctx, templateID)
 	if err != nil {
 		glog.Errorf("unable to load template: %s", templateID)
 		return nil, err
@@ -206,6 +374,12 @@ func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID
 	//now that we know the template name, set it in the status
 	deployments[deploymentID]["templateName"] = template.Name
 
+	resolved, err := template.Apply(params)
+	if err != nil {
+		glog.Errorf("Could not apply the parameters of template %s: %s", templateID, err)
+		return nil, err
+	}
+
 	UpdateDeployTemplateStatus(deploymentID, "deploy_loading_resource_pool|"+poolID)
 	pool, err := f.GetResourcePool(ctx, poolID)
 	if err != nil {
@@ -217,13 +391,13 @@ func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID
 	}
 
 	UpdateDeployTemplateStatus(deploymentID, "deploy_pulling_images")
-	if err := pullTemplateImages(template); err != nil {
+	if err := pullTemplateImages(resolved); err != nil {
 		glog.Errorf("Unable to pull one or more images")
 		return nil, err
 	}
 
-	tenantIDs := make([]string, len(template.Services))
-	for i, sd := range template.Services {
+	tenantIDs := make([]string, len(resolved.Services))
+	for i, sd := range resolved.Services {
 		glog.Infof("Deploying application %s to %s", sd.Name, deploymentID)
 		var err error
 		if tenantIDs[i], err = f.deployService(ctx, "", "", deploymentID, poolID, false, sd); err != nil {
@@ -232,9 +406,180 @@ func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID
 		}
 	}
 
+	// record the template for later upgrades; without the record an upgrade
+	// cannot tell the edits of users from the changes of the template
+	if err := f.recordDeployment(ctx, deploymentID, template, params, false); err != nil {
+		glog.Warningf("Could not record the deployment of template %s to %s: %s", templateID, deploymentID, err)
+	}
+
 	return tenantIDs, nil
 }
 
+// PlanTemplateUpgrade computes the changes that upgrade a deployed tenant to
+// a template, keeping the changes that users made to its services. The
+// parameters that the tenant was deployed with are applied to the template,
+// unless params gives them new values.
+func (f *Facade) PlanTemplateUpgrade(ctx datastore.Context, templateID, tenantID string, params map[string]string) (*servicetemplate.UpgradePlan, error) {
+	template, err := f.compileTemplate(ctx, templateID)
+	if err != nil {
+		glog.Errorf("Could not load template %s: %s", templateID, err)
+		return nil, err
+	}
+
+	tenant, err := f.serviceStore.Get(ctx, tenantID)
+	if err != nil {
+		glog.Errorf("Could not load tenant %s: %s", tenantID, err)
+		return nil, err
+	} else if tenant.ParentServiceID != "" {
+		return nil, fmt.Errorf("service %s is not a tenant", tenantID)
+	}
+
+	var base *servicedefinition.ServiceDefinition
+	fromTemplateID := ""
+	values := make(map[string]string)
+	if deployment, err := f.templateStore.GetDeployment(ctx, tenant.DeploymentID); err == nil {
+		deployed, err := deployment.Resolved()
+		if err != nil {
+			glog.Errorf("Could not load the template that %s was deployed from: %s", tenant.DeploymentID, err)
+			return nil, err
+		}
+		base = findTemplateApplication(deployed, tenant.Name)
+		fromTemplateID = deployment.TemplateID
+		values = template.DeclaredValues(deployment.Params)
+	} else if datastore.IsErrNoSuchEntity(err) {
+		glog.Warningf("The template that %s was deployed from was not recorded; changes made by users may be overwritten", tenant.DeploymentID)
+	} else {
+		glog.Errorf("Could not look up the deployment %s: %s", tenant.DeploymentID, err)
+		return nil, err
+	}
+
+	for name, value := range params {
+		values[name] = value
+	}
+	resolved, err := template.Apply(values)
+	if err != nil {
+		glog.Errorf("Could not apply the parameters of template %s: %s", templateID, err)
+		return nil, err
+	}
+	next := findTemplateApplication(resolved, tenant.Name)
+	if next == nil {
+		return nil, fmt.Errorf("template %s has no application named %s", templateID, tenant.Name)
+	}
+
+	var live []service.Service
+	if err := f.walkServices(ctx, tenantID, true, func(svc *service.Service) error {
+		if err := f.fillOutService(ctx, svc); err != nil {
+			return err
+		}
+		live = append(live, *svc)
+		return nil
+	}); err != nil {
+		glog.Errorf("Could not load the services of tenant %s: %s", tenantID, err)
+		return nil, err
+	}
+
+	plan, err := servicetemplate.PlanUpgrade(tenantID, base, next, live)
+	if err != nil {
+		return nil, err
+	}
+	plan.FromTemplateID = fromTemplateID
+	plan.ToTemplateID = templateID
+	plan.Params = values
+	return plan, nil
+}
+
+// UpgradeTemplate applies an upgrade plan and records the template that the
+// tenant was upgraded to
+func (f *Facade) UpgradeTemplate(ctx datastore.Context, plan *servicetemplate.UpgradePlan) error {
+	template, err := f.compileTemplate(ctx, plan.ToTemplateID)
+	if err != nil {
+		glog.Errorf("Could not load template %s: %s", plan.ToTemplateID, err)
+		return err
+	}
+	if _, ok := deployments[plan.DeploymentID]; !ok {
+		defer delete(deployments, plan.DeploymentID)
+	}
+	resolved, err := template.Apply(plan.Params)
+	if err != nil {
+		glog.Errorf("Could not apply the parameters of template %s: %s", plan.ToTemplateID, err)
+		return err
+	}
+	if err := pullTemplateImages(resolved); err != nil {
+		glog.Errorf("Unable to pull one or more images")
+		return err
+	}
+
+	for _, serviceID := range plan.Remove {
+		if err := f.RemoveService(ctx, serviceID); err != nil {
+			glog.Errorf("Could not remove service %s: %s", serviceID, err)
+			return err
+		}
+	}
+
+	for _, add := range plan.Add {
+		poolID, err := f.GetPoolForService(ctx, add.ParentID)
+		if err != nil {
+			glog.Errorf("Could not look up the pool of service %s: %s", add.ParentID, err)
+			return err
+		}
+		if _, err := f.deployService(ctx, plan.TenantID, add.ParentID, plan.DeploymentID, poolID, false, add.Definition); err != nil {
+			glog.Errorf("Could not deploy %s at %s: %s", add.Definition.Name, add.ParentID, err)
+			return err
+		}
+	}
+
+	for _, svc := range plan.Update {
+		// the configs of the new template are the originals that the edits of
+		// users are stored against
+		stored, err := f.serviceStore.Get(ctx, svc.ID)
+		if err != nil {
+			glog.Errorf("Could not load service %s (%s): %s", svc.Name, svc.ID, err)
+			return err
+		}
+		stored.OriginalConfigs = svc.OriginalConfigs
+		if err := f.serviceStore.Put(ctx, stored); err != nil {
+			glog.Errorf("Could not update the original configs of service %s (%s): %s", svc.Name, svc.ID, err)
+			return err
+		}
+		svc.DatabaseVersion = stored.DatabaseVersion
+
+		if err := f.evaluateEndpointTemplates(ctx, &svc); err != nil {
+			glog.Errorf("Could not evaluate endpoint templates for service %s (%s): %s", svc.Name, svc.ID, err)
+			return err
+		}
+		if svc.ImageID != stored.ImageID {
+			if err := setImageID(f.dockerRegistry, plan.TenantID, &svc); err != nil {
+				glog.Errorf("Could not set image id for service %s (%s): %s", svc.Name, svc.ID, err)
+				return err
+			}
+		}
+		if err := f.UpdateService(ctx, svc); err != nil {
+			glog.Errorf("Could not update service %s (%s): %s", svc.Name, svc.ID, err)
+			return err
+		}
+	}
+
+	if err := f.recordDeployment(ctx, plan.DeploymentID, template, plan.Params, true); err != nil {
+		glog.Errorf("Could not record the upgrade of %s to template %s: %s", plan.DeploymentID, plan.ToTemplateID, err)
+		return err
+	}
+	return nil
+}
+
+// findTemplateApplication returns the top level service definition of a
+// template with the given name, or the only one
+func findTemplateApplication(template *servicetemplate.ServiceTemplate, name string) *servicedefinition.ServiceDefinition {
+	for i := range template.Services {
+		if template.Services[i].Name == name {
+			return &template.Services[i]
+		}
+	}
+	if len(template.Services) == 1 {
+		return &template.Services[0]
+	}
+	return nil
+}
+
 // DeployService converts a service definition to a service and deploys it under
 // a specific service.  If the overwrite option is enabled, existing services
 // with the same name will be overwritten, otherwise services may only be added.
@@ -292,21 +637,7 @@ func (f *Facade) deployService(ctx datastore.Context, tenantID string, parentSer
 
 	UpdateDeployTemplateStatus(deploymentID, "deploy_loading_service|"+newsvc.Name)
 	//for each endpoint, evaluate its Application
-	getService := func(serviceID string) (service.Service, error) {
-		s, err := f.GetService(ctx, serviceID)
-		if err != nil {
-			return service.Service{}, err
-		}
-		return *s, err
-	}
-	findChildService := func(parentID, serviceName string) (service.Service, error) {
-		s, err := f.FindChildService(ctx, parentID, serviceName)
-		if err != nil {
-			return service.Service{}, err
-		}
-		return *s, err
-	}
-	if err = newsvc.EvaluateEndpointTemplates(getService, findChildService); err != nil {
+	if err = f.evaluateEndpointTemplates(ctx, newsvc); err != nil {
 		glog.Errorf("Could not evaluate endpoint templates for service %s with parent %s: %s", newsvc.Name, newsvc.ParentServiceID, err)
 		return "", err
 	}
@@ -355,6 +686,26 @@ func (f *Facade) deployService(ctx datastore.Context, tenantID string, parentSer
 	return newsvc.ID, nil
 }
 
+// evaluateEndpointTemplates evaluates the applications of the endpoints of a
+// service
+func (f *Facade) evaluateEndpointTemplates(ctx datastore.Context, svc *service.Service) error {
+	getService := func(serviceID string) (service.Service, error) {
+		s, err := f.GetService(ctx, serviceID)
+		if err != nil {
+			return service.Service{}, err
+		}
+		return *s, err
+	}
+	findChildService := func(parentID, serviceName string) (service.Service, error) {
+		s, err := f.FindChildService(ctx, parentID, serviceName)
+		if err != nil {
+			return service.Service{}, err
+		}
+		return *s, err
+	}
+	return svc.EvaluateEndpointTemplates(getService, findChildService)
+}
+
 func checkImages(imap map[string]struct{}, svcdef servicedefinition.ServiceDefinition) error {
 	if _, ok := imap[svcdef.ImageID]; !ok {
 		if _, err := docker.FindImage(svcdef.ImageID, false); err != nil {
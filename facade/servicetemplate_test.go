@@ -18,6 +18,7 @@ import (
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/domain/servicetemplate"
 	. "gopkg.in/check.v1"
 )
 
@@ -79,3 +80,32 @@ func (ft *FacadeTest) TestRenameImageID(t *C) {
 		t.FailNow()
 	}
 }
+
+func (ft *FacadeTest) Test_RemoveIncludedServiceTemplate(t *C) {
+	base := servicetemplate.ServiceTemplate{
+		Name:     "Test_RemoveIncludedServiceTemplate_base",
+		Services: []servicedefinition.ServiceDefinition{{Name: "app", Launch: "manual"}},
+	}
+	baseID, err := ft.Facade.AddServiceTemplate(ft.CTX, base)
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, baseID)
+
+	extended := servicetemplate.ServiceTemplate{
+		Name:    "Test_RemoveIncludedServiceTemplate_extended",
+		Extends: &servicetemplate.Include{Template: baseID},
+	}
+	extendedID, err := ft.Facade.AddServiceTemplate(ft.CTX, extended)
+	t.Assert(err, IsNil)
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, extendedID)
+
+	// the base cannot be removed while a template extends it
+	err = ft.Facade.RemoveServiceTemplate(ft.CTX, baseID)
+	t.Assert(err, ErrorMatches, "cannot delete template "+baseID+": found 1 templates that extend or include it: "+extendedID)
+	_, err = ft.Facade.compileTemplate(ft.CTX, extendedID)
+	t.Assert(err, IsNil)
+
+	err = ft.Facade.RemoveServiceTemplate(ft.CTX, extendedID)
+	t.Assert(err, IsNil)
+	err = ft.Facade.RemoveServiceTemplate(ft.CTX, baseID)
+	t.Assert(err, IsNil)
+}
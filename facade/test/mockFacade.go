@@ -20,6 +20,7 @@ import (
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/domain/servicetemplate"
@@ -125,3 +126,13 @@ func (mf *MockFacade) HasIP(ctx datastore.Context, poolID string, ipAddr string)
 func (mf *MockFacade) UpdateResourcePool(ctx datastore.Context, entity *pool.ResourcePool) error {
 	return mf.Mock.Called(ctx, entity).Error(0)
 }
+
+func (mf *MockFacade) GetSecrets(ctx datastore.Context) ([]secret.Info, error) {
+	args := mf.Mock.Called(ctx)
+
+	var secrets []secret.Info
+	if arg0 := args.Get(0); arg0 != nil {
+		secrets = arg0.([]secret.Info)
+	}
+	return secrets, args.Error(1)
+}
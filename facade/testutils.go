@@ -18,8 +18,11 @@ import (
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/datastore/elastic"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicestate"
@@ -48,15 +51,26 @@ func (ft *FacadeTest) SetUpSuite(c *gocheck.C) {
 	ft.Mappings = append(ft.Mappings, pool.MAPPING)
 	ft.Mappings = append(ft.Mappings, service.MAPPING)
 	ft.Mappings = append(ft.Mappings, servicetemplate.MAPPING)
+	ft.Mappings = append(ft.Mappings, servicetemplate.DeploymentMapping)
+	ft.Mappings = append(ft.Mappings, servicetemplate.VersionMapping)
 	ft.Mappings = append(ft.Mappings, addressassignment.MAPPING)
 	ft.Mappings = append(ft.Mappings, serviceconfigfile.MAPPING)
+	ft.Mappings = append(ft.Mappings, serviceconfigfile.RevisionMapping)
 	ft.Mappings = append(ft.Mappings, user.MAPPING)
+	ft.Mappings = append(ft.Mappings, event.MAPPING)
+	ft.Mappings = append(ft.Mappings, healthhistory.MAPPING)
+	ft.Mappings = append(ft.Mappings, secret.MAPPING)
 
 	ft.ElasticTest.SetUpSuite(c)
 	datastore.Register(ft.Driver())
 	ft.CTX = datastore.Get()
 
 	ft.Facade = New("localhost:5000")
+	cipher, err := secret.NewCipher(make([]byte, secret.KeySize))
+	if err != nil {
+		c.Fatalf("Could not create the secret cipher: %s", err)
+	}
+	ft.Facade.SetSecretCipher(cipher)
 
 	//mock out ZK calls to no ops
 	zkAPI = func(f *Facade) zkfuncs { return &zkMock{} }
@@ -0,0 +1,175 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/control-center/serviced/alert"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/facade"
+	"github.com/zenoss/glog"
+)
+
+// An instance that starts restartLimit times within restartWindow is
+// reported as restarting repeatedly.
+const (
+	restartLimit  = 3
+	restartWindow = 10 * time.Minute
+)
+
+var notifier *alert.Notifier
+var alertFacade *facade.Facade
+
+// tenants caches the tenant of each service
+var tenants = make(map[string]string)
+var tenantLock sync.Mutex
+
+type instanceHistory struct {
+	svc     dao.RunningService
+	starts  []time.Time
+	looping bool
+}
+
+// instances is nil until the running services have been polled once, so that
+// the instances already running when the master starts are not reported
+var instances map[string]*instanceHistory
+
+// SetNotifier sends health check transitions and instance lifecycle changes
+// to n
+func SetNotifier(n *alert.Notifier, f *facade.Facade) {
+	notifier = n
+	alertFacade = f
+}
+
+func newAlert(alertType, name, severity, serviceID, instanceID string, f *facade.Facade) alert.Alert {
+	a := alert.Alert{
+		Type:       alertType,
+		Name:       name,
+		Severity:   severity,
+		ServiceID:  serviceID,
+		InstanceID: instanceID,
+		Timestamp:  time.Now().UTC(),
+	}
+	svc, err := f.GetService(datastore.Get(), serviceID)
+	if err != nil || svc == nil {
+		glog.Warningf("Could not look up service %s for alert: %v", serviceID, err)
+		a.ServiceName = serviceID
+		return a
+	}
+	a.ServiceName = svc.Name
+	a.PoolID = svc.PoolID
+	a.TenantID = tenantID(serviceID, f)
+	return a
+}
+
+func tenantID(serviceID string, f *facade.Facade) string {
+	tenantLock.Lock()
+	defer tenantLock.Unlock()
+	if tenant, ok := tenants[serviceID]; ok {
+		return tenant
+	}
+	tenant, err := f.GetTenantID(datastore.Get(), serviceID)
+	if err != nil {
+		glog.Warningf("Could not look up tenant of service %s for alert: %s", serviceID, err)
+		return ""
+	}
+	tenants[serviceID] = tenant
+	return tenant
+}
+
+// notifyHealthCheck reports a health check that started failing or recovered
+func notifyHealthCheck(serviceID, instanceID, name, previous, status string, f *facade.Facade) {
+	var a alert.Alert
+	switch {
+	case status == "failed" && previous != "failed":
+		a = newAlert(alert.TypeHealth, name, alert.SeverityError, serviceID, instanceID, f)
+		a.Summary = fmt.Sprintf("health check %s failed on instance %s", name, instanceID)
+	case status == "passed" && previous == "failed":
+		a = newAlert(alert.TypeHealth, name, alert.SeverityError, serviceID, instanceID, f)
+		a.Summary = fmt.Sprintf("health check %s passed on instance %s", name, instanceID)
+		a.Cleared = true
+	default:
+		return
+	}
+	notifier.Notify(a)
+}
+
+// trackInstances compares the running services with the last poll and
+// reports instances that started, stopped or keep restarting
+func trackInstances(running []dao.RunningService, now time.Time) {
+	if notifier == nil {
+		return
+	}
+
+	current := make(map[string]*instanceHistory)
+	for _, svc := range running {
+		key := svc.ServiceID + "/" + strconv.Itoa(svc.InstanceID)
+		history, ok := instances[key]
+		if !ok {
+			history = &instanceHistory{}
+		}
+		current[key] = history
+		started := history.svc.ID != svc.ID
+		history.svc = svc
+		if !started || instances == nil {
+			continue
+		}
+
+		history.starts = append(history.starts, now)
+		if ok {
+			notifyLifecycle(svc, "restarted", alert.SeverityWarning, fmt.Sprintf("instance %d restarted on host %s", svc.InstanceID, svc.HostID), false)
+		} else {
+			notifyLifecycle(svc, "started", alert.SeverityInfo, fmt.Sprintf("instance %d started on host %s", svc.InstanceID, svc.HostID), false)
+		}
+	}
+
+	for key, history := range current {
+		recent := history.starts[:0]
+		for _, t := range history.starts {
+			if now.Sub(t) < restartWindow {
+				recent = append(recent, t)
+			}
+		}
+		history.starts = recent
+
+		if len(recent) >= restartLimit && !history.looping {
+			history.looping = true
+			notifyLifecycle(history.svc, "restarting", alert.SeverityCritical, fmt.Sprintf("instance %d started %d times in %s", history.svc.InstanceID, len(recent), restartWindow), false)
+		} else if len(recent) < restartLimit && history.looping {
+			history.looping = false
+			notifyLifecycle(history.svc, "restarting", alert.SeverityCritical, fmt.Sprintf("instance %d stopped restarting", history.svc.InstanceID), true)
+		}
+		delete(instances, key)
+	}
+
+	for _, history := range instances {
+		if history.looping {
+			notifyLifecycle(history.svc, "restarting", alert.SeverityCritical, fmt.Sprintf("instance %d stopped restarting", history.svc.InstanceID), true)
+		}
+		notifyLifecycle(history.svc, "stopped", alert.SeverityInfo, fmt.Sprintf("instance %d stopped on host %s", history.svc.InstanceID, history.svc.HostID), false)
+	}
+	instances = current
+}
+
+func notifyLifecycle(svc dao.RunningService, name, severity, summary string, cleared bool) {
+	a := newAlert(alert.TypeLifecycle, name, severity, svc.ServiceID, strconv.Itoa(svc.InstanceID), alertFacade)
+	a.Summary = summary
+	a.Cleared = cleared
+	notifier.Notify(a)
+}
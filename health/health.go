@@ -14,6 +14,7 @@
 package health
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,8 +22,10 @@ import (
 
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/healthhistory"
 	"github.com/control-center/serviced/facade"
 	"github.com/control-center/serviced/node"
+	"github.com/control-center/serviced/stream"
 	"github.com/zenoss/glog"
 	"github.com/zenoss/go-json-rest"
 )
@@ -98,6 +101,10 @@ func Cleanup(shutdown <-chan interface{}) {
 				glog.Warningf("Error acquiring running services: %v", err)
 				continue
 			}
+			trackInstances(runningServices, time.Now())
+			history.closeMissing(func(serviceID, instanceID string) bool {
+				return getService(serviceID, instanceID) != nil
+			})
 			lock.Lock()
 			for serviceID, instances := range healthStatuses {
 				if strings.HasPrefix(serviceID, "isvc-") {
@@ -154,6 +161,7 @@ func RegisterHealthCheck(serviceID string, instanceID string, name string, passe
 	}
 	if name == "__instance_shutdown" {
 		delete(serviceStatus, instanceID)
+		reportHistory(historyReport{serviceID: serviceID, instanceID: instanceID, at: time.Now()})
 		return
 	}
 	thisStatus, ok := instanceStatus[name]
@@ -175,6 +183,21 @@ func RegisterHealthCheck(serviceID string, instanceID string, name string, passe
 		glog.Warningf("ignoring %s health status %s, not found in service %s", passed, name, serviceID)
 		return
 	}
+	if thisStatus.Status != passed {
+		if notifier != nil {
+			// in line, so that the alerts of a check are queued in order
+			notifyHealthCheck(serviceID, instanceID, name, thisStatus.Status, passed, f)
+		}
+		go stream.Publish(stream.Event{
+			Type:       stream.HealthChanged,
+			ServiceID:  serviceID,
+			InstanceID: instanceID,
+			Summary:    fmt.Sprintf("health check %s %s on instance %s", name, passed, instanceID),
+		})
+	}
 	thisStatus.Status = passed
 	thisStatus.Timestamp = time.Now().UTC().Unix()
+	if passed == healthhistory.StatusPassed || passed == healthhistory.StatusFailed {
+		reportHistory(historyReport{serviceID: serviceID, instanceID: instanceID, name: name, status: passed, at: time.Now()})
+	}
 }
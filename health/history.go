@@ -0,0 +1,204 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/healthhistory"
+	"github.com/control-center/serviced/facade"
+	"github.com/zenoss/glog"
+)
+
+// Open periods are written every flushInterval so that reports stay current,
+// and results older than historyRetention are purged every purgeInterval.
+// Up to historyBacklog reports wait for the recorder.
+const (
+	flushInterval    = 5 * time.Minute
+	purgeInterval    = time.Hour
+	historyRetention = 90 * 24 * time.Hour
+	historyBacklog   = 1024
+)
+
+// historyReport is the status reported by a health check, or the shutdown of
+// an instance if name is empty
+type historyReport struct {
+	serviceID  string
+	instanceID string
+	name       string
+	status     string
+	at         time.Time
+}
+
+// historyReports feeds the reports to the recorder in the order they were
+// made.  It is nil until RecordHistory starts, so nothing is recorded without
+// a datastore to write to, and it is guarded by lock.
+var historyReports chan historyReport
+
+// reportHistory queues a report for the recorder.  The caller holds lock.
+func reportHistory(r historyReport) {
+	if historyReports == nil {
+		return
+	}
+	select {
+	case historyReports <- r:
+	default:
+		glog.Warningf("Dropping health check report for %s: the history recorder is behind", periodKey(r.serviceID, r.instanceID, r.name))
+	}
+}
+
+// period is a run of the same status reported by a health check
+type period struct {
+	result healthhistory.Result
+	dirty  bool
+}
+
+// historyRecorder turns health check reports into periods of passing and
+// failing, keyed by service, instance and check
+type historyRecorder struct {
+	sync.Mutex
+	open   map[string]*period
+	closed []healthhistory.Result
+}
+
+var history = &historyRecorder{open: make(map[string]*period)}
+
+func periodKey(serviceID, instanceID, name string) string {
+	return serviceID + "/" + instanceID + "/" + name
+}
+
+// record extends the open period of a check or, if the status changed,
+// closes it and opens a new one
+func (h *historyRecorder) record(serviceID, instanceID, name, status string, now time.Time) {
+	h.Lock()
+	defer h.Unlock()
+
+	key := periodKey(serviceID, instanceID, name)
+	if p, ok := h.open[key]; ok {
+		if p.result.Status == status {
+			p.result.End = now.UTC()
+			p.dirty = true
+			return
+		}
+		p.result.End = now.UTC()
+		h.closed = append(h.closed, p.result)
+		delete(h.open, key)
+	}
+	result, err := healthhistory.NewResult(serviceID, instanceID, name, status, now, now)
+	if err != nil {
+		glog.Errorf("Could not create health check result for %s: %s", key, err)
+		return
+	}
+	h.open[key] = &period{result: *result, dirty: true}
+}
+
+// closeInstance closes the open periods of an instance that was shut down
+func (h *historyRecorder) closeInstance(serviceID, instanceID string, now time.Time) {
+	h.Lock()
+	defer h.Unlock()
+	for key, p := range h.open {
+		if p.result.ServiceID == serviceID && p.result.InstanceID == instanceID {
+			p.result.End = now.UTC()
+			h.closed = append(h.closed, p.result)
+			delete(h.open, key)
+		}
+	}
+}
+
+// apply records a report
+func (h *historyRecorder) apply(r historyReport) {
+	if r.name == "" {
+		h.closeInstance(r.serviceID, r.instanceID, r.at)
+	} else {
+		h.record(r.serviceID, r.instanceID, r.name, r.status, r.at)
+	}
+}
+
+// closeMissing closes the open periods of instances that are no longer
+// running; they end at their last report.
+func (h *historyRecorder) closeMissing(running func(serviceID, instanceID string) bool) {
+	h.Lock()
+	defer h.Unlock()
+	for key, p := range h.open {
+		if !running(p.result.ServiceID, p.result.InstanceID) {
+			h.closed = append(h.closed, p.result)
+			delete(h.open, key)
+		}
+	}
+}
+
+// pending returns the results that need to be written and marks them clean
+func (h *historyRecorder) pending() []healthhistory.Result {
+	h.Lock()
+	defer h.Unlock()
+	results := h.closed
+	h.closed = nil
+	for _, p := range h.open {
+		if p.dirty {
+			results = append(results, p.result)
+			p.dirty = false
+		}
+	}
+	return results
+}
+
+func (h *historyRecorder) flush(f *facade.Facade) {
+	ctx := datastore.Get()
+	results := h.pending()
+	for i := range results {
+		result := results[i]
+		if err := f.SaveHealthResult(ctx, &result); err != nil {
+			glog.Warningf("Could not save health check result %+v: %s", result, err)
+		}
+	}
+}
+
+// RecordHistory records the health check reports in the order they were
+// made, periodically writes the history of health check results to the
+// datastore and purges results past the retention period.
+func RecordHistory(shutdown <-chan interface{}, f *facade.Facade) {
+	reports := make(chan historyReport, historyBacklog)
+	lock.Lock()
+	historyReports = reports
+	lock.Unlock()
+
+	flush := time.Tick(flushInterval)
+	purge := time.Tick(purgeInterval)
+	for {
+		select {
+		case r := <-reports:
+			history.apply(r)
+		case <-shutdown:
+			lock.Lock()
+			historyReports = nil
+			lock.Unlock()
+			for len(reports) > 0 {
+				history.apply(<-reports)
+			}
+			history.flush(f)
+			return
+		case <-flush:
+			history.flush(f)
+		case <-purge:
+			before := time.Now().Add(-historyRetention)
+			if count, err := f.PurgeHealthResults(datastore.Get(), before); err != nil {
+				glog.Warningf("Could not purge health check history: %s", err)
+			} else if count > 0 {
+				glog.Infof("Purged %d health check results from before %s", count, before)
+			}
+		}
+	}
+}
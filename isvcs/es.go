@@ -138,19 +138,3 @@ func elasticsearchHealthCheck(port int) func() error {
 		return nil
 	}
 }
-
-func PurgeLogstashIndices(days int, gb int) error {
-	iservice := elasticsearch_logstash
-	port := iservice.Ports[0]
-	glog.Infof("Purging logstash entries older than %d days", days)
-	err := iservice.Exec([]string{
-		"/usr/local/bin/curator", "--port", fmt.Sprintf("%d", port),
-		"delete", "--older-than", fmt.Sprintf("%d", days)})
-	if err != nil {
-		return err
-	}
-	glog.Infof("Purging oldest logstash entries to limit disk usage to %d GB.", gb)
-	return iservice.Exec([]string{
-		"/usr/local/bin/curator", "--port", fmt.Sprintf("%d", port),
-		"delete", "--disk-space", fmt.Sprintf("%d", gb)})
-}
@@ -69,6 +69,8 @@ import (
 const (
 	dockerEndpoint     = "unix:///var/run/docker.sock"
 	circularBufferSize = 1000
+	defaultStopTimeout = 45 * time.Second        // time a service has to exit once signalled
+	stopTimeoutEnv     = "SERVICED_STOP_TIMEOUT" // seconds an instance may take to stop
 )
 
 // HostAgent is an instance of the control center Agent.
@@ -86,10 +88,11 @@ type HostAgent struct {
 	useTLS               bool // Whether the mux uses TLS
 	proxyRegistry        proxy.ProxyRegistry
 	zkClient             *coordclient.Client
-	dockerRegistry       string          // the docker registry to use
-	maxContainerAge      time.Duration   // maximum age for a stopped container before it is removed
-	virtualAddressSubnet string          // subnet for virtual addresses
-	servicedChain        *iptables.Chain // Assigned IP rule chain
+	dockerRegistry       string                    // the docker registry to use
+	maxContainerAge      time.Duration             // maximum age for a stopped container before it is removed
+	virtualAddressSubnet string                    // subnet for virtual addresses
+	servicedChain        *iptables.Chain           // Assigned IP rule chain
+	instanceHealth       *zkservice.InstanceHealth // health checks of the instances on this host
 }
 
 func getZkDSN(zookeepers []string) string {
@@ -139,6 +142,7 @@ func NewHostAgent(options AgentOptions) (*HostAgent, error) {
 	agent.mount = options.Mount
 	agent.fsType = "rsync"
 	agent.mux = options.Mux
+	agent.instanceHealth = zkservice.NewInstanceHealth()
 	agent.useTLS = options.UseTLS
 	agent.maxContainerAge = options.MaxContainerAge
 	agent.virtualAddressSubnet = options.VirtualAddressSubnet
@@ -259,7 +263,36 @@ func (a *HostAgent) StopService(state *servicestate.ServiceState) error {
 		return err
 	}
 
-	return ctr.Stop(45 * time.Second)
+	return ctr.Stop(containerStopTimeout(ctr.Config))
+}
+
+// serviceStopTimeout returns how long an instance of the service may take to
+// stop.  The controller drains the instance and runs its pre-stop command
+// before signalling the service, so allow for that on top of the time the
+// service takes to exit.
+func serviceStopTimeout(svc *service.Service) time.Duration {
+	timeout := defaultStopTimeout + time.Duration(svc.DrainTimeout)*time.Second
+	if svc.PreStop != "" {
+		timeout += service.PreStopTimeout
+	}
+	return timeout
+}
+
+// containerStopTimeout returns the stop timeout recorded in the environment
+// of a container when it was started, so that stopping an instance does not
+// depend on reaching the master.
+func containerStopTimeout(cfg *dockerclient.Config) time.Duration {
+	if cfg != nil {
+		for _, env := range cfg.Env {
+			if !strings.HasPrefix(env, stopTimeoutEnv+"=") {
+				continue
+			}
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(env, stopTimeoutEnv+"=")); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return defaultStopTimeout
 }
 
 // reapContainers purges old containers from docker.  It looks at the
@@ -714,6 +747,7 @@ func configureContainer(a *HostAgent, client *ControlClient,
 		fmt.Sprintf("SERVICED_NOREGISTRY=%s", os.Getenv("SERVICED_NOREGISTRY")),
 		fmt.Sprintf("SERVICED_SERVICE_IMAGE=%s", svc.ImageID),
 		fmt.Sprintf("SERVICED_MAX_RPC_CLIENTS=1"),
+		fmt.Sprintf("%s=%d", stopTimeoutEnv, int(serviceStopTimeout(svc)/time.Second)),
 		fmt.Sprintf("TZ=%s", os.Getenv("TZ")))
 
 	// add dns values to setup
@@ -841,6 +875,7 @@ func (a *HostAgent) Start(shutdown <-chan interface{}) {
 		// 2) its node is registered
 		// 3) receieves signal to shutdown or breaks
 		hsListener := zkservice.NewHostStateListener(a, a.hostID)
+		hsListener.SetInstanceHealth(a.instanceHealth)
 
 		glog.Infof("Host Agent successfully started")
 		zzk.Start(shutdown, conn, hsListener, virtualIPListener, actionListener)
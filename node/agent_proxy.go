@@ -21,10 +21,12 @@ package node
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/control-center/serviced/commons/docker"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/service"
@@ -142,6 +144,43 @@ func (a *HostAgent) GetTenantId(serviceId string, tenantId *string) error {
 	return client.GetTenantId(serviceId, tenantId)
 }
 
+// GetServiceSecrets returns the values of the secrets that a service uses,
+// which are put in its containers when they start.  Only the secrets of the
+// services that have an instance running on this host are returned.
+func (a *HostAgent) GetServiceSecrets(serviceID string, values *map[string]string) error {
+	ctrs, err := docker.Containers()
+	if err != nil {
+		glog.Errorf("Could not look up the containers of this host: %s", err)
+		return err
+	}
+	if !runsService(ctrs, serviceID) {
+		glog.Warningf("Refusing the secrets of service %s: it has no instance running on this host", serviceID)
+		return fmt.Errorf("service %s has no instance running on this host", serviceID)
+	}
+
+	client, err := NewControlClient(a.master)
+	if err != nil {
+		glog.Errorf("Could not start ControlPlane client %v", err)
+		return err
+	}
+	defer client.Close()
+	return client.GetServiceSecrets(serviceID, values)
+}
+
+// runsService returns true if one of the containers is a running instance of
+// the service, which the agent starts with "serviced service proxy SERVICEID"
+func runsService(ctrs []*docker.Container, serviceID string) bool {
+	for _, ctr := range ctrs {
+		if !ctr.State.Running || ctr.Config == nil {
+			continue
+		}
+		if cmd := ctr.Config.Cmd; len(cmd) > 3 && cmd[1] == "service" && cmd[2] == "proxy" && cmd[3] == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetProxySnapshotQuiece blocks until there is a snapshot request to the service
 func (a *HostAgent) GetProxySnapshotQuiece(serviceId string, snapshotId *string) error {
 	glog.Errorf("GetProxySnapshotQuiece() Unimplemented")
@@ -192,6 +231,9 @@ func (a *HostAgent) GetHealthCheck(req HealthCheckRequest, healthChecks *map[str
 
 // LogHealthCheck proxies RegisterHealthCheck.
 func (a *HostAgent) LogHealthCheck(result domain.HealthCheckResult, unused *int) error {
+	if a.instanceHealth != nil {
+		a.instanceHealth.Report(result.ServiceID, result.InstanceID, result.Name, result.Passed)
+	}
 	controlClient, err := NewControlClient(a.master)
 	if err != nil {
 		glog.Errorf("Could not start ControlPlane client %v", err)
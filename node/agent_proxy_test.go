@@ -20,7 +20,9 @@
 package node
 
 import (
+	"github.com/control-center/serviced/commons/docker"
 	"github.com/control-center/serviced/dao"
+	dockerclient "github.com/zenoss/go-dockerclient"
 
 	"testing"
 )
@@ -81,3 +83,33 @@ func TestAddControlPlaneEndpoints(t *testing.T) {
 		t.Fatalf(" mapping failed %+v expected %+v", endpoints["tcp:443"][0], controlplane_endpoint)
 	}
 }
+
+func TestRunsService(t *testing.T) {
+	instance := func(running bool, cmd ...string) *docker.Container {
+		return &docker.Container{
+			Container: &dockerclient.Container{
+				Config: &dockerclient.Config{Cmd: cmd},
+				State:  dockerclient.State{Running: running},
+			},
+		}
+	}
+	ctrs := []*docker.Container{
+		instance(true, "/serviced/serviced", "service", "proxy", "svc-a", "0", "run a"),
+		instance(false, "/serviced/serviced", "service", "proxy", "svc-b", "0", "run b"),
+		instance(true, "/bin/sh", "-c", "svc-c"),
+		{Container: &dockerclient.Container{State: dockerclient.State{Running: true}}},
+	}
+	for _, tc := range []struct {
+		serviceID string
+		expected  bool
+	}{
+		{"svc-a", true},
+		{"svc-b", false},
+		{"svc-c", false},
+		{"svc-d", false},
+	} {
+		if actual := runsService(ctrs, tc.serviceID); actual != tc.expected {
+			t.Errorf("expected %v for service %s, got %v", tc.expected, tc.serviceID, actual)
+		}
+	}
+}
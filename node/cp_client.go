@@ -19,14 +19,21 @@
 package node
 
 import (
+	"github.com/control-center/serviced/commons/logstash"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/healthhistory"
+	"github.com/control-center/serviced/domain/manifest"
+	"github.com/control-center/serviced/domain/secret"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/domain/user"
 	"github.com/control-center/serviced/rpc/rpcutils"
+	"github.com/control-center/serviced/stream"
 	"github.com/control-center/serviced/volume"
 )
 
@@ -92,6 +99,18 @@ func (s *ControlClient) UpdateService(service service.Service, unused *int) (err
 	return s.rpcClient.Call("ControlPlane.UpdateService", service, unused)
 }
 
+func (s *ControlClient) UpdateServiceAs(request dao.ServiceUpdateRequest, unused *int) (err error) {
+	return s.rpcClient.Call("ControlPlane.UpdateServiceAs", request, unused)
+}
+
+func (s *ControlClient) GetServiceConfigHistory(request dao.ServiceConfigHistoryRequest, revisions *[]serviceconfigfile.Revision) (err error) {
+	return s.rpcClient.Call("ControlPlane.GetServiceConfigHistory", request, revisions)
+}
+
+func (s *ControlClient) RevertServiceConfig(request dao.ServiceConfigRevertRequest, unused *int) (err error) {
+	return s.rpcClient.Call("ControlPlane.RevertServiceConfig", request, unused)
+}
+
 func (s *ControlClient) RemoveService(serviceId string, unused *int) (err error) {
 	return s.rpcClient.Call("ControlPlane.RemoveService", serviceId, unused)
 }
@@ -112,6 +131,38 @@ func (s *ControlClient) GetServiceStateLogs(request dao.ServiceStateRequest, log
 	return s.rpcClient.Call("ControlPlane.GetServiceStateLogs", request, logs)
 }
 
+func (s *ControlClient) SearchLogs(request dao.LogSearchRequest, result *logstash.Result) error {
+	return s.rpcClient.Call("ControlPlane.SearchLogs", request, result)
+}
+
+func (s *ControlClient) GetEvents(request dao.EventRequest, events *[]event.Event) error {
+	return s.rpcClient.Call("ControlPlane.GetEvents", request, events)
+}
+
+func (s *ControlClient) GetServiceUptime(request dao.ServiceUptimeRequest, report *healthhistory.Report) error {
+	return s.rpcClient.Call("ControlPlane.GetServiceUptime", request, report)
+}
+
+func (s *ControlClient) GetServiceEvents(request dao.ServiceEventRequest, events *[]stream.Event) error {
+	return s.rpcClient.Call("ControlPlane.GetServiceEvents", request, events)
+}
+
+func (s *ControlClient) GetLogRetentionStatus(unused int, status *logstash.RetentionStatus) error {
+	return s.rpcClient.Call("ControlPlane.GetLogRetentionStatus", unused, status)
+}
+
+func (s *ControlClient) SetLogRetentionPolicy(policy logstash.RetentionPolicy, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.SetLogRetentionPolicy", policy, unused)
+}
+
+func (s *ControlClient) EnforceLogRetention(maxSize int, report *logstash.RetentionReport) error {
+	return s.rpcClient.Call("ControlPlane.EnforceLogRetention", maxSize, report)
+}
+
+func (s *ControlClient) RestoreLogs(day string, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.RestoreLogs", day, unused)
+}
+
 func (s *ControlClient) GetRunningServicesForHost(hostId string, runningServices *[]dao.RunningService) (err error) {
 	return s.rpcClient.Call("ControlPlane.GetRunningServicesForHost", hostId, runningServices)
 }
@@ -168,6 +219,10 @@ func (s *ControlClient) DeployTemplate(request dao.ServiceTemplateDeploymentRequ
 	return s.rpcClient.Call("ControlPlane.DeployTemplate", request, tenantIDs)
 }
 
+func (s *ControlClient) UpgradeTemplate(request dao.TemplateUpgradeRequest, plan *servicetemplate.UpgradePlan) error {
+	return s.rpcClient.Call("ControlPlane.UpgradeTemplate", request, plan)
+}
+
 func (s *ControlClient) DeployTemplateStatus(request dao.ServiceTemplateDeploymentRequest, status *string) error {
 	return s.rpcClient.Call("ControlPlane.DeployTemplateStatus", request, status)
 }
@@ -180,6 +235,14 @@ func (s *ControlClient) GetServiceTemplates(unused int, serviceTemplates *map[st
 	return s.rpcClient.Call("ControlPlane.GetServiceTemplates", unused, serviceTemplates)
 }
 
+func (s *ControlClient) GetServiceTemplateHistory(templateID string, history *[]servicetemplate.TemplateHistory) error {
+	return s.rpcClient.Call("ControlPlane.GetServiceTemplateHistory", templateID, history)
+}
+
+func (s *ControlClient) RollbackServiceTemplate(request dao.TemplateRollbackRequest, hash *string) error {
+	return s.rpcClient.Call("ControlPlane.RollbackServiceTemplate", request, hash)
+}
+
 func (s *ControlClient) AddServiceTemplate(serviceTemplate servicetemplate.ServiceTemplate, templateId *string) error {
 	return s.rpcClient.Call("ControlPlane.AddServiceTemplate", serviceTemplate, templateId)
 }
@@ -252,6 +315,14 @@ func (s *ControlClient) AsyncRestore(backupFilePath string, unused *int) error {
 	return s.rpcClient.Call("ControlPlane.AsyncRestore", backupFilePath, unused)
 }
 
+func (s *ControlClient) ExportService(request dao.ServiceExportRequest, filename *string) error {
+	return s.rpcClient.Call("ControlPlane.ExportService", request, filename)
+}
+
+func (s *ControlClient) ImportService(request dao.ServiceImportRequest, tenantID *string) error {
+	return s.rpcClient.Call("ControlPlane.ImportService", request, tenantID)
+}
+
 func (s *ControlClient) BackupStatus(notUsed int, backupStatus *string) error {
 	return s.rpcClient.Call("ControlPlane.BackupStatus", notUsed, backupStatus)
 }
@@ -260,6 +331,14 @@ func (s *ControlClient) ImageLayerCount(imageUUID string, layers *int) error {
 	return s.rpcClient.Call("ControlPlane.ImageLayerCount", imageUUID, layers)
 }
 
+func (s *ControlClient) MaintainImages(request dao.ImageMaintenanceRequest, report *dao.ImageMaintenanceReport) error {
+	return s.rpcClient.Call("ControlPlane.MaintainImages", request, report)
+}
+
+func (s *ControlClient) GetImageInventory(serviceID string, report *dao.ImageInventoryReport) error {
+	return s.rpcClient.Call("ControlPlane.GetImageInventory", serviceID, report)
+}
+
 func (s *ControlClient) ValidateCredentials(user user.User, result *bool) error {
 	return s.rpcClient.Call("ControlPlane.ValidateCredentials", user, result)
 }
@@ -275,3 +354,27 @@ func (s *ControlClient) Action(req dao.AttachRequest, unused *int) error {
 func (s *ControlClient) LogHealthCheck(result domain.HealthCheckResult, unused *int) error {
 	return s.rpcClient.Call("ControlPlane.LogHealthCheck", result, unused)
 }
+
+func (s *ControlClient) SetSecret(request dao.SecretRequest, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.SetSecret", request, unused)
+}
+
+func (s *ControlClient) GetSecret(name string, value *string) error {
+	return s.rpcClient.Call("ControlPlane.GetSecret", name, value)
+}
+
+func (s *ControlClient) GetSecrets(unused int, secrets *[]secret.Info) error {
+	return s.rpcClient.Call("ControlPlane.GetSecrets", unused, secrets)
+}
+
+func (s *ControlClient) RemoveSecret(name string, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.RemoveSecret", name, unused)
+}
+
+func (s *ControlClient) GetServiceSecrets(serviceID string, values *map[string]string) error {
+	return s.rpcClient.Call("ControlPlane.GetServiceSecrets", serviceID, values)
+}
+
+func (s *ControlClient) ApplyManifest(request dao.ApplyRequest, plan *manifest.Plan) error {
+	return s.rpcClient.Call("ControlPlane.ApplyManifest", request, plan)
+}
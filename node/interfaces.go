@@ -120,6 +120,9 @@ type LoadBalancer interface {
 	// GetService retrieves a service object with templates evaluated.
 	GetService(serviceId string, response *service.Service) error
 
+	// GetServiceSecrets retrieves the values of the secrets that a service uses
+	GetServiceSecrets(serviceID string, values *map[string]string) error
+
 	// GetServiceInstance retrieves a service object with templates evaluated using a
 	// given instance ID.
 	GetServiceInstance(req ServiceInstanceRequest, response *service.Service) error
@@ -121,6 +121,12 @@ func (a *LBClient) GetZkInfo(zkInfo *ZkInfo) error {
 	return a.rpcClient.Call("ControlPlaneAgent.GetZkInfo", "na", zkInfo)
 }
 
+// GetServiceSecrets returns the values of the secrets that a service uses
+func (a *LBClient) GetServiceSecrets(serviceID string, values *map[string]string) error {
+	glog.V(4).Infof("ControlPlaneAgent.GetServiceSecrets(serviceID:%s)", serviceID)
+	return a.rpcClient.Call("ControlPlaneAgent.GetServiceSecrets", serviceID, values)
+}
+
 // GetServiceBindMounts returns the service
 func (a *LBClient) GetServiceBindMounts(serviceID string, bindmounts *map[string]string) error {
 	glog.V(4).Infof("ControlPlaneAgent.GetServiceBindMounts(serviceID:%s)", serviceID)
@@ -0,0 +1,60 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcutils
+
+import (
+	"fmt"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// NewTimedServerCodec records the number, duration and errors of the calls
+// served through codec, per method, in metrics.DefaultRegistry
+func NewTimedServerCodec(codec rpc.ServerCodec) rpc.ServerCodec {
+	return &timedServerCodec{ServerCodec: codec, started: make(map[uint64]time.Time)}
+}
+
+type timedServerCodec struct {
+	rpc.ServerCodec
+	sync.Mutex
+	started map[uint64]time.Time
+}
+
+func (c *timedServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	err := c.ServerCodec.ReadRequestHeader(r)
+	if err == nil {
+		c.Lock()
+		c.started[r.Seq] = time.Now()
+		c.Unlock()
+	}
+	return err
+}
+
+func (c *timedServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	c.Lock()
+	start, ok := c.started[r.Seq]
+	delete(c.started, r.Seq)
+	c.Unlock()
+
+	if ok {
+		metrics.GetOrRegisterTimer(fmt.Sprintf("rpc_server_duration_seconds{method=%q}", r.ServiceMethod), metrics.DefaultRegistry).UpdateSince(start)
+		if r.Error != "" {
+			metrics.GetOrRegisterCounter(fmt.Sprintf("rpc_server_errors_total{method=%q}", r.ServiceMethod), metrics.DefaultRegistry).Inc(1)
+		}
+	}
+	return c.ServerCodec.WriteResponse(r, body)
+}
@@ -41,8 +41,9 @@ type leader struct {
 // Lead is executed by the "leader" of the control center cluster to handle its management responsibilities of:
 //    services
 //    snapshots
+//    images
 //    virtual IPs
-func Lead(shutdown <-chan interface{}, conn coordclient.Connection, cpClient dao.ControlPlane, poolID string, snapshotTTL int) {
+func Lead(shutdown <-chan interface{}, conn coordclient.Connection, cpClient dao.ControlPlane, poolID string, snapshotTTL int, maxImageLayers int) {
 
 	// creates a listener for the host registry
 	if err := zkservice.InitHostRegistry(conn); err != nil {
@@ -63,6 +64,9 @@ func Lead(shutdown <-chan interface{}, conn coordclient.Connection, cpClient dao
 	// kicks off the snapshot cleaning goroutine
 	go cleanSnapshots(cpClient, snapshotTTL, shutdown)
 
+	// kicks off the image squashing and garbage collection goroutine
+	go maintainImages(cpClient, maxImageLayers, shutdown)
+
 	// starts all of the listeners
 	zzk.Start(shutdown, conn, serviceListener, hostRegistry, snapshotListener)
 }
@@ -134,6 +138,29 @@ func cleanSnapshots(cpClient dao.ControlPlane, snapshotTTL int, shutdown <-chan
 	}
 }
 
+func maintainImages(cpClient dao.ControlPlane, maxImageLayers int, shutdown <-chan interface{}) {
+	maintenanceInterval := time.Tick(24 * time.Hour)
+
+	// Every maintenance interval, squash tenant images and clear out unused
+	// images and registry tags.
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-maintenanceInterval:
+			glog.Info("Squashing images with more than SERVICED_MAX_IMAGE_LAYERS layers and removing unused images.")
+
+			var report dao.ImageMaintenanceReport
+			if err := cpClient.MaintainImages(dao.ImageMaintenanceRequest{MaxLayers: maxImageLayers}, &report); err != nil {
+				glog.Warningf("Failed image maintenance: %s", err)
+				break
+			}
+			glog.Infof("Image maintenance squashed %d images, removed %d images and %d registry tags; reclaimed %d bytes",
+				len(report.SquashedImages), len(report.RemovedImages), len(report.RemovedTags), report.ReclaimedBytes)
+		}
+	}
+}
+
 func (l *leader) TakeSnapshot(serviceID string) (string, error) {
 	var label string
 	err := l.cpClient.Snapshot(dao.SnapshotRequest{serviceID, ""}, &label)
@@ -29,7 +29,7 @@ import (
 	"path"
 )
 
-type leaderFunc func(<-chan interface{}, coordclient.Connection, dao.ControlPlane, string, int)
+type leaderFunc func(<-chan interface{}, coordclient.Connection, dao.ControlPlane, string, int, int)
 
 type scheduler struct {
 	sync.Mutex                     // only one process can stop and start the scheduler at a time
@@ -41,6 +41,7 @@ type scheduler struct {
 	started       bool             // is the loop running
 	zkleaderFunc  leaderFunc       // multiple implementations of leader function possible
 	snapshotTTL   int
+	maxLayers     int
 	facade        *facade.Facade
 	stopped       chan interface{}
 	registry      *registry.EndpointRegistry
@@ -50,7 +51,7 @@ type scheduler struct {
 }
 
 // NewScheduler creates a new scheduler master
-func NewScheduler(poolID string, instance_id string, storageServer *storage.Server, cpDao dao.ControlPlane, facade *facade.Facade, snapshotTTL int) (*scheduler, error) {
+func NewScheduler(poolID string, instance_id string, storageServer *storage.Server, cpDao dao.ControlPlane, facade *facade.Facade, snapshotTTL int, maxLayers int) (*scheduler, error) {
 	s := &scheduler{
 		cpDao:         cpDao,
 		poolID:        poolID,
@@ -60,6 +61,7 @@ func NewScheduler(poolID string, instance_id string, storageServer *storage.Serv
 		zkleaderFunc:  Lead, // random scheduler implementation
 		facade:        facade,
 		snapshotTTL:   snapshotTTL,
+		maxLayers:     maxLayers,
 		storageServer: storageServer,
 	}
 	return s, nil
@@ -270,7 +272,7 @@ func (s *scheduler) Spawn(shutdown <-chan interface{}, poolID string) {
 
 				go func() {
 					defer close(done)
-					s.zkleaderFunc(cancel, conn, s.cpDao, poolID, s.snapshotTTL)
+					s.zkleaderFunc(cancel, conn, s.cpDao, poolID, s.snapshotTTL, s.maxLayers)
 				}()
 			}
 		} else {
@@ -0,0 +1,65 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MemoryOomControl stores data from /sys/fs/cgroup/memory/memory.oom_control.
+type MemoryOomControl struct {
+	OomKillDisable int64
+	UnderOom       int64 // 1 while the cgroup is out of memory
+	OomKill        int64 // processes killed by the oom killer; only reported by newer kernels
+}
+
+// ReadMemoryOomControl fills out and returns a MemoryOomControl struct from the given file name.
+// if fileName is "", the default path of /sys/fs/cgroup/memory/memory.oom_control is used.
+func ReadMemoryOomControl(fileName string) (*MemoryOomControl, error) {
+	if fileName == "" {
+		fileName = "/sys/fs/cgroup/memory/memory.oom_control"
+	}
+	kv, err := parseSSKVint64(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", fileName, err)
+	}
+	return &MemoryOomControl{
+		OomKillDisable: kv["oom_kill_disable"],
+		UnderOom:       kv["under_oom"],
+		OomKill:        kv["oom_kill"],
+	}, nil
+}
+
+// ReadInt64 returns the value of a cgroup file holding a single integer,
+// like memory.failcnt or memory.limit_in_bytes.
+func ReadInt64(fileName string) (int64, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		// values past an int64 mean unlimited
+		if _, uerr := strconv.ParseUint(value, 10, 64); uerr != nil {
+			return 0, fmt.Errorf("error parsing %s: %v", fileName, err)
+		}
+		n = math.MaxInt64
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroup
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestReadMemoryOomControl(t *testing.T) {
+	file, err := ioutil.TempFile("", "oom_control")
+	if err != nil {
+		t.Fatalf("could not create file: %s", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("oom_kill_disable 0\nunder_oom 1\noom_kill 3\n")
+	file.Close()
+
+	oom, err := ReadMemoryOomControl(file.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *oom != (MemoryOomControl{OomKillDisable: 0, UnderOom: 1, OomKill: 3}) {
+		t.Errorf("unexpected values %+v", *oom)
+	}
+}
+
+func TestReadInt64(t *testing.T) {
+	file, err := ioutil.TempFile("", "limit_in_bytes")
+	if err != nil {
+		t.Fatalf("could not create file: %s", err)
+	}
+	defer os.Remove(file.Name())
+
+	for value, expected := range map[string]int64{
+		"1073741824\n":           1073741824,
+		"18446744073709551615\n": math.MaxInt64,
+	} {
+		ioutil.WriteFile(file.Name(), []byte(value), 0644)
+		if n, err := ReadInt64(file.Name()); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		} else if n != expected {
+			t.Errorf("expected %d, got %d", expected, n)
+		}
+	}
+
+	ioutil.WriteFile(file.Name(), []byte("max\n"), 0644)
+	if _, err := ReadInt64(file.Name()); err == nil {
+		t.Errorf("expected an error")
+	}
+}
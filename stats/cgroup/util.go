@@ -39,6 +39,15 @@ func GetCgroupDockerStatsFilePath(dockerID string, stat string) string {
     return statsFile
 }
 
+// GetCgroupDockerFilePath returns the path of a file in the cgroup of a
+// docker container, e.g. memory.failcnt of the memory subsystem.
+func GetCgroupDockerFilePath(dockerID string, subsystem string, file string) string {
+	if utils.Platform == utils.Debian {
+		return "/sys/fs/cgroup/" + subsystem + "/docker/" + dockerID + "/" + file
+	}
+	return "/sys/fs/cgroup/" + subsystem + "/system.slice/docker-" + dockerID + ".scope/" + file
+}
+
 // parseSSKVint64 parses a space-separated key-value pair file and returns a
 // key(string):value(int64) mapping.
 func parseSSKVint64(filename string) (map[string]int64, error) {
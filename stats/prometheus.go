@@ -0,0 +1,226 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/zenoss/glog"
+)
+
+// Metrics are exposed under this prefix
+const prometheusPrefix = "serviced_"
+
+var prometheusQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Exposition collects the metrics of go-metrics registries for the
+// prometheus text format.  A registry name may carry labels in prometheus
+// syntax, e.g. rpc_server_duration_seconds{method="ControlPlane.GetService"}.
+type Exposition struct {
+	families map[string]*promFamily
+}
+
+type promFamily struct {
+	kind    string // gauge, counter or summary
+	samples []promSample
+}
+
+type promSample struct {
+	suffix string // _sum and _count of a summary
+	labels string
+	value  float64
+}
+
+// NewExposition creates an empty exposition
+func NewExposition() *Exposition {
+	return &Exposition{make(map[string]*promFamily)}
+}
+
+// AddRegistry adds every metric of the registry, labelled with labels
+func (e *Exposition) AddRegistry(r metrics.Registry, labels map[string]string) {
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var common []string
+	for _, name := range names {
+		common = append(common, fmt.Sprintf("%s=\"%s\"", promSanitize(name), promEscape(labels[name])))
+	}
+
+	r.Each(func(name string, i interface{}) {
+		family, own := name, ""
+		if n := strings.Index(name, "{"); n >= 0 && strings.HasSuffix(name, "}") {
+			family, own = name[:n], name[n+1:len(name)-1]
+		}
+		all := common
+		if own != "" {
+			all = append([]string{own}, common...)
+		}
+		e.add(family, strings.Join(all, ","), i)
+	})
+}
+
+func (e *Exposition) add(name, labels string, i interface{}) {
+	switch m := i.(type) {
+	case metrics.Gauge:
+		e.sample(promName(name, ""), "gauge", "", labels, float64(m.Value()))
+	case metrics.GaugeFloat64:
+		e.sample(promName(name, ""), "gauge", "", labels, m.Value())
+	case metrics.Counter:
+		e.sample(promName(name, ""), "counter", "", labels, float64(m.Count()))
+	case metrics.Meter:
+		e.sample(promName(name, ""), "counter", "", labels, float64(m.Count()))
+	case metrics.Timer:
+		// timers record nanoseconds
+		t := m.Snapshot()
+		name = promName(name, "_seconds")
+		for j, q := range t.Percentiles(prometheusQuantiles) {
+			e.sample(name, "summary", "", joinLabels(labels, fmt.Sprintf("quantile=\"%g\"", prometheusQuantiles[j])), q/float64(time.Second))
+		}
+		e.sample(name, "summary", "_sum", labels, float64(t.Sum())/float64(time.Second))
+		e.sample(name, "summary", "_count", labels, float64(t.Count()))
+	case metrics.Histogram:
+		h := m.Snapshot()
+		name = promName(name, "")
+		for j, q := range h.Percentiles(prometheusQuantiles) {
+			e.sample(name, "summary", "", joinLabels(labels, fmt.Sprintf("quantile=\"%g\"", prometheusQuantiles[j])), q)
+		}
+		e.sample(name, "summary", "_sum", labels, float64(h.Sum()))
+		e.sample(name, "summary", "_count", labels, float64(h.Count()))
+	}
+}
+
+func (e *Exposition) sample(name, kind, suffix, labels string, value float64) {
+	family, ok := e.families[name]
+	if !ok {
+		family = &promFamily{kind: kind}
+		e.families[name] = family
+	} else if family.kind != kind {
+		glog.V(2).Infof("Skipping %s metric %s, it is already a %s", kind, name, family.kind)
+		return
+	}
+	family.samples = append(family.samples, promSample{suffix, labels, value})
+}
+
+// Write writes the metrics in the prometheus text format (version 0.0.4)
+func (e *Exposition) Write(w io.Writer) error {
+	var names []string
+	for name := range e.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := e.families[name]
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, family.kind); err != nil {
+			return err
+		}
+		for _, s := range family.samples {
+			labels := ""
+			if s.labels != "" {
+				labels = "{" + s.labels + "}"
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%s %s\n", name, s.suffix, labels, promValue(s.value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// promName converts a metric name like load.avg1m to serviced_load_avg1m
+func promName(name, suffix string) string {
+	name = promSanitize(name)
+	if !strings.HasPrefix(name, prometheusPrefix) {
+		name = prometheusPrefix + name
+	}
+	if !strings.HasSuffix(name, suffix) {
+		name += suffix
+	}
+	return name
+}
+
+// promSanitize lowercases name and replaces the characters prometheus does
+// not allow in names with underscores
+func promSanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		} else if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, name)
+}
+
+var promEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func promEscape(value string) string {
+	return promEscaper.Replace(value)
+}
+
+func promValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func joinLabels(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+// PrometheusHandler serves /metrics in the prometheus text format: the
+// control plane internals recorded in metrics.DefaultRegistry and, once a
+// reporter is set, the host and container stats of this host.
+type PrometheusHandler struct {
+	sync.Mutex
+	reporter *StatsReporter
+}
+
+// SetReporter adds the registries of the stats reporter to the exposition
+func (h *PrometheusHandler) SetReporter(sr *StatsReporter) {
+	h.Lock()
+	defer h.Unlock()
+	h.reporter = sr
+}
+
+func (h *PrometheusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+
+	e := NewExposition()
+	e.AddRegistry(metrics.DefaultRegistry, nil)
+	h.Lock()
+	if h.reporter != nil {
+		h.reporter.expose(e)
+	}
+	h.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := e.Write(w); err != nil {
+		glog.V(2).Infof("Could not write metrics: %s", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestExposition(t *testing.T) {
+	host := metrics.NewRegistry()
+	metrics.GetOrRegisterGaugeFloat64("load.avg1m", host).Update(0.5)
+	metrics.GetOrRegisterGauge("Serviced.OpenFileDescriptors", host).Update(12)
+
+	container1 := metrics.NewRegistry()
+	metrics.GetOrRegisterGauge("cgroup.memory.totalrss", container1).Update(1024)
+	container2 := metrics.NewRegistry()
+	metrics.GetOrRegisterGauge("cgroup.memory.totalrss", container2).Update(2048)
+
+	internal := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter(`scheduler_decisions_total{pool="default",decision="started"}`, internal).Inc(3)
+	timer := metrics.GetOrRegisterTimer(`rpc_server_duration_seconds{method="ControlPlane.GetService"}`, internal)
+	timer.Update(2 * time.Second)
+	timer.Update(4 * time.Second)
+
+	e := NewExposition()
+	e.AddRegistry(internal, nil)
+	e.AddRegistry(host, map[string]string{"host_id": "abc"})
+	e.AddRegistry(container1, map[string]string{"host_id": "abc", "service_id": "s1", "instance_id": "0"})
+	e.AddRegistry(container2, map[string]string{"host_id": "abc", "service_id": "s\"2", "instance_id": "1"})
+
+	var buf bytes.Buffer
+	if err := e.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `# TYPE serviced_cgroup_memory_totalrss gauge
+serviced_cgroup_memory_totalrss{host_id="abc",instance_id="0",service_id="s1"} 1024
+serviced_cgroup_memory_totalrss{host_id="abc",instance_id="1",service_id="s\"2"} 2048
+# TYPE serviced_load_avg1m gauge
+serviced_load_avg1m{host_id="abc"} 0.5
+# TYPE serviced_openfiledescriptors gauge
+serviced_openfiledescriptors{host_id="abc"} 12
+# TYPE serviced_rpc_server_duration_seconds summary
+serviced_rpc_server_duration_seconds{method="ControlPlane.GetService",quantile="0.5"} 3
+serviced_rpc_server_duration_seconds{method="ControlPlane.GetService",quantile="0.9"} 4
+serviced_rpc_server_duration_seconds{method="ControlPlane.GetService",quantile="0.99"} 4
+serviced_rpc_server_duration_seconds_sum{method="ControlPlane.GetService"} 6
+serviced_rpc_server_duration_seconds_count{method="ControlPlane.GetService"} 2
+# TYPE serviced_scheduler_decisions_total counter
+serviced_scheduler_decisions_total{pool="default",decision="started"} 3
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	metrics.GetOrRegisterCounter("test_requests_total", metrics.DefaultRegistry).Inc(1)
+	defer metrics.DefaultRegistry.Unregister("test_requests_total")
+
+	h := &PrometheusHandler{}
+	r, _ := http.NewRequest("GET", "http://localhost/metrics", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "serviced_test_requests_total 1\n") {
+		t.Errorf("missing counter in\n%s", w.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "http://localhost/other", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 404 {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
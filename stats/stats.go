@@ -60,6 +60,7 @@ type registryKey struct {
 }
 
 // NewStatsReporter creates a new StatsReporter and kicks off the reporting goroutine.
+// Stats are not posted when destination is empty.
 func NewStatsReporter(destination string, interval time.Duration, conn coordclient.Connection) (*StatsReporter, error) {
 	hostID, err := utils.HostID()
 	if err != nil {
@@ -150,6 +151,10 @@ func (sr *StatsReporter) report(d time.Duration) {
 		case t := <-tc:
 			glog.V(1).Info("Reporting container stats at:", t)
 			sr.updateStats()
+			if sr.destination == "" {
+				// only exposed to prometheus
+				continue
+			}
 			stats := sr.gatherStats(t)
 			err := Post(sr.destination, stats)
 			if err != nil {
@@ -224,6 +229,32 @@ func (sr *StatsReporter) updateHostStats() {
 	}
 }
 
+// updateMemoryPressure records how close a container is to its memory limit
+// and how often it hit it.
+func (sr *StatsReporter) updateMemoryPressure(dockerID string, containerRegistry metrics.Registry) {
+	if usage, err := cgroup.ReadInt64(cgroup.GetCgroupDockerFilePath(dockerID, cgroup.Memory, "memory.usage_in_bytes")); err != nil {
+		glog.V(4).Infof("Couldn't read memory usage: %s", err)
+	} else {
+		metrics.GetOrRegisterGauge("cgroup.memory.usage", containerRegistry).Update(usage)
+	}
+	if limit, err := cgroup.ReadInt64(cgroup.GetCgroupDockerFilePath(dockerID, cgroup.Memory, "memory.limit_in_bytes")); err != nil {
+		glog.V(4).Infof("Couldn't read memory limit: %s", err)
+	} else {
+		metrics.GetOrRegisterGauge("cgroup.memory.limit", containerRegistry).Update(limit)
+	}
+	if failcnt, err := cgroup.ReadInt64(cgroup.GetCgroupDockerFilePath(dockerID, cgroup.Memory, "memory.failcnt")); err != nil {
+		glog.V(4).Infof("Couldn't read memory failcnt: %s", err)
+	} else {
+		metrics.GetOrRegisterGauge("cgroup.memory.failcnt", containerRegistry).Update(failcnt)
+	}
+	if oom, err := cgroup.ReadMemoryOomControl(cgroup.GetCgroupDockerFilePath(dockerID, cgroup.Memory, "memory.oom_control")); err != nil {
+		glog.V(4).Infof("Couldn't read MemoryOomControl: %s", err)
+	} else {
+		metrics.GetOrRegisterGauge("cgroup.memory.underoom", containerRegistry).Update(oom.UnderOom)
+		metrics.GetOrRegisterGauge("cgroup.memory.oomkill", containerRegistry).Update(oom.OomKill)
+	}
+}
+
 // Updates the default registry.
 func (sr *StatsReporter) updateStats() {
 	// Stats for host.
@@ -251,6 +282,7 @@ func (sr *StatsReporter) updateStats() {
 				metrics.GetOrRegisterGauge("cgroup.memory.totalrss", containerRegistry).Update(memoryStat.TotalRss)
 				metrics.GetOrRegisterGauge("cgroup.memory.cache", containerRegistry).Update(memoryStat.Cache)
 			}
+			sr.updateMemoryPressure(rs.DockerID, containerRegistry)
 		} else {
 			glog.V(4).Infof("Skipping stats update for %s (%s), no container ID exists yet", rs.Name, rs.ServiceID)
 		}
@@ -292,6 +324,20 @@ func (sr *StatsReporter) gatherStats(t time.Time) []Sample {
 	return stats
 }
 
+// expose adds the host and container registries to a prometheus exposition.
+func (sr *StatsReporter) expose(e *Exposition) {
+	sr.Lock()
+	defer sr.Unlock()
+	e.AddRegistry(sr.hostRegistry, map[string]string{"host_id": sr.hostID})
+	for key, registry := range sr.containerRegistries {
+		e.AddRegistry(registry, map[string]string{
+			"host_id":     sr.hostID,
+			"service_id":  key.serviceID,
+			"instance_id": strconv.Itoa(key.instanceID),
+		})
+	}
+}
+
 // Send the list of stats to the TSDB.
 func Post(destination string, stats []Sample) error {
 	payload := map[string][]Sample{"metrics": stats}
@@ -0,0 +1,201 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream broadcasts changes to services, their instances and hosts to
+// the clients following them.
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	ServiceAdded        = "service.added"        // a service was deployed or added
+	ServiceUpdated      = "service.updated"      // the definition of a service was edited
+	ServiceRemoved      = "service.removed"      // a service was removed
+	ServiceDesiredState = "service.desiredstate" // a service was started, stopped or paused
+	InstanceScheduled   = "instance.scheduled"   // an instance was scheduled on a host
+	InstanceStarted     = "instance.started"     // the container of an instance started
+	InstanceStopped     = "instance.stopped"     // an instance was removed from its host
+	HealthChanged       = "health.changed"       // a health check of an instance passed or failed
+	HostJoined          = "host.joined"          // a host agent registered with the master
+	HostLeft            = "host.left"            // a host agent went away
+)
+
+// DefaultBufferSize is the number of events kept for clients that fall behind
+const DefaultBufferSize = 1000
+
+// Event describes a change
+type Event struct {
+	ID         uint64 // increases with every event published by the broker, also across restarts
+	Type       string
+	Timestamp  time.Time
+	TenantID   string
+	ServiceID  string
+	InstanceID string
+	HostID     string
+	Summary    string
+}
+
+// Filter selects the events a client follows. Empty fields match any event.
+type Filter struct {
+	TenantID  string
+	ServiceID string
+	Types     []string
+}
+
+// Match returns true if e passes the filter
+func (f Filter) Match(e Event) bool {
+	if f.TenantID != "" && f.TenantID != e.TenantID {
+		return false
+	}
+	if f.ServiceID != "" && f.ServiceID != e.ServiceID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Broker numbers published events and keeps the most recent ones for the
+// clients that poll for them.  Numbering starts at the time the broker was
+// created, in microseconds, so that the ids of a restarted broker are higher
+// than those handed out before the restart.
+type Broker struct {
+	mu       sync.Mutex
+	size     int
+	events   []Event       // the most recent events, oldest first
+	lastID   uint64        // the id of the last published event
+	notify   chan struct{} // closed when an event is published
+	tenantOf func(serviceID string) string
+	tenants  map[string]string
+}
+
+// NewBroker keeps the last size events
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:    size,
+		lastID:  uint64(time.Now().UnixNano() / int64(time.Microsecond)),
+		notify:  make(chan struct{}),
+		tenants: make(map[string]string),
+	}
+}
+
+// SetTenantResolver looks up the tenant of events that only name a service
+func (b *Broker) SetTenantResolver(tenantOf func(serviceID string) string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tenantOf = tenantOf
+}
+
+// tenantID returns the tenant of a service, which is cached because it never
+// changes
+func (b *Broker) tenantID(serviceID string) string {
+	b.mu.Lock()
+	tenantID, ok := b.tenants[serviceID]
+	tenantOf := b.tenantOf
+	b.mu.Unlock()
+	if ok || tenantOf == nil {
+		return tenantID
+	}
+	if tenantID = tenantOf(serviceID); tenantID != "" {
+		b.mu.Lock()
+		b.tenants[serviceID] = tenantID
+		b.mu.Unlock()
+	}
+	return tenantID
+}
+
+// Publish numbers e and wakes up the clients waiting for events
+func (b *Broker) Publish(e Event) Event {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.TenantID == "" && e.ServiceID != "" {
+		e.TenantID = b.tenantID(e.ServiceID)
+	} else if e.TenantID != "" && e.ServiceID != "" {
+		b.mu.Lock()
+		b.tenants[e.ServiceID] = e.TenantID
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e.ID = b.lastID
+	b.events = append(b.events, e)
+	if len(b.events) > b.size {
+		b.events = append([]Event{}, b.events[len(b.events)-b.size:]...)
+	}
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return e
+}
+
+// LastID returns the id of the last published event
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// since returns the buffered events after the given id that match the filter,
+// and a channel that is closed when the next event is published.  An id the
+// broker has not handed out yet returns every buffered event.
+func (b *Broker) since(after uint64, filter Filter) ([]Event, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if after > b.lastID {
+		after = 0
+	}
+	var events []Event
+	for _, e := range b.events {
+		if e.ID > after && filter.Match(e) {
+			events = append(events, e)
+		}
+	}
+	return events, b.notify
+}
+
+// Wait returns the events after the given id that match the filter. If there
+// are none, it waits for at most timeout for one to be published.
+func (b *Broker) Wait(after uint64, filter Filter, timeout time.Duration) []Event {
+	expired := time.After(timeout)
+	for {
+		events, notify := b.since(after, filter)
+		if len(events) > 0 {
+			return events
+		}
+		select {
+		case <-notify:
+		case <-expired:
+			return nil
+		}
+	}
+}
+
+// Default is the broker of the master
+var Default = NewBroker(DefaultBufferSize)
+
+// Publish publishes an event with the default broker
+func Publish(e Event) {
+	Default.Publish(e)
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	e := Event{Type: InstanceStarted, TenantID: "tenant", ServiceID: "svc"}
+	for _, tc := range []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{}, true},
+		{Filter{TenantID: "tenant"}, true},
+		{Filter{TenantID: "other"}, false},
+		{Filter{ServiceID: "svc"}, true},
+		{Filter{ServiceID: "other"}, false},
+		{Filter{Types: []string{InstanceStopped, InstanceStarted}}, true},
+		{Filter{Types: []string{InstanceStopped}}, false},
+		{Filter{TenantID: "tenant", ServiceID: "svc", Types: []string{InstanceStarted}}, true},
+	} {
+		if actual := tc.filter.Match(e); actual != tc.expected {
+			t.Errorf("filter %+v: expected %t, got %t", tc.filter, tc.expected, actual)
+		}
+	}
+}
+
+func TestBrokerBuffer(t *testing.T) {
+	b := NewBroker(3)
+	first := b.LastID()
+	for i := 0; i < 5; i++ {
+		b.Publish(Event{Type: ServiceUpdated})
+	}
+	if b.LastID() != first+5 {
+		t.Fatalf("expected last id %d, got %d", first+5, b.LastID())
+	}
+	events := b.Wait(0, Filter{}, time.Millisecond)
+	if len(events) != 3 || events[0].ID != first+3 || events[2].ID != first+5 {
+		t.Errorf("expected events 3 to 5, got %+v", events)
+	}
+	if events := b.Wait(first+4, Filter{}, time.Millisecond); len(events) != 1 || events[0].ID != first+5 {
+		t.Errorf("expected event 5, got %+v", events)
+	}
+	if events := b.Wait(first+5, Filter{}, time.Millisecond); len(events) != 0 {
+		t.Errorf("expected no events, got %+v", events)
+	}
+}
+
+func TestBrokerRestart(t *testing.T) {
+	before := NewBroker(DefaultBufferSize)
+	for i := 0; i < 5; i++ {
+		before.Publish(Event{Type: ServiceUpdated})
+	}
+	time.Sleep(time.Millisecond)
+
+	b := NewBroker(DefaultBufferSize)
+	e := b.Publish(Event{Type: ServiceAdded})
+	if e.ID <= before.LastID() {
+		t.Fatalf("expected id after %d, got %d", before.LastID(), e.ID)
+	}
+	if events := b.Wait(before.LastID(), Filter{}, time.Millisecond); len(events) != 1 || events[0].ID != e.ID {
+		t.Errorf("expected the event published after the restart, got %+v", events)
+	}
+	if events := b.Wait(e.ID+100, Filter{}, time.Millisecond); len(events) != 1 || events[0].ID != e.ID {
+		t.Errorf("expected an unknown id to return every event, got %+v", events)
+	}
+}
+
+func TestBrokerWait(t *testing.T) {
+	b := NewBroker(DefaultBufferSize)
+	first := b.LastID()
+	b.SetTenantResolver(func(serviceID string) string { return "tenant-" + serviceID })
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Publish(Event{Type: InstanceStarted, ServiceID: "a"})
+		b.Publish(Event{Type: InstanceStarted, ServiceID: "b"})
+	}()
+	events := b.Wait(0, Filter{TenantID: "tenant-b"}, time.Second)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %+v", events)
+	}
+	if e := events[0]; e.ID != first+2 || e.ServiceID != "b" || e.TenantID != "tenant-b" || e.Timestamp.IsZero() {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/zenoss/glog"
+
+	"strconv"
+	"time"
+)
+
+// DefaultInterval is how often thresholds are evaluated, and the interval
+// the metrics are averaged over
+const DefaultInterval = time.Minute
+
+// how thresholds are applied (ThresholdConfig.AppliedTo)
+const (
+	appliedToEverything      = 0
+	appliedToServices        = 1
+	appliedToRunningServices = 2
+)
+
+// Facade is the part of the facade the engine needs
+type Facade interface {
+	GetServices(ctx datastore.Context, request dao.EntityRequest) ([]service.Service, error)
+	GetServiceStates(ctx datastore.Context, serviceID string) ([]servicestate.ServiceState, error)
+	GetResourcePools(ctx datastore.Context) ([]pool.ResourcePool, error)
+	FindHostsInPool(ctx datastore.Context, poolID string) ([]host.Host, error)
+	AddEvent(ctx datastore.Context, evt *event.Event) error
+}
+
+// Engine periodically evaluates the thresholds of the monitoring profiles of
+// services and pools, recording an event when a threshold starts or stops
+// being violated
+type Engine struct {
+	facade   Facade
+	ctx      datastore.Context
+	client   *QueryClient
+	interval time.Duration
+
+	// thresholds applied to every pool, in addition to those of its profile
+	poolThresholds []domain.ThresholdConfig
+
+	// the violation event of every threshold currently violated
+	active map[string]*event.Event
+}
+
+// NewEngine creates an engine that queries metrics from queryURL
+func NewEngine(facade Facade, ctx datastore.Context, queryURL string, poolThresholds []domain.ThresholdConfig) *Engine {
+	return &Engine{
+		facade:         facade,
+		ctx:            ctx,
+		client:         NewQueryClient(queryURL),
+		interval:       DefaultInterval,
+		poolThresholds: poolThresholds,
+		active:         make(map[string]*event.Event),
+	}
+}
+
+// target is a set of thresholds applied to metrics with the same tags
+type target struct {
+	sourceType string
+	sourceID   string
+	instanceID string
+	tags       map[string][]string
+	thresholds []domain.ThresholdConfig
+}
+
+// Run evaluates the thresholds every interval until shutdown is closed
+func (e *Engine) Run(shutdown <-chan interface{}) {
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(e.interval):
+			e.Evaluate(time.Now())
+		}
+	}
+}
+
+// Evaluate evaluates every threshold once
+func (e *Engine) Evaluate(now time.Time) {
+	targets, err := e.targets()
+	if err != nil {
+		glog.Errorf("Could not look up thresholds: %s", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		for _, config := range t.thresholds {
+			for _, key := range e.evaluate(t, config, now) {
+				seen[key] = true
+			}
+		}
+	}
+
+	// thresholds that were removed, or whose service or pool was removed,
+	// are no longer violated
+	for key, active := range e.active {
+		if !seen[key] {
+			delete(e.active, key)
+			glog.V(1).Infof("Dropping violation of threshold %s on %s: threshold no longer evaluated", active.ThresholdID, active.SourceID)
+		}
+	}
+}
+
+// evaluate checks a threshold on each of its metrics, returning the keys of
+// the metrics evaluated.  The keys are returned even if the metrics could not
+// be queried, so that the violations stay active until the metrics show they
+// have cleared.
+func (e *Engine) evaluate(t target, config domain.ThresholdConfig, now time.Time) []string {
+	var keys []string
+	for _, metric := range config.DataPoints {
+		keys = append(keys, t.sourceID+"/"+t.instanceID+"/"+config.ID+"/"+metric)
+	}
+
+	window, err := Window(config, e.interval)
+	if err != nil {
+		glog.Warningf("Skipping threshold %s of %s %s: %s", config.ID, t.sourceType, t.sourceID, err)
+		return keys
+	}
+	series, err := e.client.Query(config.DataPoints, t.tags, window, e.interval)
+	if err != nil {
+		glog.Warningf("Could not query metrics for threshold %s of %s %s: %s", config.ID, t.sourceType, t.sourceID, err)
+		return keys
+	}
+
+	for i, metric := range config.DataPoints {
+		key := keys[i]
+		result, err := Evaluate(config, series[metric], now)
+		if err != nil {
+			glog.Warningf("Could not evaluate threshold %s of %s %s: %s", config.ID, t.sourceType, t.sourceID, err)
+			continue
+		} else if result == nil {
+			continue
+		}
+
+		active := e.active[key]
+		if result.Violated && active == nil {
+			if evt := e.record(t, config, metric, result, false); evt != nil {
+				e.active[key] = evt
+			}
+		} else if !result.Violated && active != nil {
+			result.Summary = config.Name + ": cleared"
+			if evt := e.record(t, config, metric, result, true); evt != nil {
+				delete(e.active, key)
+			}
+		}
+	}
+	return keys
+}
+
+// record saves an event, returning nil if it could not be saved
+func (e *Engine) record(t target, config domain.ThresholdConfig, metric string, result *Result, cleared bool) *event.Event {
+	evt, err := event.New(t.sourceType, t.sourceID, config.ID)
+	if err != nil {
+		glog.Errorf("Could not create event: %s", err)
+		return nil
+	}
+	evt.ThresholdName = config.Name
+	evt.ThresholdType = config.Type
+	evt.InstanceID = t.instanceID
+	evt.MetricSource = config.MetricSource
+	evt.Metric = metric
+	evt.Value = result.Value
+	evt.Predicted = result.Predicted
+	evt.Cleared = cleared
+	evt.Summary = result.Summary
+	evt.Tags = config.EventTags
+
+	if err := e.facade.AddEvent(e.ctx, evt); err != nil {
+		glog.Errorf("Could not record event for threshold %s of %s %s: %s", config.ID, t.sourceType, t.sourceID, err)
+		return nil
+	}
+	glog.Infof("Threshold event on %s %s: %s", t.sourceType, t.sourceID, evt.Summary)
+	return evt
+}
+
+// targets returns the thresholds of every service, service instance and pool
+func (e *Engine) targets() ([]target, error) {
+	var targets []target
+
+	svcs, err := e.facade.GetServices(e.ctx, dao.ServiceRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range svcs {
+		var serviceThresholds, instanceThresholds []domain.ThresholdConfig
+		for _, config := range svc.MonitoringProfile.ThresholdConfigs {
+			if config.AppliedTo == appliedToRunningServices {
+				instanceThresholds = append(instanceThresholds, config)
+			} else {
+				serviceThresholds = append(serviceThresholds, config)
+			}
+		}
+
+		if len(serviceThresholds) > 0 {
+			targets = append(targets, target{
+				sourceType: event.SourceService,
+				sourceID:   svc.ID,
+				tags:       map[string][]string{"controlplane_service_id": []string{svc.ID}},
+				thresholds: serviceThresholds,
+			})
+		}
+		if len(instanceThresholds) > 0 {
+			states, err := e.facade.GetServiceStates(e.ctx, svc.ID)
+			if err != nil {
+				glog.Warningf("Could not get instances of service %s: %s", svc.ID, err)
+				continue
+			}
+			for _, state := range states {
+				instanceID := strconv.Itoa(state.InstanceID)
+				targets = append(targets, target{
+					sourceType: event.SourceService,
+					sourceID:   svc.ID,
+					instanceID: instanceID,
+					tags: map[string][]string{
+						"controlplane_service_id":  []string{svc.ID},
+						"controlplane_instance_id": []string{instanceID},
+					},
+					thresholds: instanceThresholds,
+				})
+			}
+		}
+	}
+
+	pools, err := e.facade.GetResourcePools(e.ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pools {
+		thresholds := poolThresholds(p.MonitoringProfile.ThresholdConfigs, e.poolThresholds)
+		if len(thresholds) == 0 {
+			continue
+		}
+		hosts, err := e.facade.FindHostsInPool(e.ctx, p.ID)
+		if err != nil {
+			glog.Warningf("Could not get hosts of pool %s: %s", p.ID, err)
+			continue
+		} else if len(hosts) == 0 {
+			continue
+		}
+		hostIDs := make([]string, len(hosts))
+		for i, h := range hosts {
+			hostIDs[i] = h.ID
+		}
+		targets = append(targets, target{
+			sourceType: event.SourcePool,
+			sourceID:   p.ID,
+			tags:       map[string][]string{"controlplane_host_id": hostIDs},
+			thresholds: thresholds,
+		})
+	}
+	return targets, nil
+}
+
+// poolThresholds merges the thresholds of a pool's profile with the default
+// pool thresholds; the pool's own thresholds win
+func poolThresholds(own, defaults []domain.ThresholdConfig) []domain.ThresholdConfig {
+	thresholds := append([]domain.ThresholdConfig{}, own...)
+	for _, config := range defaults {
+		found := false
+		for _, o := range own {
+			found = found || o.ID == config.ID
+		}
+		if !found {
+			thresholds = append(thresholds, config)
+		}
+	}
+	return thresholds
+}
@@ -0,0 +1,187 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicestate"
+
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testFacade struct {
+	services []service.Service
+	pools    []pool.ResourcePool
+	events   []*event.Event
+}
+
+func (f *testFacade) GetServices(ctx datastore.Context, request dao.EntityRequest) ([]service.Service, error) {
+	return f.services, nil
+}
+
+func (f *testFacade) GetServiceStates(ctx datastore.Context, serviceID string) ([]servicestate.ServiceState, error) {
+	return []servicestate.ServiceState{{ServiceID: serviceID, InstanceID: 0}}, nil
+}
+
+func (f *testFacade) GetResourcePools(ctx datastore.Context) ([]pool.ResourcePool, error) {
+	return f.pools, nil
+}
+
+func (f *testFacade) FindHostsInPool(ctx datastore.Context, poolID string) ([]host.Host, error) {
+	return []host.Host{{ID: "host1", PoolID: poolID}}, nil
+}
+
+func (f *testFacade) AddEvent(ctx datastore.Context, evt *event.Event) error {
+	f.events = append(f.events, evt)
+	return nil
+}
+
+// metricServer answers every query with the current value of each metric, or
+// with an error while values has no metrics
+func metricServer(values map[string]float64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(values) == 0 {
+			http.Error(w, "metric store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var request queryRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		var results []string
+		for _, m := range request.Metrics {
+			results = append(results, fmt.Sprintf(`{"metric":%q,"datapoints":[{"timestamp":%d,"value":%g}]}`, m.Metric, now.Unix(), values[m.Metric]))
+		}
+		fmt.Fprintf(w, `{"results":[%s]}`, strings.Join(results, ","))
+	}))
+}
+
+func TestEngine_Transitions(t *testing.T) {
+	values := map[string]float64{"swap.free": 100}
+	server := metricServer(values)
+	defer server.Close()
+
+	threshold := domain.ThresholdConfig{
+		ID:           "swap.empty",
+		Name:         "Swap empty",
+		Type:         MinMax,
+		MetricSource: "memory",
+		DataPoints:   []string{"swap.free"},
+		Threshold:    domain.MinMaxThreshold{Min: int64p(1)},
+		EventTags:    map[string]interface{}{"Severity": 1},
+	}
+	facade := &testFacade{
+		services: []service.Service{{ID: "svc1"}},
+		pools:    []pool.ResourcePool{{ID: "default"}},
+	}
+	engine := NewEngine(facade, nil, server.URL, []domain.ThresholdConfig{threshold})
+
+	engine.Evaluate(now)
+	if len(facade.events) != 0 {
+		t.Fatalf("expected no events, got %d", len(facade.events))
+	}
+
+	values["swap.free"] = 0
+	engine.Evaluate(now)
+	engine.Evaluate(now)
+	if len(facade.events) != 1 {
+		t.Fatalf("expected one violation event, got %d", len(facade.events))
+	}
+	evt := facade.events[0]
+	if evt.SourceType != event.SourcePool || evt.SourceID != "default" || evt.ThresholdID != "swap.empty" || evt.Cleared || evt.Tags["Severity"] != 1 {
+		t.Errorf("unexpected violation event %+v", evt)
+	}
+
+	values["swap.free"] = 50
+	engine.Evaluate(now)
+	if len(facade.events) != 2 || !facade.events[1].Cleared {
+		t.Fatalf("expected a cleared event, got %+v", facade.events)
+	}
+}
+
+func TestEngine_QueryError(t *testing.T) {
+	values := map[string]float64{"cpu": 90}
+	server := metricServer(values)
+	defer server.Close()
+
+	svc := service.Service{ID: "svc1"}
+	svc.MonitoringProfile.ThresholdConfigs = []domain.ThresholdConfig{
+		{ID: "cpu.high", Type: MinMax, DataPoints: []string{"cpu"}, Threshold: domain.MinMaxThreshold{Max: int64p(80)}},
+	}
+	facade := &testFacade{services: []service.Service{svc}}
+	engine := NewEngine(facade, nil, server.URL, nil)
+	engine.Evaluate(now)
+	if len(facade.events) != 1 || facade.events[0].Cleared {
+		t.Fatalf("expected one violation event, got %+v", facade.events)
+	}
+
+	// the violation stays active while the metrics cannot be queried
+	delete(values, "cpu")
+	engine.Evaluate(now)
+	if len(engine.active) != 1 {
+		t.Fatalf("expected the violation to stay active, got %v", engine.active)
+	}
+
+	// and is neither raised again nor cleared until the metrics recover
+	values["cpu"] = 90
+	engine.Evaluate(now)
+	if len(facade.events) != 1 {
+		t.Fatalf("expected no new events, got %+v", facade.events)
+	}
+	values["cpu"] = 50
+	engine.Evaluate(now)
+	if len(facade.events) != 2 || !facade.events[1].Cleared {
+		t.Fatalf("expected a cleared event, got %+v", facade.events)
+	}
+}
+
+func TestEngine_ServiceInstances(t *testing.T) {
+	server := metricServer(map[string]float64{"cpu": 90})
+	defer server.Close()
+
+	svc := service.Service{ID: "svc1"}
+	svc.MonitoringProfile.ThresholdConfigs = []domain.ThresholdConfig{
+		{ID: "cpu.high", Type: MinMax, DataPoints: []string{"cpu"}, Threshold: domain.MinMaxThreshold{Max: int64p(80)}},
+		{ID: "cpu.instance", Type: MinMax, AppliedTo: appliedToRunningServices, DataPoints: []string{"cpu"}, Threshold: domain.MinMaxThreshold{Max: int64p(80)}},
+	}
+	facade := &testFacade{services: []service.Service{svc}}
+	engine := NewEngine(facade, nil, server.URL, nil)
+	engine.Evaluate(now)
+
+	if len(facade.events) != 2 {
+		t.Fatalf("expected two events, got %d", len(facade.events))
+	}
+	if facade.events[0].ThresholdID != "cpu.high" || facade.events[0].InstanceID != "" {
+		t.Errorf("unexpected service event %+v", facade.events[0])
+	}
+	if facade.events[1].ThresholdID != "cpu.instance" || facade.events[1].InstanceID != "0" {
+		t.Errorf("unexpected instance event %+v", facade.events[1])
+	}
+
+	// a removed threshold is forgotten
+	facade.services = nil
+	engine.Evaluate(now)
+	if len(engine.active) != 0 {
+		t.Errorf("expected no active violations, got %v", engine.active)
+	}
+}
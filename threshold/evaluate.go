@@ -0,0 +1,177 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package threshold evaluates the thresholds of monitoring profiles against
+// the metric store and records an event whenever one is violated or cleared.
+package threshold
+
+import (
+	"github.com/control-center/serviced/domain"
+
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Threshold types
+const (
+	MinMax      = "MinMax"
+	Duration    = "Duration"
+	ValueChange = "ValueChange"
+	HoltWinters = "HoltWinters"
+)
+
+// Point is a value of a metric at a time (seconds since the epoch)
+type Point struct {
+	Timestamp int64
+	Value     float64
+}
+
+// Result is the outcome of evaluating a threshold against a metric
+type Result struct {
+	Violated  bool
+	Value     float64  // latest value of the metric
+	Predicted *float64 // value expected by a HoltWinters threshold
+	Summary   string
+}
+
+// Decode returns the threshold data of a config as a MinMaxThreshold,
+// DurationThreshold or HoltWintersThreshold.  ValueChange thresholds have no
+// data.  Configs read from json hold a map rather than the typed threshold.
+func Decode(config domain.ThresholdConfig) (interface{}, error) {
+	var threshold interface{}
+	switch config.Type {
+	case MinMax:
+		threshold = &domain.MinMaxThreshold{}
+	case Duration:
+		threshold = &domain.DurationThreshold{}
+	case HoltWinters:
+		threshold = &domain.HoltWintersThreshold{}
+	case ValueChange:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown threshold type %q", config.Type)
+	}
+
+	data, err := json.Marshal(config.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, threshold); err != nil {
+		return nil, fmt.Errorf("invalid %s threshold %s: %s", config.Type, config.ID, err)
+	}
+	return threshold, nil
+}
+
+// Window returns how far back the metric store must be queried to evaluate a
+// threshold, given the interval between points
+func Window(config domain.ThresholdConfig, interval time.Duration) (time.Duration, error) {
+	threshold, err := Decode(config)
+	if err != nil {
+		return 0, err
+	}
+	switch t := threshold.(type) {
+	case *domain.DurationThreshold:
+		return t.TimePeriod, nil
+	case *domain.HoltWintersThreshold:
+		return time.Duration(t.Rows) * interval, nil
+	}
+	return 10 * interval, nil
+}
+
+// Evaluate checks a series of points, oldest first, against a threshold.  It
+// returns nil if there aren't enough points to tell.
+func Evaluate(config domain.ThresholdConfig, points []Point, now time.Time) (*Result, error) {
+	threshold, err := Decode(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+	last := points[len(points)-1].Value
+
+	switch t := threshold.(type) {
+	case *domain.MinMaxThreshold:
+		result := &Result{Value: last}
+		if reason := outside(last, t.Min, t.Max); reason != "" {
+			result.Violated = true
+			result.Summary = fmt.Sprintf("%s: value %g %s", config.Name, last, reason)
+		}
+		return result, nil
+
+	case *domain.DurationThreshold:
+		since := now.Add(-t.TimePeriod).Unix()
+		total, violations := 0, 0
+		for _, point := range points {
+			if point.Timestamp < since {
+				continue
+			}
+			total++
+			if outside(point.Value, t.Min, t.Max) != "" {
+				violations++
+			}
+		}
+		if total == 0 {
+			return nil, nil
+		}
+		result := &Result{Value: last}
+		if violations > 0 && violations*100 >= t.Percentage*total {
+			result.Violated = true
+			result.Summary = fmt.Sprintf("%s: %d of %d values in the last %s out of range", config.Name, violations, total, t.TimePeriod)
+		}
+		return result, nil
+
+	case *domain.HoltWintersThreshold:
+		values := make([]float64, len(points))
+		for i, point := range points {
+			values[i] = point.Value
+		}
+		if t.Rows > 0 && int64(len(values)) > t.Rows {
+			values = values[int64(len(values))-t.Rows:]
+		}
+		predicted, deviation, ok := predictLast(*t, values)
+		if !ok {
+			return nil, nil
+		}
+		result := &Result{Value: last, Predicted: &predicted}
+		if diff := last - predicted; diff > deviationScale*deviation || -diff > deviationScale*deviation {
+			result.Violated = true
+			result.Summary = fmt.Sprintf("%s: value %g differs from the predicted %g", config.Name, last, predicted)
+		}
+		return result, nil
+	}
+
+	// ValueChange
+	if len(points) < 2 {
+		return nil, nil
+	}
+	result := &Result{Value: last}
+	if previous := points[len(points)-2].Value; previous != last {
+		result.Violated = true
+		result.Summary = fmt.Sprintf("%s: value changed from %g to %g", config.Name, previous, last)
+	}
+	return result, nil
+}
+
+// outside describes how a value is outside the min and max, or returns an
+// empty string if it isn't
+func outside(value float64, min, max *int64) string {
+	if min != nil && value < float64(*min) {
+		return fmt.Sprintf("is below the minimum %d", *min)
+	}
+	if max != nil && value > float64(*max) {
+		return fmt.Sprintf("is above the maximum %d", *max)
+	}
+	return ""
+}
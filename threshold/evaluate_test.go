@@ -0,0 +1,203 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"github.com/control-center/serviced/domain"
+
+	"math"
+	"testing"
+	"time"
+)
+
+var now = time.Unix(1420070400, 0)
+
+func series(values ...float64) []Point {
+	points := make([]Point, len(values))
+	for i, value := range values {
+		points[i] = Point{Timestamp: now.Unix() - int64(60*(len(values)-1-i)), Value: value}
+	}
+	return points
+}
+
+func int64p(i int64) *int64 {
+	return &i
+}
+
+func TestDecode(t *testing.T) {
+	// thresholds read from json hold maps
+	config := domain.ThresholdConfig{
+		ID:        "t",
+		Type:      Duration,
+		Threshold: map[string]interface{}{"Max": 10, "TimePeriod": 300, "Percentage": 50},
+	}
+	threshold, err := Decode(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	duration, ok := threshold.(*domain.DurationThreshold)
+	if !ok {
+		t.Fatalf("expected a duration threshold, got %T", threshold)
+	}
+	if duration.Max == nil || *duration.Max != 10 || duration.TimePeriod != 5*time.Minute || duration.Percentage != 50 {
+		t.Errorf("unexpected threshold %+v", duration)
+	}
+
+	config = domain.ThresholdConfig{ID: "t", Type: MinMax, Threshold: domain.MinMaxThreshold{Min: int64p(0)}}
+	if threshold, err := Decode(config); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if minmax := threshold.(*domain.MinMaxThreshold); minmax.Min == nil || *minmax.Min != 0 || minmax.Max != nil {
+		t.Errorf("unexpected threshold %+v", minmax)
+	}
+
+	if _, err := Decode(domain.ThresholdConfig{Type: "Bogus"}); err == nil {
+		t.Errorf("expected error decoding unknown threshold type")
+	}
+}
+
+func TestEvaluate_MinMax(t *testing.T) {
+	config := domain.ThresholdConfig{Name: "swap", Type: MinMax, Threshold: domain.MinMaxThreshold{Min: int64p(0), Max: int64p(10)}}
+	for _, tc := range []struct {
+		points   []Point
+		violated bool
+	}{
+		{series(5), false},
+		{series(20, 10), false},
+		{series(5, 11), true},
+		{series(5, -1), true},
+	} {
+		result, err := Evaluate(config, tc.points, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result.Violated != tc.violated {
+			t.Errorf("expected violated=%t for %v, got %+v", tc.violated, tc.points, result)
+		}
+	}
+
+	if result, err := Evaluate(config, nil, now); err != nil || result != nil {
+		t.Errorf("expected no result without points, got %+v, %v", result, err)
+	}
+}
+
+func TestEvaluate_Duration(t *testing.T) {
+	config := domain.ThresholdConfig{
+		Type:      Duration,
+		Threshold: domain.DurationThreshold{Max: int64p(10), TimePeriod: 3 * time.Minute, Percentage: 50},
+	}
+	// only the last 4 points are within the time period
+	for _, tc := range []struct {
+		points   []Point
+		violated bool
+	}{
+		{series(20, 20, 20, 1, 1, 1, 20), false},
+		{series(1, 1, 1, 20, 1, 1, 20), true},
+		{series(1, 1, 20, 20, 20, 1), true},
+	} {
+		result, err := Evaluate(config, tc.points, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result.Violated != tc.violated {
+			t.Errorf("expected violated=%t for %v, got %+v", tc.violated, tc.points, result)
+		}
+	}
+
+	// any violation at 0 percent
+	config.Threshold = domain.DurationThreshold{Max: int64p(10), TimePeriod: 3 * time.Minute}
+	if result, _ := Evaluate(config, series(1, 1, 1, 20), now); !result.Violated {
+		t.Errorf("expected a single violation to trigger at 0 percent")
+	}
+}
+
+func TestEvaluate_ValueChange(t *testing.T) {
+	config := domain.ThresholdConfig{Type: ValueChange}
+	if result, _ := Evaluate(config, series(3), now); result != nil {
+		t.Errorf("expected no result for a single point, got %+v", result)
+	}
+	if result, _ := Evaluate(config, series(3, 3), now); result.Violated {
+		t.Errorf("expected no violation for unchanged value")
+	}
+	if result, _ := Evaluate(config, series(3, 4), now); !result.Violated {
+		t.Errorf("expected violation for changed value")
+	}
+}
+
+func seasonal(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = 100 + 10*math.Sin(2*math.Pi*float64(i)/12) + float64(i)*0.5
+	}
+	return values
+}
+
+func TestEvaluate_HoltWinters(t *testing.T) {
+	config := domain.ThresholdConfig{
+		Type:      HoltWinters,
+		Threshold: domain.HoltWintersThreshold{Alpha: 0.5, Beta: 0.1, Rows: 60, Season: 12},
+	}
+
+	values := seasonal(60)
+	result, err := Evaluate(config, series(values...), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Violated || result.Predicted == nil {
+		t.Errorf("expected a prediction without violation for a seasonal series, got %+v", result)
+	} else if math.Abs(*result.Predicted-values[59]) > 1 {
+		t.Errorf("expected prediction close to %g, got %g", values[59], *result.Predicted)
+	}
+
+	values[59] += 50
+	if result, _ := Evaluate(config, series(values...), now); !result.Violated {
+		t.Errorf("expected violation for a spike, got %+v", result)
+	}
+
+	// not enough points to train the model
+	if result, _ := Evaluate(config, series(seasonal(30)...), now); result != nil {
+		t.Errorf("expected no result with too few points, got %+v", result)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	threshold := domain.HoltWintersThreshold{Alpha: 0.5, Beta: 0.1, Season: 12}
+	values := seasonal(72)
+	forecast, err := Forecast(threshold, values[:60], 12)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, predicted := range forecast {
+		if math.Abs(predicted-values[60+i]) > 2 {
+			t.Errorf("forecast %d: expected about %g, got %g", i, values[60+i], predicted)
+		}
+	}
+
+	if _, err := Forecast(threshold, values[:20], 1); err == nil {
+		t.Errorf("expected error forecasting from less than two seasons")
+	}
+}
+
+func TestParseQueryResponse(t *testing.T) {
+	data := []byte(`{"results":[{"metric":"cpu","datapoints":[{"timestamp":1,"value":2.5},{"timestamp":2,"value":3}]},{"metric":"mem","datapoints":[]}]}`)
+	series, err := parseQueryResponse(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(series["cpu"]) != 2 || series["cpu"][1] != (Point{2, 3}) {
+		t.Errorf("unexpected series %v", series)
+	}
+	if len(series["mem"]) != 0 {
+		t.Errorf("expected no points for mem, got %v", series["mem"])
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"github.com/control-center/serviced/domain"
+
+	"fmt"
+	"math"
+)
+
+// a value is a violation when it is further than this many deviations from
+// the prediction, as with the FAILURES rra of rrdtool
+const deviationScale = 2
+
+// holtWinters is an additive Holt-Winters (triple exponential smoothing)
+// model.  Like rrdtool, the seasonal coefficients and deviations adapt at
+// the same rate (gamma) as the baseline (alpha).
+type holtWinters struct {
+	alpha, beta, gamma float64
+	level, trend       float64
+	seasonal           []float64
+	deviation          []float64
+	n                  int // number of values fitted
+}
+
+// fit trains a model on values, oldest first.  A season of less than two
+// values fits a model without seasonality.  At least two seasons of values
+// are needed.
+func fit(t domain.HoltWintersThreshold, values []float64) (*holtWinters, error) {
+	if t.Alpha < 0 || t.Alpha > 1 || t.Beta < 0 || t.Beta > 1 {
+		return nil, fmt.Errorf("alpha and beta must be between 0 and 1")
+	}
+	season := int(t.Season)
+	if season < 1 {
+		season = 1
+	}
+	if len(values) < 2*season {
+		return nil, fmt.Errorf("need %d values, have %d", 2*season, len(values))
+	}
+
+	hw := &holtWinters{
+		alpha:     t.Alpha,
+		beta:      t.Beta,
+		gamma:     t.Alpha,
+		seasonal:  make([]float64, season),
+		deviation: make([]float64, season),
+	}
+
+	// the first season sets the baseline and seasonal coefficients, the
+	// second the trend
+	first, second := mean(values[:season]), mean(values[season:2*season])
+	hw.trend = (second - first) / float64(season)
+	if season > 1 {
+		for i := 0; i < season; i++ {
+			// remove the trend within the season from the coefficients
+			hw.seasonal[i] = values[i] - (first + (float64(i)-float64(season-1)/2)*hw.trend)
+		}
+	}
+	// the baseline as of the last value of the first season
+	hw.level = first + float64(season-1)/2*hw.trend
+	hw.n = season
+
+	for _, value := range values[season:] {
+		hw.update(value)
+	}
+	return hw, nil
+}
+
+func (hw *holtWinters) update(value float64) {
+	i := hw.n % len(hw.seasonal)
+	predicted := hw.level + hw.trend + hw.seasonal[i]
+	hw.deviation[i] = hw.gamma*math.Abs(value-predicted) + (1-hw.gamma)*hw.deviation[i]
+
+	level := hw.alpha*(value-hw.seasonal[i]) + (1-hw.alpha)*(hw.level+hw.trend)
+	hw.trend = hw.beta*(level-hw.level) + (1-hw.beta)*hw.trend
+	if len(hw.seasonal) > 1 {
+		hw.seasonal[i] = hw.gamma*(value-level) + (1-hw.gamma)*hw.seasonal[i]
+	}
+	hw.level = level
+	hw.n++
+}
+
+// predict returns the value expected h steps after the last fitted value,
+// and its expected deviation
+func (hw *holtWinters) predict(h int) (float64, float64) {
+	i := (hw.n + h - 1) % len(hw.seasonal)
+	return hw.level + float64(h)*hw.trend + hw.seasonal[i], hw.deviation[i]
+}
+
+// predictLast fits a model on all values but the last, and returns the
+// value expected in place of the last one.  ok is false when there are too
+// few values to train the model for a season past its initialization.
+func predictLast(t domain.HoltWintersThreshold, values []float64) (predicted, deviation float64, ok bool) {
+	season := int(t.Season)
+	if season < 1 {
+		season = 1
+	}
+	if len(values) < 3*season+1 {
+		return 0, 0, false
+	}
+	hw, err := fit(t, values[:len(values)-1])
+	if err != nil {
+		return 0, 0, false
+	}
+	predicted, deviation = hw.predict(1)
+	return predicted, deviation, true
+}
+
+// Forecast predicts the next n values of a series, oldest first, under a
+// HoltWinters threshold
+func Forecast(t domain.HoltWintersThreshold, values []float64, n int) ([]float64, error) {
+	hw, err := fit(t, values)
+	if err != nil {
+		return nil, err
+	}
+	forecast := make([]float64, n)
+	for h := range forecast {
+		forecast[h], _ = hw.predict(h + 1)
+	}
+	return forecast, nil
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
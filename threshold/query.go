@@ -0,0 +1,104 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package threshold
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// QueryClient reads metrics from the central query service
+type QueryClient struct {
+	url string
+}
+
+// NewQueryClient returns a client for the performance query api at url,
+// e.g. http://127.0.0.1:8888/api/performance/query
+func NewQueryClient(url string) *QueryClient {
+	return &QueryClient{url: url}
+}
+
+type queryMetric struct {
+	Metric     string              `json:"metric"`
+	Aggregator string              `json:"aggregator"`
+	Tags       map[string][]string `json:"tags"`
+}
+
+type queryRequest struct {
+	Start      string        `json:"start"`
+	End        string        `json:"end"`
+	Downsample string        `json:"downsample"`
+	ReturnSet  string        `json:"returnset"`
+	Metrics    []queryMetric `json:"metrics"`
+}
+
+type queryResponse struct {
+	Results []struct {
+		Metric     string `json:"metric"`
+		Datapoints []struct {
+			Timestamp int64   `json:"timestamp"`
+			Value     float64 `json:"value"`
+		} `json:"datapoints"`
+	} `json:"results"`
+}
+
+// Query returns the points of each metric with the tags over the window,
+// averaged over each interval, oldest first
+func (c *QueryClient) Query(metrics []string, tags map[string][]string, window, interval time.Duration) (map[string][]Point, error) {
+	request := queryRequest{
+		Start:      fmt.Sprintf("%ds-ago", int64(window/time.Second)),
+		End:        "0s-ago",
+		Downsample: fmt.Sprintf("%ds-avg", int64(interval/time.Second)),
+		ReturnSet:  "EXACT",
+	}
+	for _, metric := range metrics {
+		request.Metrics = append(request.Metrics, queryMetric{Metric: metric, Aggregator: "avg", Tags: tags})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metric query returned %s: %s", resp.Status, respBody)
+	}
+	return parseQueryResponse(respBody)
+}
+
+func parseQueryResponse(data []byte) (map[string][]Point, error) {
+	var response queryResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("could not parse metric query response: %s", err)
+	}
+	series := make(map[string][]Point)
+	for _, result := range response.Results {
+		for _, dp := range result.Datapoints {
+			series[result.Metric] = append(series[result.Metric], Point{Timestamp: dp.Timestamp, Value: dp.Value})
+		}
+	}
+	return series, nil
+}
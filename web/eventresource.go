@@ -0,0 +1,63 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/node"
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+)
+
+// restGetEvents lists threshold events, newest first. Query parameters are
+// source (service or pool id), since (RFC3339 timestamp or duration ago) and
+// limit. Response is []event.Event
+func restGetEvents(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	query := r.URL.Query()
+	request := dao.EventRequest{SourceID: query.Get("source")}
+
+	if since := query.Get("since"); since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			request.Since = t
+		} else if d, err := time.ParseDuration(since); err == nil {
+			request.Since = time.Now().UTC().Add(-d)
+		} else {
+			restBadRequest(w, fmt.Errorf("invalid since: %s", since))
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if request.Limit, err = strconv.Atoi(limit); err != nil || request.Limit < 0 {
+			restBadRequest(w, fmt.Errorf("invalid limit: %s", limit))
+			return
+		}
+	}
+
+	var events []event.Event
+	if err := client.GetEvents(request, &events); err != nil {
+		glog.Errorf("Could not get events: %v", err)
+		restServerError(w, err)
+		return
+	}
+	if events == nil {
+		events = []event.Event{}
+	}
+	w.WriteJson(&events)
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/healthhistory"
+	"github.com/control-center/serviced/node"
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+)
+
+// restGetServiceHealthHistory reports the availability of a service from its
+// health check history. Query parameters are window (comma separated, e.g.
+// 1h,7d; may repeat) and history (true to include the results). Response is
+// healthhistory.Report
+func restGetServiceHealthHistory(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	request := dao.ServiceUptimeRequest{ServiceID: serviceID, History: query.Get("history") == "true"}
+	for _, windows := range query["window"] {
+		for _, window := range strings.Split(windows, ",") {
+			if window = strings.TrimSpace(window); window == "" {
+				continue
+			}
+			if _, err := healthhistory.ParseWindow(window); err != nil {
+				restBadRequest(w, err)
+				return
+			}
+			request.Windows = append(request.Windows, window)
+		}
+	}
+
+	var report healthhistory.Report
+	if err := client.GetServiceUptime(request, &report); err != nil {
+		glog.Errorf("Could not get health history for service %s: %v", serviceID, err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&report)
+}
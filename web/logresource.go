@@ -0,0 +1,80 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"github.com/control-center/serviced/commons/logstash"
+	"github.com/control-center/serviced/node"
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+
+	"net/url"
+)
+
+// restGetLogRetention gets the log retention policy and the state of the
+// logstash indices. Response is logstash.RetentionStatus
+func restGetLogRetention(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	var status logstash.RetentionStatus
+	if err := client.GetLogRetentionStatus(0, &status); err != nil {
+		glog.Errorf("Could not get log retention status: %v", err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&status)
+}
+
+// restSetLogRetention replaces the log retention policy. Request input is
+// logstash.RetentionPolicy
+func restSetLogRetention(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	var policy logstash.RetentionPolicy
+	if err := r.DecodeJsonPayload(&policy); err != nil {
+		glog.V(1).Infof("Could not decode log retention policy: %v", err)
+		restBadRequest(w, err)
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		restBadRequest(w, err)
+		return
+	}
+
+	var unused int
+	if err := client.SetLogRetentionPolicy(policy, &unused); err != nil {
+		glog.Errorf("Could not update log retention policy: %v", err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&simpleResponse{"Updated log retention policy", logRetentionLinks()})
+}
+
+// restRestoreLogs loads an archived day (yyyy.mm.dd) back into logstash
+func restRestoreLogs(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	day, err := url.QueryUnescape(r.PathParam("day"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+	if day, err = logstash.NormalizeYYYYMMDD(day); err != nil {
+		restBadRequest(w, err)
+		return
+	}
+
+	var unused int
+	if err := client.RestoreLogs(day, &unused); err != nil {
+		glog.Errorf("Could not restore logs for %s: %v", day, err)
+		restServerError(w, err)
+		return
+	}
+	glog.V(0).Info("Restored logs for ", day)
+	w.WriteJson(&simpleResponse{"Restored logs for " + day, logRetentionLinks()})
+}
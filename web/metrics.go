@@ -527,3 +527,9 @@ func newRSSConfigGraph(tags map[string][]string, totalMemory uint64) domain.Grap
 		Description: "Bytes used",
 	}
 }
+
+// PoolThresholds returns the thresholds of the host and pool monitoring
+// profile, which are evaluated against every resource pool
+func PoolThresholds() []domain.ThresholdConfig {
+	return hostPoolProfile.ThresholdConfigs
+}
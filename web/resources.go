@@ -26,8 +26,10 @@ import (
 	"github.com/zenoss/glog"
 	"github.com/zenoss/go-json-rest"
 
+	"github.com/control-center/serviced/commons/logstash"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/isvcs"
 	"github.com/control-center/serviced/node"
@@ -62,7 +64,7 @@ func restAddAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node.Co
 
 	var b bytes.Buffer
 	_, err = io.Copy(&b, file)
-	template, err := servicetemplate.FromJSON(b.String())
+	template, err := servicetemplate.FromYAML(b.String())
 	if err != nil {
 		restServerError(w, err)
 		return
@@ -97,6 +99,45 @@ func restRemoveAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node
 	w.WriteJson(&simpleResponse{templateID, servicesLinks()})
 }
 
+func restGetAppTemplateHistory(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	templateID, err := url.QueryUnescape(r.PathParam("templateId"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+
+	var history []servicetemplate.TemplateHistory
+	if err := client.GetServiceTemplateHistory(templateID, &history); err != nil {
+		glog.Errorf("Could not get the history of template %s: %s", templateID, err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&history)
+}
+
+func restRollbackAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	templateID, err := url.QueryUnescape(r.PathParam("templateId"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+	var payload dao.TemplateRollbackRequest
+	if err := r.DecodeJsonPayload(&payload); err != nil {
+		glog.V(1).Info("Could not decode rollback payload: ", err)
+		restBadRequest(w, err)
+		return
+	}
+	payload.TemplateID = templateID
+
+	var hash string
+	if err := client.RollbackServiceTemplate(payload, &hash); err != nil {
+		glog.Errorf("Could not roll back template %s to %s: %s", templateID, payload.Hash, err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&simpleResponse{hash, servicesLinks()})
+}
+
 func restDeployAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
 	var payload dao.ServiceTemplateDeploymentRequest
 	err := r.DecodeJsonPayload(&payload)
@@ -575,7 +616,12 @@ func restUpdateService(w *rest.ResponseWriter, r *rest.Request, client *node.Con
 		restBadRequest(w, err)
 		return
 	}
-	err = client.UpdateService(payload, &unused)
+	request := dao.ServiceUpdateRequest{
+		Service: payload,
+		Author:  sessionUser(r),
+		Restart: r.URL.Query().Get("restart") == "true",
+	}
+	err = client.UpdateServiceAs(request, &unused)
 	if err != nil {
 		glog.Errorf("Unable to update service %s: %v", serviceID, err)
 		restServerError(w, err)
@@ -585,6 +631,28 @@ func restUpdateService(w *rest.ResponseWriter, r *rest.Request, client *node.Con
 	w.WriteJson(&simpleResponse{"Updated service", serviceLinks(serviceID)})
 }
 
+func restGetServiceConfigHistory(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+	request := dao.ServiceConfigHistoryRequest{
+		ServiceID: serviceID,
+		Filename:  r.URL.Query().Get("filename"),
+	}
+	var revisions []serviceconfigfile.Revision
+	if err := client.GetServiceConfigHistory(request, &revisions); err != nil {
+		glog.Errorf("Could not get the config history of service %s: %v", serviceID, err)
+		restServerError(w, err)
+		return
+	}
+	if revisions == nil {
+		revisions = []serviceconfigfile.Revision{}
+	}
+	w.WriteJson(&revisions)
+}
+
 func restRemoveService(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
 	var unused int
 	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
@@ -618,6 +686,64 @@ func restGetServiceLogs(w *rest.ResponseWriter, r *rest.Request, client *node.Co
 	w.WriteJson(&simpleResponse{logs, serviceLinks(serviceID)})
 }
 
+// restSearchServiceLogs searches the logstash indices for messages from a
+// service and its children.  Supported query parameters are q (lucene query),
+// from and to (RFC3339 timestamps), field (name:value, repeatable), offset
+// and limit.
+func restSearchServiceLogs(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+
+	request := dao.LogSearchRequest{
+		ServiceID: serviceID,
+		Query:     r.URL.Query().Get("q"),
+		Fields:    make(map[string]string),
+	}
+	if from := r.URL.Query().Get("from"); from != "" {
+		if request.From, err = time.Parse(time.RFC3339, from); err != nil {
+			restBadRequest(w, err)
+			return
+		}
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		if request.To, err = time.Parse(time.RFC3339, to); err != nil {
+			restBadRequest(w, err)
+			return
+		}
+	}
+	for _, field := range r.URL.Query()["field"] {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			restBadRequest(w, fmt.Errorf("field filter must be name:value, got %q", field))
+			return
+		}
+		request.Fields[parts[0]] = parts[1]
+	}
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		if request.Offset, err = strconv.Atoi(offset); err != nil {
+			restBadRequest(w, err)
+			return
+		}
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if request.Limit, err = strconv.Atoi(limit); err != nil {
+			restBadRequest(w, err)
+			return
+		}
+	}
+
+	var result logstash.Result
+	if err := client.SearchLogs(request, &result); err != nil {
+		glog.Errorf("Unexpected error searching service logs: %v", err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&result)
+}
+
 // restRestartService restarts the service with the given id and all of its children
 func restRestartService(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
 	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
@@ -716,6 +842,22 @@ func restSnapshotService(w *rest.ResponseWriter, r *rest.Request, client *node.C
 	w.WriteJson(&simpleResponse{label, serviceLinks(serviceID)})
 }
 
+func restGetImageInventory(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w, err)
+		return
+	}
+
+	var report dao.ImageInventoryReport
+	if err := client.GetImageInventory(serviceID, &report); err != nil {
+		glog.Errorf("Could not get image inventory for service %s: %v", serviceID, err)
+		restServerError(w, err)
+		return
+	}
+	w.WriteJson(&report)
+}
+
 func restGetRunningService(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
 	serviceStateID, err := url.QueryUnescape(r.PathParam("serviceStateId"))
 	if err != nil {
@@ -64,6 +64,8 @@ func (sc *ServiceConfig) getRoutes() []rest.Route {
 		rest.Route{"GET", "/services/:serviceId", gz(sc.authorizedClient(restGetService))},
 		rest.Route{"GET", "/services/:serviceId/running", gz(sc.authorizedClient(restGetRunningForService))},
 		rest.Route{"GET", "/services/:serviceId/status", gz(sc.authorizedClient(restGetStatusForService))},
+		rest.Route{"GET", "/services/:serviceId/health/history", gz(sc.authorizedClient(restGetServiceHealthHistory))},
+		rest.Route{"GET", "/services/:serviceId/config/history", gz(sc.authorizedClient(restGetServiceConfigHistory))},
 		rest.Route{"GET", "/services/:serviceId/running/:serviceStateId", gz(sc.authorizedClient(restGetRunningService))},
 		rest.Route{"GET", "/services/:serviceId/:serviceStateId/logs", gz(sc.authorizedClient(restGetServiceStateLogs))},
 		rest.Route{"GET", "/services/:serviceId/:serviceStateId/logs/download", gz(sc.authorizedClient(downloadServiceStateLogs))},
@@ -71,8 +73,15 @@ func (sc *ServiceConfig) getRoutes() []rest.Route {
 		rest.Route{"POST", "/services/deploy", gz(sc.authorizedClient(restDeployService))},
 		rest.Route{"DELETE", "/services/:serviceId", gz(sc.authorizedClient(restRemoveService))},
 		rest.Route{"GET", "/services/:serviceId/logs", gz(sc.authorizedClient(restGetServiceLogs))},
+		rest.Route{"GET", "/services/:serviceId/logs/search", gz(sc.authorizedClient(restSearchServiceLogs))},
+		rest.Route{"GET", "/logs/retention", gz(sc.authorizedClient(restGetLogRetention))},
+		rest.Route{"PUT", "/logs/retention", gz(sc.authorizedClient(restSetLogRetention))},
+		rest.Route{"POST", "/logs/restore/:day", gz(sc.authorizedClient(restRestoreLogs))},
+		rest.Route{"GET", "/events", gz(sc.authorizedClient(restGetEvents))},
+		rest.Route{"GET", "/events/stream", sc.authorizedClient(restStreamServiceEvents)},
 		rest.Route{"PUT", "/services/:serviceId", gz(sc.authorizedClient(restUpdateService))},
 		rest.Route{"GET", "/services/:serviceId/snapshot", gz(sc.authorizedClient(restSnapshotService))},
+		rest.Route{"GET", "/services/:serviceId/inventory", gz(sc.authorizedClient(restGetImageInventory))},
 		rest.Route{"PUT", "/services/:serviceId/restartService", gz(sc.authorizedClient(restRestartService))},
 		rest.Route{"PUT", "/services/:serviceId/startService", gz(sc.authorizedClient(restStartService))},
 		rest.Route{"PUT", "/services/:serviceId/stopService", gz(sc.authorizedClient(restStopService))},
@@ -90,6 +99,8 @@ func (sc *ServiceConfig) getRoutes() []rest.Route {
 		rest.Route{"GET", "/templates", gz(sc.authorizedClient(restGetAppTemplates))},
 		rest.Route{"POST", "/templates/add", gz(sc.authorizedClient(restAddAppTemplate))},
 		rest.Route{"DELETE", "/templates/:templateId", gz(sc.authorizedClient(restRemoveAppTemplate))},
+		rest.Route{"GET", "/templates/:templateId/history", gz(sc.authorizedClient(restGetAppTemplateHistory))},
+		rest.Route{"POST", "/templates/:templateId/rollback", gz(sc.authorizedClient(restRollbackAppTemplate))},
 		rest.Route{"POST", "/templates/deploy", gz(sc.authorizedClient(restDeployAppTemplate))},
 		rest.Route{"POST", "/templates/deploy/status", gz(sc.authorizedClient(restDeployAppTemplateStatus))},
 		rest.Route{"GET", "/templates/deploy/active", gz(sc.authorizedClient(restDeployAppTemplateActive))},
@@ -63,9 +63,14 @@ var internalCounterStats = []string{
 	"net.tx_dropped", "net.tx_errors", "net.tx_fifo_errors",
 	"net.tx_heartbeat_errors", "net.tx_packets", "net.tx_window_errors",
 	"cgroup.cpuacct.system", "cgroup.cpuacct.user", "cgroup.memory.pgmajfault",
+	"cgroup.memory.failcnt", "cgroup.memory.oomkill", "process.cpu.user", "process.cpu.system",
 }
 var internalGuageStats = []string{
 	"cgroup.memory.totalrss", "cgroup.memory.cache", "net.rx_bytes", "net.rx_compressed",
+	"cgroup.memory.usage", "cgroup.memory.limit", "cgroup.memory.underoom",
+	"process.count", "process.rss", "process.threads", "process.openfiles",
+	"fs.rootfs.total", "fs.rootfs.used", "fs.rootfs.free", "fs.rootfs.inodes.used", "fs.rootfs.inodes.free",
+	"fs.volume.used", "fs.volume.free", "fs.volume.inodes.free",
 }
 
 func removeInternalGraphConfigs(svc *service.Service) {
@@ -298,6 +303,155 @@ func addInternalGraphConfigs(svc *service.Service) {
 			},
 		},
 	)
+
+	// processes graph
+	svc.MonitoringProfile.GraphConfigs = append(
+		svc.MonitoringProfile.GraphConfigs,
+		domain.GraphConfig{
+			ID:          "internalProcesses",
+			Name:        "Processes",
+			BuiltIn:     true,
+			Format:      "%4.0f",
+			ReturnSet:   "EXACT",
+			Type:        "line",
+			Tags:        tags,
+			YAxisLabel:  "count",
+			Description: "Processes, threads and open files of all instances",
+			MinY:        &zero,
+			Range:       &tRange,
+			Units:       "Count",
+			DataPoints: []domain.DataPoint{
+				domain.DataPoint{
+					Aggregator:   "sum",
+					Format:       "%4.0f",
+					Legend:       "Processes",
+					Metric:       "process.count",
+					MetricSource: "metrics",
+					ID:           "process.count",
+					Name:         "Processes",
+					Rate:         false,
+					Type:         "line",
+				},
+				domain.DataPoint{
+					Aggregator:   "sum",
+					Format:       "%4.0f",
+					Legend:       "Threads",
+					Metric:       "process.threads",
+					MetricSource: "metrics",
+					ID:           "process.threads",
+					Name:         "Threads",
+					Rate:         false,
+					Type:         "line",
+				},
+				domain.DataPoint{
+					Aggregator:   "sum",
+					Format:       "%4.0f",
+					Legend:       "Open Files",
+					Metric:       "process.openfiles",
+					MetricSource: "metrics",
+					ID:           "process.openfiles",
+					Name:         "Open Files",
+					Rate:         false,
+					Type:         "line",
+				},
+			},
+		},
+	)
+
+	// disk usage graph
+	svc.MonitoringProfile.GraphConfigs = append(
+		svc.MonitoringProfile.GraphConfigs,
+		domain.GraphConfig{
+			ID:          "internalDiskUsage",
+			Name:        "Disk Usage",
+			BuiltIn:     true,
+			Format:      "%4.2f",
+			ReturnSet:   "EXACT",
+			Type:        "line",
+			Tags:        tags,
+			YAxisLabel:  "bytes",
+			Description: "Space used by the container filesystem and volumes",
+			MinY:        &zero,
+			Range:       &tRange,
+			Units:       "Bytes",
+			Base:        1024,
+			DataPoints: []domain.DataPoint{
+				domain.DataPoint{
+					Aggregator:   "avg",
+					Format:       "%4.2f",
+					Legend:       "Container",
+					Metric:       "fs.rootfs.used",
+					MetricSource: "metrics",
+					ID:           "fs.rootfs.used",
+					Name:         "Container Filesystem",
+					Rate:         false,
+					Type:         "line",
+				},
+				domain.DataPoint{
+					Aggregator:   "avg",
+					Format:       "%4.2f",
+					Legend:       "Volumes",
+					Metric:       "fs.volume.used",
+					MetricSource: "metrics",
+					ID:           "fs.volume.used",
+					Name:         "Volumes",
+					Rate:         false,
+					Type:         "line",
+				},
+			},
+		},
+	)
+
+	// memory pressure graph
+	svc.MonitoringProfile.GraphConfigs = append(
+		svc.MonitoringProfile.GraphConfigs,
+		domain.GraphConfig{
+			ID:          "internalMemoryPressure",
+			Name:        "Memory Pressure",
+			BuiltIn:     true,
+			Format:      "%4.2f",
+			ReturnSet:   "EXACT",
+			Type:        "line",
+			Tags:        tags,
+			YAxisLabel:  "events per second",
+			Description: "Memory limit hits and out of memory kills over last hour",
+			MinY:        &zero,
+			Range:       &tRange,
+			Units:       "Events per second",
+			DataPoints: []domain.DataPoint{
+				domain.DataPoint{
+					Aggregator:   "sum",
+					Format:       "%4.2f",
+					Legend:       "Limit Hits",
+					Metric:       "cgroup.memory.failcnt",
+					MetricSource: "metrics",
+					ID:           "cgroup.memory.failcnt",
+					Name:         "Memory Limit Hits",
+					Rate:         true,
+					RateOptions: &domain.DataPointRateOptions{
+						Counter:        true,
+						ResetThreshold: 1,
+					},
+					Type: "line",
+				},
+				domain.DataPoint{
+					Aggregator:   "sum",
+					Format:       "%4.2f",
+					Legend:       "OOM Kills",
+					Metric:       "cgroup.memory.oomkill",
+					MetricSource: "metrics",
+					ID:           "cgroup.memory.oomkill",
+					Name:         "Out Of Memory Kills",
+					Rate:         true,
+					RateOptions: &domain.DataPointRateOptions{
+						Counter:        true,
+						ResetThreshold: 1,
+					},
+					Type: "line",
+				},
+			},
+		},
+	)
 }
 
 // addInternalMetrics adds internal metrics to the config. It assumes that
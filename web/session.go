@@ -119,6 +119,24 @@ func loginOK(r *rest.Request) bool {
 	return true
 }
 
+/*
+ * Returns the name of the user logged in to the session of the request
+ */
+func sessionUser(r *rest.Request) string {
+	cookie, err := r.Request.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+
+	sessionsLock.RLock()
+	defer sessionsLock.RUnlock()
+	session, err := findsessionT(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return session.User
+}
+
 /*
  * Perform logout, return JSON
  */
@@ -0,0 +1,110 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/node"
+	"github.com/control-center/serviced/stream"
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+)
+
+// keepaliveInterval is how often an idle event stream sends a comment so
+// that proxies do not close it
+var keepaliveInterval = 15 * time.Second
+
+// restStreamServiceEvents streams changes to services, instances and hosts as
+// server-sent events. Query parameters tenant, service and type (comma
+// separated) filter the events. The Last-Event-ID header, or the after
+// parameter, resumes the stream after an earlier event.
+func restStreamServiceEvents(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		restServerError(w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	query := r.URL.Query()
+	request := dao.ServiceEventRequest{
+		Filter: stream.Filter{
+			TenantID:  query.Get("tenant"),
+			ServiceID: query.Get("service"),
+		},
+		Timeout: keepaliveInterval,
+	}
+	if types := query.Get("type"); types != "" {
+		request.Filter.Types = strings.Split(types, ",")
+	}
+	after := r.Header.Get("Last-Event-ID")
+	if after == "" {
+		after = query.Get("after")
+	}
+	if after != "" {
+		var err error
+		if request.After, err = strconv.ParseUint(after, 10, 64); err != nil {
+			restBadRequest(w, fmt.Errorf("invalid event id: %s", after))
+			return
+		}
+	}
+
+	var closed <-chan bool
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		var events []stream.Event
+		if err := client.GetServiceEvents(request, &events); err != nil {
+			glog.Errorf("Could not get service events: %s", err)
+			return
+		}
+
+		var err error
+		if len(events) == 0 {
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		}
+		for _, e := range events {
+			data, _ := json.Marshal(e)
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				break
+			}
+			request.After = e.ID
+		}
+		if err != nil {
+			glog.V(1).Infof("Closing event stream: %s", err)
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-closed:
+			glog.V(1).Infof("Event stream client went away")
+			return
+		default:
+		}
+	}
+}
@@ -253,6 +253,16 @@ func templateLinks(templateID string) []link {
 	}
 }
 
+/*
+ * Provide a list of log retention related API calls.
+ */
+func logRetentionLinks() []link {
+	return []link{
+		link{retrievelink, "GET", "/logs/retention"},
+		link{updatelink, "PUT", "/logs/retention"},
+	}
+}
+
 /*
  * Inform browsers that this call should not be cached. Ever.
  */
@@ -80,6 +80,7 @@ type HostStateListener struct {
 	handler  HostStateHandler
 	hostID   string
 	registry string
+	health   *InstanceHealth
 }
 
 // NewHostListener instantiates a HostListener object
@@ -90,6 +91,10 @@ func NewHostStateListener(handler HostStateHandler, hostID string) *HostStateLis
 	}
 }
 
+// SetInstanceHealth enables restarting instances that fail their health
+// checks, as reported to h
+func (l *HostStateListener) SetInstanceHealth(h *InstanceHealth) { l.health = h }
+
 // GetConnection implements zzk.Listener
 func (l *HostStateListener) SetConnection(conn client.Connection) { l.conn = conn }
 
@@ -144,13 +149,19 @@ func (l *HostStateListener) PostProcess(p map[string]struct{}) {}
 // Spawn listens for changes in the host state and manages running instances
 func (l *HostStateListener) Spawn(shutdown <-chan interface{}, stateID string) {
 	var (
-		processDone <-chan struct{}
-		state       *servicestate.ServiceState
+		processDone   <-chan struct{}
+		state         *servicestate.ServiceState
+		restarts      *restartTracker
+		restartReason string           // why the instance is being restarted; empty for the first start
+		restartWait   <-chan time.Time // set while backing off before a restart
 	)
 
 	hpath := l.GetPath(stateID)
 
 	defer func() {
+		if state != nil && l.health != nil {
+			l.health.Forget(state.ServiceID, state.InstanceID)
+		}
 		if state != nil {
 			glog.V(0).Infof("Stopping service instance: %s", state.ID)
 			l.stopInstance(processDone, state)
@@ -185,14 +196,26 @@ func (l *HostStateListener) Spawn(shutdown <-chan interface{}, stateID string) {
 			return
 		}
 
+		if restarts == nil {
+			restarts = newRestartTracker(svc.RestartPolicy)
+		}
+
 		glog.V(2).Infof("Processing %s (%s); Desired State: %d", svc.Name, svc.ID, hs.DesiredState)
 		switch service.DesiredState(hs.DesiredState) {
 		case service.SVCRun:
 			var err error
-			if !state.IsRunning() {
+			if state.Quarantined {
+				// held until the instance is stopped or restarted
+			} else if !state.IsRunning() {
 				// process has stopped
-				glog.Infof("Starting a new instance for %s", state.ID)
-				processDone, err = l.startInstance(&svc, state)
+				if restartReason != "" && restartWait == nil {
+					restartWait, err = l.restartInstance(state, restarts, restartReason)
+					restartReason = ""
+				}
+				if err == nil && restartWait == nil && !state.Quarantined {
+					glog.Infof("Starting a new instance for %s", state.ID)
+					processDone, err = l.startInstance(&svc, state)
+				}
 			} else if processDone == nil {
 				glog.Infof("Attaching to instance %s via %s", state.ID, state.DockerID)
 				processDone, err = l.attachInstance(&svc, state)
@@ -223,9 +246,27 @@ func (l *HostStateListener) Spawn(shutdown <-chan interface{}, stateID string) {
 			glog.V(2).Infof("Unhandled service %s (%s)", svc.Name, svc.ID)
 		}
 
+		var unhealthy <-chan string
+		if l.health != nil && svc.RestartPolicy != nil && svc.RestartPolicy.FailureThreshold > 0 && state.IsRunning() {
+			unhealthy = l.health.Watch(svc.ID, state.InstanceID, svc.RestartPolicy.FailureThreshold)
+		}
+
 		select {
 		case <-processDone:
 			glog.V(2).Infof("Process ended for instance: ", hs.ServiceStateID)
+			processDone = nil
+			if restartReason == "" {
+				restartReason = "exited"
+			}
+		case reason := <-unhealthy:
+			glog.Warningf("Restarting instance %s of %s (%s): %s", state.ID, svc.Name, svc.ID, reason)
+			if err := l.handler.StopService(state); err != nil {
+				glog.Errorf("Could not stop unhealthy instance %s: %s", state.ID, err)
+				return
+			}
+			restartReason = reason
+		case <-restartWait:
+			restartWait = nil
 		case e := <-event:
 			glog.V(3).Info("Receieved event: ", e)
 			if e.Type == client.EventNodeDeleted {
@@ -238,6 +279,36 @@ func (l *HostStateListener) Spawn(shutdown <-chan interface{}, stateID string) {
 	}
 }
 
+// restartInstance applies the restart policy to an instance that stopped.
+// It returns a channel to wait on before starting the instance again, or
+// quarantines the instance if it has restarted too often.
+func (l *HostStateListener) restartInstance(state *servicestate.ServiceState, restarts *restartTracker, reason string) (<-chan time.Time, error) {
+	if l.health != nil {
+		l.health.Forget(state.ServiceID, state.InstanceID)
+	}
+	delay, quarantine := restarts.next(time.Now())
+	if quarantine {
+		glog.Warningf("Quarantining service instance %s after %d restarts in %s: %s", state.ID, len(restarts.restarts)-1, restarts.policy.Window(), reason)
+		state.Quarantined = true
+		state.Reason = fmt.Sprintf("quarantined after %d restarts in %s: %s", len(restarts.restarts)-1, restarts.policy.Window(), reason)
+		return nil, UpdateServiceState(l.conn, state)
+	}
+
+	state.Restarts++
+	state.Reason = reason
+	if restarts.crashLooping() {
+		state.Reason = fmt.Sprintf("crash loop, %d restarts in %s: %s", len(restarts.restarts), restarts.policy.Window(), reason)
+	}
+	if err := UpdateServiceState(l.conn, state); err != nil {
+		return nil, err
+	}
+	if delay <= 0 {
+		return nil, nil
+	}
+	glog.Infof("Restarting service instance %s in %s (%s)", state.ID, delay, state.Reason)
+	return time.After(delay), nil
+}
+
 func (l *HostStateListener) startInstance(svc *service.Service, state *servicestate.ServiceState) (<-chan struct{}, error) {
 	done := make(chan struct{})
 	serviceID := svc.ID
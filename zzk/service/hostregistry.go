@@ -15,10 +15,12 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"path"
 
 	"github.com/control-center/serviced/coordinator/client"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/stream"
 	"github.com/control-center/serviced/zzk"
 	"github.com/zenoss/glog"
 )
@@ -106,6 +108,7 @@ func (l *HostRegistryListener) PostProcess(p map[string]struct{}) {}
 
 // Spawn listens on the host registry and waits til the node is deleted to unregister
 func (l *HostRegistryListener) Spawn(shutdown <-chan interface{}, eHostID string) {
+	joined := false
 	for {
 		var host host.Host
 		event, err := l.conn.GetW(l.GetPath(eHostID), &HostNode{Host: &host})
@@ -113,12 +116,17 @@ func (l *HostRegistryListener) Spawn(shutdown <-chan interface{}, eHostID string
 			glog.Errorf("Could not load ephemeral node %s: %s", eHostID, err)
 			return
 		}
+		if !joined {
+			stream.Publish(stream.Event{Type: stream.HostJoined, HostID: host.ID, Summary: fmt.Sprintf("host %s (%s) joined pool %s", host.Name, host.ID, host.PoolID)})
+			joined = true
+		}
 
 		select {
 		case e := <-event:
 			if e.Type == client.EventNodeDeleted {
 				glog.V(1).Info("Unregistering host: ", host.ID)
 				l.unregister(host.ID)
+				stream.Publish(stream.Event{Type: stream.HostLeft, HostID: host.ID, Summary: fmt.Sprintf("host %s (%s) left pool %s", host.Name, host.ID, host.PoolID)})
 				return
 			}
 			glog.V(2).Infof("Receieved event: ", e)
@@ -0,0 +1,41 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Scheduler decisions about a service instance
+const (
+	decisionStarted = "started" // the instance was assigned to a host
+	decisionLocked  = "locked"  // not started because services are locked
+	decisionNoHost  = "no_host" // no host could run the instance
+	decisionFailed  = "failed"  // the instance could not be added to the host
+	decisionStopped = "stopped" // the instance was told to stop
+	decisionPaused  = "paused"  // the instance was told to pause
+)
+
+// countDecision records a scheduler decision in metrics.DefaultRegistry
+func countDecision(poolID, decision string) {
+	metrics.GetOrRegisterCounter(fmt.Sprintf("scheduler_decisions_total{pool=%q,decision=%q}", poolID, decision), metrics.DefaultRegistry).Inc(1)
+}
+
+// timeHostSelection records how long it took to choose a host
+func timeHostSelection(poolID string, start time.Time) {
+	metrics.GetOrRegisterTimer(fmt.Sprintf("scheduler_host_selection_duration_seconds{pool=%q}", poolID), metrics.DefaultRegistry).UpdateSince(start)
+}
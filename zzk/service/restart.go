@@ -0,0 +1,128 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+// InstanceHealth counts the consecutive failures of the health checks of the
+// service instances on a host, and tells the host state listener when an
+// instance fails a check too many times in a row.
+type InstanceHealth struct {
+	sync.Mutex
+	failures map[string]map[string]int // instance -> check -> consecutive failures
+	watches  map[string]*healthWatch
+}
+
+type healthWatch struct {
+	threshold int
+	unhealthy chan string
+}
+
+// NewInstanceHealth instantiates an InstanceHealth
+func NewInstanceHealth() *InstanceHealth {
+	return &InstanceHealth{
+		failures: make(map[string]map[string]int),
+		watches:  make(map[string]*healthWatch),
+	}
+}
+
+func instanceKey(serviceID, instanceID string) string {
+	return serviceID + "/" + instanceID
+}
+
+// Report records the result of a health check of an instance
+func (h *InstanceHealth) Report(serviceID, instanceID, check, status string) {
+	h.Lock()
+	defer h.Unlock()
+	key := instanceKey(serviceID, instanceID)
+	checks, ok := h.failures[key]
+	if !ok {
+		checks = make(map[string]int)
+		h.failures[key] = checks
+	}
+	if status != "failed" {
+		delete(checks, check)
+		return
+	}
+	checks[check]++
+	if w, ok := h.watches[key]; ok && checks[check] >= w.threshold {
+		select {
+		case w.unhealthy <- fmt.Sprintf("health check %s failed %d times", check, checks[check]):
+		default:
+		}
+	}
+}
+
+// Watch returns a channel that receives the reason once any check of an
+// instance fails threshold times in a row. It replaces any earlier watch of
+// the instance.
+func (h *InstanceHealth) Watch(serviceID string, instanceID, threshold int) <-chan string {
+	h.Lock()
+	defer h.Unlock()
+	w := &healthWatch{threshold: threshold, unhealthy: make(chan string, 1)}
+	h.watches[instanceKey(serviceID, strconv.Itoa(instanceID))] = w
+	return w.unhealthy
+}
+
+// Forget clears the failures and watch of an instance
+func (h *InstanceHealth) Forget(serviceID string, instanceID int) {
+	h.Lock()
+	defer h.Unlock()
+	key := instanceKey(serviceID, strconv.Itoa(instanceID))
+	delete(h.failures, key)
+	delete(h.watches, key)
+}
+
+// restartTracker applies a restart policy to the restarts of an instance
+type restartTracker struct {
+	policy   domain.RestartPolicy
+	restarts []time.Time
+}
+
+func newRestartTracker(policy *domain.RestartPolicy) *restartTracker {
+	if policy == nil {
+		return &restartTracker{policy: domain.DefaultRestartPolicy}
+	}
+	return &restartTracker{policy: *policy}
+}
+
+// next records a restart at now and returns the delay before it, or whether
+// the instance has restarted too often and should be quarantined instead
+func (t *restartTracker) next(now time.Time) (time.Duration, bool) {
+	window := t.policy.Window()
+	recent := t.restarts[:0]
+	for _, at := range t.restarts {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+	t.restarts = append(recent, now)
+	if t.policy.MaxRestarts > 0 && len(t.restarts) > t.policy.MaxRestarts {
+		return 0, true
+	}
+	return t.policy.Backoff(len(t.restarts)), false
+}
+
+// crashLooping is true while the instance has restarted more than once in the
+// window
+func (t *restartTracker) crashLooping() bool {
+	return len(t.restarts) > 1
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+func TestInstanceHealth(t *testing.T) {
+	health := NewInstanceHealth()
+	unhealthy := health.Watch("svc", 0, 2)
+
+	health.Report("svc", "0", "http", "failed")
+	health.Report("svc", "0", "http", "passed")
+	health.Report("svc", "0", "http", "failed")
+	health.Report("svc", "1", "http", "failed")
+	health.Report("svc", "1", "http", "failed")
+	select {
+	case reason := <-unhealthy:
+		t.Fatalf("unexpected restart: %s", reason)
+	default:
+	}
+
+	health.Report("svc", "0", "http", "failed")
+	select {
+	case reason := <-unhealthy:
+		if reason != "health check http failed 2 times" {
+			t.Errorf("unexpected reason: %s", reason)
+		}
+	default:
+		t.Fatalf("expected instance 0 to be unhealthy")
+	}
+
+	health.Forget("svc", 0)
+	health.Report("svc", "0", "http", "failed")
+	health.Report("svc", "0", "http", "failed")
+	select {
+	case reason := <-unhealthy:
+		t.Fatalf("unexpected restart after forgetting the instance: %s", reason)
+	default:
+	}
+}
+
+func TestRestartTracker(t *testing.T) {
+	tracker := newRestartTracker(&domain.RestartPolicy{BackoffSeconds: 10, MaxBackoffSeconds: 30, MaxRestarts: 4, WindowSeconds: 600})
+	now := time.Now()
+	for i, expected := range []time.Duration{0, 10 * time.Second, 20 * time.Second, 30 * time.Second} {
+		delay, quarantine := tracker.next(now.Add(time.Duration(i) * time.Minute))
+		if quarantine || delay != expected {
+			t.Errorf("restart %d: expected a delay of %s, got %s (quarantine %v)", i+1, expected, delay, quarantine)
+		}
+	}
+	if !tracker.crashLooping() {
+		t.Errorf("expected a crash loop")
+	}
+	if _, quarantine := tracker.next(now.Add(5 * time.Minute)); !quarantine {
+		t.Errorf("expected the fifth restart in the window to quarantine the instance")
+	}
+
+	// restarts outside the window are forgotten
+	tracker = newRestartTracker(nil)
+	tracker.next(now)
+	if delay, quarantine := tracker.next(now.Add(time.Hour)); quarantine || delay != 0 || tracker.crashLooping() {
+		t.Errorf("expected an immediate restart, got %s (quarantine %v)", delay, quarantine)
+	}
+}
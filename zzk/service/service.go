@@ -107,8 +107,13 @@ func (l *ServiceListener) PostProcess(p map[string]struct{}) {}
 
 // Spawn watches a service and syncs the number of running instances
 func (l *ServiceListener) Spawn(shutdown <-chan interface{}, serviceID string) {
+	var changes serviceChanges
+	stopWatchingStarts := func() {}
+	defer func() { stopWatchingStarts() }()
+
 	for {
 		var retry <-chan time.Time
+		stopWatchingStarts()
 
 		var lockEvent <-chan client.Event
 		if exists, err := zzk.PathExists(l.conn, zkServiceLock); err != nil {
@@ -160,6 +165,10 @@ func (l *ServiceListener) Spawn(shutdown <-chan interface{}, serviceID string) {
 			}
 		}
 
+		changes.update(&svc, rss)
+		var startEvent <-chan client.Event
+		startEvent, stopWatchingStarts = watchStarts(l.conn, rss)
+
 		glog.V(2).Infof("Service %s (%s) waiting for event", svc.Name, svc.ID)
 
 		select {
@@ -180,6 +189,8 @@ func (l *ServiceListener) Spawn(shutdown <-chan interface{}, serviceID string) {
 				return
 			}
 			glog.V(2).Infof("Service %s (%s) received event: %v", svc.Name, svc.ID, e)
+		case e := <-startEvent:
+			glog.V(2).Infof("Instance of service %s (%s) received event: %v", svc.Name, svc.ID, e)
 		case <-retry:
 			glog.Infof("Re-syncing service %s (%s)", svc.Name, svc.ID)
 		case <-shutdown:
@@ -302,14 +313,18 @@ func (l *ServiceListener) start(svc *service.Service, instanceIDs []int) int {
 				return false
 			} else if locked {
 				glog.Warningf("Could not start instance %d; service %s (%s) is locked", instanceID, svc.Name, svc.ID)
+				countDecision(svc.PoolID, decisionLocked)
 				return false
 			}
 
 			glog.V(2).Infof("Service is not locked, selecting a host for service %s (%s) #%d", svc.Name, svc.ID, id)
 
+			selecting := time.Now()
 			host, err := l.handler.SelectHost(svc)
+			timeHostSelection(svc.PoolID, selecting)
 			if err != nil {
 				glog.Warningf("Could not assign a host to service %s (%s): %s", svc.Name, svc.ID, err)
+				countDecision(svc.PoolID, decisionNoHost)
 				return false
 			}
 
@@ -318,6 +333,7 @@ func (l *ServiceListener) start(svc *service.Service, instanceIDs []int) int {
 			state, err := servicestate.BuildFromService(svc, host.ID)
 			if err != nil {
 				glog.Warningf("Error creating service state for service %s (%s): %s", svc.Name, svc.ID, err)
+				countDecision(svc.PoolID, decisionFailed)
 				return false
 			}
 
@@ -325,9 +341,11 @@ func (l *ServiceListener) start(svc *service.Service, instanceIDs []int) int {
 			state.InstanceID = instanceID
 			if err := addInstance(l.conn, state); err != nil {
 				glog.Warningf("Could not add service instance %s for service %s (%s): %s", state.ID, svc.Name, svc.ID, err)
+				countDecision(svc.PoolID, decisionFailed)
 				return false
 			}
 			glog.V(2).Infof("Starting service instance %s for service %s (%s) on host %s", state.ID, svc.Name, svc.ID, host.ID)
+			countDecision(svc.PoolID, decisionStarted)
 			return true
 		}(id); !success {
 			// 'i' is the index of the unsuccessful instance id which should portray
@@ -349,6 +367,7 @@ func (l *ServiceListener) stop(rss []dao.RunningService) {
 			continue
 		}
 		glog.V(2).Infof("Stopping service instance %s (%s) for service %s on host %s", state.ID, state.Name, state.ServiceID, state.HostID)
+		countDecision(state.PoolID, decisionStopped)
 	}
 }
 
@@ -360,6 +379,7 @@ func (l *ServiceListener) pause(rss []dao.RunningService) {
 			continue
 		}
 		glog.V(2).Infof("Pausing service instance %s (%s) for service %s on host %s", state.ID, state.Name, state.ServiceID, state.HostID)
+		countDecision(state.PoolID, decisionPaused)
 	}
 }
 
@@ -185,5 +185,9 @@ func getStatus(conn client.Connection, state *servicestate.ServiceState) (dao.St
 		return dao.Status{}, ErrUnknownState
 	}
 
+	if state.Quarantined && status == dao.Starting {
+		status = dao.Quarantined
+	}
+
 	return status, nil
 }
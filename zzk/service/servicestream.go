@@ -0,0 +1,104 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/control-center/serviced/coordinator/client"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/stream"
+	"github.com/zenoss/glog"
+)
+
+// serviceChanges publishes the changes to the desired state and the
+// instances of a service seen by its listener
+type serviceChanges struct {
+	loaded       bool
+	desiredState int
+	instances    map[string]dao.RunningService
+}
+
+// update publishes what changed since the last time the service was loaded
+func (c *serviceChanges) update(svc *service.Service, rss []dao.RunningService) {
+	publish := func(eventType string, rs *dao.RunningService, summary string, args ...interface{}) {
+		e := stream.Event{Type: eventType, ServiceID: svc.ID, Summary: fmt.Sprintf(summary, args...)}
+		if rs != nil {
+			e.InstanceID = strconv.Itoa(rs.InstanceID)
+			e.HostID = rs.HostID
+		}
+		stream.Publish(e)
+	}
+
+	instances := make(map[string]dao.RunningService)
+	for _, rs := range rss {
+		instances[rs.ID] = rs
+		if !c.loaded {
+			continue
+		}
+		last, ok := c.instances[rs.ID]
+		if !ok {
+			publish(stream.InstanceScheduled, &rs, "instance %d of %s scheduled on host %s", rs.InstanceID, svc.Name, rs.HostID)
+		}
+		if !rs.StartedAt.IsZero() && (!ok || last.StartedAt.IsZero()) {
+			publish(stream.InstanceStarted, &rs, "instance %d of %s started on host %s", rs.InstanceID, svc.Name, rs.HostID)
+		}
+	}
+
+	if c.loaded {
+		for id, rs := range c.instances {
+			if _, ok := instances[id]; !ok {
+				publish(stream.InstanceStopped, &rs, "instance %d of %s stopped on host %s", rs.InstanceID, svc.Name, rs.HostID)
+			}
+		}
+		if c.desiredState != svc.DesiredState {
+			publish(stream.ServiceDesiredState, nil, "service %s desired state changed to %s", svc.Name, service.DesiredState(svc.DesiredState))
+		}
+	}
+
+	c.loaded = true
+	c.desiredState = svc.DesiredState
+	c.instances = instances
+}
+
+// watchStarts watches the instances that have not started yet. The returned
+// channel receives an event when one of them changes, until stop is called.
+func watchStarts(conn client.Connection, rss []dao.RunningService) (<-chan client.Event, func()) {
+	events := make(chan client.Event, 1)
+	done := make(chan struct{})
+	for _, rs := range rss {
+		if !rs.StartedAt.IsZero() {
+			continue
+		}
+		event, err := conn.GetW(servicepath(rs.ServiceID, rs.ID), &ServiceStateNode{ServiceState: &servicestate.ServiceState{}})
+		if err != nil {
+			glog.V(2).Infof("Could not watch instance %s of service %s: %s", rs.ID, rs.ServiceID, err)
+			continue
+		}
+		go func() {
+			select {
+			case e := <-event:
+				select {
+				case events <- e:
+				default:
+				}
+			case <-done:
+			}
+		}()
+	}
+	return events, func() { close(done) }
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 The Serviced Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/stream"
+)
+
+func TestServiceChanges(t *testing.T) {
+	defer func(broker *stream.Broker) { stream.Default = broker }(stream.Default)
+	reset := func() {
+		stream.Default = stream.NewBroker(stream.DefaultBufferSize)
+		stream.Default.SetTenantResolver(func(string) string { return "tenant" })
+	}
+	reset()
+
+	svc := &service.Service{ID: "svc", Name: "svc", DesiredState: int(service.SVCStop)}
+	changes := serviceChanges{}
+	expect := func(types ...string) {
+		events := stream.Default.Wait(0, stream.Filter{}, time.Millisecond)
+		if len(events) != len(types) {
+			t.Fatalf("expected events %v, got %+v", types, events)
+		}
+		for i, e := range events {
+			if e.Type != types[i] || e.ServiceID != "svc" || e.TenantID != "tenant" {
+				t.Errorf("expected a %s event, got %+v", types[i], e)
+			}
+		}
+		reset()
+	}
+
+	// the first load only records the state of the service
+	running := dao.RunningService{ID: "a", ServiceID: "svc", HostID: "host", InstanceID: 0, StartedAt: time.Now()}
+	changes.update(svc, []dao.RunningService{running})
+	expect()
+
+	svc.DesiredState = int(service.SVCRun)
+	scheduled := dao.RunningService{ID: "b", ServiceID: "svc", HostID: "host", InstanceID: 1}
+	changes.update(svc, []dao.RunningService{running, scheduled})
+	expect(stream.InstanceScheduled, stream.ServiceDesiredState)
+
+	scheduled.StartedAt = time.Now()
+	changes.update(svc, []dao.RunningService{scheduled})
+	expect(stream.InstanceStarted, stream.InstanceStopped)
+
+	changes.update(svc, []dao.RunningService{scheduled})
+	expect()
+}